@@ -2,14 +2,18 @@ package main
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -23,8 +27,21 @@ type Message struct {
 	Content string `json:"content,omitempty"`
 	Session int    `json:"session,omitempty"`
 	Index   int    `json:"index,omitempty"` // option index for "select" type
+	Data    string `json:"data,omitempty"`  // base64 PTY bytes for "pty_output"/"pty_input"
+	Cols    uint16 `json:"cols,omitempty"`  // terminal width for "pty_resize"
+	Rows    uint16 `json:"rows,omitempty"`  // terminal height for "pty_resize"
 }
 
+// ptyOutputChunkSize and ptyOutputCoalesceWindow bound how often we flush PTY
+// output to the coordinator: up to ~16 KiB per "pty_output" frame, or every
+// 20ms, whichever comes first, so a remote xterm.js viewer stays responsive
+// without flooding the WebSocket during a fast `cat` or syntax-highlighted
+// redraw.
+const (
+	ptyOutputChunkSize      = 16 * 1024
+	ptyOutputCoalesceWindow = 20 * time.Millisecond
+)
+
 func main() {
 	wsURL := flag.String("ws", "ws://localhost:53937/ws", "coordinator WebSocket URL")
 	name := flag.String("name", defaultName(), "device name for registration")
@@ -54,7 +71,7 @@ func main() {
 	session := quickRegister(*wsURL, *name)
 	if session > 0 {
 		os.Setenv("CC_SESSION", strconv.Itoa(session))
-		fmt.Fprintf(os.Stdout, "\x1b]2;CC #%d\x07", session)
+		setTitle(session)
 	}
 
 	// Create PTY and spawn the command.
@@ -67,17 +84,44 @@ func main() {
 	defer p.Close()
 
 	// Put host terminal in raw mode so key-by-key input works.
-	restoreFn := enableRawMode()
-	defer restoreFn()
+	guard := newRawModeGuard()
+	defer guard.disable()
 
-	// PTY stdout -> host stdout
-	go func() { io.Copy(os.Stdout, p) }()
+	// Resize the PTY to the host terminal now and on every SIGWINCH so
+	// full-screen TUIs redraw correctly; suspend/resume (Ctrl-Z) cleanly via
+	// SIGTSTP/SIGCONT instead of corrupting the terminal. Both are no-ops on
+	// platforms without job control (Windows).
+	go watchResize(p)
+	go watchSuspend(guard, session)
+
+	// PTY stdout -> host stdout, teed into the pty_output hub so a connected
+	// coordinator can fan it out to a remote xterm.js viewer, and into the
+	// select ring buffer so injectSelect can read the TUI's own highlight
+	// state back instead of navigating blind.
+	ptyOut := newPTYOutputHub()
+	selectRing := &selectRingBuffer{}
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := p.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				os.Stdout.Write(chunk)
+				ptyOut.write(chunk)
+				selectRing.write(chunk)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
 
 	// Host stdin -> PTY stdin
 	go func() { io.Copy(p, os.Stdin) }()
 
 	// WebSocket client: connect, register, receive text -> write to PTY
-	go wsLoop(*wsURL, *name, p)
+	go wsLoop(*wsURL, *name, session, p, ptyOut, selectRing)
 
 	// Forward Ctrl-C to PTY instead of killing wrapper
 	sigCh := make(chan os.Signal, 1)
@@ -90,10 +134,48 @@ func main() {
 
 	// Wait for child to exit
 	code := p.Wait()
-	restoreFn()
+	guard.disable()
 	os.Exit(code)
 }
 
+// rawModeGuard tracks the current raw-mode restore function so it can be
+// re-entered after a SIGTSTP/SIGCONT suspend cycle without main() having to
+// juggle a reassignable closure across goroutines.
+type rawModeGuard struct {
+	mu      sync.Mutex
+	restore func()
+}
+
+func newRawModeGuard() *rawModeGuard {
+	g := &rawModeGuard{}
+	g.enable()
+	return g
+}
+
+func (g *rawModeGuard) enable() {
+	fn := enableRawMode()
+	g.mu.Lock()
+	g.restore = fn
+	g.mu.Unlock()
+}
+
+func (g *rawModeGuard) disable() {
+	g.mu.Lock()
+	fn := g.restore
+	g.mu.Unlock()
+	if fn != nil {
+		fn()
+	}
+}
+
+// setTitle reasserts the terminal title after a resume, since some terminal
+// emulators clear it while the process is stopped.
+func setTitle(session int) {
+	if session > 0 {
+		fmt.Fprintf(os.Stdout, "\x1b]2;CC #%d\x07", session)
+	}
+}
+
 func defaultName() string {
 	host, _ := os.Hostname()
 	if host == "" {
@@ -133,14 +215,75 @@ func quickRegister(url, name string) int {
 	return msg.Session
 }
 
-func wsLoop(url, name string, p ptyHandle) {
+// Reconnect backoff: wsLoop redials with exponential backoff (base
+// wsReconnectBaseDelay, ×wsReconnectFactor per consecutive failure, capped at
+// wsReconnectMaxDelay) plus ±wsReconnectJitterPct jitter so a coordinator
+// restart doesn't get hammered by every cc-wrapper reconnecting in lockstep.
+// The backoff resets to base once a connection has stayed up for at least
+// wsHealthyConnDuration.
+const (
+	wsReconnectBaseDelay  = 500 * time.Millisecond
+	wsReconnectMaxDelay   = 30 * time.Second
+	wsReconnectFactor     = 1.7
+	wsReconnectJitterPct  = 0.25
+	wsHealthyConnDuration = 30 * time.Second
+
+	// wsPingInterval/wsPongTimeout detect half-open TCP connections (common
+	// after laptop sleep/wake over Tailscale) that would otherwise leave
+	// ReadJSON blocked for minutes with no indication anything is wrong.
+	wsPingInterval = 15 * time.Second
+	wsPongTimeout  = 5 * time.Second
+)
+
+func wsLoop(url, name string, session int, p ptyHandle, ptyOut *ptyOutputHub, selectRing *selectRingBuffer) {
+	delay := time.Duration(wsReconnectBaseDelay)
+	attempt := 0
 	for {
-		connectAndServe(url, name, p)
-		time.Sleep(5 * time.Second)
+		attempt++
+		connectedAt := time.Now()
+		connectAndServe(url, name, session, p, ptyOut, selectRing)
+
+		if time.Since(connectedAt) >= wsHealthyConnDuration {
+			delay = wsReconnectBaseDelay
+			attempt = 0
+		}
+
+		setReconnectingTitle(session, attempt)
+		time.Sleep(jittered(delay))
+
+		delay = time.Duration(float64(delay) * wsReconnectFactor)
+		if delay > wsReconnectMaxDelay {
+			delay = wsReconnectMaxDelay
+		}
 	}
 }
 
-func connectAndServe(url, name string, p ptyHandle) {
+// jittered returns d adjusted by a random offset within
+// ±wsReconnectJitterPct, so that many cc-wrapper instances backing off at
+// once don't all redial in the same instant.
+func jittered(d time.Duration) time.Duration {
+	spread := int64(float64(d) * wsReconnectJitterPct)
+	if spread <= 0 {
+		return d
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(2*spread+1))
+	if err != nil {
+		return d
+	}
+	return d + time.Duration(n.Int64()-spread)
+}
+
+// setReconnectingTitle marks the PTY title as reconnecting so the user can
+// tell the wrapper is still alive between attempts; setTitle (called from
+// the "registered" case below) restores the normal title once reconnected.
+func setReconnectingTitle(session, attempt int) {
+	if session > 0 {
+		fmt.Fprintf(os.Stdout, "\x1b]2;CC #%d (reconnecting…)\x07", session)
+	}
+	log.Printf("[ws] reconnecting (attempt %d)", attempt)
+}
+
+func connectAndServe(url, name string, session int, p ptyHandle, ptyOut *ptyOutputHub, selectRing *selectRingBuffer) {
 	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
 		log.Printf("[ws] connect failed: %v", err)
@@ -156,6 +299,12 @@ func connectAndServe(url, name string, p ptyHandle) {
 	}
 	log.Printf("[ws] registered as %s", name)
 
+	ptyOut.setConn(conn)
+	defer ptyOut.setConn(nil)
+
+	stopPing := startHeartbeat(conn)
+	defer stopPing()
+
 	for {
 		var msg Message
 		if err := conn.ReadJSON(&msg); err != nil {
@@ -166,6 +315,7 @@ func connectAndServe(url, name string, p ptyHandle) {
 		switch msg.Type {
 		case "registered":
 			log.Printf("[ws] confirmed: %s (session=%d)", msg.Name, msg.Session)
+			setTitle(session)
 		case "text":
 			if msg.Content == "" {
 				continue
@@ -177,11 +327,118 @@ func connectAndServe(url, name string, p ptyHandle) {
 			// If Content is set, it's an "Other" response: navigate to last
 			// option (index), press Enter to select "Other", then type the text.
 			log.Printf("[ws] selecting option index=%d content=%q", msg.Index, msg.Content)
-			injectSelect(p, msg.Index, msg.Content)
+			injectSelect(p, selectRing, msg.Index, msg.Content)
+		case "pty_input":
+			data, err := base64.StdEncoding.DecodeString(msg.Data)
+			if err != nil {
+				log.Printf("[ws] bad pty_input payload: %v", err)
+				continue
+			}
+			p.Write(data)
+		case "pty_resize":
+			if msg.Cols == 0 || msg.Rows == 0 {
+				continue
+			}
+			log.Printf("[ws] remote resize: cols=%d rows=%d", msg.Cols, msg.Rows)
+			if err := p.Resize(msg.Cols, msg.Rows); err != nil {
+				log.Printf("[pty] remote resize failed: %v", err)
+			}
 		}
 	}
 }
 
+// startHeartbeat sends a WebSocket ping every wsPingInterval and extends
+// conn's read deadline each time a pong comes back. If a pong is missed, the
+// deadline lapses and the blocked ReadJSON in connectAndServe's loop fails,
+// which triggers a reconnect — catching a half-open connection without
+// waiting for a TCP-level timeout. Returns a func to stop the ping ticker
+// when the connection is torn down.
+func startHeartbeat(conn *websocket.Conn) func() {
+	conn.SetReadDeadline(time.Now().Add(wsPingInterval + wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(wsPingInterval + wsPongTimeout))
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsPongTimeout)); err != nil {
+					log.Printf("[ws] ping failed: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// ptyOutputHub coalesces PTY output into ~ptyOutputCoalesceWindow-spaced
+// "pty_output" frames (capped at ptyOutputChunkSize) and forwards them to
+// whichever coordinator WebSocket connection is currently live. Output is
+// dropped while disconnected — the host terminal already has it via the
+// stdout tee, and there's no replay buffer for a reconnecting viewer.
+type ptyOutputHub struct {
+	mu   sync.Mutex
+	buf  []byte
+	conn *websocket.Conn
+}
+
+func newPTYOutputHub() *ptyOutputHub {
+	h := &ptyOutputHub{}
+	go h.flushLoop()
+	return h
+}
+
+func (h *ptyOutputHub) write(p []byte) {
+	h.mu.Lock()
+	h.buf = append(h.buf, p...)
+	full := len(h.buf) >= ptyOutputChunkSize
+	h.mu.Unlock()
+
+	if full {
+		h.flush()
+	}
+}
+
+func (h *ptyOutputHub) setConn(conn *websocket.Conn) {
+	h.mu.Lock()
+	h.conn = conn
+	h.mu.Unlock()
+}
+
+func (h *ptyOutputHub) flushLoop() {
+	ticker := time.NewTicker(ptyOutputCoalesceWindow)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.flush()
+	}
+}
+
+func (h *ptyOutputHub) flush() {
+	h.mu.Lock()
+	conn := h.conn
+	chunk := h.buf
+	h.buf = nil
+	h.mu.Unlock()
+
+	if conn == nil || len(chunk) == 0 {
+		return
+	}
+
+	msg := Message{Type: "pty_output", Data: base64.StdEncoding.EncodeToString(chunk)}
+	if err := conn.WriteJSON(msg); err != nil {
+		log.Printf("[ws] pty_output send failed: %v", err)
+	}
+}
+
 // enableRawMode puts stdin into raw mode and returns a restore function.
 func enableRawMode() func() {
 	restoreFn, err := setRawTerminal()
@@ -206,29 +463,195 @@ func injectText(p ptyHandle, text string) {
 	p.Write([]byte{'\r'})
 }
 
-// injectSelect navigates an AskUserQuestion TUI picker.
-// It sends `index` down-arrow presses to reach the desired option, then Enter.
-// If `otherText` is non-empty, the selected option is "Other" — after pressing
-// Enter on it, we type the custom text and press Enter again.
-func injectSelect(p ptyHandle, index int, otherText string) {
-	downArrow := []byte("\x1b[B") // ANSI escape: cursor down
-	for i := 0; i < index; i++ {
-		p.Write(downArrow)
-		time.Sleep(30 * time.Millisecond)
+var (
+	upArrow   = []byte("\x1b[A") // ANSI escape: cursor up
+	downArrow = []byte("\x1b[B") // ANSI escape: cursor down
+)
+
+const (
+	sgrReverseOn  = "\x1b[7m"
+	sgrReverseOff = "\x1b[27m"
+
+	// askQuestionMarker is the line Claude Code's AskUserQuestion TUI renders
+	// above its option list. It anchors the scan to the most recent question
+	// frame so reverse video left over from an earlier, unrelated redraw
+	// can't be mistaken for the current highlight.
+	askQuestionMarker = "Select an option"
+
+	// selectFrameTimeout bounds how long injectSelect waits for the
+	// AskUserQuestion frame to appear in the ring buffer at all before
+	// giving up on TUI-aware navigation and falling back to the old fixed
+	// timed path (e.g. if a future TUI redesign changes its rendering).
+	selectFrameTimeout = 500 * time.Millisecond
+	// selectHighlightTimeout bounds how long injectSelect waits, after each
+	// arrow keystroke, for the TUI's highlight to actually move before
+	// sending the next one.
+	selectHighlightTimeout = 150 * time.Millisecond
+	selectPollInterval     = 10 * time.Millisecond
+)
+
+// selectRingBuffer retains recent PTY output so injectSelect can read the
+// TUI's own rendering back and navigate by the actual highlight position,
+// rather than guessing with fixed sleeps against an assumed starting index.
+// It's separate from ptyOutputHub, which only fans out to a connected
+// coordinator and drops output while disconnected — injectSelect needs to
+// read synchronously regardless of connection state.
+type selectRingBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const selectRingBufferCap = 64 * 1024
+
+func (r *selectRingBuffer) write(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf = append(r.buf, p...)
+	if over := len(r.buf) - selectRingBufferCap; over > 0 {
+		r.buf = r.buf[over:]
+	}
+}
+
+func (r *selectRingBuffer) snapshot() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]byte, len(r.buf))
+	copy(out, r.buf)
+	return out
+}
+
+// highlightedOptionIndex scans buf for the most recent AskUserQuestion frame
+// and returns the 0-based index, among that frame's option lines, of the one
+// currently wrapped in SGR reverse video. ok is false if no frame or
+// highlight is found.
+func highlightedOptionIndex(buf []byte) (index int, ok bool) {
+	s := string(buf)
+	start := strings.LastIndex(s, askQuestionMarker)
+	if start < 0 {
+		return 0, false
+	}
+
+	lines := strings.Split(s[start:], "\n")
+	opt := -1
+	for _, line := range lines[1:] { // skip the marker line itself
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		opt++
+		if strings.Contains(line, sgrReverseOn) {
+			return opt, true
+		}
+	}
+	return 0, false
+}
+
+// waitForHighlight polls ring for up to timeout for an AskUserQuestion
+// frame's current highlight to appear.
+func waitForHighlight(ring *selectRingBuffer, timeout time.Duration) (int, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if idx, ok := highlightedOptionIndex(ring.snapshot()); ok {
+			return idx, true
+		}
+		if time.Now().After(deadline) {
+			return 0, false
+		}
+		time.Sleep(selectPollInterval)
 	}
-	// Press Enter to select the option
+}
+
+// waitForHighlightMove polls ring for up to timeout for the highlighted
+// option to change away from from, i.e. confirmation that the TUI processed
+// the arrow keystroke that was just sent.
+func waitForHighlightMove(ring *selectRingBuffer, from int, timeout time.Duration) (int, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if idx, ok := highlightedOptionIndex(ring.snapshot()); ok && idx != from {
+			return idx, true
+		}
+		if time.Now().After(deadline) {
+			return 0, false
+		}
+		time.Sleep(selectPollInterval)
+	}
+}
+
+// injectSelect navigates an AskUserQuestion TUI picker to option `index`,
+// then presses Enter. If `otherText` is non-empty, the selected option is
+// "Other" — after pressing Enter on it, it types the custom text and
+// presses Enter again.
+//
+// Navigation reads the TUI's own highlight position back from ring rather
+// than assuming the cursor starts on option 0: it sends one arrow press at a
+// time and waits for the highlight to actually move before sending the
+// next, so it can't overshoot on a slow machine or a laggy SSH pipe. If no
+// AskUserQuestion frame is detected within selectFrameTimeout, it falls back
+// to the old fixed-delay path instead.
+func injectSelect(p ptyHandle, ring *selectRingBuffer, index int, otherText string) {
+	current, ok := waitForHighlight(ring, selectFrameTimeout)
+	if !ok {
+		injectSelectTimed(p, index, otherText)
+		return
+	}
+
+	for current != index {
+		key := downArrow
+		if index < current {
+			key = upArrow
+		}
+		p.Write(key)
+
+		next, moved := waitForHighlightMove(ring, current, selectHighlightTimeout)
+		if !moved {
+			log.Printf("[select] highlight stopped tracking keystrokes at index %d, finishing blind", current)
+			injectArrowPresses(p, key, absInt(index-current)-1)
+			break
+		}
+		current = next
+	}
+
 	time.Sleep(50 * time.Millisecond)
 	p.Write([]byte{'\r'})
 
 	if otherText != "" {
-		// Wait for "Other" text input to appear, then type
 		time.Sleep(200 * time.Millisecond)
 		injectText(p, otherText)
 	}
 }
 
+// injectSelectTimed is the legacy fixed-delay navigation path: it sends
+// `index` down-arrow presses assuming the cursor starts on option 0, then
+// Enter. Used when injectSelect can't find a tracked highlight in the PTY
+// output.
+func injectSelectTimed(p ptyHandle, index int, otherText string) {
+	injectArrowPresses(p, downArrow, index)
+
+	time.Sleep(50 * time.Millisecond)
+	p.Write([]byte{'\r'})
+
+	if otherText != "" {
+		time.Sleep(200 * time.Millisecond)
+		injectText(p, otherText)
+	}
+}
+
+func injectArrowPresses(p ptyHandle, key []byte, n int) {
+	for i := 0; i < n; i++ {
+		p.Write(key)
+		time.Sleep(30 * time.Millisecond)
+	}
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
 // ptyHandle abstracts the PTY interface across platforms.
 type ptyHandle interface {
 	io.ReadWriteCloser
 	Wait() int
+	Resize(cols, rows uint16) error
 }
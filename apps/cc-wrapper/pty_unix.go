@@ -27,24 +27,55 @@ func spawnPTY(args []string) (ptyHandle, error) {
 		return nil, err
 	}
 
-	// Inherit terminal size
+	// Inherit terminal size; watchResize takes over from here (initial push
+	// plus every subsequent SIGWINCH).
 	if sz, err := pty.GetsizeFull(os.Stdin); err == nil {
 		pty.Setsize(ptmx, sz)
 	}
 
-	// Forward SIGWINCH for terminal resize
-	go handleResize(ptmx)
-
 	return &unixPTY{ptmx: ptmx, cmd: cmd}, nil
 }
 
-func handleResize(ptmx *os.File) {
+// watchResize pushes the host terminal's current size to p immediately, then
+// again on every SIGWINCH, so full-screen TUIs like Claude Code redraw
+// correctly when the user resizes their window.
+func watchResize(p ptyHandle) {
+	applyHostSize(p)
+
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGWINCH)
 	for range ch {
-		if sz, err := pty.GetsizeFull(os.Stdin); err == nil {
-			pty.Setsize(ptmx, sz)
-		}
+		applyHostSize(p)
+	}
+}
+
+func applyHostSize(p ptyHandle) {
+	if w, h, err := term.GetSize(int(os.Stdin.Fd())); err == nil {
+		p.Resize(uint16(w), uint16(h))
+	}
+}
+
+// watchSuspend handles Ctrl-Z (SIGTSTP): it restores the host terminal,
+// temporarily resets SIGTSTP's disposition and re-raises it so the process
+// actually stops (signal.Notify otherwise swallows it), then on SIGCONT
+// re-enters raw mode and reasserts the terminal title, which some emulators
+// clear while a process is stopped.
+func watchSuspend(guard *rawModeGuard, session int) {
+	tstp := make(chan os.Signal, 1)
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+
+	for {
+		signal.Notify(tstp, syscall.SIGTSTP)
+		<-tstp
+
+		guard.disable()
+		signal.Reset(syscall.SIGTSTP)
+		syscall.Kill(syscall.Getpid(), syscall.SIGTSTP)
+
+		<-cont
+		guard.enable()
+		setTitle(session)
 	}
 }
 
@@ -64,6 +95,13 @@ func (u *unixPTY) Close() error {
 	return u.ptmx.Close()
 }
 
+// Resize applies cols/rows to the PTY via TIOCSWINSZ so the child's full-screen
+// TUI reflows, whether the new size came from a local SIGWINCH or a remote
+// viewer's pty_resize message.
+func (u *unixPTY) Resize(cols, rows uint16) error {
+	return pty.Setsize(u.ptmx, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
 func (u *unixPTY) Wait() int {
 	err := u.cmd.Wait()
 	if err != nil {
@@ -49,11 +49,23 @@ func (w *winPTY) Close() error {
 	return w.cpty.Close()
 }
 
+// Resize applies cols/rows to the ConPTY pseudoconsole via its resize API.
+func (w *winPTY) Resize(cols, rows uint16) error {
+	return w.cpty.Resize(int(cols), int(rows))
+}
+
 func (w *winPTY) Wait() int {
 	exitCode, _ := w.cpty.Wait(context.Background())
 	return int(exitCode)
 }
 
+// watchResize and watchSuspend are no-ops on Windows: there's no SIGWINCH or
+// SIGTSTP/job control, and spawnPTY already sizes the ConPTY from the host
+// console at startup.
+func watchResize(p ptyHandle) {}
+
+func watchSuspend(guard *rawModeGuard, session int) {}
+
 func setRawTerminal() (func(), error) {
 	fd := int(os.Stdin.Fd())
 	oldState, err := term.MakeRaw(fd)
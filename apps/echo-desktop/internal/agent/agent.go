@@ -1,13 +1,14 @@
 package agent
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -27,39 +28,179 @@ func agentSystemPrompt() string {
 
 const maxIterations = 3
 
+const (
+	// defaultToolTimeout bounds how long a single tool call may run.
+	defaultToolTimeout = 8 * time.Second
+
+	// toolBudget bounds how long a round of (possibly concurrent) tool
+	// calls may run in total; any still in flight when it expires are
+	// cancelled and their result becomes a timeout note rather than
+	// blocking the rest of the conversation.
+	toolBudget = 20 * time.Second
+)
+
 // ProgressFunc is called by the agent to report state changes during execution.
-// state is one of: "thinking", "searching"
-// detail provides additional info (e.g. tool name for "searching")
+// state is one of: "thinking", "searching", "result"
+// detail provides additional info (e.g. tool name for "searching"; the raw
+// tool output for "result")
 type ProgressFunc func(state, detail string)
 
 // Agent runs an LLM agent loop with tool calling.
 type Agent struct {
 	apiURL   string
+	toolsDir string
+	safeMode bool
+
+	toolsMu  sync.RWMutex
 	tools    []ToolDef
 	handlers map[string]ToolHandler
+	toolMeta map[string]ToolDef
 	oaiTools []map[string]interface{}
+
+	httpClient *http.Client
+	retryLimit int
+	breaker    *circuitBreaker
+
+	// providers are external gRPC tool providers spawned once in NewAgent
+	// (see ToolProviderSpec/grpctool.go). Unlike YAML/plugin tools, they're
+	// not re-scanned on ReloadTools — only their ListTools is re-queried —
+	// since respawning a subprocess on every tools-dir fsnotify tick would
+	// be wasteful and could drop provider-held state.
+	providers []*grpcToolProvider
+}
+
+// NewAgent creates a new Agent, spawns toolProviders, and loads its initial
+// tool set from toolsDir (YAML tool defs plus, unless safeMode is set,
+// compiled .so plugins — see ReloadTools and plugins.go) merged with every
+// tool the providers advertise. A provider that fails to start is logged and
+// skipped rather than failing NewAgent outright, matching how
+// llm.NewRegistry tolerates a partial backend failure.
+func NewAgent(apiURL, toolsDir string, safeMode bool, toolProviders []ToolProviderSpec) (*Agent, error) {
+	a := &Agent{
+		apiURL:     apiURL,
+		toolsDir:   toolsDir,
+		safeMode:   safeMode,
+		httpClient: &http.Client{Timeout: llmHTTPTimeout},
+		retryLimit: defaultLLMRetryLimit,
+		breaker:    newCircuitBreaker(),
+	}
+	for _, spec := range toolProviders {
+		p, err := startToolProvider(spec)
+		if err != nil {
+			log.Printf("agent: skipping tool provider %q: %v", spec.Name, err)
+			continue
+		}
+		a.providers = append(a.providers, p)
+	}
+	if err := a.ReloadTools(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Close stops every gRPC tool provider subprocess this Agent spawned.
+func (a *Agent) Close() {
+	for _, p := range a.providers {
+		p.Close()
+	}
 }
 
-// NewAgent creates a new Agent, loading tools from the given directory.
-func NewAgent(apiURL, toolsDir string) (*Agent, error) {
-	tools, err := LoadTools(toolsDir)
+// SetRetryLimit overrides how many attempts callLLM makes (including the
+// first) before giving up, for callers that want cfg.AgentRetryLimit
+// instead of the built-in default. n <= 0 is ignored.
+func (a *Agent) SetRetryLimit(n int) {
+	if n > 0 {
+		a.retryLimit = n
+	}
+}
+
+// buildToolSet scans a.toolsDir for YAML tool defs, unless safe mode is on
+// compiled .so tool plugins, and every tool currently advertised by a.providers,
+// and returns the combined tool defs and handlers. gRPC providers aren't
+// gated by safe mode — they're out-of-process already, so safe mode's
+// "don't load arbitrary in-process code" rationale doesn't apply to them.
+// It doesn't touch Agent state — callers swap the results in under toolsMu
+// (see ReloadTools).
+func (a *Agent) buildToolSet() ([]ToolDef, map[string]ToolHandler, error) {
+	tools, err := LoadTools(a.toolsDir)
 	if err != nil {
-		return nil, fmt.Errorf("loading tools: %w", err)
+		return nil, nil, fmt.Errorf("loading tools: %w", err)
 	}
 
 	handlers := make(map[string]ToolHandler)
 	for _, t := range tools {
-		if h, ok := builtinHandlers[t.Type]; ok {
+		switch t.Type {
+		case "http", "webhook":
+			h, err := NewHTTPToolHandler(t)
+			if err != nil {
+				log.Printf("agent: skipping tool %q: %v", t.Name, err)
+				continue
+			}
 			handlers[t.Name] = h
+		default:
+			if h, ok := builtinHandlers[t.Type]; ok {
+				handlers[t.Name] = h
+			}
 		}
 	}
 
-	return &Agent{
-		apiURL:   apiURL,
-		tools:    tools,
-		handlers: handlers,
-		oaiTools: BuildOpenAITools(tools),
-	}, nil
+	if !a.safeMode {
+		pluginDefs, pluginHandlers := scanToolPlugins(a.toolsDir)
+		tools = append(tools, pluginDefs...)
+		for name, h := range pluginHandlers {
+			handlers[name] = h
+		}
+	}
+
+	for _, p := range a.providers {
+		providerDefs, providerHandlers, err := p.listTools()
+		if err != nil {
+			log.Printf("agent: tool provider %q: %v", p.name, err)
+			continue
+		}
+		tools = append(tools, providerDefs...)
+		for name, h := range providerHandlers {
+			handlers[name] = h
+		}
+	}
+
+	return tools, handlers, nil
+}
+
+// ReloadTools re-scans toolsDir and re-queries every tool provider, then
+// atomically swaps in the new tool set, so a fsnotify-triggered reload (see
+// WatchTools) or the tray's "Reload Tools" menu item takes effect without
+// restarting the agent loop or dropping a conversation in flight.
+func (a *Agent) ReloadTools() error {
+	tools, handlers, err := a.buildToolSet()
+	if err != nil {
+		return err
+	}
+
+	toolMeta := make(map[string]ToolDef, len(tools))
+	for _, t := range tools {
+		toolMeta[t.Name] = t
+	}
+
+	a.toolsMu.Lock()
+	a.tools = tools
+	a.handlers = handlers
+	a.toolMeta = toolMeta
+	a.oaiTools = BuildOpenAITools(tools)
+	a.toolsMu.Unlock()
+
+	log.Printf("agent: tools reloaded (%d tools)", len(tools))
+	return nil
+}
+
+// toolsSnapshot returns the currently active oaiTools/handlers/toolMeta/tool
+// count under toolsMu. Agent never mutates a tools/handlers/toolMeta slice
+// or map in place — ReloadTools always swaps in fresh ones — so holding
+// these references after the lock is released is safe.
+func (a *Agent) toolsSnapshot() (oaiTools []map[string]interface{}, handlers map[string]ToolHandler, toolMeta map[string]ToolDef, count int) {
+	a.toolsMu.RLock()
+	defer a.toolsMu.RUnlock()
+	return a.oaiTools, a.handlers, a.toolMeta, len(a.tools)
 }
 
 // chatMessage is an OpenAI-format chat message.
@@ -95,9 +236,13 @@ func (a *Agent) Run(userText string) (string, error) {
 	return a.RunWithProgress(userText, nil)
 }
 
-// RunWithProgress executes the agent loop with a progress callback.
+// RunWithProgress executes the agent loop with a progress callback. It
+// snapshots the active tool set once up front so a concurrent ReloadTools
+// (from the tools-dir watcher or a tray reload) can't hand a single run a
+// mix of old and new tools mid-conversation.
 func (a *Agent) RunWithProgress(userText string, onProgress ProgressFunc) (string, error) {
-	log.Printf("Agent.Run: input=%q, tools=%d", userText, len(a.tools))
+	oaiTools, handlers, toolMeta, toolCount := a.toolsSnapshot()
+	log.Printf("Agent.Run: input=%q, tools=%d", userText, toolCount)
 
 	// First call: NO /no_think — thinking is needed for tool calling decisions
 	messages := []chatMessage{
@@ -109,12 +254,15 @@ func (a *Agent) RunWithProgress(userText string, onProgress ProgressFunc) (strin
 		// On the last iteration, omit tools to force a text response
 		var tools []map[string]interface{}
 		if iteration < maxIterations-1 {
-			tools = a.oaiTools
+			tools = oaiTools
 		}
 
 		log.Printf("Agent iteration %d: sending %d messages, tools=%v", iteration, len(messages), len(tools) > 0)
 		resp, err := a.callLLM(messages, tools)
 		if err != nil {
+			if errors.Is(err, errBreakerOpen) {
+				return breakerOpenSpokenResponse, nil
+			}
 			return "", fmt.Errorf("LLM call failed: %w", err)
 		}
 
@@ -140,21 +288,8 @@ func (a *Agent) RunWithProgress(userText string, onProgress ProgressFunc) (strin
 			ToolCalls: choice.ToolCalls,
 		})
 
-		// Execute each tool call
-		for _, tc := range choice.ToolCalls {
-			// Notify progress: searching
-			if onProgress != nil {
-				onProgress("searching", tc.Function.Name)
-			}
-
-			result := a.executeTool(tc)
-			log.Printf("Agent tool result for %s (len=%d): %.200s", tc.Function.Name, len(result), result)
-			messages = append(messages, chatMessage{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: tc.ID,
-			})
-		}
+		// Execute the tool calls — concurrently when there's more than one
+		messages = append(messages, a.runToolCalls(choice.ToolCalls, handlers, toolMeta, onProgress)...)
 
 		// After tool results, add /no_think to speed up response generation
 		// (thinking was needed for the tool decision, not for synthesizing results)
@@ -172,6 +307,9 @@ func (a *Agent) RunWithProgress(userText string, onProgress ProgressFunc) (strin
 	// If we exhausted iterations, make one final call without tools
 	resp, err := a.callLLM(messages, nil)
 	if err != nil {
+		if errors.Is(err, errBreakerOpen) {
+			return breakerOpenSpokenResponse, nil
+		}
 		return "", fmt.Errorf("final LLM call failed: %w", err)
 	}
 	if len(resp.Choices) > 0 {
@@ -180,32 +318,41 @@ func (a *Agent) RunWithProgress(userText string, onProgress ProgressFunc) (strin
 	return "I'm sorry, I couldn't generate a response.", nil
 }
 
-func (a *Agent) callLLM(messages []chatMessage, tools []map[string]interface{}) (*chatResponse, error) {
+// chatRequestBody builds the JSON body shared by the non-streaming and
+// streaming chat-completion calls.
+func chatRequestBody(messages []chatMessage, tools []map[string]interface{}, stream bool) map[string]interface{} {
 	reqBody := map[string]interface{}{
 		"model":       "qwen3",
 		"messages":    messages,
 		"max_tokens":  1024,
 		"temperature": 0.7,
 	}
+	if stream {
+		reqBody["stream"] = true
+	}
 	if len(tools) > 0 {
 		reqBody["tools"] = tools
 		reqBody["tool_choice"] = "auto"
 	}
+	return reqBody
+}
 
-	data, _ := json.Marshal(reqBody)
-	resp, err := http.Post(a.apiURL+"/v1/chat/completions", "application/json", bytes.NewReader(data))
+// callLLM sends one chat-completions request and decodes the response, via
+// postChatWithRetry's retry/backoff/circuit-breaker handling (see
+// llmclient.go). Callers should check errors.Is(err, errBreakerOpen)
+// before treating a failure as fatal — it means the breaker is cooling down,
+// not that this particular request is unrecoverable.
+func (a *Agent) callLLM(messages []chatMessage, tools []map[string]interface{}) (*chatResponse, error) {
+	reqBody := chatRequestBody(messages, tools, false)
+
+	data, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := a.postChatWithRetry(data)
 	if err != nil {
-		return nil, fmt.Errorf("reading LLM response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("LLM returned status %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
 	log.Printf("LLM raw response: %.500s", string(body))
@@ -217,8 +364,63 @@ func (a *Agent) callLLM(messages []chatMessage, tools []map[string]interface{})
 	return &result, nil
 }
 
-func (a *Agent) executeTool(tc toolCall) string {
-	handler, ok := a.handlers[tc.Function.Name]
+// runToolCalls executes toolCalls concurrently, each bounded by
+// defaultToolTimeout (or the tool's own TimeoutMS, from toolMeta, if set)
+// and all of them sharing the toolBudget deadline. It returns one "tool"
+// chatMessage per call, in the same order as toolCalls regardless of
+// completion order. onProgress fires a "searching" event per tool with an
+// instance id (its ToolCallID) appended to detail, so two concurrent calls
+// to the same tool name are distinguishable; a tool whose ToolDef has
+// ShowToUser set also gets a best-effort "result" event with its raw output.
+func (a *Agent) runToolCalls(toolCalls []toolCall, handlers map[string]ToolHandler, toolMeta map[string]ToolDef, onProgress ProgressFunc) []chatMessage {
+	budgetCtx, cancel := context.WithTimeout(context.Background(), toolBudget)
+	defer cancel()
+
+	results := make([]string, len(toolCalls))
+	var wg sync.WaitGroup
+	for i, tc := range toolCalls {
+		wg.Add(1)
+		go func(i int, tc toolCall) {
+			defer wg.Done()
+
+			if onProgress != nil {
+				onProgress("searching", fmt.Sprintf("%s#%s", tc.Function.Name, tc.ID))
+			}
+
+			timeout := defaultToolTimeout
+			meta := toolMeta[tc.Function.Name]
+			if meta.TimeoutMS > 0 {
+				timeout = time.Duration(meta.TimeoutMS) * time.Millisecond
+			}
+
+			ctx, cancel := context.WithTimeout(budgetCtx, timeout)
+			defer cancel()
+
+			result := a.executeTool(ctx, tc, handlers)
+			if ctx.Err() != nil {
+				if budgetCtx.Err() != nil {
+					result = fmt.Sprintf("Tool error: cancelled — exceeded the %s shared tool budget for this turn", toolBudget)
+				} else {
+					result = fmt.Sprintf("Tool error: %s timed out after %s", tc.Function.Name, timeout)
+				}
+			} else if meta.ShowToUser && onProgress != nil {
+				onProgress("result", result)
+			}
+			results[i] = result
+		}(i, tc)
+	}
+	wg.Wait()
+
+	messages := make([]chatMessage, len(toolCalls))
+	for i, tc := range toolCalls {
+		log.Printf("Agent tool result for %s (len=%d): %.200s", tc.Function.Name, len(results[i]), results[i])
+		messages[i] = chatMessage{Role: "tool", Content: results[i], ToolCallID: tc.ID}
+	}
+	return messages
+}
+
+func (a *Agent) executeTool(ctx context.Context, tc toolCall, handlers map[string]ToolHandler) string {
+	handler, ok := handlers[tc.Function.Name]
 	if !ok {
 		return fmt.Sprintf("Error: unknown tool '%s'", tc.Function.Name)
 	}
@@ -229,7 +431,7 @@ func (a *Agent) executeTool(tc toolCall) string {
 	}
 
 	log.Printf("Agent tool call: %s(%v)", tc.Function.Name, args)
-	result, err := handler(args)
+	result, err := handler(ctx, args)
 	if err != nil {
 		return fmt.Sprintf("Tool error: %v", err)
 	}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitBreaker trips after breakerFailureThreshold consecutive callLLM
+// failures within breakerWindow, then short-circuits further calls with
+// errBreakerOpen for breakerCooldown instead of letting retries pile up
+// against a backend that's already down.
+type circuitBreaker struct {
+	mu          sync.Mutex
+	fails       int
+	windowStart time.Time
+	openUntil   time.Time
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{}
+}
+
+// allow reports whether a call may proceed.
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.After(b.openUntil)
+}
+
+// recordSuccess resets the consecutive-failure count.
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fails = 0
+}
+
+// recordFailure counts a failure against the current window, resetting it
+// if breakerWindow has elapsed since the first failure in it. It reports
+// true the moment the failure trips the breaker open.
+func (b *circuitBreaker) recordFailure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.windowStart.IsZero() || now.Sub(b.windowStart) > breakerWindow {
+		b.windowStart = now
+		b.fails = 0
+	}
+	b.fails++
+	if b.fails >= breakerFailureThreshold {
+		b.openUntil = now.Add(breakerCooldown)
+		b.fails = 0
+		return true
+	}
+	return false
+}
@@ -0,0 +1,526 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fetchUserAgent is a browser-like UA — the DDG/OpenSearch scrapers use a
+// plain "VoiceRelay/1.0" tag since they're polling a search engine's own
+// API-ish endpoint, but an arbitrary page is more likely to serve real
+// content to something that looks like an actual browser.
+const fetchUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+// fetchDefaultMaxBytes bounds how much of a page body FetchURL reads when
+// FetchConfig.MaxBytes is unset.
+const fetchDefaultMaxBytes = 2 << 20 // 2 MiB
+
+// FetchConfig controls FetchURL's host allow/deny list, byte cap, and disk
+// cache location. Configured once at startup via ConfigureFetch (see
+// main.go and config.WebFetchSpec).
+type FetchConfig struct {
+	AllowHosts []string
+	DenyHosts  []string
+	MaxBytes   int64
+	CacheDir   string
+}
+
+var (
+	fetchConfigMu sync.RWMutex
+	fetchConfig   = FetchConfig{MaxBytes: fetchDefaultMaxBytes}
+)
+
+// ConfigureFetch replaces the host allow/deny list, byte cap, and cache
+// directory FetchURL uses. cfg.MaxBytes <= 0 falls back to
+// fetchDefaultMaxBytes.
+func ConfigureFetch(cfg FetchConfig) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = fetchDefaultMaxBytes
+	}
+	fetchConfigMu.Lock()
+	fetchConfig = cfg
+	fetchConfigMu.Unlock()
+}
+
+func currentFetchConfig() FetchConfig {
+	fetchConfigMu.RLock()
+	defer fetchConfigMu.RUnlock()
+	return fetchConfig
+}
+
+// FetchedPage is the result of a readability-extracted page fetch.
+type FetchedPage struct {
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	Title        string `json:"title"`
+	Text         string `json:"text"`
+}
+
+// fetchCacheEntry is what's persisted to disk, keyed by URL+ETag, so a
+// repeat fetch of an unchanged page costs a conditional GET instead of
+// re-downloading and re-extracting it.
+type fetchCacheEntry struct {
+	ETag string      `json:"etag"`
+	Page FetchedPage `json:"page"`
+}
+
+// Fetch runs FetchURL against this result's URL — a convenience for a
+// caller (like the fetch_url tool's auto-followup path) that already has a
+// SearchResult in hand and wants the full page behind it.
+func (r SearchResult) Fetch(ctx context.Context) (*FetchedPage, error) {
+	return FetchURL(ctx, r.URL)
+}
+
+// FetchURL retrieves rawURL, honoring robots.txt and the configured
+// allow/deny host list, caps the body at FetchConfig.MaxBytes, and runs a
+// readability-style extraction to return clean article text plus the page's
+// title and canonical URL. Results are cached on disk keyed by URL+ETag
+// (see FetchConfig.CacheDir) so re-fetching an unchanged page is a cheap
+// conditional GET.
+func FetchURL(ctx context.Context, rawURL string) (*FetchedPage, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid URL %q", rawURL)
+	}
+
+	cfg := currentFetchConfig()
+	if !hostAllowed(parsed.Host, cfg) {
+		return nil, fmt.Errorf("fetch_url: %s is not in the allowed host list", parsed.Host)
+	}
+
+	allowed, err := robotsAllowed(ctx, parsed)
+	if err != nil {
+		// A robots.txt fetch failure shouldn't block the page fetch — most
+		// sites without one just 404, which net/http surfaces as a normal
+		// response, not an error, so an error here means something else
+		// (DNS, TLS, timeout) already went wrong and the real fetch below
+		// will fail the same way and report it properly.
+		allowed = true
+	}
+	if !allowed {
+		return nil, fmt.Errorf("fetch_url: %s disallows this path via robots.txt", parsed.Host)
+	}
+
+	cacheKey := fetchCacheKey(rawURL)
+	cached, _ := loadFetchCache(cfg.CacheDir, cacheKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return &cached.Page, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, cfg.MaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rawURL, err)
+	}
+
+	page := extractReadableText(string(body), rawURL)
+
+	etag := resp.Header.Get("ETag")
+	if etag != "" {
+		saveFetchCache(cfg.CacheDir, cacheKey, fetchCacheEntry{ETag: etag, Page: page})
+	}
+
+	return &page, nil
+}
+
+// hostAllowed applies cfg.DenyHosts then cfg.AllowHosts against host. A host
+// matches a pattern if it equals it or is a subdomain of it. No allow list
+// means everything not explicitly denied is allowed.
+func hostAllowed(host string, cfg FetchConfig) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range cfg.DenyHosts {
+		if hostMatches(host, pattern) {
+			return false
+		}
+	}
+	if len(cfg.AllowHosts) == 0 {
+		return true
+	}
+	for _, pattern := range cfg.AllowHosts {
+		if hostMatches(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(pattern, "*."))
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// robotsAllowed fetches host's robots.txt and reports whether the
+// "User-agent: *" group disallows parsed's path. Scoped to that one group
+// only — voice-relay doesn't identify itself with a dedicated token, so
+// there's nothing else in a robots.txt file it could match.
+func robotsAllowed(ctx context.Context, target *url.URL) (bool, error) {
+	robotsURL := fmt.Sprintf("%s://%s/robots.txt", target.Scheme, target.Host)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return true, nil // no robots.txt (or can't read it) means nothing is disallowed
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return true, err
+	}
+
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	inWildcardGroup := false
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		switch key {
+		case "user-agent":
+			inWildcardGroup = val == "*"
+		case "disallow":
+			if inWildcardGroup && val != "" && strings.HasPrefix(path, val) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func fetchCacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func loadFetchCache(cacheDir, key string) (*fetchCacheEntry, error) {
+	if cacheDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, key+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var entry fetchCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func saveFetchCache(cacheDir, key string, entry fetchCacheEntry) {
+	if cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(cacheDir, key+".json"), data, 0644)
+}
+
+// FetchURLHandler is the builtin handler for the fetch_url tool type.
+func FetchURLHandler(ctx context.Context, args map[string]interface{}) (string, error) {
+	rawURL, _ := args["url"].(string)
+	if rawURL == "" {
+		return "Error: no url provided", nil
+	}
+
+	page, err := FetchURL(ctx, rawURL)
+	if err != nil {
+		return fmt.Sprintf("Fetch failed: %v", err), nil
+	}
+
+	var sb strings.Builder
+	if page.Title != "" {
+		sb.WriteString(page.Title + "\n")
+	}
+	sb.WriteString(page.CanonicalURL + "\n\n")
+	sb.WriteString(page.Text)
+	return sb.String(), nil
+}
+
+// htmlBlock is a candidate subtree extractReadableText scores, captured
+// along with its opening tag (for the class/id heuristics) separately from
+// its inner content (for the text-density heuristics).
+type htmlBlock struct {
+	openTag string
+	content string
+}
+
+// readableBlockTags are the elements worth scoring as the page's main
+// content — article/main first since they're the semantic signal, div/
+// section as the fallback most pages still rely on.
+var readableBlockTags = []string{"article", "main", "section", "div"}
+
+// readableBoilerplateHints are class/id substrings that strongly suggest a
+// block is chrome, not content.
+var readableBoilerplateHints = []string{"nav", "footer", "sidebar", "menu", "comment", "share", "related", "promo", "advert", "banner", "cookie"}
+
+// readableContentHints are class/id substrings that suggest a block is the
+// article body.
+var readableContentHints = []string{"article", "content", "main", "story", "post", "body"}
+
+// extractReadableText scores every <article>/<main>/<section>/<div>
+// subtree in html by text-length-to-tag-density — penalizing link-heavy and
+// nav/footer/sidebar-classed blocks, boosting paragraph and comma density —
+// and returns the stripped text of the top scorer, plus the page's title
+// and canonical URL.
+func extractReadableText(html string, pageURL string) FetchedPage {
+	var best htmlBlock
+	bestScore := -1.0
+
+	for _, tag := range readableBlockTags {
+		for _, b := range extractBlocks(html, tag) {
+			score := scoreBlock(b)
+			if score > bestScore {
+				bestScore = score
+				best = b
+			}
+		}
+	}
+
+	text := best.content
+	if text == "" {
+		text = html
+	}
+	text = collapseWhitespace(stripTags(text))
+
+	return FetchedPage{
+		URL:          pageURL,
+		CanonicalURL: firstNonEmpty(extractCanonicalURL(html), pageURL),
+		Title:        extractTitle(html),
+		Text:         text,
+	}
+}
+
+// scoreBlock rates b by how likely it is to be the page's main content:
+// longer stripped text and more commas/paragraphs score higher, a large
+// share of that text sitting inside <a> links (typical of a nav menu or a
+// "related articles" list) and boilerplate class/id names score lower.
+func scoreBlock(b htmlBlock) float64 {
+	text := stripTags(b.content)
+	textLen := len(strings.TrimSpace(text))
+	if textLen < 40 {
+		return -1
+	}
+
+	commas := strings.Count(text, ",")
+	lower := strings.ToLower(b.content)
+	pCount := strings.Count(lower, "<p>") + strings.Count(lower, "<p ")
+	linkTextLen := sumAnchorTextLen(b.content)
+
+	score := float64(textLen) - float64(linkTextLen)*2 + float64(commas)*8 + float64(pCount)*30
+
+	class := strings.ToLower(extractAttr(b.openTag, "class") + " " + extractAttr(b.openTag, "id"))
+	for _, hint := range readableBoilerplateHints {
+		if strings.Contains(class, hint) {
+			score -= 500
+		}
+	}
+	for _, hint := range readableContentHints {
+		if strings.Contains(class, hint) {
+			score += 200
+		}
+	}
+	return score
+}
+
+// sumAnchorTextLen adds up the stripped text length of every <a ...>...</a>
+// in s, used by scoreBlock to penalize link-heavy blocks like nav menus.
+func sumAnchorTextLen(s string) int {
+	total := 0
+	remaining := s
+	for {
+		idx := strings.Index(remaining, "<a ")
+		if idx < 0 {
+			anchorIdx := strings.Index(remaining, "<a>")
+			if anchorIdx < 0 {
+				break
+			}
+			idx = anchorIdx
+		}
+		chunk := remaining[idx:]
+		gt := strings.Index(chunk, ">")
+		if gt < 0 {
+			break
+		}
+		end := strings.Index(chunk[gt:], "</a>")
+		if end < 0 {
+			remaining = chunk[gt:]
+			continue
+		}
+		total += len(stripTags(chunk[gt+1 : gt+end]))
+		remaining = chunk[gt+end+len("</a>"):]
+	}
+	return total
+}
+
+// extractBlocks finds every well-nested <tagName>...</tagName> subtree in
+// html, tracking open/close depth so a block containing further nested
+// instances of the same tag (<div><div>...</div></div>) is still captured
+// whole rather than cut off at the first closing tag.
+func extractBlocks(html, tagName string) []htmlBlock {
+	lower := strings.ToLower(html)
+	openNeedle := "<" + tagName
+	closeNeedle := "</" + tagName + ">"
+
+	var blocks []htmlBlock
+	pos := 0
+	for pos < len(lower) {
+		idx := strings.Index(lower[pos:], openNeedle)
+		if idx < 0 {
+			break
+		}
+		start := pos + idx
+		afterName := start + len(openNeedle)
+		if afterName < len(lower) && !isTagBoundary(lower[afterName]) {
+			pos = start + 1
+			continue
+		}
+		gt := strings.Index(lower[start:], ">")
+		if gt < 0 {
+			break
+		}
+		openTag := html[start : start+gt+1]
+		contentStart := start + gt + 1
+
+		depth := 1
+		cursor := contentStart
+		for depth > 0 && cursor < len(lower) {
+			nextOpen := strings.Index(lower[cursor:], openNeedle)
+			nextClose := strings.Index(lower[cursor:], closeNeedle)
+			if nextClose < 0 {
+				cursor = len(lower)
+				break
+			}
+			if nextOpen >= 0 && nextOpen < nextClose {
+				depth++
+				cursor += nextOpen + len(openNeedle)
+			} else {
+				depth--
+				cursor += nextClose + len(closeNeedle)
+			}
+		}
+
+		contentEnd := cursor - len(closeNeedle)
+		if depth == 0 && contentEnd > contentStart {
+			blocks = append(blocks, htmlBlock{openTag: openTag, content: html[contentStart:contentEnd]})
+		}
+		pos = start + len(openNeedle)
+	}
+	return blocks
+}
+
+// isTagBoundary reports whether c can follow a tag name, ruling out e.g.
+// "<articleX>" matching a search for "<article".
+func isTagBoundary(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '>' || c == '/'
+}
+
+// extractTitle returns the page's <title> text, if any.
+func extractTitle(html string) string {
+	lower := strings.ToLower(html)
+	start := strings.Index(lower, "<title")
+	if start < 0 {
+		return ""
+	}
+	gt := strings.Index(lower[start:], ">")
+	if gt < 0 {
+		return ""
+	}
+	contentStart := start + gt + 1
+	end := strings.Index(lower[contentStart:], "</title>")
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(stripTags(html[contentStart : contentStart+end]))
+}
+
+// extractCanonicalURL returns the href of <link rel="canonical" ...>, if any.
+func extractCanonicalURL(html string) string {
+	lower := strings.ToLower(html)
+	needle := "rel=\"canonical\""
+	idx := strings.Index(lower, needle)
+	if idx < 0 {
+		needle = "rel='canonical'"
+		idx = strings.Index(lower, needle)
+	}
+	if idx < 0 {
+		return ""
+	}
+	tagStart := strings.LastIndex(lower[:idx], "<link")
+	if tagStart < 0 {
+		return ""
+	}
+	gt := strings.Index(lower[tagStart:], ">")
+	if gt < 0 {
+		return ""
+	}
+	return extractAttr(html[tagStart:tagStart+gt+1], "href")
+}
+
+// collapseWhitespace turns runs of whitespace (including newlines) into a
+// single space, so stripped tag boundaries don't leave ragged blank lines.
+func collapseWhitespace(s string) string {
+	fields := strings.Fields(s)
+	return strings.Join(fields, " ")
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/voice-relay/echo-desktop/internal/agent/toolproviderpb"
+)
+
+// ToolProviderSpec configures an external tool-provider process NewAgent
+// spawns and supervises, then talks to over toolprovider.proto — the
+// extensible path for adding tools in any language without rebuilding
+// voice-relay, mirroring how internal/llm.WorkerSpec supervises an external
+// LLM runtime.
+type ToolProviderSpec struct {
+	// Name identifies this provider in logs; it isn't sent over the wire.
+	Name string
+
+	// Command and Args launch the provider binary. It's expected to listen
+	// on a Unix domain socket at the path given via the
+	// VOICE_RELAY_TOOL_SOCKET environment variable and answer
+	// ListTools/Invoke/Health once ready.
+	Command string
+	Args    []string
+
+	// SocketPath overrides the auto-generated socket path (under
+	// os.TempDir(), named "voice-relay-tool-<name>.sock"). Mainly useful for
+	// a provider that wants a fixed, predictable path across restarts.
+	SocketPath string
+
+	// ReadyTimeout bounds how long to poll Health before giving up; zero
+	// means toolProviderReadyTimeoutDefault.
+	ReadyTimeout time.Duration
+}
+
+const (
+	toolProviderReadyTimeoutDefault = 30 * time.Second
+	toolProviderHealthPollInterval  = 500 * time.Millisecond
+)
+
+// grpcToolProvider is a running, healthy connection to one ToolProviderSpec's
+// process. Unlike a .so plugin (see plugins.go), it's a subprocess dialed
+// over a Unix domain socket, so it can be written in any language — the same
+// out-of-process tradeoff internal/llm.GRPCBackend makes for LLM runtimes.
+//
+// grpc-go's "unix://" dialer uses Go's cross-platform AF_UNIX support on all
+// platforms voice-relay builds for, including Windows 10+; this is not a
+// genuine Windows named pipe, just a socket type Windows also happens to
+// support.
+type grpcToolProvider struct {
+	name       string
+	conn       *grpc.ClientConn
+	client     toolproviderpb.ToolProviderClient
+	cmd        *exec.Cmd
+	socketPath string
+}
+
+// startToolProvider spawns spec.Command, waits for it to report healthy over
+// toolprovider.proto, and returns a grpcToolProvider wrapping the now-ready
+// connection. The subprocess is killed if it fails to come up in time.
+func startToolProvider(spec ToolProviderSpec) (*grpcToolProvider, error) {
+	socketPath := spec.SocketPath
+	if socketPath == "" {
+		socketPath = filepath.Join(os.TempDir(), fmt.Sprintf("voice-relay-tool-%s.sock", spec.Name))
+	}
+	os.Remove(socketPath) // drop a stale socket left by a crashed previous run
+
+	cmd := exec.Command(spec.Command, spec.Args...)
+	cmd.Env = append(os.Environ(), "VOICE_RELAY_TOOL_SOCKET="+socketPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setSysProcAttr(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting tool provider %s: %w", spec.Name, err)
+	}
+
+	conn, err := grpc.NewClient("unix://"+socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("dialing tool provider %s: %w", spec.Name, err)
+	}
+
+	p := &grpcToolProvider{
+		name:       spec.Name,
+		conn:       conn,
+		client:     toolproviderpb.NewToolProviderClient(conn),
+		cmd:        cmd,
+		socketPath: socketPath,
+	}
+
+	timeout := spec.ReadyTimeout
+	if timeout <= 0 {
+		timeout = toolProviderReadyTimeoutDefault
+	}
+	if err := p.waitHealthy(timeout); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *grpcToolProvider) waitHealthy(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		resp, err := p.client.Health(ctx, &structpb.Struct{})
+		cancel()
+		if err == nil && resp.Fields["ready"].GetBoolValue() {
+			return nil
+		}
+		time.Sleep(toolProviderHealthPollInterval)
+	}
+	return fmt.Errorf("tool provider %s did not become healthy within %v", p.name, timeout)
+}
+
+// Close tears down the gRPC connection, stops the provider subprocess, and
+// removes its socket file.
+func (p *grpcToolProvider) Close() {
+	p.conn.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	os.Remove(p.socketPath)
+}
+
+// listTools calls ListTools and returns the advertised ToolDefs alongside a
+// ToolHandler for each, built from invokeHandler. Called once right after
+// the provider comes up and again on every Agent.ReloadTools.
+func (p *grpcToolProvider) listTools() ([]ToolDef, map[string]ToolHandler, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := p.client.ListTools(ctx, &structpb.Struct{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("tool provider %s ListTools: %w", p.name, err)
+	}
+
+	var defs []ToolDef
+	handlers := make(map[string]ToolHandler)
+	for _, v := range resp.Fields["tools"].GetListValue().GetValues() {
+		t := v.GetStructValue()
+		if t == nil {
+			continue
+		}
+		name := t.Fields["name"].GetStringValue()
+		if name == "" {
+			continue
+		}
+		defs = append(defs, ToolDef{
+			Name:        name,
+			Type:        "grpc_provider",
+			Description: t.Fields["description"].GetStringValue(),
+			Parameters:  t.Fields["json_schema"].GetStructValue().AsMap(),
+			TimeoutMS:   int(t.Fields["timeout_ms"].GetNumberValue()),
+			ShowToUser:  t.Fields["show_to_user"].GetBoolValue(),
+		})
+		handlers[name] = p.invokeHandler(name)
+	}
+	return defs, handlers, nil
+}
+
+// invokeHandler returns a ToolHandler that calls the provider's Invoke RPC
+// for the named tool, draining its result stream into the final text.
+func (p *grpcToolProvider) invokeHandler(name string) ToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("marshal args for %s: %w", name, err)
+		}
+		req, err := structpb.NewStruct(map[string]any{
+			"name":      name,
+			"args_json": string(argsJSON),
+		})
+		if err != nil {
+			return "", fmt.Errorf("build request for %s: %w", name, err)
+		}
+
+		stream, err := p.client.Invoke(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("tool provider %s Invoke %s: %w", p.name, name, err)
+		}
+
+		var out strings.Builder
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("tool provider %s Invoke %s: %w", p.name, name, err)
+			}
+			if result := msg.Fields["result"].GetStringValue(); result != "" {
+				return result, nil
+			}
+			out.WriteString(msg.Fields["delta"].GetStringValue())
+			if msg.Fields["done"].GetBoolValue() {
+				break
+			}
+		}
+		return out.String(), nil
+	}
+}
@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPToolConfig configures a "type: http" (or "type: webhook") tool — one
+// that calls an external REST endpoint declared entirely in YAML, with no
+// Go code, so users can wire the agent into N8N, Zapier, or an internal
+// service.
+type HTTPToolConfig struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	// AuthEnv names an environment variable whose value is sent as
+	// "Authorization: Bearer <value>", so secrets live outside the YAML file.
+	AuthEnv string `yaml:"auth_env"`
+
+	// Body is a JSON template; "{{arg}}" is replaced with the JSON encoding
+	// of the tool call's "arg" parameter (e.g. body: `{"query": {{query}}}`).
+	Body string `yaml:"body"`
+
+	// ResponsePath selects the value to return from the JSON response body,
+	// as dot-separated object keys and array indices (e.g. "data.0.text").
+	// Empty returns the raw response body.
+	ResponsePath string `yaml:"response_path"`
+
+	// MaxChars truncates the extracted result so it stays speakable.
+	// 0 means no limit.
+	MaxChars int `yaml:"max_chars"`
+
+	// TimeoutSeconds bounds the HTTP call. 0 uses a 10s default.
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+const (
+	defaultHTTPToolTimeout   = 10 * time.Second
+	maxHTTPToolResponseBytes = 1 << 20 // 1 MiB
+)
+
+// NewHTTPToolHandler builds a ToolHandler for an http/webhook tool def,
+// closing over its HTTPToolConfig so each YAML-defined tool gets its own
+// handler bound to its own URL, headers, and body template.
+func NewHTTPToolHandler(t ToolDef) (ToolHandler, error) {
+	cfg := t.HTTP
+	if cfg == nil {
+		return nil, fmt.Errorf("tool %q has type %q but no http config", t.Name, t.Type)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("tool %q: http.url is required", t.Name)
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	timeout := defaultHTTPToolTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		url := renderTemplate(cfg.URL, args, rawArg)
+
+		var bodyReader io.Reader
+		if cfg.Body != "" {
+			bodyReader = strings.NewReader(renderTemplate(cfg.Body, args, jsonArg))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+		if err != nil {
+			return "", fmt.Errorf("building request: %w", err)
+		}
+		if bodyReader != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for k, v := range cfg.Headers {
+			req.Header.Set(k, renderTemplate(v, args, rawArg))
+		}
+		if cfg.AuthEnv != "" {
+			if secret := os.Getenv(cfg.AuthEnv); secret != "" {
+				req.Header.Set("Authorization", "Bearer "+secret)
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPToolResponseBytes))
+		if err != nil {
+			return "", fmt.Errorf("reading response: %w", err)
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", fmt.Errorf("request returned status %d: %s", resp.StatusCode, truncate(string(body), 200))
+		}
+
+		result := string(body)
+		if cfg.ResponsePath != "" {
+			var decoded interface{}
+			if err := json.Unmarshal(body, &decoded); err != nil {
+				return "", fmt.Errorf("parsing response as JSON: %w", err)
+			}
+			value, err := selectPath(decoded, cfg.ResponsePath)
+			if err != nil {
+				return "", err
+			}
+			result = stringify(value)
+		}
+
+		return truncate(result, cfg.MaxChars), nil
+	}, nil
+}
+
+// rawArg renders an arg as its plain string form, for use in URLs and headers.
+func rawArg(v interface{}) string {
+	return fmt.Sprint(v)
+}
+
+// jsonArg renders an arg as a JSON value, so a string substitutes as a
+// quoted JSON string and a number or bool substitutes bare — keeping a
+// body template valid JSON as long as the template itself is.
+func jsonArg(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+// renderTemplate replaces every "{{name}}" in tmpl with render(args[name]),
+// leaving unknown placeholders untouched.
+func renderTemplate(tmpl string, args map[string]interface{}, render func(interface{}) string) string {
+	var sb strings.Builder
+	rest := tmpl
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end += start
+
+		sb.WriteString(rest[:start])
+		name := strings.TrimSpace(rest[start+2 : end])
+		if v, ok := args[name]; ok {
+			sb.WriteString(render(v))
+		} else {
+			sb.WriteString(rest[start : end+2])
+		}
+		rest = rest[end+2:]
+	}
+	return sb.String()
+}
+
+// selectPath walks data using path's dot-separated segments, each either an
+// object key or (if numeric) an array index — a minimal stand-in for a full
+// JSONPath implementation, covering the common "data.0.text" shape.
+func selectPath(data interface{}, path string) (interface{}, error) {
+	current := data
+	for _, seg := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[seg]
+			if !ok {
+				return nil, fmt.Errorf("response_path %q: no key %q", path, seg)
+			}
+			current = next
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("response_path %q: invalid array index %q", path, seg)
+			}
+			current = v[idx]
+		default:
+			return nil, fmt.Errorf("response_path %q: cannot index into %T at %q", path, current, seg)
+		}
+	}
+	return current, nil
+}
+
+func stringify(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// truncate trims s to maxChars, e.g. to keep a response speakable.
+// maxChars <= 0 means no limit.
+func truncate(s string, maxChars int) string {
+	if maxChars <= 0 || len(s) <= maxChars {
+		return s
+	}
+	return s[:maxChars]
+}
@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/metrics"
+)
+
+const (
+	// llmHTTPTimeout bounds a single callLLM HTTP round trip (connect,
+	// send, and read the full response body), independent of the retry
+	// loop around it.
+	llmHTTPTimeout = 20 * time.Second
+
+	// llmRetryBaseDelay and llmRetryMaxDelay bound the exponential backoff
+	// between callLLM retries; llmRetryJitter randomizes it by up to this
+	// fraction so concurrent conversations don't retry in lockstep.
+	llmRetryBaseDelay = 500 * time.Millisecond
+	llmRetryMaxDelay  = 5 * time.Second
+	llmRetryJitter    = 0.2
+
+	// llmRetryWallClockCap bounds the total time callLLM may spend across
+	// every attempt, so a string of retries can't stall the speech
+	// pipeline any longer than this.
+	llmRetryWallClockCap = 30 * time.Second
+
+	// defaultLLMRetryLimit is how many attempts callLLM makes, including
+	// the first, absent cfg.AgentRetryLimit (see SetRetryLimit).
+	defaultLLMRetryLimit = 3
+
+	// breakerFailureThreshold consecutive callLLM failures within
+	// breakerWindow trip the circuit breaker; breakerCooldown is how long
+	// it then stays open.
+	breakerFailureThreshold = 5
+	breakerWindow           = 30 * time.Second
+	breakerCooldown         = 20 * time.Second
+)
+
+// errBreakerOpen is returned by callLLM (and streamLLM) when the circuit
+// breaker is open, instead of attempting — or retrying — the request.
+var errBreakerOpen = errors.New("llm backend circuit breaker is open")
+
+// breakerOpenSpokenResponse is what Run/RunStreaming say aloud when
+// errBreakerOpen comes back, rather than surfacing it as an error.
+const breakerOpenSpokenResponse = "I can't reach the assistant right now."
+
+// httpStatusError is returned by postChatOnce for a non-200 response, so
+// retry logic can distinguish a retryable 5xx/429 from a 4xx that won't
+// succeed no matter how many times it's retried.
+type httpStatusError struct {
+	status     int
+	retryAfter time.Duration
+	body       string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("LLM returned status %d: %s", e.status, e.body)
+}
+
+func isRetryableStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// isRetryable reports whether err is worth retrying: any non-2xx status
+// other than a retryable one is terminal, everything else (connection
+// refused, DNS failure, client-side timeout, ...) is a transient network
+// error worth another attempt.
+func isRetryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.status)
+	}
+	return true
+}
+
+// retryDelay computes how long to wait before the next attempt, honoring a
+// server-supplied Retry-After if lastErr carried one, otherwise falling
+// back to jittered exponential backoff.
+func retryDelay(attempt int, lastErr error) time.Duration {
+	var statusErr *httpStatusError
+	if errors.As(lastErr, &statusErr) && statusErr.retryAfter > 0 {
+		return statusErr.retryAfter
+	}
+
+	delay := llmRetryBaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > llmRetryMaxDelay {
+		delay = llmRetryMaxDelay
+	}
+	jitterRange := float64(delay) * llmRetryJitter
+	delay += time.Duration(jitterRange*2*rand.Float64() - jitterRange)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns 0 if v is empty, unparseable, or
+// already in the past.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// recordLLMSuccess marks a callLLM/streamLLM attempt as successful, both in
+// the /metrics counters and against the circuit breaker.
+func (a *Agent) recordLLMSuccess() {
+	metrics.LLMCallSuccessesTotal.Inc()
+	a.breaker.recordSuccess()
+}
+
+// recordLLMFailure marks a callLLM/streamLLM attempt as failed, both in the
+// /metrics counters and against the circuit breaker, logging once if this
+// failure is the one that trips the breaker open.
+func (a *Agent) recordLLMFailure() {
+	metrics.LLMCallFailuresTotal.Inc()
+	if a.breaker.recordFailure(time.Now()) {
+		metrics.LLMBreakerTripsTotal.Inc()
+		log.Printf("Agent: LLM backend circuit breaker tripped after %d consecutive failures, cooling down for %s", breakerFailureThreshold, breakerCooldown)
+	}
+}
+
+// postChatOnce performs a single POST to the chat-completions endpoint,
+// returning the raw response body on success or an *httpStatusError for a
+// non-200 response.
+func (a *Agent) postChatOnce(data []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, a.apiURL+"/v1/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading LLM response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{
+			status:     resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			body:       string(body),
+		}
+	}
+	return body, nil
+}
+
+// postChatWithRetry POSTs data to the chat-completions endpoint, retrying
+// transient failures (connection errors, 5xx, 429 honoring Retry-After)
+// with exponential backoff and jitter, up to a.retryLimit attempts or
+// llmRetryWallClockCap of total wall-clock time, whichever comes first. It
+// checks the circuit breaker before every attempt, short-circuiting with
+// errBreakerOpen rather than adding to a pile of failures against a
+// backend that's already tripped it.
+func (a *Agent) postChatWithRetry(data []byte) ([]byte, error) {
+	deadline := time.Now().Add(llmRetryWallClockCap)
+
+	var lastErr error
+	for attempt := 0; attempt < a.retryLimit; attempt++ {
+		if !a.breaker.allow(time.Now()) {
+			metrics.LLMBreakerShortCircuitsTotal.Inc()
+			return nil, errBreakerOpen
+		}
+
+		if attempt > 0 {
+			delay := retryDelay(attempt, lastErr)
+			if time.Now().Add(delay).After(deadline) {
+				break
+			}
+			time.Sleep(delay)
+		} else if time.Now().After(deadline) {
+			break
+		}
+
+		metrics.LLMCallAttemptsTotal.Inc()
+		body, err := a.postChatOnce(data)
+		if err == nil {
+			a.recordLLMSuccess()
+			return body, nil
+		}
+
+		a.recordLLMFailure()
+		lastErr = err
+		if !isRetryable(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("LLM call failed after retries: %w", lastErr)
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// openSearchDescription mirrors the handful of <OpenSearchDescription>
+// fields web_search cares about — the standard format search engines and
+// browsers use to describe a search endpoint
+// (https://github.com/dewitt/opensearch).
+type openSearchDescription struct {
+	ShortName string `xml:"ShortName"`
+	Urls      []struct {
+		Type     string `xml:"type,attr"`
+		Template string `xml:"template,attr"`
+	} `xml:"Url"`
+}
+
+// openSearchProvider queries a search engine described by an OpenSearch XML
+// file dropped in the searchproviders/ directory — the generic escape hatch
+// for an engine with no dedicated adapter in provider.go. OpenSearch only
+// standardizes the request URL, not the response shape, so results are
+// scraped as anchor tags using the same heuristic parseDDGLite uses for DDG
+// Lite's HTML; an engine with a very different results page layout will
+// just yield no results and get skipped over by WebSearchHandler's failover.
+type openSearchProvider struct {
+	name     string
+	template string
+}
+
+// loadOpenSearchProviders scans dir for *.xml OpenSearch descriptors and
+// returns one provider per file that declares an HTML search Url. A missing
+// dir is not an error — it just means no extra providers come from disk.
+func loadOpenSearchProviders(dir string) ([]SearchProvider, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading search providers dir: %w", err)
+	}
+
+	var providers []SearchProvider
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".xml") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var desc openSearchDescription
+		if err := xml.Unmarshal(data, &desc); err != nil {
+			continue
+		}
+
+		template := ""
+		for _, u := range desc.Urls {
+			if u.Type == "text/html" || u.Type == "" {
+				template = u.Template
+				break
+			}
+		}
+		if template == "" {
+			continue
+		}
+
+		name := desc.ShortName
+		if name == "" {
+			name = strings.TrimSuffix(e.Name(), ".xml")
+		}
+		providers = append(providers, &openSearchProvider{name: name, template: template})
+	}
+	return providers, nil
+}
+
+func (p *openSearchProvider) Name() string { return p.name }
+
+func (p *openSearchProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	searchURL := strings.ReplaceAll(p.template, "{searchTerms}", url.QueryEscape(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; VoiceRelay/1.0)")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	return scrapeAnchors(string(body)), nil
+}
+
+// scrapeAnchors extracts a generic engine's result links as a last resort:
+// every <a href="..."> pointing at an external URL, in document order.
+func scrapeAnchors(html string) []SearchResult {
+	const maxResults = 5
+
+	var results []SearchResult
+	remaining := html
+	for len(results) < maxResults {
+		idx := strings.Index(remaining, "<a ")
+		if idx < 0 {
+			break
+		}
+		tagChunk := remaining[idx:]
+
+		gt := strings.Index(tagChunk, ">")
+		if gt < 0 {
+			break
+		}
+		end := strings.Index(tagChunk[gt:], "</a>")
+		if end < 0 {
+			remaining = tagChunk[gt:]
+			continue
+		}
+
+		href := extractAttr(tagChunk[:gt], "href")
+		title := stripTags(tagChunk[gt+1 : gt+end])
+		remaining = tagChunk[gt+end+len("</a>"):]
+
+		if href == "" || title == "" || !strings.HasPrefix(href, "http") {
+			continue
+		}
+		results = append(results, SearchResult{Title: title, URL: href})
+	}
+	return results
+}
@@ -0,0 +1,79 @@
+//go:build !windows
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"plugin"
+)
+
+// PluginSymbol is the symbol name a tools-dir *.so file must export: a
+// package-level variable of type Tool (see tools.go). See plugins_windows.go
+// for why this extension point doesn't exist there.
+const PluginSymbol = "Tool"
+
+// scanToolPlugins opens every dir/*.so and, for each that resolves and
+// type-asserts cleanly, returns its ToolDef and a ToolHandler that forwards
+// to the plugin's Invoke. Like llm.ScanPlugins, a bad plugin — missing
+// symbol, wrong type, ABI mismatch after a voice-relay rebuild — is logged
+// and skipped rather than treated as fatal, since one broken .so shouldn't
+// take down the rest of the agent's tools.
+func scanToolPlugins(dir string) ([]ToolDef, map[string]ToolHandler) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, nil
+	}
+
+	var defs []ToolDef
+	handlers := make(map[string]ToolHandler)
+	for _, path := range matches {
+		def, handler, err := loadToolPlugin(path)
+		if err != nil {
+			log.Printf("agent: loading tool plugin %s: %v", path, err)
+			continue
+		}
+		defs = append(defs, def)
+		handlers[def.Name] = handler
+	}
+	return defs, handlers
+}
+
+func loadToolPlugin(path string) (ToolDef, ToolHandler, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return ToolDef{}, nil, err
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return ToolDef{}, nil, err
+	}
+	tool, ok := sym.(Tool)
+	if !ok {
+		return ToolDef{}, nil, fmt.Errorf("%s does not satisfy agent.Tool (stale build against a different voice-relay version?)", PluginSymbol)
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(tool.Schema(), &params); err != nil {
+		return ToolDef{}, nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	def := ToolDef{
+		Name:       tool.Name(),
+		Type:       "plugin",
+		Parameters: params,
+	}
+	handler := func(ctx context.Context, args map[string]interface{}) (string, error) {
+		argsJSON, err := json.Marshal(args)
+		if err != nil {
+			return "", err
+		}
+		return tool.Invoke(ctx, argsJSON)
+	}
+
+	log.Printf("agent: tool plugin loaded: %s (tool %q)", path, def.Name)
+	return def, handler, nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package agent
+
+// scanToolPlugins is a no-op on Windows: the standard library's plugin
+// package only supports linux/darwin, so there's no tools-dir *.so
+// extension point here. Built-in and declarative YAML tools still load.
+func scanToolPlugins(dir string) ([]ToolDef, map[string]ToolHandler) {
+	return nil, nil
+}
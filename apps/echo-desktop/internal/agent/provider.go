@@ -0,0 +1,291 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProviderSpec configures one SearchProvider for LoadSearchProviders,
+// mirroring config.SearchProviderSpec the way llm.BackendSpec mirrors
+// config.LLMBackendSpec.
+type ProviderSpec struct {
+	Name            string
+	Type            string // "ddg" (default), "searxng", "brave", or "kagi"
+	BaseURL         string
+	APIKey          string
+	RateLimitPerMin int
+}
+
+// LoadSearchProviders builds the provider list WebSearchHandler fails over
+// across. specs (cfg.SearchProviders) come first, in configured priority
+// order; any OpenSearch XML descriptor found in dir is appended after them,
+// so it's there as an extra fallback without needing a config entry of its
+// own. A spec that fails to build (missing api_key, etc.) is logged and
+// skipped rather than aborting the whole list, same as llm.NewRegistry.
+func LoadSearchProviders(dir string, specs []ProviderSpec) ([]SearchProvider, error) {
+	var providers []SearchProvider
+	for _, spec := range specs {
+		p, err := newBuiltinProvider(spec)
+		if err != nil {
+			return nil, fmt.Errorf("search provider %q: %w", spec.Name, err)
+		}
+		if spec.RateLimitPerMin > 0 {
+			p = &rateLimitedProvider{inner: p, limiter: newRateLimiter(spec.RateLimitPerMin)}
+		}
+		providers = append(providers, p)
+	}
+
+	openSearchProviders, err := loadOpenSearchProviders(dir)
+	if err != nil {
+		return nil, err
+	}
+	providers = append(providers, openSearchProviders...)
+
+	return providers, nil
+}
+
+func newBuiltinProvider(spec ProviderSpec) (SearchProvider, error) {
+	name := spec.Name
+	switch spec.Type {
+	case "", "ddg":
+		if name == "" {
+			name = "ddg"
+		}
+		return &ddgLiteProvider{name: name}, nil
+	case "searxng":
+		if spec.BaseURL == "" {
+			return nil, fmt.Errorf("searxng provider needs base_url")
+		}
+		if name == "" {
+			name = "searxng"
+		}
+		return &searxngProvider{name: name, baseURL: spec.BaseURL}, nil
+	case "brave":
+		if spec.APIKey == "" {
+			return nil, fmt.Errorf("brave provider needs api_key")
+		}
+		if name == "" {
+			name = "brave"
+		}
+		return &braveProvider{name: name, apiKey: spec.APIKey}, nil
+	case "kagi":
+		if spec.APIKey == "" {
+			return nil, fmt.Errorf("kagi provider needs api_key")
+		}
+		if name == "" {
+			name = "kagi"
+		}
+		return &kagiProvider{name: name, apiKey: spec.APIKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", spec.Type)
+	}
+}
+
+// rateLimiter is a minimal fixed-window-per-minute gate — enough to keep a
+// free-tier API key, or a shared SearXNG instance other things also depend
+// on, under its request quota without pulling in a token-bucket library for
+// one counter.
+type rateLimiter struct {
+	mu          sync.Mutex
+	perMinute   int
+	windowStart time.Time
+	count       int
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: perMinute}
+}
+
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.windowStart) > time.Minute {
+		r.windowStart = now
+		r.count = 0
+	}
+	if r.count >= r.perMinute {
+		return false
+	}
+	r.count++
+	return true
+}
+
+// rateLimitedProvider wraps a SearchProvider so a request over the limit
+// fails fast with an error, which WebSearchHandler treats the same as any
+// other provider error and fails over to the next one.
+type rateLimitedProvider struct {
+	inner   SearchProvider
+	limiter *rateLimiter
+}
+
+func (p *rateLimitedProvider) Name() string { return p.inner.Name() }
+
+func (p *rateLimitedProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	if !p.limiter.allow(time.Now()) {
+		return nil, fmt.Errorf("rate limit exceeded")
+	}
+	return p.inner.Search(ctx, query)
+}
+
+// searxngProvider queries a SearXNG instance's JSON search API (enabled by
+// setting "json" in that instance's settings.yml formats list).
+type searxngProvider struct {
+	name    string
+	baseURL string
+}
+
+func (p *searxngProvider) Name() string { return p.name }
+
+func (p *searxngProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	const maxResults = 5
+
+	u := strings.TrimRight(p.baseURL, "/") + "/search?format=json&q=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; VoiceRelay/1.0)")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("SearXNG request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SearXNG returned %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing SearXNG response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, maxResults)
+	for _, r := range parsed.Results {
+		if len(results) >= maxResults {
+			break
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// braveProvider queries the Brave Search API.
+// https://api-dashboard.search.brave.com/app/documentation/web-search/get-started
+type braveProvider struct {
+	name   string
+	apiKey string
+}
+
+func (p *braveProvider) Name() string { return p.name }
+
+func (p *braveProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	const maxResults = 5
+
+	u := "https://api.search.brave.com/res/v1/web/search?q=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", p.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Brave request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Brave returned %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing Brave response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, maxResults)
+	for _, r := range parsed.Web.Results {
+		if len(results) >= maxResults {
+			break
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+// kagiProvider queries the Kagi Search API.
+// https://help.kagi.com/kagi/api/search.html
+type kagiProvider struct {
+	name   string
+	apiKey string
+}
+
+func (p *kagiProvider) Name() string { return p.name }
+
+func (p *kagiProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	const maxResults = 5
+
+	u := "https://kagi.com/api/v0/search?q=" + url.QueryEscape(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bot "+p.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Kagi request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Kagi returned %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Data []struct {
+			Type    int    `json:"t"` // 0 = search result, 1 = related searches
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing Kagi response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, maxResults)
+	for _, r := range parsed.Data {
+		if r.Type != 0 {
+			continue
+		}
+		if len(results) >= maxResults {
+			break
+		}
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Snippet})
+	}
+	return results, nil
+}
@@ -0,0 +1,343 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/metrics"
+)
+
+// RunStreaming is RunWithProgress, but it streams the LLM's response as it's
+// generated: onDelta is called with each complete sentence of spoken text as
+// soon as it's available, so the TTS layer can start speaking before the
+// model finishes the full response. Tool calls still only execute once a
+// choice closes — streamed tool-call argument fragments arrive piecewise,
+// keyed by index, and must be fully reassembled before they're valid JSON.
+func (a *Agent) RunStreaming(userText string, onDelta func(text string), onProgress ProgressFunc) (string, error) {
+	oaiTools, handlers, toolMeta, toolCount := a.toolsSnapshot()
+	log.Printf("Agent.RunStreaming: input=%q, tools=%d", userText, toolCount)
+
+	messages := []chatMessage{
+		{Role: "system", Content: agentSystemPrompt()},
+		{Role: "user", Content: userText},
+	}
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		var tools []map[string]interface{}
+		if iteration < maxIterations-1 {
+			tools = oaiTools
+		}
+
+		log.Printf("Agent stream iteration %d: sending %d messages, tools=%v", iteration, len(messages), len(tools) > 0)
+		result, err := a.streamLLM(messages, tools, onDelta)
+		if err != nil {
+			if errors.Is(err, errBreakerOpen) {
+				if onDelta != nil {
+					onDelta(breakerOpenSpokenResponse)
+				}
+				return breakerOpenSpokenResponse, nil
+			}
+			return "", fmt.Errorf("LLM call failed: %w", err)
+		}
+
+		if len(result.toolCalls) == 0 {
+			log.Printf("Agent stream iteration %d: final text response (len=%d)", iteration, len(result.content))
+			return result.content, nil
+		}
+
+		log.Printf("Agent stream iteration %d: %d tool calls requested", iteration, len(result.toolCalls))
+
+		messages = append(messages, chatMessage{
+			Role:      "assistant",
+			Content:   result.rawContent,
+			ToolCalls: result.toolCalls,
+		})
+
+		messages = append(messages, a.runToolCalls(result.toolCalls, handlers, toolMeta, onProgress)...)
+
+		messages = append(messages, chatMessage{
+			Role:    "user",
+			Content: "Now answer my original question using the information above. Be concise — this will be spoken aloud. /no_think",
+		})
+
+		if onProgress != nil {
+			onProgress("thinking", "")
+		}
+	}
+
+	// Exhausted iterations — one final call without tools, to force a
+	// text-only response.
+	result, err := a.streamLLM(messages, nil, onDelta)
+	if err != nil {
+		if errors.Is(err, errBreakerOpen) {
+			if onDelta != nil {
+				onDelta(breakerOpenSpokenResponse)
+			}
+			return breakerOpenSpokenResponse, nil
+		}
+		return "", fmt.Errorf("final LLM call failed: %w", err)
+	}
+	return result.content, nil
+}
+
+// llmStreamResult is the accumulated result of one streamed chat-completion
+// call: the full chat history needs rawContent (think tags and all, exactly
+// as the model produced it) and the assembled tool calls, while callers care
+// about content (the cleaned, spoken text).
+type llmStreamResult struct {
+	rawContent string
+	content    string
+	toolCalls  []toolCall
+}
+
+// streamChunk is one "data: {...}" line of an OpenAI-compatible streaming
+// chat-completion response.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// streamLLM POSTs messages with stream: true and parses the SSE response,
+// merging tool-call deltas by index and stripping <think>...</think> blocks
+// from the text as it arrives so hidden reasoning never reaches onDelta.
+func (a *Agent) streamLLM(messages []chatMessage, tools []map[string]interface{}, onDelta func(string)) (*llmStreamResult, error) {
+	if !a.breaker.allow(time.Now()) {
+		metrics.LLMBreakerShortCircuitsTotal.Inc()
+		return nil, errBreakerOpen
+	}
+	metrics.LLMCallAttemptsTotal.Inc()
+
+	reqBody := chatRequestBody(messages, tools, true)
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.apiURL+"/v1/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.recordLLMFailure()
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		a.recordLLMFailure()
+		return nil, fmt.Errorf("LLM returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var rawContent strings.Builder
+	var visible strings.Builder
+	think := &thinkFilter{}
+	sentences := &sentenceEmitter{}
+
+	appendVisible := func(v string) {
+		if v == "" {
+			return
+		}
+		visible.WriteString(v)
+		if onDelta != nil {
+			sentences.feed(v, onDelta)
+		}
+	}
+
+	toolCallsByIndex := map[int]*toolCall{}
+	var toolCallOrder []int
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+
+		if delta.Content != "" {
+			rawContent.WriteString(delta.Content)
+			appendVisible(think.feed(delta.Content))
+		}
+
+		for _, tc := range delta.ToolCalls {
+			existing, ok := toolCallsByIndex[tc.Index]
+			if !ok {
+				existing = &toolCall{}
+				toolCallsByIndex[tc.Index] = existing
+				toolCallOrder = append(toolCallOrder, tc.Index)
+			}
+			if tc.ID != "" {
+				existing.ID = tc.ID
+			}
+			if tc.Type != "" {
+				existing.Type = tc.Type
+			}
+			existing.Function.Name += tc.Function.Name
+			existing.Function.Arguments += tc.Function.Arguments
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		a.recordLLMFailure()
+		return nil, fmt.Errorf("reading stream: %w", err)
+	}
+
+	appendVisible(think.flush())
+	if onDelta != nil {
+		sentences.flush(onDelta)
+	}
+
+	sort.Ints(toolCallOrder)
+	toolCalls := make([]toolCall, 0, len(toolCallOrder))
+	for _, idx := range toolCallOrder {
+		toolCalls = append(toolCalls, *toolCallsByIndex[idx])
+	}
+
+	a.recordLLMSuccess()
+	return &llmStreamResult{
+		rawContent: rawContent.String(),
+		content:    strings.TrimSpace(visible.String()),
+		toolCalls:  toolCalls,
+	}, nil
+}
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkFilter strips <think>...</think> spans from text arriving in
+// arbitrary-sized chunks, holding back just enough of a chunk's tail to
+// avoid splitting a tag across two feed calls.
+type thinkFilter struct {
+	pending strings.Builder
+	inThink bool
+}
+
+// feed appends raw text and returns the portion of it (if any) that's now
+// confirmed to be outside a <think> block.
+func (f *thinkFilter) feed(raw string) string {
+	f.pending.WriteString(raw)
+	return f.drain(false)
+}
+
+// flush returns whatever visible text remains once no more input is coming,
+// discarding an unterminated trailing <think> block rather than leaking it.
+func (f *thinkFilter) flush() string {
+	return f.drain(true)
+}
+
+func (f *thinkFilter) drain(final bool) string {
+	var out strings.Builder
+	s := f.pending.String()
+
+	for {
+		if f.inThink {
+			idx := strings.Index(s, thinkCloseTag)
+			if idx < 0 {
+				if final {
+					s = ""
+				} else if keep := len(thinkCloseTag) - 1; len(s) > keep {
+					s = s[len(s)-keep:]
+				}
+				break
+			}
+			s = s[idx+len(thinkCloseTag):]
+			f.inThink = false
+			continue
+		}
+
+		idx := strings.Index(s, thinkOpenTag)
+		if idx < 0 {
+			if final {
+				out.WriteString(s)
+				s = ""
+			} else if keep := len(thinkOpenTag) - 1; len(s) > keep {
+				out.WriteString(s[:len(s)-keep])
+				s = s[len(s)-keep:]
+			}
+			break
+		}
+		out.WriteString(s[:idx])
+		s = s[idx+len(thinkOpenTag):]
+		f.inThink = true
+	}
+
+	f.pending.Reset()
+	f.pending.WriteString(s)
+	return out.String()
+}
+
+// streamSentenceBoundary matches a run of sentence-ending punctuation
+// followed by whitespace — a boundary we can confidently emit on mid-stream,
+// unlike end-of-text punctuation with nothing after it yet.
+var streamSentenceBoundary = regexp.MustCompile(`[.!?]+\s+`)
+
+// sentenceEmitter buffers streamed text and calls onDelta once per complete
+// sentence, so a long response starts being spoken before the model is done
+// generating it.
+type sentenceEmitter struct {
+	buf strings.Builder
+}
+
+func (e *sentenceEmitter) feed(text string, onDelta func(string)) {
+	e.buf.WriteString(text)
+	for {
+		s := e.buf.String()
+		loc := streamSentenceBoundary.FindStringIndex(s)
+		if loc == nil {
+			break
+		}
+		onDelta(s[:loc[1]])
+		e.buf.Reset()
+		e.buf.WriteString(s[loc[1]:])
+	}
+}
+
+// flush emits whatever's left in the buffer once streaming has ended.
+func (e *sentenceEmitter) flush(onDelta func(string)) {
+	if s := e.buf.String(); strings.TrimSpace(s) != "" {
+		onDelta(s)
+	}
+	e.buf.Reset()
+}
@@ -0,0 +1,84 @@
+// Package toolproviderpb is the generated-style client stub for the
+// ToolProvider service defined in ../toolprovider.proto. As with
+// internal/llm/llmserverpb, request and response are google.protobuf.Struct,
+// so the only hand-maintained code is this thin client wrapper — matching
+// what protoc-gen-go-grpc would emit for a multi-method service with one
+// server-streaming RPC.
+package toolproviderpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// serviceName is the fully-qualified service name from toolprovider.proto.
+const serviceName = "toolprovider.ToolProvider"
+
+// ToolProviderClient is the client API for the ToolProvider service.
+type ToolProviderClient interface {
+	ListTools(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	Invoke(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (ToolProvider_InvokeClient, error)
+	Health(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+}
+
+type toolProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewToolProviderClient creates a client for the ToolProvider service
+// reachable through cc.
+func NewToolProviderClient(cc grpc.ClientConnInterface) ToolProviderClient {
+	return &toolProviderClient{cc}
+}
+
+func (c *toolProviderClient) ListTools(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/ListTools", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ToolProvider_InvokeClient is the client-side stream handle for Invoke,
+// matching the shape protoc-gen-go-grpc emits for a server-streaming RPC.
+type ToolProvider_InvokeClient interface {
+	Recv() (*structpb.Struct, error)
+	grpc.ClientStream
+}
+
+type toolProviderInvokeClient struct {
+	grpc.ClientStream
+}
+
+func (c *toolProviderClient) Invoke(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (ToolProvider_InvokeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &grpc.StreamDesc{StreamName: "Invoke", ServerStreams: true}, "/"+serviceName+"/Invoke", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &toolProviderInvokeClient{stream}
+	if err := x.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *toolProviderInvokeClient) Recv() (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := x.ClientStream.RecvMsg(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *toolProviderClient) Health(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Health", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,20 +11,52 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Tool is the interface a compiled .so tool plugin must satisfy, exported
+// under the symbol name "Tool" — see plugins.go. It mirrors ToolDef/
+// ToolHandler for declarative YAML tools, but lets a plugin own its own
+// schema and implementation instead of shelling out to an HTTP endpoint.
+type Tool interface {
+	Name() string
+	Schema() json.RawMessage
+	Invoke(ctx context.Context, args json.RawMessage) (string, error)
+}
+
 // ToolDef describes a tool loaded from YAML.
 type ToolDef struct {
 	Name        string                 `yaml:"name"`
 	Type        string                 `yaml:"type"`
 	Description string                 `yaml:"description"`
 	Parameters  map[string]interface{} `yaml:"parameters"`
+
+	// HTTP configures a "type: http" (or "type: webhook") tool — see
+	// HTTPToolConfig. Nil for builtin tool types.
+	HTTP *HTTPToolConfig `yaml:"http,omitempty"`
+
+	// TimeoutMS overrides defaultToolTimeout for this tool; 0 uses the
+	// default. Only settable by a gRPC tool provider's ListTools today (see
+	// grpctool.go) — YAML tool defs don't expose it yet.
+	TimeoutMS int
+
+	// ShowToUser marks a tool whose result should be surfaced to the user
+	// directly rather than only fed back to the LLM for it to summarize —
+	// e.g. a provider that returns a large table or a file listing better
+	// read verbatim than re-narrated. Same provenance as TimeoutMS.
+	ShowToUser bool
 }
 
-// ToolHandler executes a tool call and returns the result text.
-type ToolHandler func(args map[string]interface{}) (string, error)
+// ToolHandler executes a tool call and returns the result text. ctx carries
+// the per-tool timeout (and the shared per-turn tool budget) Agent sets up
+// before calling the handler; handlers that make network calls should
+// thread it through so a slow tool can actually be cancelled.
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (string, error)
 
 // builtinHandlers maps tool type strings to their Go implementations.
+// "http"/"webhook" tools aren't listed here — they're per-instance, built by
+// NewHTTPToolHandler from each tool's own HTTPToolConfig, and wired up in
+// NewAgent instead.
 var builtinHandlers = map[string]ToolHandler{
 	"web_search": WebSearchHandler,
+	"fetch_url":  FetchURLHandler,
 }
 
 // LoadTools reads all .yaml files from dir and returns parsed ToolDefs.
@@ -70,10 +104,34 @@ parameters:
     query:
       type: string
       description: "The search query"
+    engine:
+      type: string
+      description: "Optional: name of a specific configured search provider to use instead of the default priority order"
   required:
     - query
 `
-	return os.WriteFile(wsPath, []byte(defaultYAML), 0644)
+	if err := os.WriteFile(wsPath, []byte(defaultYAML), 0644); err != nil {
+		return err
+	}
+
+	fetchPath := filepath.Join(dir, "fetch_url.yaml")
+	if _, err := os.Stat(fetchPath); err == nil {
+		return nil
+	}
+
+	fetchYAML := `name: fetch_url
+type: fetch_url
+description: "Fetch a web page by URL and extract its main readable text (stripped of navigation, ads, and other boilerplate). Use this as a followup to web_search when a result's snippet isn't enough to answer the question."
+parameters:
+  type: object
+  properties:
+    url:
+      type: string
+      description: "The page URL to fetch, usually one returned by a prior web_search call"
+  required:
+    - url
+`
+	return os.WriteFile(fetchPath, []byte(fetchYAML), 0644)
 }
 
 // BuildOpenAITools converts ToolDefs into the OpenAI function calling format.
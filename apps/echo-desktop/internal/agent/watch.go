@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"log"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// toolsWatchDebounce mirrors llm.profilesWatchDebounce: coalesce the burst
+// of write/create/rename events a single tool save (or plugin rebuild) can
+// produce into one ReloadTools call.
+const toolsWatchDebounce = 250 * time.Millisecond
+
+// WatchTools watches a.toolsDir and calls a.ReloadTools whenever it
+// changes, so editing a tool's YAML — or dropping in a freshly built .so
+// plugin — picks up on save without restarting voice-relay. Like
+// llm.WatchPlugins, an already-loaded Go plugin can't actually be
+// unloaded or replaced in-process; this only picks up *new* plugin files
+// or YAML-only changes until the process restarts. Returns a stop function
+// that shuts the watcher down; callers should defer it.
+func (a *Agent) WatchTools() (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(a.toolsDir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go a.watchToolsLoop(watcher, done)
+
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+func (a *Agent) watchToolsLoop(watcher *fsnotify.Watcher, done chan struct{}) {
+	var debounce *time.Timer
+
+	reload := func() {
+		if err := a.ReloadTools(); err != nil {
+			log.Printf("agent: reloading tools: %v", err)
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.AfterFunc(toolsWatchDebounce, reload)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("agent: tools watcher error: %v", err)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
+	"sync"
 )
 
 // SearchResult holds a single web search result.
@@ -16,23 +18,83 @@ type SearchResult struct {
 	Snippet string
 }
 
-// WebSearchHandler is the builtin handler for the web_search tool type.
-func WebSearchHandler(args map[string]interface{}) (string, error) {
+// SearchProvider is implemented by every backend WebSearchHandler can query:
+// the builtin DDG Lite/SearXNG/Brave/Kagi adapters (see provider.go) and any
+// generic adapter built from an OpenSearch XML descriptor (see opensearch.go).
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+}
+
+var (
+	searchProvidersMu sync.RWMutex
+	searchProviders   = []SearchProvider{&ddgLiteProvider{name: "ddg"}}
+)
+
+// ConfigureSearchProviders replaces the provider list WebSearchHandler fails
+// over across, in priority order. Called once at startup from main.go after
+// cfg.SearchProviders and the searchproviders/ directory are loaded (see
+// LoadSearchProviders); an empty list restores the built-in DDG Lite default
+// instead of leaving web_search with nothing to call.
+func ConfigureSearchProviders(providers []SearchProvider) {
+	if len(providers) == 0 {
+		providers = []SearchProvider{&ddgLiteProvider{name: "ddg"}}
+	}
+	searchProvidersMu.Lock()
+	searchProviders = providers
+	searchProvidersMu.Unlock()
+}
+
+func configuredSearchProviders() []SearchProvider {
+	searchProvidersMu.RLock()
+	defer searchProvidersMu.RUnlock()
+	return searchProviders
+}
+
+// WebSearchHandler is the builtin handler for the web_search tool type. It
+// walks the configured SearchProviders in priority order, failing over to
+// the next on error or an empty result set, so a single provider going down
+// (or DDG changing its markup) doesn't take down the whole tool. args may
+// carry an optional "engine" hint naming a specific provider; if given and
+// recognized, only that provider is tried.
+func WebSearchHandler(ctx context.Context, args map[string]interface{}) (string, error) {
 	query, _ := args["query"].(string)
 	if query == "" {
 		return "Error: no search query provided", nil
 	}
 
-	results, err := searchDuckDuckGo(query)
-	if err != nil {
-		return fmt.Sprintf("Search failed: %v. I'll answer based on what I know.", err), nil
+	providers := configuredSearchProviders()
+	if hint, _ := args["engine"].(string); hint != "" {
+		for _, p := range providers {
+			if p.Name() == hint {
+				providers = []SearchProvider{p}
+				break
+			}
+		}
 	}
 
-	if len(results) == 0 {
-		return "No search results found.", nil
+	var lastErr error
+	for _, p := range providers {
+		results, err := p.Search(ctx, query)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", p.Name(), err)
+			log.Printf("web_search: %s failed, trying next provider: %v", p.Name(), err)
+			continue
+		}
+		if len(results) == 0 {
+			continue
+		}
+		return formatSearchResults(query, results), nil
 	}
 
-	// Format results as readable text for the LLM
+	if lastErr != nil {
+		return fmt.Sprintf("Search failed: %v. I'll answer based on what I know.", lastErr), nil
+	}
+	return "No search results found.", nil
+}
+
+// formatSearchResults renders results as readable text for the LLM.
+func formatSearchResults(query string, results []SearchResult) string {
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Search results for: %s\n\n", query))
 	for i, r := range results {
@@ -42,15 +104,24 @@ func WebSearchHandler(args map[string]interface{}) (string, error) {
 		}
 		sb.WriteString(fmt.Sprintf("   %s\n\n", r.URL))
 	}
-	return sb.String(), nil
+	return sb.String()
 }
 
-// searchDuckDuckGo queries DuckDuckGo HTML Lite and parses results.
-func searchDuckDuckGo(query string) ([]SearchResult, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
+// ddgLiteProvider is the original, dependency-free default: it scrapes
+// DuckDuckGo's HTML Lite endpoint, which needs no API key or extra config.
+// It stays the fallback of last resort in ConfigureSearchProviders' default
+// list even once other providers are configured.
+type ddgLiteProvider struct {
+	name string
+}
+
+func (p *ddgLiteProvider) Name() string { return p.name }
+
+func (p *ddgLiteProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	client := &http.Client{}
 
 	form := url.Values{"q": {query}}
-	req, err := http.NewRequest("POST", "https://lite.duckduckgo.com/lite/", strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://lite.duckduckgo.com/lite/", strings.NewReader(form.Encode()))
 	if err != nil {
 		return nil, err
 	}
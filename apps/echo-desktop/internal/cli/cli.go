@@ -0,0 +1,469 @@
+// Package cli implements voice-relay's non-interactive subcommand surface, for
+// scripted setup and operation on headless machines (Docker, systemd units,
+// unattended installs) where the Zenity wizard and tray icon aren't usable.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/voice-relay/echo-desktop/internal/config"
+	"github.com/voice-relay/echo-desktop/internal/coordinator"
+	"github.com/voice-relay/echo-desktop/internal/hookrunner"
+	"github.com/voice-relay/echo-desktop/internal/setup"
+	"github.com/voice-relay/echo-desktop/internal/updater"
+)
+
+// Exit codes returned by Run, matching the conventions expected by process
+// supervisors (systemd, Docker healthchecks) driving voice-relay headlessly.
+const (
+	ExitSuccess       = 0
+	ExitError         = 1
+	ExitConfigInvalid = 2
+	ExitNoUpdate      = 3
+	ExitUpdateFailed  = 4
+)
+
+// subcommandNames lists every first argument main() should route into Run instead
+// of launching the tray app.
+var subcommandNames = map[string]bool{
+	"setup":   true,
+	"connect": true,
+	"config":  true,
+	"update":  true,
+	"status":  true,
+	"serve":   true,
+	"hook":    true,
+}
+
+// IsSubcommand reports whether arg names one of voice-relay's CLI subcommands.
+func IsSubcommand(arg string) bool {
+	return subcommandNames[arg]
+}
+
+// Run executes the CLI app against args (typically os.Args) and returns the
+// process exit code to use.
+func Run(args []string) int {
+	exitCode := ExitSuccess
+
+	app := &cli.App{
+		Name:  "voice-relay",
+		Usage: "Voice Relay desktop agent",
+		Commands: []*cli.Command{
+			setupCommand(&exitCode),
+			connectCommand(&exitCode),
+			configCommand(&exitCode),
+			updateCommand(&exitCode),
+			statusCommand(&exitCode),
+			serveCommand(&exitCode),
+			hookCommand(&exitCode),
+		},
+	}
+
+	if err := app.Run(args); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		if exitCode == ExitSuccess {
+			exitCode = ExitError
+		}
+	}
+
+	return exitCode
+}
+
+func setupCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:  "setup",
+		Usage: "configure voice-relay without the interactive wizard",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "coordinator", Usage: "run this machine as the coordinator"},
+			&cli.IntFlag{Name: "port", Usage: "coordinator port", Value: config.DefaultPort},
+			&cli.StringFlag{Name: "name", Usage: "device name"},
+			&cli.StringFlag{Name: "llm-model", Usage: "LLM model to use for cleanup/notifications"},
+			&cli.BoolFlag{Name: "no-wizard", Usage: "accepted for compatibility; setup never launches the wizard from the CLI"},
+		},
+		Action: func(c *cli.Context) error {
+			cfg := config.Load()
+
+			cfg.RunAsCoordinator = c.Bool("coordinator")
+			if c.IsSet("port") {
+				cfg.Port = c.Int("port")
+			}
+			if c.IsSet("name") {
+				cfg.Name = c.String("name")
+			}
+			if c.IsSet("llm-model") {
+				cfg.LLMModel = c.String("llm-model")
+			}
+
+			cfg.SetupComplete = true
+			cfg.Save()
+
+			fmt.Printf("Configuration saved to %s\n", config.Path())
+			return nil
+		},
+	}
+}
+
+func connectCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:      "connect",
+		Usage:     "connect this device to a coordinator by code or URL",
+		ArgsUsage: "<code-or-url>",
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				*exitCode = ExitConfigInvalid
+				return errors.New("expected exactly one argument: a connection code or URL")
+			}
+
+			cfg := config.Load()
+
+			wsURL, err := setup.ResolveCoordinatorURLWithProviders(c.Args().First(), cfg.ShortURLProviders)
+			if err != nil {
+				*exitCode = ExitError
+				return fmt.Errorf("resolving coordinator: %w", err)
+			}
+
+			cfg.RunAsCoordinator = false
+			cfg.CoordinatorURL = wsURL
+			cfg.SetupComplete = true
+			cfg.Save()
+
+			fmt.Printf("Connected to %s\n", wsURL)
+			return nil
+		},
+	}
+}
+
+func configCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "inspect or edit the config file",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "get",
+				Usage:     "print a config value",
+				ArgsUsage: "<key>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						*exitCode = ExitConfigInvalid
+						return errors.New("expected exactly one argument: the key to read")
+					}
+					value, err := configGet(config.Load(), c.Args().First())
+					if err != nil {
+						*exitCode = ExitConfigInvalid
+						return err
+					}
+					fmt.Println(value)
+					return nil
+				},
+			},
+			{
+				Name:      "set",
+				Usage:     "set a config value",
+				ArgsUsage: "<key> <value>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						*exitCode = ExitConfigInvalid
+						return errors.New("expected exactly two arguments: the key and the value to set")
+					}
+					cfg := config.Load()
+					if err := configSet(cfg, c.Args().Get(0), c.Args().Get(1)); err != nil {
+						*exitCode = ExitConfigInvalid
+						return err
+					}
+					cfg.Save()
+					return nil
+				},
+			},
+			{
+				Name:  "reset",
+				Usage: "delete the config file and regenerate defaults",
+				Action: func(c *cli.Context) error {
+					os.Remove(config.Path())
+					config.Load()
+					fmt.Printf("Config reset to defaults at %s\n", config.Path())
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// configGet and configSet cover the handful of fields worth scripting; anything
+// more exotic (e.g. ShortURLProviders) is still editable by hand in the YAML file.
+func configGet(cfg *config.Config, key string) (string, error) {
+	switch key {
+	case "name":
+		return cfg.Name, nil
+	case "coordinator_url":
+		return cfg.CoordinatorURL, nil
+	case "port":
+		return strconv.Itoa(cfg.Port), nil
+	case "run_as_coordinator":
+		return strconv.FormatBool(cfg.RunAsCoordinator), nil
+	case "llm_model":
+		return cfg.LLMModel, nil
+	case "llm_enabled":
+		return strconv.FormatBool(cfg.LLMEnabled), nil
+	case "output_mode":
+		return cfg.OutputMode, nil
+	default:
+		return "", fmt.Errorf("unknown key %q", key)
+	}
+}
+
+func configSet(cfg *config.Config, key, value string) error {
+	switch key {
+	case "name":
+		cfg.Name = value
+	case "coordinator_url":
+		cfg.CoordinatorURL = value
+	case "port":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("port must be an integer: %w", err)
+		}
+		cfg.Port = port
+	case "run_as_coordinator":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("run_as_coordinator must be true/false: %w", err)
+		}
+		cfg.RunAsCoordinator = b
+	case "llm_model":
+		cfg.LLMModel = value
+	case "llm_enabled":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("llm_enabled must be true/false: %w", err)
+		}
+		cfg.LLMEnabled = b
+	case "output_mode":
+		cfg.OutputMode = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+func updateCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:  "update",
+		Usage: "check for or apply an update",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{Name: "check", Usage: "only check whether an update is available"},
+			&cli.BoolFlag{Name: "apply", Usage: "download and install the latest update"},
+		},
+		Action: func(c *cli.Context) error {
+			configureUpdater(config.Load())
+
+			switch {
+			case c.Bool("apply"):
+				if err := updater.Apply(); err != nil {
+					if errors.Is(err, updater.ErrNoUpdate) {
+						*exitCode = ExitNoUpdate
+						fmt.Println("Already on the latest version.")
+						return nil
+					}
+					*exitCode = ExitUpdateFailed
+					return err
+				}
+				fmt.Println("Update installed. Restart voice-relay to use it.")
+				return nil
+
+			default: // --check, or no flag given
+				version, available, err := updater.CheckOnly()
+				if err != nil {
+					*exitCode = ExitError
+					return err
+				}
+				if !available {
+					*exitCode = ExitNoUpdate
+					fmt.Printf("Already on the latest version (%s).\n", updater.CurrentVersion)
+					return nil
+				}
+				fmt.Printf("Update available: %s (current: %s)\n", version, updater.CurrentVersion)
+				return nil
+			}
+		},
+	}
+}
+
+// configureUpdater points internal/updater at cfg's configured release
+// source, translating config.UpdaterSpec to updater.FetcherConfig field by
+// field the same way main.go maps config.LLMBackendSpec to llm.BackendSpec.
+func configureUpdater(cfg *config.Config) {
+	updater.Configure(updater.Config{Fetcher: updater.FetcherConfig{
+		Source:      cfg.Updater.Source,
+		RepoOwner:   cfg.Updater.RepoOwner,
+		RepoName:    cfg.Updater.RepoName,
+		ManifestURL: cfg.Updater.ManifestURL,
+		BucketURL:   cfg.Updater.BucketURL,
+	}})
+}
+
+func serveCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "run the coordinator hub under a supervisor, with zero-downtime updates (Linux/macOS only)",
+		Action: func(c *cli.Context) error {
+			if updater.IsSlave() {
+				return updater.RunSlave(coordinator.StartOnListener, coordinator.PrepareUpgrade)
+			}
+
+			if runtime.GOOS == "windows" {
+				*exitCode = ExitError
+				return fmt.Errorf("serve is not supported on Windows; run voice-relay normally instead")
+			}
+
+			cfg := config.Load()
+			configureUpdater(cfg)
+
+			sup, err := updater.NewSupervisor()
+			if err != nil {
+				*exitCode = ExitError
+				return err
+			}
+
+			// Check for an update once at startup, same as the tray app's
+			// `go updater.CheckForUpdates()` — but applied via the supervisor
+			// so the swap doesn't drop whatever the slave is already serving.
+			go func() {
+				time.Sleep(2 * time.Second)
+				if err := sup.CheckAndApplyUpdate(); err != nil && !errors.Is(err, updater.ErrNoUpdate) {
+					log.Printf("Supervised update check failed: %v", err)
+				}
+			}()
+
+			if err := sup.Run(fmt.Sprintf(":%d", cfg.Port)); err != nil {
+				*exitCode = ExitError
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+func statusCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "print the current configuration and connection mode",
+		Action: func(c *cli.Context) error {
+			cfg := config.Load()
+
+			fmt.Printf("Version:            %s\n", updater.CurrentVersion)
+			fmt.Printf("Config:             %s\n", config.Path())
+			fmt.Printf("Name:               %s\n", cfg.Name)
+			if cfg.RunAsCoordinator {
+				fmt.Printf("Mode:               coordinator (port %d)\n", cfg.Port)
+			} else {
+				fmt.Printf("Mode:               client\n")
+				fmt.Printf("Coordinator URL:    %s\n", cfg.CoordinatorURL)
+			}
+			fmt.Printf("LLM enabled:        %v (%s)\n", cfg.LLMEnabled, cfg.LLMModel)
+			return nil
+		},
+	}
+}
+
+// hookCommand is what the shim scripts install.Install writes actually run:
+// "voice-relay hook stop" and "voice-relay hook ask-intercept" read the
+// Claude Code hook JSON off stdin and hand it to internal/hookrunner. This
+// is hidden from --help — it's an implementation detail of the hook
+// scripts, not something a user would invoke by hand.
+func hookCommand(exitCode *int) *cli.Command {
+	return &cli.Command{
+		Name:   "hook",
+		Hidden: true,
+		Subcommands: []*cli.Command{
+			{
+				Name: "stop",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "notif-dir", Usage: "pending-notification directory (defaults to the user config dir; set by project-scoped hook installs)"},
+				},
+				Action: func(c *cli.Context) error {
+					notifDir := c.String("notif-dir")
+					if notifDir == "" {
+						notifDir = filepath.Join(config.Dir(), "notifications")
+					}
+					cfg := config.Load()
+					debounceWindow := time.Duration(cfg.StopDebounceWindowMS) * time.Millisecond
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunStop(os.Stdin, notifDir, coordinatorURL, debounceWindow); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name: "ask-intercept",
+				Action: func(c *cli.Context) error {
+					cfg := config.Load()
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunAskIntercept(os.Stdin, coordinatorURL); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name: "notification",
+				Action: func(c *cli.Context) error {
+					cfg := config.Load()
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunNotification(os.Stdin, coordinatorURL); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name: "subagent-stop",
+				Action: func(c *cli.Context) error {
+					cfg := config.Load()
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunSubagentStop(os.Stdin, coordinatorURL); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name: "pre-compact",
+				Action: func(c *cli.Context) error {
+					cfg := config.Load()
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunPreCompact(os.Stdin, coordinatorURL); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name: "user-prompt-submit",
+				Action: func(c *cli.Context) error {
+					cfg := config.Load()
+					coordinatorURL := fmt.Sprintf("http://localhost:%d", cfg.Port)
+					if err := hookrunner.RunUserPromptSubmit(os.Stdin, coordinatorURL); err != nil {
+						*exitCode = ExitError
+						return err
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -1,12 +1,16 @@
 package client
 
 import (
+	"encoding/base64"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/atotto/clipboard"
 	"github.com/gorilla/websocket"
 
+	"github.com/voice-relay/echo-desktop/internal/discovery"
+	"github.com/voice-relay/echo-desktop/internal/e2e"
 	"github.com/voice-relay/echo-desktop/internal/keyboard"
 )
 
@@ -15,36 +19,105 @@ type Message struct {
 	Type    string `json:"type"`
 	Name    string `json:"name,omitempty"`
 	Content string `json:"content,omitempty"`
+
+	// E2E-sealed content (see internal/e2e.Sealer). When Ciphertext is set,
+	// Content is empty and the receiver must Open it before use.
+	Ciphertext string `json:"ciphertext,omitempty"`
+	Nonce      string `json:"nonce,omitempty"`
+	Epoch      int    `json:"epoch,omitempty"`
+
+	// Handshake material for Type "handshake" (see internal/e2e.Sealer.
+	// StartHandshake/CompleteHandshake) — PeerKey and Tag are base64 of the
+	// [32]byte values those methods return.
+	PeerKey string `json:"peerKey,omitempty"`
+	Tag     string `json:"tag,omitempty"`
 }
 
+// rehandshakeInterval is how often a connected Client with a Sealer
+// re-runs the handshake to rotate its session key with the coordinator,
+// bounding how much gets exposed if a later session key leaks.
+const rehandshakeInterval = 30 * time.Minute
+
+// coordinatorPeer is the peer name Sealer uses for the coordinator side of
+// this connection. There's exactly one coordinator per connection, so a
+// constant stands in for a real peer name.
+const coordinatorPeer = "coordinator"
+
 // StatusFunc is called to update the UI with connection status.
 type StatusFunc func(connected bool, status string)
 
+// DiscoverFunc is called with the coordinators found on the local network
+// whenever discovery's browse loop gets a fresh answer. It's only used while
+// CoordinatorURL is empty (see Run); a client started with a fixed URL never
+// browses, so it's never called.
+type DiscoverFunc func(instances []discovery.Instance)
+
+// InterimFunc is called with each in-progress dictation hypothesis the
+// coordinator sends as a "stt_interim" message (see
+// coordinator.PublishInterimText), ahead of the "text" message that carries
+// the final, pasted result. Nil if the caller has no preview UI to update.
+type InterimFunc func(text string)
+
+// discoverInterval is how often Run re-browses for a moved coordinator once
+// one has been auto-selected.
+const discoverInterval = 15 * time.Second
+
 // Client manages the WebSocket connection to the coordinator.
 type Client struct {
 	Name           string
 	CoordinatorURL string
 	OnStatus       StatusFunc
+	OnDiscover     DiscoverFunc
+	OnInterim      InterimFunc
 	Reconnect      chan bool
 
+	// Passphrase, when set, turns on end-to-end encryption of "text"
+	// messages (see Sealer): New uses it to construct a Sealer, and connect
+	// runs a handshake with the coordinator before trusting any message.
+	Passphrase string
+
 	conn       *websocket.Conn
 	lastText   string
 	lastTextAt time.Time
+	watcher    *discovery.Watcher
+	selected   *discovery.Instance
+	sealer     *e2e.Sealer
+	stopRotate chan struct{}
 }
 
-// New creates a new echo client.
-func New(name, coordinatorURL string, onStatus StatusFunc) *Client {
-	return &Client{
+// New creates a new echo client. If coordinatorURL is empty, Run resolves one
+// by browsing mDNS for voice-relay coordinators instead of dialing a fixed
+// address, picking the first instance it finds and then watching for it to
+// move to a new address. If passphrase is non-empty, Run end-to-end
+// encrypts dictated text with the coordinator instead of trusting it as
+// plaintext — see Sealer.
+func New(name, coordinatorURL, passphrase string, onStatus StatusFunc) *Client {
+	c := &Client{
 		Name:           name,
 		CoordinatorURL: coordinatorURL,
+		Passphrase:     passphrase,
 		OnStatus:       onStatus,
 		Reconnect:      make(chan bool, 1),
 	}
+	if passphrase != "" {
+		c.sealer = e2e.New(passphrase)
+	}
+	return c
 }
 
 // Run starts the connection manager loop. It blocks forever, reconnecting as needed.
 func (c *Client) Run() {
+	defer func() {
+		if c.watcher != nil {
+			c.watcher.Stop()
+		}
+	}()
+
 	for {
+		if c.CoordinatorURL == "" {
+			c.discoverAndSelect()
+		}
+
 		c.connect()
 
 		select {
@@ -55,6 +128,53 @@ func (c *Client) Run() {
 	}
 }
 
+// discoverAndSelect blocks until a coordinator is found via mDNS, then starts
+// a Watcher so a later move to a different address triggers a reconnect.
+func (c *Client) discoverAndSelect() {
+	c.OnStatus(false, "Looking for a coordinator...")
+	for {
+		instances, err := discovery.Browse(3 * time.Second)
+		if err == nil && len(instances) > 0 {
+			if c.OnDiscover != nil {
+				c.OnDiscover(instances)
+			}
+			c.selectInstance(instances[0])
+			break
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	if c.watcher == nil {
+		c.watcher = discovery.Watch(discoverInterval, c.onDiscoveryUpdate)
+	}
+}
+
+func (c *Client) onDiscoveryUpdate(instances []discovery.Instance) {
+	if c.OnDiscover != nil {
+		c.OnDiscover(instances)
+	}
+	if c.selected == nil {
+		return
+	}
+	for _, inst := range instances {
+		if inst.Name != c.selected.Name {
+			continue
+		}
+		url := fmt.Sprintf("ws://%s:%d/ws", inst.Host, inst.Port)
+		if url != c.CoordinatorURL {
+			log.Printf("Coordinator %s moved to %s", inst.Name, url)
+			c.selectInstance(inst)
+			c.TriggerReconnect()
+		}
+		return
+	}
+}
+
+func (c *Client) selectInstance(inst discovery.Instance) {
+	c.selected = &inst
+	c.CoordinatorURL = fmt.Sprintf("ws://%s:%d/ws", inst.Host, inst.Port)
+}
+
 // Close closes the current connection if any.
 func (c *Client) Close() {
 	if c.conn != nil {
@@ -89,6 +209,14 @@ func (c *Client) connect() {
 		return
 	}
 
+	if c.sealer != nil {
+		if err := c.sendHandshake(conn); err != nil {
+			log.Printf("e2e: starting handshake failed: %v", err)
+		}
+		c.stopRotate = make(chan struct{})
+		go c.rotateHandshakes(conn, c.stopRotate)
+	}
+
 	c.OnStatus(true, "Connected as "+c.Name)
 	log.Printf("Connected as %s", c.Name)
 
@@ -102,17 +230,134 @@ func (c *Client) connect() {
 		switch msg.Type {
 		case "registered":
 			log.Printf("Registered as: %s", msg.Name)
+		case "handshake":
+			c.handleHandshake(conn, msg)
 		case "text":
-			c.handleText(msg.Content)
+			c.handleText(msg)
+		case "stt_interim":
+			if c.OnInterim != nil {
+				c.OnInterim(msg.Content)
+			}
+		case "reconnect":
+			// The coordinator is about to hand off to a freshly spawned
+			// process (see updater.RunSlave's onDrain hook); redial right
+			// away instead of waiting out this connection's death.
+			log.Println("Coordinator requested reconnect (upgrade in progress)")
+			c.TriggerReconnect()
+			conn.Close()
 		}
 	}
 
+	if c.stopRotate != nil {
+		close(c.stopRotate)
+		c.stopRotate = nil
+	}
 	conn.Close()
 	c.conn = nil
 	c.OnStatus(false, "Disconnected")
 }
 
-func (c *Client) handleText(text string) {
+// sendHandshake starts (or rotates) the e2e session key with the
+// coordinator by sending a fresh ephemeral public key and passphrase tag
+// (see e2e.Sealer.StartHandshake).
+func (c *Client) sendHandshake(conn *websocket.Conn) error {
+	pub, tag, epoch, err := c.sealer.StartHandshake(coordinatorPeer)
+	if err != nil {
+		return err
+	}
+	return conn.WriteJSON(Message{
+		Type:    "handshake",
+		PeerKey: base64.StdEncoding.EncodeToString(pub[:]),
+		Tag:     base64.StdEncoding.EncodeToString(tag[:]),
+		Epoch:   epoch,
+	})
+}
+
+// handleHandshake completes a "handshake" message from the coordinator,
+// replying with our own ephemeral public key if the coordinator was the one
+// that initiated (see e2e.Sealer.CompleteHandshake).
+func (c *Client) handleHandshake(conn *websocket.Conn, msg Message) {
+	pubBytes, err := base64.StdEncoding.DecodeString(msg.PeerKey)
+	if err != nil || len(pubBytes) != 32 {
+		log.Printf("e2e: invalid handshake public key from coordinator")
+		return
+	}
+	tagBytes, err := base64.StdEncoding.DecodeString(msg.Tag)
+	if err != nil || len(tagBytes) != 32 {
+		log.Printf("e2e: invalid handshake tag from coordinator")
+		return
+	}
+	var pub, tag [32]byte
+	copy(pub[:], pubBytes)
+	copy(tag[:], tagBytes)
+
+	ourPub, ourTag, responded, err := c.sealer.CompleteHandshake(coordinatorPeer, pub, tag, msg.Epoch)
+	if err != nil {
+		log.Printf("e2e: handshake with coordinator failed: %v", err)
+		return
+	}
+	if responded {
+		conn.WriteJSON(Message{
+			Type:    "handshake",
+			PeerKey: base64.StdEncoding.EncodeToString(ourPub[:]),
+			Tag:     base64.StdEncoding.EncodeToString(ourTag[:]),
+			Epoch:   msg.Epoch,
+		})
+	}
+	log.Printf("e2e: session key established with coordinator (epoch %d)", msg.Epoch)
+}
+
+// rotateHandshakes periodically re-runs the handshake with the coordinator
+// to rotate the e2e session key for forward secrecy, until stop is closed —
+// which happens when this connection ends (see connect).
+func (c *Client) rotateHandshakes(conn *websocket.Conn, stop chan struct{}) {
+	ticker := time.NewTicker(rehandshakeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.sendHandshake(conn); err != nil {
+				log.Printf("e2e: key rotation failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (c *Client) handleText(msg Message) {
+	text := msg.Content
+
+	if c.sealer != nil {
+		if msg.Ciphertext == "" {
+			log.Println("Rejecting unencrypted text message: end-to-end encryption is configured")
+			return
+		}
+		if !c.sealer.Established(coordinatorPeer) {
+			log.Println("Rejecting text message: no e2e session key established with the coordinator yet")
+			return
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(msg.Ciphertext)
+		if err != nil {
+			log.Printf("e2e: invalid ciphertext: %v", err)
+			return
+		}
+		nonce, err := base64.StdEncoding.DecodeString(msg.Nonce)
+		if err != nil {
+			log.Printf("e2e: invalid nonce: %v", err)
+			return
+		}
+		plaintext, err := c.sealer.Open(coordinatorPeer, ciphertext, nonce, msg.Epoch)
+		if err != nil {
+			log.Printf("e2e: decryption failed, dropping message: %v", err)
+			return
+		}
+		text = string(plaintext)
+	} else if msg.Ciphertext != "" {
+		log.Println("Ignoring encrypted text message: no passphrase configured to decrypt it")
+		return
+	}
+
 	if text == "" {
 		return
 	}
@@ -20,19 +20,245 @@ type Config struct {
 	OutputMode     string `yaml:"output_mode"` // "paste" or "type"
 	SetupComplete  bool   `yaml:"setup_complete,omitempty"`
 
+	// E2EPassphrase, when set, turns on end-to-end encryption of dictated
+	// text between this device and the coordinator (see internal/e2e and
+	// client.Client's Sealer field): both the echo client and the
+	// coordinator it pairs with must be configured with the same
+	// passphrase, shared out of band (typed in, or via the pairing QR
+	// code). Leave empty to keep the existing plaintext "text" messages.
+	E2EPassphrase string `yaml:"e2e_passphrase,omitempty"`
+
 	// Coordinator mode
-	RunAsCoordinator bool   `yaml:"run_as_coordinator,omitempty"`
-	Port             int    `yaml:"port,omitempty"`           // default 53937
-	WhisperModel     string `yaml:"whisper_model,omitempty"`  // default "base"
-	LLMModel         string `yaml:"llm_model,omitempty"`      // default "qwen3-0.6b"
-	LLMEnabled       bool   `yaml:"llm_enabled,omitempty"`    // default true
+	RunAsCoordinator     bool   `yaml:"run_as_coordinator,omitempty"`
+	Port                 int    `yaml:"port,omitempty"`                    // default 53937
+	WhisperModel         string `yaml:"whisper_model,omitempty"`           // default "base"
+	LLMModel             string `yaml:"llm_model,omitempty"`               // default "qwen3-0.6b"
+	LLMEnabled           bool   `yaml:"llm_enabled,omitempty"`             // default true
+	LLMWorkers           int    `yaml:"llm_workers,omitempty"`             // default 1; concurrent requests the local llama-server engine serves at once
+	AgentRetryLimit      int    `yaml:"agent_retry_limit,omitempty"`       // default 3; Agent.callLLM attempts before giving up and tripping its circuit breaker
+	StopDebounceWindowMS int    `yaml:"stop_debounce_window_ms,omitempty"` // default 1500; Stop hook notifications for the same session/reply_target arriving within this window are merged into one instead of firing separate TTS
+	AgentToolsSafeMode   bool   `yaml:"agent_tools_safe_mode,omitempty"`   // when true, disables loading compiled .so tool plugins from the tools dir — built-in and declarative YAML tools, and AgentToolProviders' out-of-process gRPC providers, still load
+	DesktopNotifications bool   `yaml:"desktop_notifications,omitempty"`   // default true; also post each notification as a native OS toast, not just to the PWA
+
+	// ShortURLProviders is the ordered chain of providers tried when resolving a bare
+	// connection code to a coordinator URL. Recognized values: "is.gd", "tinyurl",
+	// "self-hosted" (queries the coordinator's own /code/{code} endpoint over
+	// Tailscale/Funnel), "dns" (looks up a _voicerelay.<code>.<domain> TXT record), or
+	// a raw "https://host/{code}"-style template treated as a generic JSON resolver.
+	ShortURLProviders []string `yaml:"short_url_providers,omitempty"`
+
+	// TrustedProxies lists CIDRs of reverse proxies (e.g. a local Tailscale Funnel
+	// listener) whose X-Forwarded-For header the coordinator will honor when
+	// resolving a request's real client IP. Requests arriving directly from an
+	// address outside this list have their RemoteAddr used as-is, since an
+	// untrusted peer could set X-Forwarded-For to anything.
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
+
+	// Notification dispatch sink credentials, shared across every routing rule
+	// (see internal/notifications/dispatch.go). A rule references a sink by a
+	// "kind:target" spec like "webhook:https://…"; these fields supply the
+	// secrets that spec alone can't carry.
+	NotifWebhookSecret string `yaml:"notif_webhook_secret,omitempty"` // HMAC key for X-Voicerelay-Signature
+	NotifSMTPRelay     string `yaml:"notif_smtp_relay,omitempty"`     // host:port, e.g. "smtp.gmail.com:587"
+	NotifSMTPFrom      string `yaml:"notif_smtp_from,omitempty"`
+	NotifSMTPUser      string `yaml:"notif_smtp_user,omitempty"`
+	NotifSMTPPassword  string `yaml:"notif_smtp_password,omitempty"`
+	NotifFCMServerKey  string `yaml:"notif_fcm_server_key,omitempty"` // legacy FCM HTTP server key
+
+	// Engines declares additional TTS/STT backends beyond the built-in Piper and
+	// whisper-server, keyed by the name a "voice" value references as "<name>:<voice>"
+	// (see internal/engine.SplitVoiceSpec) instead of a bare Piper voice name. For
+	// example an entry named "elevenlabs" of type "openai" lets TTSVoice or a
+	// /tts-preview request voice of "elevenlabs:Rachel" route long narration to a
+	// remote high-quality engine while short interactive replies stay on local Piper.
+	Engines map[string]EngineSpec `yaml:"engines,omitempty"`
+
+	// STTRouter routes /transcribe's speech-to-text call across the
+	// Recognizer backends declared in Engines instead of always using the
+	// local whisper-server, falling back to it if the chosen backend errors
+	// (a cloud call failing, or the network being down). An empty Rules list
+	// preserves today's behavior of always using the local engine.
+	STTRouter STTRouterSpec `yaml:"stt_router,omitempty"`
+
+	// LLMBackends declares additional LLM backends beyond the default local
+	// llama-server, keyed by a name (see internal/llm.Registry). LLMDefaultBackend
+	// selects which one CleanupText/GenerateNotification use; an empty value (or
+	// one naming a backend that fails to start) falls back to the local engine.
+	// For example an entry named "shared" of type "grpc" pointing at a GPU box on
+	// the Tailscale network lets every coordinator on the tailnet share one model
+	// instead of each running its own local llama-server.
+	LLMBackends       map[string]LLMBackendSpec `yaml:"llm_backends,omitempty"`
+	LLMDefaultBackend string                    `yaml:"llm_default_backend,omitempty"`
+
+	// SearchProviders configures additional web_search providers beyond the
+	// built-in DuckDuckGo Lite fallback (see internal/agent.SearchProvider).
+	// Order is priority: WebSearchHandler tries each in turn and fails over
+	// to the next on error or an empty result set, so listing a self-hosted
+	// SearXNG instance before DDG lets it absorb normal traffic while DDG
+	// stays the break-glass fallback if that instance is ever down. Another
+	// source of providers is any OpenSearch XML descriptor dropped in the
+	// data dir's searchproviders/ directory; those are appended after this
+	// list.
+	SearchProviders []SearchProviderSpec `yaml:"search_providers,omitempty"`
+
+	// AgentToolProviders declares external gRPC tool-provider processes,
+	// keyed by name, for internal/agent's Agent to spawn and supervise
+	// alongside its YAML/plugin tools (see internal/agent.ToolProviderSpec
+	// and toolprovider.proto). This is how a calendar, email, MCP bridge, or
+	// scraping tool gets added without rebuilding voice-relay — the provider
+	// can be written in any language, as long as it speaks toolprovider.proto
+	// over the Unix domain socket path it's given.
+	AgentToolProviders map[string]AgentToolProviderSpec `yaml:"agent_tool_providers,omitempty"`
+
+	// WebFetch configures the fetch_url tool's host allow/deny list and byte
+	// cap (see internal/agent.FetchURL). The zero value fetches any
+	// http(s) host, subject only to robots.txt, capped at the package's
+	// built-in default of 2 MiB.
+	WebFetch WebFetchSpec `yaml:"web_fetch,omitempty"`
+
+	// Updater configures where CheckForUpdates, `voice-relay update`, and the
+	// supervised `serve` subcommand look for new releases. The zero value uses
+	// the default: GitHub Releases on dzearing/voice-relay.
+	Updater UpdaterSpec `yaml:"updater,omitempty"`
+}
+
+// UpdaterSpec configures internal/updater's release Fetcher.
+type UpdaterSpec struct {
+	Source string `yaml:"source,omitempty"` // "github" (default), "https", or "s3"
+
+	// github: defaults to dzearing/voice-relay if left blank.
+	RepoOwner string `yaml:"repo_owner,omitempty"`
+	RepoName  string `yaml:"repo_name,omitempty"`
+
+	// https: ManifestURL serves a JSON version manifest (self-hosted releases).
+	ManifestURL string `yaml:"manifest_url,omitempty"`
+
+	// s3: BucketURL is an S3-compatible bucket's base URL, serving the same
+	// manifest layout as ManifestURL at "<BucketURL>/manifest.json".
+	BucketURL string `yaml:"bucket_url,omitempty"`
+}
+
+// LLMBackendSpec configures one named backend for internal/llm's Registry.
+type LLMBackendSpec struct {
+	Type string `yaml:"type"` // "openai" or "grpc" (the default local llama-server isn't configured here)
+
+	// openai: an already-running OpenAI-compatible chat completions server
+	// (Ollama, vLLM, LM Studio, LocalAI, or a llama-server on another host).
+	BaseURL string `yaml:"base_url,omitempty"`
+	APIKey  string `yaml:"api_key,omitempty"`
+
+	// grpc: a third-party engine speaking llmserver.proto (see internal/llm).
+	// Target alone dials an already-running external server. Setting Command
+	// (and optionally Args) instead has voice-relay spawn and supervise the
+	// worker itself — the way to wire in a whisper/rwkv/gpt4all/etc runtime
+	// without forking this repo.
+	Target  string   `yaml:"target,omitempty"`
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+
+	// Model is the model name sent in chat-completions requests (openai, grpc).
+	// Unused for "local", which always hosts cfg.LLMModel.
+	Model string `yaml:"model,omitempty"`
+}
+
+// SearchProviderSpec configures one named provider for internal/agent's
+// web_search tool.
+type SearchProviderSpec struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // "ddg" (default), "searxng", "brave", or "kagi"
+
+	// searxng: base URL of a self-hosted or public SearXNG instance, queried
+	// with "?format=json".
+	BaseURL string `yaml:"base_url,omitempty"`
+
+	// brave, kagi: API key for that provider's search API.
+	APIKey string `yaml:"api_key,omitempty"`
+
+	// RateLimitPerMin caps requests per minute against this provider; 0
+	// means unlimited. Mainly useful for a free-tier Brave/Kagi key or a
+	// shared SearXNG instance other things also depend on.
+	RateLimitPerMin int `yaml:"rate_limit_per_min,omitempty"`
+}
+
+// AgentToolProviderSpec configures one named external gRPC tool provider
+// process for internal/agent's Agent (see internal/agent.ToolProviderSpec).
+// Command is spawned at startup and is expected to listen on the Unix
+// domain socket path given via the VOICE_RELAY_TOOL_SOCKET environment
+// variable, then answer ListTools/Invoke/Health over
+// internal/agent/toolproviderpb — the same external-gRPC pattern
+// LLMBackendSpec's "grpc" type already uses for LLM backends.
+type AgentToolProviderSpec struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+}
+
+// WebFetchSpec configures internal/agent's fetch_url tool.
+type WebFetchSpec struct {
+	// AllowHosts, if non-empty, restricts fetch_url to these hosts (and
+	// their subdomains) only; DenyHosts is checked first regardless.
+	AllowHosts []string `yaml:"allow_hosts,omitempty"`
+	DenyHosts  []string `yaml:"deny_hosts,omitempty"`
+
+	// MaxBytes caps how much of a page body is read; 0 uses the package
+	// default (2 MiB).
+	MaxBytes int64 `yaml:"max_bytes,omitempty"`
+}
+
+// EngineSpec configures one named backend for internal/engine's Registry.
+type EngineSpec struct {
+	Type string `yaml:"type"` // "openai", "deepgram", or "kokoro"
+
+	// openai: an OpenAI-compatible server exposing /v1/audio/speech and
+	// /v1/audio/transcriptions (OpenAI itself, faster-whisper-server, ElevenLabs-
+	// compatible proxies, ...).
+	//
+	// deepgram: Deepgram's prerecorded transcription API (recognizer only;
+	// BaseURL defaults to Deepgram's own API, APIKey is required, Model is
+	// e.g. "nova-2").
+	BaseURL string `yaml:"base_url,omitempty"`
+	APIKey  string `yaml:"api_key,omitempty"`
+	Model   string `yaml:"model,omitempty"`
+
+	// kokoro: a local Kokoro ONNX voice run through onnxruntime.
+	BinPath    string `yaml:"bin_path,omitempty"`
+	ModelPath  string `yaml:"model_path,omitempty"`
+	VoicesPath string `yaml:"voices_path,omitempty"`
+}
+
+// STTRouterSpec configures an engine.Router for /transcribe. See
+// Config.STTRouter.
+type STTRouterSpec struct {
+	Rules []STTRouteRule `yaml:"rules,omitempty"`
+}
+
+// STTRouteRule mirrors engine.RouterRule: the first rule matching a request
+// wins. Backend names an entry in Engines; empty means "use the local
+// whisper-server".
+type STTRouteRule struct {
+	Backend       string `yaml:"backend,omitempty"`
+	Language      string `yaml:"language,omitempty"`
+	MaxAudioBytes int64  `yaml:"max_audio_bytes,omitempty"`
 }
 
 // DefaultPort is the default coordinator port.
 const DefaultPort = 53937
 
-// Load reads the config from disk, or creates a default one.
-// If the config version is outdated, the config is deleted so setup runs again.
+// migration upgrades a config at fromVersion to fromVersion+1 in place, operating on
+// the raw decoded YAML so it can rename or restructure keys that no longer exist on
+// the Config struct. Append new entries here as the schema evolves instead of
+// bumping CurrentConfigVersion with no migration and losing users' settings.
+type migration struct {
+	fromVersion int
+	migrate     func(raw map[string]any) error
+}
+
+// migrations is applied in order; each entry must have fromVersion equal to the
+// version left behind by the previous entry (or CurrentConfigVersion's predecessor
+// for the first one), so a config is walked forward one version at a time.
+var migrations = []migration{}
+
+// Load reads the config from disk, or creates a default one. A config written by an
+// older version is migrated forward in place via migrations rather than discarded.
 func Load() *Config {
 	cfg := &Config{}
 	configPath := Path()
@@ -50,24 +276,56 @@ func Load() *Config {
 		return cfg
 	}
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
 		log.Printf("Error parsing config: %v", err)
+		raw = map[string]any{}
 	}
 
-	// Reset outdated config
-	if cfg.ConfigVersion < CurrentConfigVersion {
-		log.Printf("Config version %d is outdated (current: %d), resetting", cfg.ConfigVersion, CurrentConfigVersion)
-		os.Remove(configPath)
-		cfg = &Config{}
-		cfg.setDefaults()
-		cfg.Save()
-		return cfg
+	version, _ := raw["config_version"].(int)
+	migrated := false
+
+	for version < CurrentConfigVersion {
+		m := migrationFrom(version)
+		if m == nil {
+			log.Printf("No migration registered from config version %d to %d; leaving remaining fields as-is", version, CurrentConfigVersion)
+			break
+		}
+		if err := m.migrate(raw); err != nil {
+			log.Printf("Migrating config from version %d failed: %v; leaving config at version %d for manual review", version, err, version)
+			break
+		}
+		version++
+		migrated = true
+	}
+	raw["config_version"] = version
+
+	if reencoded, err := yaml.Marshal(raw); err == nil {
+		if err := yaml.Unmarshal(reencoded, cfg); err != nil {
+			log.Printf("Error applying migrated config: %v", err)
+		}
+	} else if err := yaml.Unmarshal(data, cfg); err != nil {
+		log.Printf("Error parsing config: %v", err)
 	}
 
 	cfg.applyDefaults()
+	if migrated {
+		cfg.Save()
+	}
 	return cfg
 }
 
+// migrationFrom returns the registered migration that upgrades fromVersion, or nil
+// if none is registered.
+func migrationFrom(fromVersion int) *migration {
+	for i := range migrations {
+		if migrations[i].fromVersion == fromVersion {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
 // Save writes the config to disk.
 func (c *Config) Save() {
 	configPath := Path()
@@ -116,6 +374,12 @@ func (c *Config) setDefaults() {
 	c.WhisperModel = "base"
 	c.LLMModel = "qwen3-4b"
 	c.LLMEnabled = true
+	c.LLMWorkers = 1
+	c.AgentRetryLimit = 3
+	c.StopDebounceWindowMS = 1500
+	c.DesktopNotifications = true
+	c.ShortURLProviders = DefaultShortURLProviders
+	c.TrustedProxies = DefaultTrustedProxies
 }
 
 func (c *Config) applyDefaults() {
@@ -137,8 +401,32 @@ func (c *Config) applyDefaults() {
 	if c.LLMModel == "" || c.LLMModel == "qwen3-0.6b" {
 		c.LLMModel = "qwen3-4b"
 	}
+	if c.LLMWorkers == 0 {
+		c.LLMWorkers = 1
+	}
+	if c.AgentRetryLimit == 0 {
+		c.AgentRetryLimit = 3
+	}
+	if c.StopDebounceWindowMS == 0 {
+		c.StopDebounceWindowMS = 1500
+	}
+	if len(c.ShortURLProviders) == 0 {
+		c.ShortURLProviders = DefaultShortURLProviders
+	}
+	if len(c.TrustedProxies) == 0 {
+		c.TrustedProxies = DefaultTrustedProxies
+	}
 }
 
+// DefaultTrustedProxies is the proxy allowlist used when a config predates the
+// trusted_proxies field or leaves it unset. Loopback covers the common case of a
+// local Tailscale Funnel/Serve listener forwarding into the coordinator.
+var DefaultTrustedProxies = []string{"127.0.0.1/32", "::1/128"}
+
+// DefaultShortURLProviders is the provider chain used when a config predates the
+// short_url_providers field or leaves it unset.
+var DefaultShortURLProviders = []string{"is.gd", "tinyurl"}
+
 // DefaultName returns the best available machine name for the current platform.
 func DefaultName() string {
 	return computerName()
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of write/rename/create events many editors and
+// atomic-save tools emit for a single logical save into one reload.
+const watchDebounce = 250 * time.Millisecond
+
+// Watch reloads the config from disk whenever its file changes and invokes onChange
+// with the freshly loaded Config. It watches the containing directory rather than
+// the file itself so it keeps working across editors that save by renaming a temp
+// file over the original (which orphans an fsnotify watch on the file directly).
+// Watch returns a stop function that shuts down the watcher; callers should defer it.
+func Watch(onChange func(*Config)) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(Dir()); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go watchLoop(watcher, onChange, done)
+
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+func watchLoop(watcher *fsnotify.Watcher, onChange func(*Config), done chan struct{}) {
+	configPath := Path()
+	var debounce *time.Timer
+
+	reload := func() {
+		log.Printf("Config file changed, reloading")
+		onChange(Load())
+	}
+
+	for {
+		select {
+		case <-done:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Name != configPath {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.AfterFunc(watchDebounce, reload)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watcher error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package coordinator
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/config"
+)
+
+// Scopes a minted token can be granted. A handler gated by requireScope
+// accepts a token carrying that scope or ScopeAdmin.
+const (
+	ScopeTTSPreview      = "tts:preview"
+	ScopeNotifSubmit     = "notif:submit"
+	ScopeNotifDismiss    = "notif:dismiss"
+	ScopeHooksInstall    = "hooks:install"
+	ScopeQuestionsAnswer = "questions:answer"
+	ScopeAdmin           = "admin" // bypasses any single scope check, and loopback-only gating
+)
+
+// authToken is a minted API token, persisted with only its secret's hash —
+// the plaintext is shown once at mint time (as "<id>.<secret>") and never
+// stored.
+type authToken struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name,omitempty"`
+	HashedSecret string    `json:"hashed_secret"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (t *authToken) hasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenStore persists minted tokens to disk and authenticates bearer values
+// against their stored hash, the same JSON-file pattern pairing.go uses for
+// paired devices.
+type tokenStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]*authToken // id -> token
+}
+
+func tokenStorePath() string {
+	return filepath.Join(config.Dir(), "tokens.json")
+}
+
+var tokens = &tokenStore{path: tokenStorePath(), tokens: map[string]*authToken{}}
+
+// loadTokens populates the global token store from disk. Missing or corrupt
+// files are treated as an empty store, same as config.Load's handling of a
+// missing config.
+func loadTokens() {
+	data, err := os.ReadFile(tokens.path)
+	if err != nil {
+		return
+	}
+
+	var list []*authToken
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("auth: failed to parse token store: %v", err)
+		return
+	}
+
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+	for _, t := range list {
+		tokens.tokens[t.ID] = t
+	}
+}
+
+func (s *tokenStore) saveLocked() {
+	list := make([]*authToken, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		list = append(list, t)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(s.path, data, 0644)
+}
+
+// mint generates a new random secret, stores only its hash under a fresh ID,
+// and returns the plaintext "id.secret" bearer value — the only time it's
+// available in full.
+func (s *tokenStore) mint(name string, scopes []string) (string, *authToken) {
+	id := generateCode(8)
+	secret := generateSecret(32)
+
+	tok := &authToken{
+		ID:           id,
+		Name:         name,
+		HashedSecret: hashSecret(secret),
+		Scopes:       scopes,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.tokens[id] = tok
+	s.saveLocked()
+	s.mu.Unlock()
+
+	return id + "." + secret, tok
+}
+
+// authenticate parses a "id.secret" bearer value and returns the matching
+// token if secret's hash matches what's stored for id, or nil otherwise.
+func (s *tokenStore) authenticate(bearer string) *authToken {
+	id, secret, ok := strings.Cut(bearer, ".")
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	tok, ok := s.tokens[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(tok.HashedSecret)) != 1 {
+		return nil
+	}
+	return tok
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateSecret(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Rate limiting: a per-token+IP token bucket modeled on ntfy's visitor
+// limiter, bounding both request rate and request body size.
+const (
+	requestsPerSecond = 5.0
+	requestBurst      = 20.0
+	bytesPerSecond    = 1 << 20 // 1MB/s sustained
+	byteBurst         = 4 << 20 // 4MB burst
+)
+
+type rateBucket struct {
+	mu         sync.Mutex
+	reqTokens  float64
+	byteTokens float64
+	lastRefill time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rateBucket
+}
+
+var limiter = &rateLimiter{buckets: map[string]*rateBucket{}}
+
+// allow reports whether a request of size bytes under key (a token+IP pair)
+// is within the default requestsPerSecond/bytesPerSecond budget.
+func (rl *rateLimiter) allow(key string, size int) bool {
+	return rl.allowRate(key, size, requestsPerSecond, requestBurst)
+}
+
+// allowRate is allow with an overridable requests-per-second rate and burst,
+// for callers (e.g. rateLimitByIP) that need a tighter budget than the
+// default. Byte-budget refill always uses bytesPerSecond/byteBurst.
+func (rl *rateLimiter) allowRate(key string, size int, reqPerSecond, reqBurst float64) bool {
+	rl.mu.Lock()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &rateBucket{reqTokens: reqBurst, byteTokens: byteBurst, lastRefill: time.Now()}
+		rl.buckets[key] = b
+	}
+	rl.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.lastRefill = now
+	b.reqTokens = minF(reqBurst, b.reqTokens+elapsed*reqPerSecond)
+	b.byteTokens = minF(byteBurst, b.byteTokens+elapsed*bytesPerSecond)
+
+	if b.reqTokens < 1 || b.byteTokens < float64(size) {
+		return false
+	}
+	b.reqTokens--
+	b.byteTokens -= float64(size)
+	return true
+}
+
+func minF(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// bearerFromRequest extracts the token from an "Authorization: Bearer …"
+// header, or "" if the header is missing or malformed.
+func bearerFromRequest(r *http.Request) string {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(h, prefix)
+}
+
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireScope wraps handler so it only runs for requests bearing a valid
+// token with scope (or ScopeAdmin), and within that token+IP's rate-limit
+// budget.
+func requireScope(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bearer := bearerFromRequest(r)
+		if bearer == "" {
+			writeJSONError(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		tok := tokens.authenticate(bearer)
+		if tok == nil {
+			writeJSONError(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		if !tok.hasScope(scope) {
+			writeJSONError(w, fmt.Sprintf("Token lacks required scope %q", scope), http.StatusForbidden)
+			return
+		}
+		if !limiter.allow(tok.ID+"@"+clientIP(r), int(r.ContentLength)) {
+			writeJSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// rateLimitByIP wraps handler so it only runs within clientIP(r)'s rate-limit
+// budget, for endpoints that are unauthenticated by design (pairing, which
+// hands out the only credential an unpaired device has) and so can't be
+// gated by requireScope's token+IP key. pairCodeGuesses uses a much tighter
+// budget than the general per-token limiter, since a connection code is
+// short enough that an unthrottled caller could brute-force it within its
+// rotation window.
+const (
+	pairCodeGuessesPerSecond = 0.2 // 1 guess per 5s sustained
+	pairCodeGuessBurst       = 5.0
+)
+
+var pairCodeGuesses = &rateLimiter{buckets: map[string]*rateBucket{}}
+
+func rateLimitByIP(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if !pairCodeGuesses.allowRate(ip, int(r.ContentLength), pairCodeGuessesPerSecond, pairCodeGuessBurst) {
+			writeJSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requireLoopbackOrAdmin restricts handler to requests from a loopback
+// address, or a bearer token with ScopeAdmin for callers that genuinely need
+// remote access. Used for endpoints that can affect another machine's Claude
+// Code settings (hook install/uninstall) or mint new credentials, where even
+// a correctly-scoped non-admin token shouldn't be enough.
+func requireLoopbackOrAdmin(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if isLoopback(clientIP(r)) {
+			handler(w, r)
+			return
+		}
+
+		tok := tokens.authenticate(bearerFromRequest(r))
+		if tok == nil || !tok.hasScope(ScopeAdmin) {
+			writeJSONError(w, "This endpoint requires a loopback connection or the admin scope", http.StatusForbidden)
+			return
+		}
+		if !limiter.allow(tok.ID+"@"+clientIP(r), int(r.ContentLength)) {
+			writeJSONError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// handleMintToken issues a new API token with the requested scopes. Minting
+// is itself loopback-or-admin gated so a remote unauthenticated caller can't
+// self-issue credentials — the first token has to be minted locally (e.g.
+// from the CLI or curl on the same machine), and any token minted after that
+// requires one with the admin scope.
+func handleMintToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Scopes) == 0 {
+		writeJSONError(w, "Invalid request: scopes is required", http.StatusBadRequest)
+		return
+	}
+
+	plaintext, tok := tokens.mint(body.Name, body.Scopes)
+	log.Printf("Auth: minted token %s (name=%q, scopes=%v)", tok.ID, tok.Name, tok.Scopes)
+	writeJSON(w, map[string]interface{}{
+		"token":  plaintext,
+		"id":     tok.ID,
+		"scopes": tok.Scopes,
+	})
+}
@@ -0,0 +1,91 @@
+package coordinator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/voice-relay/echo-desktop/internal/e2e"
+)
+
+// e2eSealer holds the per-echo-client session keys negotiated over "handshake"
+// messages (see handleE2EHandshake), or nil if no E2EPassphrase is configured
+// — in which case "text" messages stay plaintext, as before this feature.
+var e2eSealer *e2e.Sealer
+
+// SetE2EPassphrase configures end-to-end encryption of "text" messages with
+// any echo client configured with the same passphrase (see
+// config.Config.E2EPassphrase and client.Client.Passphrase). An empty
+// passphrase disables it.
+func SetE2EPassphrase(passphrase string) {
+	if passphrase == "" {
+		e2eSealer = nil
+		return
+	}
+	e2eSealer = e2e.New(passphrase)
+}
+
+// handleE2EHandshake processes a "handshake" message from deviceName,
+// completing the key exchange (see e2e.Sealer.CompleteHandshake) and
+// replying with our own ephemeral public key if deviceName was the
+// initiator.
+func handleE2EHandshake(conn *websocket.Conn, deviceName, peerKeyB64, tagB64 string, epoch int) {
+	if e2eSealer == nil {
+		return
+	}
+
+	pubBytes, err := base64.StdEncoding.DecodeString(peerKeyB64)
+	if err != nil || len(pubBytes) != 32 {
+		log.Printf("e2e: invalid handshake public key from %s", deviceName)
+		return
+	}
+	tagBytes, err := base64.StdEncoding.DecodeString(tagB64)
+	if err != nil || len(tagBytes) != 32 {
+		log.Printf("e2e: invalid handshake tag from %s", deviceName)
+		return
+	}
+	var pub, tag [32]byte
+	copy(pub[:], pubBytes)
+	copy(tag[:], tagBytes)
+
+	ourPub, ourTag, responded, err := e2eSealer.CompleteHandshake(deviceName, pub, tag, epoch)
+	if err != nil {
+		log.Printf("e2e: handshake with %s failed: %v", deviceName, err)
+		return
+	}
+	if responded {
+		resp, _ := json.Marshal(map[string]interface{}{
+			"type":    "handshake",
+			"peerKey": base64.StdEncoding.EncodeToString(ourPub[:]),
+			"tag":     base64.StdEncoding.EncodeToString(ourTag[:]),
+			"epoch":   epoch,
+		})
+		conn.WriteMessage(websocket.TextMessage, resp)
+	}
+	log.Printf("e2e: session key established with %s (epoch %d)", deviceName, epoch)
+}
+
+// sealForTarget seals text for delivery to target. sealed is false with a
+// nil err only when E2E isn't configured at all (e2eSealer == nil), in
+// which case the message was always going to be plaintext and sendText may
+// send it as such. Once E2E is configured, though, "the coordinator only
+// ever relays ciphertext" is the whole point of the feature, so a target we
+// haven't finished a handshake with yet, or an AEAD failure, is returned as
+// an error instead of silently falling back to plaintext on the wire —
+// sendText must fail the send rather than downgrade it.
+func sealForTarget(target, text string) (ciphertextB64, nonceB64 string, epoch int, sealed bool, err error) {
+	if e2eSealer == nil {
+		return "", "", 0, false, nil
+	}
+	if !e2eSealer.Established(target) {
+		return "", "", 0, false, fmt.Errorf("e2e: no session key established with %s yet", target)
+	}
+	ciphertext, nonce, ep, err := e2eSealer.Seal(target, []byte(text))
+	if err != nil {
+		return "", "", 0, false, fmt.Errorf("e2e: sealing text for %s failed: %w", target, err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), base64.StdEncoding.EncodeToString(nonce), ep, true, nil
+}
@@ -0,0 +1,340 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event topics for the /events subscription API, modeled after ntfy's topic
+// streams. New producers (the chunk9 hook-event work, for instance) publish
+// onto TopicHooks as that surface grows.
+const (
+	TopicNotifications = "notifications"
+	TopicQuestions     = "questions"
+	TopicTTS           = "tts"
+	TopicHooks         = "hooks"
+	TopicTerminal      = "terminal"
+)
+
+var allTopics = []string{TopicNotifications, TopicQuestions, TopicTTS, TopicHooks, TopicTerminal}
+
+func isValidTopic(topic string) bool {
+	for _, t := range allTopics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// eventBacklogSize is how many recent events per topic are kept for replay via
+// ?since=, mirroring logBacklogSize's role for /logs.
+const eventBacklogSize = 200
+
+// event is a single published item in a topic's ring buffer.
+type event struct {
+	ID    uint64          `json:"id"`
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// eventSubscriber is one /events client, filtered to a set of topics and
+// (optionally) event types within them.
+type eventSubscriber struct {
+	topics map[string]bool
+	types  map[string]bool // empty/nil means no type filtering
+	events chan event
+}
+
+func (s *eventSubscriber) wants(e event) bool {
+	if !s.topics[e.Topic] {
+		return false
+	}
+	if len(s.types) > 0 && !s.types[e.Type] {
+		return false
+	}
+	return true
+}
+
+// eventHub fans out published events to live subscribers and keeps a
+// per-topic ring buffer so a client reconnecting with ?since= can catch up on
+// whatever it missed instead of polling /notifications and /questions.
+type eventHub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	backlog     map[string][]event // topic -> ring buffer
+	subscribers map[*eventSubscriber]bool
+}
+
+var events = &eventHub{
+	backlog:     make(map[string][]event),
+	subscribers: make(map[*eventSubscriber]bool),
+}
+
+// publishEvent records a new event on topic and fans it out to subscribers
+// whose filters match. data is marshaled to JSON; a marshal failure just
+// drops the event, mirroring registry.broadcastEvent's handling of bad data.
+func publishEvent(topic, eventType string, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal %s/%s event: %v", topic, eventType, err)
+		return
+	}
+
+	events.mu.Lock()
+	events.nextID++
+	e := event{ID: events.nextID, Topic: topic, Type: eventType, Time: time.Now().UTC(), Data: raw}
+	buf := append(events.backlog[topic], e)
+	if len(buf) > eventBacklogSize {
+		buf = buf[len(buf)-eventBacklogSize:]
+	}
+	events.backlog[topic] = buf
+
+	subs := make([]*eventSubscriber, 0, len(events.subscribers))
+	for s := range events.subscribers {
+		subs = append(subs, s)
+	}
+	events.mu.Unlock()
+
+	for _, s := range subs {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			log.Printf("Events subscriber too slow, dropping %s/%s event", e.Topic, e.Type)
+		}
+	}
+}
+
+// subscribe registers a new subscriber for topics (further filtered by types,
+// if non-empty) and returns it along with whatever backlog entries satisfy
+// since ("all", a numeric event ID, or an RFC3339 timestamp). An empty since
+// replays nothing — the subscriber only sees events from here on.
+func (h *eventHub) subscribe(topics []string, types map[string]bool, since string) (*eventSubscriber, []event) {
+	s := &eventSubscriber{
+		topics: make(map[string]bool, len(topics)),
+		types:  types,
+		events: make(chan event, 32),
+	}
+	for _, t := range topics {
+		s.topics[t] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[s] = true
+
+	var replay []event
+	if since != "" {
+		for _, t := range topics {
+			for _, e := range h.backlog[t] {
+				if s.wants(e) && eventMatchesSince(e, since) {
+					replay = append(replay, e)
+				}
+			}
+		}
+		sort.Slice(replay, func(i, j int) bool { return replay[i].ID < replay[j].ID })
+	}
+	return s, replay
+}
+
+func (h *eventHub) unsubscribe(s *eventSubscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, s)
+	h.mu.Unlock()
+	close(s.events)
+}
+
+// eventMatchesSince reports whether e is new enough to replay for a given
+// ?since= cursor: "all" replays the whole backlog, a bare integer is an event
+// ID cursor (replay anything after it), and anything else is parsed as an
+// RFC3339 timestamp.
+func eventMatchesSince(e event, since string) bool {
+	if since == "all" {
+		return true
+	}
+	if id, err := strconv.ParseUint(since, 10, 64); err == nil {
+		return e.ID > id
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return e.Time.After(t)
+	}
+	return false
+}
+
+// parseTypeFilter parses ?filter=type=question,notification into a set of
+// event types to allow through. An empty or malformed filter means no
+// restriction — only "type=" filtering is supported today.
+func parseTypeFilter(filter string) map[string]bool {
+	if filter == "" {
+		return nil
+	}
+	key, value, ok := strings.Cut(filter, "=")
+	if !ok || key != "type" {
+		return nil
+	}
+	types := make(map[string]bool)
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	return types
+}
+
+// handleEvents serves the unified /events subscription API: GET /events (all
+// topics) or GET /events/{topic} (notifications, questions, tts, or hooks),
+// in the format chosen by ?format= (sse, the default; ws; or json for a
+// single non-streaming snapshot). ?since=<id|timestamp|all> replays whatever
+// backlog a reconnecting client missed, and ?filter=type=a,b restricts the
+// subscription to specific event types within the chosen topic(s).
+func handleEvents(w http.ResponseWriter, r *http.Request, topicPath string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var topics []string
+	if topicPath == "" {
+		topics = allTopics
+	} else if isValidTopic(topicPath) {
+		topics = []string{topicPath}
+	} else {
+		writeJSONError(w, fmt.Sprintf("Unknown topic %q", topicPath), http.StatusNotFound)
+		return
+	}
+
+	types := parseTypeFilter(r.URL.Query().Get("filter"))
+	since := r.URL.Query().Get("since")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "sse"
+	}
+
+	switch format {
+	case "json":
+		serveEventsJSON(w, topics, types, since)
+	case "ws":
+		serveEventsWS(w, r, topics, types, since)
+	case "sse":
+		serveEventsSSE(w, r, topics, types, since)
+	default:
+		writeJSONError(w, fmt.Sprintf("Unknown format %q", format), http.StatusBadRequest)
+	}
+}
+
+// serveEventsJSON returns the matching backlog as a single JSON array with no
+// live streaming, for one-shot polling clients (curl, cron jobs) that don't
+// want to hold a connection open. It defaults ?since= to "all" since a
+// snapshot with no replay window would always return an empty array.
+func serveEventsJSON(w http.ResponseWriter, topics []string, types map[string]bool, since string) {
+	if since == "" {
+		since = "all"
+	}
+	s, replay := events.subscribe(topics, types, since)
+	events.unsubscribe(s)
+	if replay == nil {
+		replay = []event{}
+	}
+	writeJSON(w, replay)
+}
+
+// serveEventsSSE streams matching events as Server-Sent Events, replaying
+// backlog first (if ?since= was given) before switching to live delivery.
+func serveEventsSSE(w http.ResponseWriter, r *http.Request, topics []string, types map[string]bool, since string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	s, replay := events.subscribe(topics, types, since)
+	defer events.unsubscribe(s)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeSSE := func(e event) bool {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Topic, msg); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	for _, e := range replay {
+		if !writeSSE(e) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-s.events:
+			if !ok || !writeSSE(e) {
+				return
+			}
+		}
+	}
+}
+
+// serveEventsWS is the WebSocket counterpart of serveEventsSSE, for clients
+// that would rather multiplex this over the same connection type as /ws.
+func serveEventsWS(w http.ResponseWriter, r *http.Request, topics []string, types map[string]bool, since string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Events WS upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	s, replay := events.subscribe(topics, types, since)
+	defer events.unsubscribe(s)
+
+	for _, e := range replay {
+		if conn.WriteJSON(e) != nil {
+			return
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case e, ok := <-s.events:
+			if !ok || conn.WriteJSON(e) != nil {
+				return
+			}
+		}
+	}
+}
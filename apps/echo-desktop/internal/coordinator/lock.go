@@ -0,0 +1,43 @@
+package coordinator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/voice-relay/echo-desktop/internal/config"
+)
+
+// lockPath is the PID file used to enforce a single running coordinator.
+func lockPath() string {
+	return filepath.Join(config.Dir(), "coordinator.lock")
+}
+
+// AcquireLock claims the single-instance lock for coordinator mode, returning an
+// error if another live coordinator process already holds it. A lock file left
+// behind by a process that no longer exists (crash, kill -9) is treated as stale
+// and reclaimed automatically rather than requiring the user to delete it by hand.
+func AcquireLock() (release func(), err error) {
+	path := lockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, perr := strconv.Atoi(strings.TrimSpace(string(data))); perr == nil {
+			if processAlive(pid) {
+				return nil, fmt.Errorf("coordinator already running (pid %d); remove %s if this is wrong", pid, path)
+			}
+		}
+		// Stale lock from a pid that's gone (or unparsable) — reclaim it.
+		os.Remove(path)
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return nil, err
+	}
+
+	return func() { os.Remove(path) }, nil
+}
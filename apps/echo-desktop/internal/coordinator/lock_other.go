@@ -0,0 +1,18 @@
+//go:build !windows
+
+package coordinator
+
+import (
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether pid identifies a running process, using the
+// POSIX convention that signal 0 performs error checking without delivery.
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,21 @@
+package coordinator
+
+import "syscall"
+
+const stillActive = 259
+
+// processAlive reports whether pid identifies a running process, checked via
+// OpenProcess/GetExitCodeProcess since Windows has no POSIX signal(0) probe.
+func processAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
@@ -0,0 +1,116 @@
+package coordinator
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// logBacklogSize is how many recent log lines a newly connected log observer is
+// sent immediately, so opening the stream doesn't start with an empty screen.
+const logBacklogSize = 200
+
+// logBroadcaster is an io.Writer that sits alongside the standard logger's usual
+// output (stderr), keeping a ring buffer of recent lines and pushing each new one
+// to any WebSocket clients streaming /logs.
+type logBroadcaster struct {
+	mu        sync.Mutex
+	backlog   []string
+	observers map[*websocket.Conn]bool
+}
+
+var logStream = &logBroadcaster{observers: map[*websocket.Conn]bool{}}
+
+// InstallLogStreaming tees the standard logger's output through logStream, in
+// addition to wherever it was already writing (normally stderr), so /logs
+// observers see everything the coordinator logs without changing existing
+// log.Printf call sites anywhere else in the app.
+func InstallLogStreaming() {
+	log.SetOutput(multiWriter{log.Writer(), logStream})
+}
+
+type multiWriter struct {
+	a, b interface{ Write([]byte) (int, error) }
+}
+
+func (m multiWriter) Write(p []byte) (int, error) {
+	m.a.Write(p)
+	return m.b.Write(p)
+}
+
+func (b *logBroadcaster) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+
+	b.mu.Lock()
+	b.backlog = append(b.backlog, line)
+	if len(b.backlog) > logBacklogSize {
+		b.backlog = b.backlog[len(b.backlog)-logBacklogSize:]
+	}
+	observers := make([]*websocket.Conn, 0, len(b.observers))
+	for conn := range b.observers {
+		observers = append(observers, conn)
+	}
+	b.mu.Unlock()
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"type": "log",
+		"line": line,
+		"time": time.Now().UTC().Format(time.RFC3339),
+	})
+	if err == nil {
+		for _, conn := range observers {
+			conn.WriteMessage(websocket.TextMessage, msg)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (b *logBroadcaster) addObserver(conn *websocket.Conn) {
+	b.mu.Lock()
+	b.observers[conn] = true
+	backlog := append([]string(nil), b.backlog...)
+	b.mu.Unlock()
+
+	for _, line := range backlog {
+		msg, err := json.Marshal(map[string]interface{}{"type": "log", "line": line})
+		if err != nil {
+			continue
+		}
+		if conn.WriteMessage(websocket.TextMessage, msg) != nil {
+			return
+		}
+	}
+}
+
+func (b *logBroadcaster) removeObserver(conn *websocket.Conn) {
+	b.mu.Lock()
+	delete(b.observers, conn)
+	b.mu.Unlock()
+}
+
+// handleLogsWebSocket upgrades to a WebSocket that streams log lines as they're
+// written, starting with a backlog of recent lines. It's a read-only feed — the
+// connection is kept open only to detect when the client disconnects.
+func handleLogsWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("Logs WS upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	logStream.addObserver(conn)
+	defer logStream.removeObserver(conn)
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
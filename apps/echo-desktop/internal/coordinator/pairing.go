@@ -0,0 +1,252 @@
+package coordinator
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+
+	"github.com/voice-relay/echo-desktop/internal/config"
+)
+
+// codeRotationInterval is how long a connection code stays valid before a fresh
+// one is generated. Short-lived codes limit how long a code glimpsed over
+// someone's shoulder (or left on a whiteboard) remains usable to pair a device.
+const codeRotationInterval = 10 * time.Minute
+
+// codeAlphabet excludes visually ambiguous characters (0/O, 1/I/L) since codes
+// are meant to be read off a screen and typed on another device.
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// PairedDevice is a device that has exchanged a connection code for long-lived
+// trust, persisted across restarts so it doesn't need to re-pair every launch.
+type PairedDevice struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	PairedAt time.Time `json:"pairedAt"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+var (
+	pairingMu    sync.Mutex
+	pairedByID   = map[string]*PairedDevice{}
+	codeExpiry   time.Time
+	rotateOnce   sync.Once
+)
+
+// pairingStorePath is the on-disk JSON file persisting paired devices.
+func pairingStorePath() string {
+	return filepath.Join(config.Dir(), "paired_devices.json")
+}
+
+// loadPairedDevices populates pairedByID from disk. Missing or corrupt files are
+// treated as an empty store rather than an error, same as config.Load's handling
+// of a missing config.
+func loadPairedDevices() {
+	data, err := os.ReadFile(pairingStorePath())
+	if err != nil {
+		return
+	}
+
+	var devices []*PairedDevice
+	if err := json.Unmarshal(data, &devices); err != nil {
+		log.Printf("Pairing: failed to parse paired device store: %v", err)
+		return
+	}
+
+	pairingMu.Lock()
+	defer pairingMu.Unlock()
+	for _, d := range devices {
+		pairedByID[d.ID] = d
+	}
+}
+
+// savePairedDevicesLocked writes pairedByID to disk. Caller must hold pairingMu.
+func savePairedDevicesLocked() {
+	devices := make([]*PairedDevice, 0, len(pairedByID))
+	for _, d := range pairedByID {
+		devices = append(devices, d)
+	}
+	data, err := json.MarshalIndent(devices, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(config.Dir(), 0755); err != nil {
+		return
+	}
+	os.WriteFile(pairingStorePath(), data, 0644)
+}
+
+// PairedDevices returns all currently paired devices.
+func PairedDevices() []*PairedDevice {
+	pairingMu.Lock()
+	defer pairingMu.Unlock()
+	devices := make([]*PairedDevice, 0, len(pairedByID))
+	for _, d := range pairedByID {
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+// RevokePairedDevice removes a device's trust, e.g. if a phone is lost.
+func RevokePairedDevice(id string) {
+	pairingMu.Lock()
+	delete(pairedByID, id)
+	savePairedDevicesLocked()
+	pairingMu.Unlock()
+}
+
+// IsPaired reports whether a device ID has completed pairing, updating its
+// last-seen timestamp if so.
+func IsPaired(id string) bool {
+	pairingMu.Lock()
+	defer pairingMu.Unlock()
+	d, ok := pairedByID[id]
+	if !ok {
+		return false
+	}
+	d.LastSeen = time.Now()
+	return true
+}
+
+// StartCodeRotation loads the persisted device store and begins generating a
+// fresh connection code every codeRotationInterval. It's idempotent; only the
+// first call starts the rotation goroutine.
+func StartCodeRotation() {
+	loadPairedDevices()
+	rotateOnce.Do(func() {
+		rotateConnectionCode()
+		go func() {
+			ticker := time.NewTicker(codeRotationInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				rotateConnectionCode()
+			}
+		}()
+	})
+}
+
+func rotateConnectionCode() {
+	code := generateCode(6)
+	pairingMu.Lock()
+	connectionCode = code
+	codeExpiry = time.Now().Add(codeRotationInterval)
+	pairingMu.Unlock()
+	log.Printf("Pairing: rotated connection code")
+}
+
+// PrintPairingInstructions writes a terminal-rendered QR code plus the plain-text
+// connection code and URL to stdout, so a user running the coordinator headlessly
+// (SSH, a server with no display) can pair a device without the GUI's /connect
+// page or the desktop app's QR menu item.
+func PrintPairingInstructions(url string) {
+	qr, err := qrcode.New(url, qrcode.Medium)
+	if err != nil {
+		log.Printf("Pairing: failed to render terminal QR code: %v", err)
+	} else {
+		fmt.Println()
+		fmt.Println(qr.ToSmallString(false))
+	}
+
+	fmt.Printf("Connection code: %s\n", GetConnectionCode())
+	fmt.Printf("Or open:         %s\n\n", url)
+}
+
+// codesEqual compares a and b in constant time, so a timing difference in
+// how far into the string a guess matches can't leak the connection code to
+// an attacker probing /pair or /code/.
+func codesEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func generateCode(length int) string {
+	b := make([]byte, length)
+	rand.Read(b)
+	out := make([]byte, length)
+	for i, v := range b {
+		out[i] = codeAlphabet[int(v)%len(codeAlphabet)]
+	}
+	return string(out)
+}
+
+// pairRequest is the body of a POST /pair request.
+type pairRequest struct {
+	Code       string `json:"code"`
+	DeviceID   string `json:"deviceId"`
+	DeviceName string `json:"deviceName"`
+}
+
+// handlePair exchanges a still-valid rotating connection code for permanent
+// device trust, persisting the result so the device doesn't need to re-pair on
+// the coordinator's next restart.
+func handlePair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req pairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.DeviceID == "" {
+		writeJSONError(w, "deviceId is required", http.StatusBadRequest)
+		return
+	}
+
+	pairingMu.Lock()
+	valid := req.Code != "" && codesEqual(req.Code, connectionCode) && time.Now().Before(codeExpiry)
+	if valid {
+		pairedByID[req.DeviceID] = &PairedDevice{
+			ID:       req.DeviceID,
+			Name:     req.DeviceName,
+			PairedAt: time.Now(),
+			LastSeen: time.Now(),
+		}
+		savePairedDevicesLocked()
+	}
+	pairingMu.Unlock()
+
+	if !valid {
+		writeJSONError(w, "Invalid or expired connection code", http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("Pairing: device paired (id=%s, name=%s)", req.DeviceID, req.DeviceName)
+	writeJSON(w, map[string]interface{}{"success": true})
+}
+
+func handlePairedDevices(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, PairedDevices())
+}
+
+// handleCode resolves the coordinator's current rotating connection code to its
+// WebSocket URL, backing the self-hosted short-URL resolver in the setup package
+// (see internal/setup/shorturl.go) so a code can be exchanged for a URL without a
+// third-party shortener.
+func handleCode(w http.ResponseWriter, r *http.Request, code string) {
+	pairingMu.Lock()
+	valid := code != "" && codesEqual(code, connectionCode) && time.Now().Before(codeExpiry)
+	pairingMu.Unlock()
+
+	if !valid {
+		http.Error(w, "Unknown or expired code", http.StatusNotFound)
+		return
+	}
+
+	wsURL := fmt.Sprintf("ws://localhost:%d/ws", coordinatorPort)
+	if externalURL != "" {
+		wsURL = fmt.Sprintf("wss://%s/ws", strings.TrimPrefix(strings.TrimPrefix(externalURL, "https://"), "http://"))
+	}
+	writeJSON(w, map[string]string{"wsUrl": wsURL})
+}
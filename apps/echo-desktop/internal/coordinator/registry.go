@@ -12,26 +12,66 @@ import (
 )
 
 type echoService struct {
-	Name        string    `json:"name"`
+	Name        string          `json:"name"`
 	Conn        *websocket.Conn `json:"-"`
-	ConnectedAt time.Time `json:"connectedAt"`
-	Session     int       `json:"session,omitempty"`
+	ConnectedAt time.Time       `json:"connectedAt"`
+	Session     int             `json:"session,omitempty"`
 }
 
 type registry struct {
 	mu          sync.RWMutex
 	services    map[string]*echoService
 	observers   map[*websocket.Conn]string // value = sessionId
-	nextSession int                         // monotonic counter for claude sessions
+	nextSession int                        // monotonic counter for claude sessions
+
+	pendingMu sync.Mutex
+	pending   map[string]chan *rpcMessage // keyed by outbound request id, see sendRPCRequest
 }
 
 func newRegistry() *registry {
 	return &registry{
 		services:  make(map[string]*echoService),
 		observers: make(map[*websocket.Conn]string),
+		pending:   make(map[string]chan *rpcMessage),
+	}
+}
+
+// awaitRPC registers a channel that will receive the response to an
+// outbound request with the given id, once dispatchRPC sees it come back in.
+func (r *registry) awaitRPC(id string) chan *rpcMessage {
+	ch := make(chan *rpcMessage, 1)
+	r.pendingMu.Lock()
+	r.pending[id] = ch
+	r.pendingMu.Unlock()
+	return ch
+}
+
+// resolvePending delivers msg to the channel awaiting the given response id,
+// if any; ids that don't (or no longer) match anything are dropped.
+func (r *registry) resolvePending(id interface{}, msg *rpcMessage) {
+	key, ok := id.(string)
+	if !ok {
+		return
+	}
+	r.pendingMu.Lock()
+	ch, ok := r.pending[key]
+	if ok {
+		delete(r.pending, key)
+	}
+	r.pendingMu.Unlock()
+	if ok {
+		ch <- msg
 	}
 }
 
+// cancelPending removes a pending wait after it times out, so a late
+// response doesn't block forever trying to send on an unread channel.
+func (r *registry) cancelPending(id string) {
+	r.pendingMu.Lock()
+	delete(r.pending, id)
+	r.pendingMu.Unlock()
+}
+
 func (r *registry) register(name string, conn *websocket.Conn) int {
 	r.mu.Lock()
 
@@ -119,6 +159,10 @@ func (r *registry) broadcastMachines() {
 	})
 
 	for _, conn := range observers {
+		if isRPC(conn) {
+			writeRPCNotification(conn, "machines.list", map[string]interface{}{"machines": machines})
+			continue
+		}
 		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
 			log.Printf("Failed to send to observer: %v", err)
 		}
@@ -130,6 +174,11 @@ func (r *registry) sendMachinesTo(conn *websocket.Conn) {
 	machines := r.listLocked()
 	r.mu.RUnlock()
 
+	if isRPC(conn) {
+		writeRPCNotification(conn, "machines.list", map[string]interface{}{"machines": machines})
+		return
+	}
+
 	msg, _ := json.Marshal(map[string]interface{}{
 		"type":     "machines",
 		"machines": machines,
@@ -176,10 +225,15 @@ func (r *registry) broadcastAudio(wavData []byte) {
 	})
 
 	for _, conn := range observers {
+		if isRPC(conn) {
+			writeRPCNotification(conn, "audio.broadcast", map[string]string{"data": b64})
+			continue
+		}
 		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
 			log.Printf("Failed to send audio to observer: %v", err)
 		}
 	}
+	publishEvent(TopicTTS, "audio", map[string]string{"data": b64})
 }
 
 // broadcastEvent sends a JSON event to all observer connections.
@@ -201,6 +255,37 @@ func (r *registry) broadcastEvent(data map[string]interface{}) {
 	}
 }
 
+// broadcastReconnect tells every connected echo client and PWA observer to
+// redial immediately instead of waiting to notice the connection drop. It's
+// used ahead of a supervised binary swap (see updater.RunSlave's onDrain
+// hook) so internal/client's Client, which understands a "reconnect"
+// message, and the PWA hand off to the newly spawned slave without sitting
+// on a dead socket first.
+func (r *registry) broadcastReconnect() {
+	msg, err := json.Marshal(map[string]string{"type": "reconnect"})
+	if err != nil {
+		return
+	}
+
+	r.mu.RLock()
+	conns := make([]*websocket.Conn, 0, len(r.services)+len(r.observers))
+	for _, svc := range r.services {
+		conns = append(conns, svc.Conn)
+	}
+	for conn := range r.observers {
+		conns = append(conns, conn)
+	}
+	r.mu.RUnlock()
+
+	for _, conn := range conns {
+		if isRPC(conn) {
+			writeRPCNotification(conn, "server.reconnect", nil)
+			continue
+		}
+		conn.WriteMessage(websocket.TextMessage, msg)
+	}
+}
+
 // sendToSession sends a JSON event only to the observer with the matching sessionId.
 func (r *registry) sendToSession(sessionId string, data map[string]interface{}) {
 	msg, err := json.Marshal(data)
@@ -222,6 +307,19 @@ func (r *registry) sendToSession(sessionId string, data map[string]interface{})
 	}
 }
 
+// sendText delivers text to the named echo-service. If the connection
+// negotiated JSON-RPC, this awaits a real ack via sendRPCRequest so the
+// return value reflects whether the service actually processed it, not just
+// whether the write syscall succeeded; legacy connections keep the old
+// write-and-assume behavior since they have no way to ack.
+//
+// If E2E is configured (see SetE2EPassphrase), text is sealed and sent as
+// ciphertext instead of plaintext — see sealForTarget. JSON-RPC connections
+// (cc-wrapper devices, not internal/client's Client) don't go through Sealer
+// at all; this feature is scoped to the legacy ad-hoc JSON protocol
+// client.Client speaks. Once E2E is configured, a target we haven't
+// finished a handshake with yet, or a sealing failure, fails the send
+// outright rather than silently delivering plaintext on the wire.
 func (r *registry) sendText(name, text string) bool {
 	r.mu.RLock()
 	svc, ok := r.services[name]
@@ -231,9 +329,22 @@ func (r *registry) sendText(name, text string) bool {
 		return false
 	}
 
-	msg := map[string]string{
-		"type":    "text",
-		"content": text,
+	if isRPC(svc.Conn) {
+		return r.sendRPCRequest(svc.Conn, "text.send", map[string]string{"content": text}) == nil
+	}
+
+	msg := map[string]interface{}{"type": "text"}
+	ciphertext, nonce, epoch, sealed, err := sealForTarget(name, text)
+	switch {
+	case err != nil:
+		log.Printf("Refusing to send unsealed text to %s: %v", name, err)
+		return false
+	case sealed:
+		msg["ciphertext"] = ciphertext
+		msg["nonce"] = nonce
+		msg["epoch"] = epoch
+	default:
+		msg["content"] = text
 	}
 
 	data, err := json.Marshal(msg)
@@ -251,6 +362,8 @@ func (r *registry) sendText(name, text string) bool {
 // sendSelect sends a "select" message to a cc-wrapper device, instructing it
 // to navigate an AskUserQuestion TUI by pressing down-arrow `index` times
 // then Enter. If otherText is non-empty, it types that after selecting "Other".
+// Like sendText, it awaits a real ack over JSON-RPC connections instead of
+// just reporting whether the write succeeded.
 func (r *registry) sendSelect(name string, index int, otherText string) bool {
 	r.mu.RLock()
 	svc, ok := r.services[name]
@@ -260,15 +373,17 @@ func (r *registry) sendSelect(name string, index int, otherText string) bool {
 		return false
 	}
 
-	msg := map[string]interface{}{
-		"type":  "select",
-		"index": index,
-	}
+	params := map[string]interface{}{"index": index}
 	if otherText != "" {
-		msg["content"] = otherText
+		params["content"] = otherText
 	}
 
-	data, err := json.Marshal(msg)
+	if isRPC(svc.Conn) {
+		return r.sendRPCRequest(svc.Conn, "select.send", params) == nil
+	}
+
+	params["type"] = "select"
+	data, err := json.Marshal(params)
 	if err != nil {
 		return false
 	}
@@ -279,3 +394,41 @@ func (r *registry) sendSelect(name string, index int, otherText string) bool {
 
 	return true
 }
+
+// sendPTYInput delivers base64-encoded keystrokes to a cc-wrapper device's
+// PTY, for a remote Terminal viewer driving the session.
+func (r *registry) sendPTYInput(name, dataB64 string) bool {
+	r.mu.RLock()
+	svc, ok := r.services[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	msg, err := json.Marshal(map[string]string{"type": "pty_input", "data": dataB64})
+	if err != nil {
+		return false
+	}
+
+	return svc.Conn.WriteMessage(websocket.TextMessage, msg) == nil
+}
+
+// sendPTYResize tells a cc-wrapper device's PTY to adopt a new size, so a
+// remote xterm.js viewer's window dimensions reach the underlying TUI.
+func (r *registry) sendPTYResize(name string, cols, rows uint16) bool {
+	r.mu.RLock()
+	svc, ok := r.services[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	msg, err := json.Marshal(map[string]interface{}{"type": "pty_resize", "cols": cols, "rows": rows})
+	if err != nil {
+		return false
+	}
+
+	return svc.Conn.WriteMessage(websocket.TextMessage, msg) == nil
+}
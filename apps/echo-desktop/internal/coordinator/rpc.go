@@ -0,0 +1,165 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// rpcSubprotocol is the WebSocket subprotocol a client requests (via the
+// standard Sec-WebSocket-Protocol handshake header) to opt /ws into
+// JSON-RPC 2.0 framing instead of the legacy ad-hoc {"type": ...} envelopes.
+// A connection that doesn't request it keeps getting legacy framing, so
+// older clients need no changes.
+const rpcSubprotocol = "jsonrpc-2.0"
+
+const jsonrpcVersion = "2.0"
+
+// rpcAckTimeout bounds how long sendRPCRequest waits for a response before
+// giving up and reporting delivery as failed.
+const rpcAckTimeout = 5 * time.Second
+
+// JSON-RPC 2.0 standard error codes.
+// See https://www.jsonrpc.org/specification#error_object.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcError is a JSON-RPC 2.0 error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcMessage covers all three JSON-RPC 2.0 shapes this package sends or
+// receives: a request (Method + ID), a notification (Method, no ID), and a
+// response (ID + Result or Error, no Method).
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+var rpcNextID int64
+
+// nextRPCID returns a new, process-unique id for an outbound request
+// (text.send, select.send) that needs a correlated response.
+func nextRPCID() string {
+	return fmt.Sprintf("srv-%d", atomic.AddInt64(&rpcNextID, 1))
+}
+
+func marshalParams(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func writeRPC(conn *websocket.Conn, msg rpcMessage) {
+	msg.JSONRPC = jsonrpcVersion
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("rpc: marshal failed: %v", err)
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Printf("rpc: write failed: %v", err)
+	}
+}
+
+func writeRPCResult(conn *websocket.Conn, id interface{}, result interface{}) {
+	writeRPC(conn, rpcMessage{ID: id, Result: result})
+}
+
+func writeRPCError(conn *websocket.Conn, id interface{}, code int, message string) {
+	writeRPC(conn, rpcMessage{ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func writeRPCNotification(conn *websocket.Conn, method string, params interface{}) {
+	writeRPC(conn, rpcMessage{Method: method, Params: marshalParams(params)})
+}
+
+// sendRPCRequest sends method/params to conn as a JSON-RPC request and
+// blocks (up to rpcAckTimeout) for its response, returning the response's
+// error, if any, or nil on success. This is what lets sendText/sendSelect
+// report real delivery success instead of just "the write syscall didn't
+// return an error".
+func (r *registry) sendRPCRequest(conn *websocket.Conn, method string, params interface{}) error {
+	id := nextRPCID()
+	ch := r.awaitRPC(id)
+
+	writeRPC(conn, rpcMessage{ID: id, Method: method, Params: marshalParams(params)})
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", method, resp.Error.Message)
+		}
+		return nil
+	case <-time.After(rpcAckTimeout):
+		r.cancelPending(id)
+		return fmt.Errorf("%s: timed out waiting for ack", method)
+	}
+}
+
+// isRPC reports whether conn negotiated the JSON-RPC subprotocol.
+func isRPC(conn *websocket.Conn) bool {
+	return conn.Subprotocol() == rpcSubprotocol
+}
+
+// dispatchRPC handles one inbound JSON-RPC 2.0 frame on an /ws connection
+// that negotiated rpcSubprotocol: a response to one of our own outbound
+// requests (no Method) is routed to whoever's waiting on it; a request
+// (Method + ID) or notification (Method, no ID) is dispatched by name.
+func dispatchRPC(conn *websocket.Conn, data []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		writeRPCError(conn, nil, rpcParseError, "invalid JSON-RPC message")
+		return
+	}
+
+	if msg.Method == "" {
+		reg.resolvePending(msg.ID, &msg)
+		return
+	}
+
+	switch msg.Method {
+	case "register":
+		var params struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			writeRPCError(conn, msg.ID, rpcInvalidParams, "register requires a non-empty name")
+			return
+		}
+		session := reg.register(params.Name, conn)
+		writeRPCResult(conn, msg.ID, map[string]interface{}{"name": params.Name, "session": session})
+
+	case "observe", "machines.subscribe":
+		var params struct {
+			SessionID string `json:"sessionId"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		reg.addObserver(conn, params.SessionID)
+		writeRPCResult(conn, msg.ID, map[string]bool{"ok": true})
+
+	case "machines.list":
+		writeRPCResult(conn, msg.ID, map[string]interface{}{"machines": reg.list()})
+
+	default:
+		writeRPCError(conn, msg.ID, rpcMethodNotFound, fmt.Sprintf("unknown method %q", msg.Method))
+	}
+}
@@ -1,11 +1,13 @@
 package coordinator
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -14,27 +16,39 @@ import (
 	"github.com/gorilla/websocket"
 	qrcode "github.com/skip2/go-qrcode"
 
+	"github.com/voice-relay/echo-desktop/internal/config"
+	"github.com/voice-relay/echo-desktop/internal/discovery"
 	"github.com/voice-relay/echo-desktop/internal/hooks"
+	"github.com/voice-relay/echo-desktop/internal/metrics"
 	"github.com/voice-relay/echo-desktop/internal/notifications"
+	"github.com/voice-relay/echo-desktop/internal/sounds"
 )
 
 var (
-	reg             *registry
-	sttFunc         func(audioData []byte, filename string) (string, error)
-	llmFunc         func(rawText string) (string, string, error)
-	ttsFunc         func(text, voice, language string) ([]byte, error)
-	ttsChangeFunc   func(voiceName string) error              // callback to switch voice at runtime
-	ttsPreviewFunc  func(text, voiceName string) ([]byte, error) // preview any voice without changing selection
-	agentFunc       func(rawText string, onProgress func(string, string)) (string, error) // talk mode agent function
-	funcMu          sync.RWMutex
-	coordinatorPort int
-	externalURL     string // e.g. "http://100.x.x.x:53937" for Tailscale
-	ttsVoice        string // configured TTS voice name
-	devURL          string // dev-mode Vite URL (HTTPS via Tailscale)
-
-	notifWatcher *notifications.Watcher
-	notifGenFunc func() (map[string]string, error) // generates random notification via LLM
-	notifDir     string                             // notification directory for hook install
+	reg                 *registry
+	sttFunc             func(audioData []byte, filename string) (string, error)
+	sttStreamFunc       func(audio <-chan []byte, onInterim func(text string)) (string, error) // optional live-dictation counterpart to sttFunc; see SetSTTStreamFunc
+	llmFunc             func(rawText string) (string, string, error)
+	llmStreamFunc       func(rawText string, onDelta func(cleaned, summary string)) (string, string, error) // optional streaming counterpart to llmFunc
+	ttsFunc             func(text, voice, language string) ([]byte, error)
+	ttsChangeFunc       func(voiceName string) error                                                                                        // callback to switch voice at runtime
+	ttsPreviewFunc      func(text, voiceName string) ([]byte, error)                                                                        // preview any voice without changing selection
+	ttsStreamFunc       func(ctx context.Context, text, voiceName, format string) (chunks <-chan AudioChunk, contentType string, err error) // chunked preview for /tts/stream; format is "wav" (default), "opus", or "flac"
+	voiceListFunc       func() ([]VoiceInfo, error)                                                                                         // catalog backing GET /api/voices
+	agentFunc           func(rawText string, onProgress func(string, string)) (string, error)                                               // talk mode agent function
+	agentToolReloadFunc func() error                                                                                                        // reloads the talk agent's tool set; triggered by the tray "Reload Tools" item
+	funcMu              sync.RWMutex
+	coordinatorPort     int
+	externalURL         string       // e.g. "http://100.x.x.x:53937" for Tailscale
+	ttsVoice            string       // configured TTS voice name
+	devURL              string       // dev-mode Vite URL (HTTPS via Tailscale)
+	trustedProxies      []*net.IPNet // reverse proxies whose X-Forwarded-For is honored
+
+	notifWatcher       *notifications.Watcher
+	notifGenFunc       func() (map[string]string, error)                                                // generates random notification via LLM
+	summarizeNotifFunc func(userText, assistantText string) (title, summary, details string, err error) // LLM-summarizes an assistant response into a notification
+	notifDir           string                                                                           // notification directory for hook install
+	routeStore         *notifications.RouteStore                                                        // routing rules fanning notifications out to external sinks
 
 	interimCache   map[string]string // phrase → base64 WAV
 	interimCacheMu sync.RWMutex
@@ -43,23 +57,68 @@ var (
 	// keyed by question ID.
 	pendingQuestions   map[string]*PendingQuestion
 	pendingQuestionsMu sync.RWMutex
+
+	// activeStreams tracks in-flight /tts/stream synthesis by the id the
+	// caller supplied, so /tts/cancel can abort mid-utterance for barge-in.
+	activeStreams   map[string]context.CancelFunc
+	activeStreamsMu sync.Mutex
 )
 
+// AudioChunk is one piece of streamed TTS audio, or a terminal error,
+// mirroring tts.AudioChunk without the coordinator package depending on the
+// tts package directly — the same decoupling SetTTSFunc/SetTTSPreviewFunc use.
+type AudioChunk struct {
+	Data []byte
+	Err  error
+}
+
+// VoiceInfo mirrors tts.VoiceInfo for the same reason AudioChunk mirrors
+// tts.AudioChunk: GET /api/voices shouldn't require this package to import
+// internal/tts directly.
+type VoiceInfo struct {
+	Name       string `json:"name"`
+	Language   string `json:"language"`
+	Quality    string `json:"quality"`
+	SampleRate int    `json:"sample_rate"`
+	SizeBytes  int64  `json:"size_bytes"`
+	SampleURL  string `json:"sample_url,omitempty"`
+}
+
 // PendingQuestion represents an AskUserQuestion intercepted by a PreToolUse hook.
 type PendingQuestion struct {
-	ID          string              `json:"id"`
-	ReplyTarget string              `json:"reply_target"`
-	Questions   []QuestionItem      `json:"questions"`
-	CreatedAt   string              `json:"created_at"`
-	Answered    bool                `json:"answered"`
+	ID          string         `json:"id"`
+	ReplyTarget string         `json:"reply_target"`
+	Questions   []QuestionItem `json:"questions"`
+	CreatedAt   string         `json:"created_at"`
+	Answered    bool           `json:"answered"`
+
+	// Priority is a free-form hint (e.g. "high") surfaced to the PWA so it can
+	// sort or highlight time-sensitive questions; it has no effect on the
+	// reaper below.
+	Priority string `json:"priority,omitempty"`
+	// TTLSeconds discards the question outright once it's been pending this
+	// long, answered or not — a hard backstop against Questions accumulating
+	// forever if a hook's reply target never reconnects. Zero means no limit.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// DefaultIndex is the option auto-selected when AutoAnswerAfterSeconds elapses.
+	DefaultIndex int `json:"default_index,omitempty"`
+	// AutoAnswerAfterSeconds, if non-zero, has the reaper answer with
+	// DefaultIndex when nobody has responded after this many seconds.
+	AutoAnswerAfterSeconds int `json:"auto_answer_after_seconds,omitempty"`
+	// Sound is the cue file internal/sounds resolved for this question
+	// (see handleHookQuestion), played by the PWA before it reads the
+	// question aloud.
+	Sound string `json:"sound,omitempty"`
+
+	createdAt time.Time // internal clock for TTL/AutoAnswerAfter, independent of CreatedAt's string format
 }
 
 // QuestionItem mirrors Claude Code's AskUserQuestion schema.
 type QuestionItem struct {
-	Question    string         `json:"question"`
-	Header      string         `json:"header"`
-	Options     []QuestionOpt  `json:"options"`
-	MultiSelect bool           `json:"multiSelect"`
+	Question    string        `json:"question"`
+	Header      string        `json:"header"`
+	Options     []QuestionOpt `json:"options"`
+	MultiSelect bool          `json:"multiSelect"`
 }
 
 // QuestionOpt is a single option in an AskUserQuestion.
@@ -70,10 +129,11 @@ type QuestionOpt struct {
 
 func init() {
 	pendingQuestions = make(map[string]*PendingQuestion)
+	activeStreams = make(map[string]context.CancelFunc)
 }
 
 var (
-	shortURL      string
+	shortURL       string
 	connectionCode string // just the unique part, e.g. "abc123"
 )
 
@@ -82,13 +142,12 @@ func SetExternalURL(url string) {
 	externalURL = url
 }
 
-// SetShortURL sets the shortened URL and extracts the connection code.
+// SetShortURL sets the shortened URL shown alongside the rotating connection
+// code on the /connect page. It no longer derives the connection code itself —
+// that's now generated and rotated by StartCodeRotation so it can't go stale
+// the way a one-time shortener slug would.
 func SetShortURL(url string) {
 	shortURL = url
-	// Extract just the code from "https://is.gd/abc123"
-	if idx := strings.LastIndex(url, "/"); idx >= 0 {
-		connectionCode = url[idx+1:]
-	}
 }
 
 // GetExternalURL returns the external URL if set.
@@ -111,6 +170,60 @@ func SetDevURL(url string) {
 	devURL = url
 }
 
+// SetTrustedProxies configures which reverse proxies' X-Forwarded-For header is
+// honored when resolving a request's real client IP. Entries that fail to parse
+// as a CIDR are skipped with a log line rather than rejected outright.
+func SetTrustedProxies(cidrs []string) {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy CIDR %q: %v", cidr, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	trustedProxies = nets
+}
+
+// clientIP resolves the real client address for r. If the immediate peer
+// (r.RemoteAddr) is a trusted reverse proxy, the left-most address in
+// X-Forwarded-For is used instead, since that's the address the proxy itself
+// observed. Requests from untrusted peers use RemoteAddr as-is — an untrusted
+// client could set X-Forwarded-For to anything.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	fwd := r.Header.Get("X-Forwarded-For")
+	if fwd == "" {
+		return host
+	}
+	if idx := strings.Index(fwd, ","); idx >= 0 {
+		fwd = fwd[:idx]
+	}
+	return strings.TrimSpace(fwd)
+}
+
+func isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetDevURL returns the dev-mode URL if set.
 func GetDevURL() string {
 	return devURL
@@ -123,6 +236,31 @@ func SetSTTFunc(fn func(audioData []byte, filename string) (string, error)) {
 	sttFunc = fn
 }
 
+// SetSTTStreamFunc sets an optional live-dictation counterpart to sttFunc:
+// given a channel of raw audio frames, it transcribes incrementally,
+// reporting each interim hypothesis through onInterim before returning the
+// final text once the channel closes (mirrors SetLLMStreamFunc's onDelta
+// shape). Nothing in this module feeds it a live frame channel yet — the
+// only audio ingestion today is /transcribe's one-shot upload — but this is
+// the seam a push-to-talk streaming endpoint would hang off, delivering
+// each interim hypothesis to the caller's session as a "stt_interim" event
+// the way PublishInterimText does.
+func SetSTTStreamFunc(fn func(audio <-chan []byte, onInterim func(text string)) (string, error)) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	sttStreamFunc = fn
+}
+
+// PublishInterimText sends an in-progress dictation hypothesis to a specific
+// session as a "stt_interim" event, for a preview UI to show before the
+// final text is pasted. See SetSTTStreamFunc.
+func PublishInterimText(sessionId, text string) {
+	reg.sendToSession(sessionId, map[string]interface{}{
+		"type":    "stt_interim",
+		"content": text,
+	})
+}
+
 // SetLLMFunc sets the text cleanup function used by the /transcribe endpoint.
 // The function returns (cleaned text, summary, error).
 func SetLLMFunc(fn func(rawText string) (string, string, error)) {
@@ -131,6 +269,17 @@ func SetLLMFunc(fn func(rawText string) (string, string, error)) {
 	llmFunc = fn
 }
 
+// SetLLMStreamFunc sets an optional streaming counterpart to llmFunc. When
+// set, handleTranscribe calls it instead so partial cleaned text can be
+// pushed to the requesting session as "llm_delta" events while the model is
+// still generating, rather than blocking the whole /transcribe response on
+// the full completion.
+func SetLLMStreamFunc(fn func(rawText string, onDelta func(cleaned, summary string)) (string, string, error)) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	llmStreamFunc = fn
+}
+
 // SetTTSFunc sets the text-to-speech function for audio feedback.
 func SetTTSFunc(fn func(text, voice, language string) ([]byte, error)) {
 	funcMu.Lock()
@@ -157,6 +306,24 @@ func SetTTSPreviewFunc(fn func(text, voiceName string) ([]byte, error)) {
 	ttsPreviewFunc = fn
 }
 
+// SetTTSStreamFunc sets the callback used by /tts/stream to synthesize audio
+// in chunks rather than all at once. fn should stop producing chunks and
+// close the channel soon after ctx is cancelled. The returned chunks are
+// already framed in the requested container (see internal/tts.Encoder), so
+// handleTTSStream just needs to relay bytes and set contentType.
+func SetTTSStreamFunc(fn func(ctx context.Context, text, voiceName, format string) (<-chan AudioChunk, string, error)) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	ttsStreamFunc = fn
+}
+
+// SetVoiceListFunc sets the function backing GET /api/voices.
+func SetVoiceListFunc(fn func() ([]VoiceInfo, error)) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	voiceListFunc = fn
+}
+
 // SetAgentFunc sets the talk-mode agent function.
 func SetAgentFunc(fn func(rawText string, onProgress func(string, string)) (string, error)) {
 	funcMu.Lock()
@@ -164,6 +331,15 @@ func SetAgentFunc(fn func(rawText string, onProgress func(string, string)) (stri
 	agentFunc = fn
 }
 
+// SetAgentToolReloadFunc sets the function the tray's "Reload Tools" menu
+// item (and any future trigger) calls to re-scan the talk agent's toolsDir,
+// alongside SetAgentFunc.
+func SetAgentToolReloadFunc(fn func() error) {
+	funcMu.Lock()
+	defer funcMu.Unlock()
+	agentToolReloadFunc = fn
+}
+
 // SetNotificationWatcher sets the notification watcher used by the /notifications endpoints.
 func SetNotificationWatcher(w *notifications.Watcher) {
 	notifWatcher = w
@@ -174,11 +350,25 @@ func SetNotifGenFunc(fn func() (map[string]string, error)) {
 	notifGenFunc = fn
 }
 
+// SetSummarizeNotifFunc sets the function used by /notifications/summarize to
+// turn an assistant response into a title/summary/details notification via
+// the LLM.
+func SetSummarizeNotifFunc(fn func(userText, assistantText string) (title, summary, details string, err error)) {
+	summarizeNotifFunc = fn
+}
+
 // SetNotifDir sets the notification directory for hook installation.
 func SetNotifDir(dir string) {
 	notifDir = dir
 }
 
+// SetRouteStore sets the routing rule store used to fan submitted
+// notifications out to external sinks (webhook, email, ntfy, FCM) in
+// addition to the local PWA.
+func SetRouteStore(s *notifications.RouteStore) {
+	routeStore = s
+}
+
 // BroadcastNotificationsReady sends a notifications_updated event to all PWA observers.
 func BroadcastNotificationsReady() {
 	if reg != nil {
@@ -186,6 +376,20 @@ func BroadcastNotificationsReady() {
 			"type": "notifications_updated",
 		})
 	}
+	publishEvent(TopicNotifications, "notifications_updated", map[string]interface{}{})
+}
+
+// PublishAudioChunk fans out one piece of a notification's streamed TTS
+// render on the "tts" topic, so a PWA subscribed to /events can begin
+// playback well before the whole utterance has synthesized. final marks the
+// last chunk for a given notification id, with no data of its own.
+func PublishAudioChunk(id string, seq int, data []byte, final bool) {
+	publishEvent(TopicTTS, "audio_chunk", map[string]interface{}{
+		"id":    id,
+		"seq":   seq,
+		"data":  base64.StdEncoding.EncodeToString(data),
+		"final": final,
+	})
 }
 
 // interimPhrases are the fixed phrases spoken while the agent searches.
@@ -229,46 +433,136 @@ func PreCacheInterimPhrases() {
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
+	// Subprotocols lets a client opt into JSON-RPC 2.0 framing by requesting
+	// rpcSubprotocol via Sec-WebSocket-Protocol; conn.Subprotocol() reports
+	// which (if any) was negotiated. Clients that don't ask for it keep
+	// getting the legacy ad-hoc {"type": ...} envelopes.
+	Subprotocols: []string{rpcSubprotocol},
 }
 
-// Start launches the coordinator HTTP/WS server on the given port.
+// Start launches the coordinator HTTP/WS server on the given port. It refuses to
+// start if another coordinator process is already running, so a second launch
+// (e.g. the app accidentally opened twice) doesn't silently fight over the port.
 func Start(port int) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return StartOnListener(ln)
+}
+
+// Advertise publishes this coordinator over mDNS under name so clients with
+// no configured CoordinatorURL can find it via internal/discovery's browse
+// flow instead of the user typing an address. It's opt-in: callers that
+// don't want the coordinator discoverable (e.g. a Funnel-exposed instance
+// that's reached over the internet rather than the LAN) simply don't call
+// it. Stop the returned Advertiser to stop answering queries.
+func Advertise(port int, name string) (*discovery.Advertiser, error) {
+	return discovery.Advertise(port, name)
+}
+
+// PrepareUpgrade tells every connected client to redial now rather than wait
+// for this process's listener to close. It's meant as the onDrain hook
+// passed to updater.RunSlave, so clients reconnect onto the freshly spawned
+// slave as soon as a supervised binary swap starts instead of only noticing
+// once this process actually exits.
+func PrepareUpgrade() {
+	if reg != nil {
+		reg.broadcastReconnect()
+	}
+}
+
+// StartOnListener runs the coordinator on an already-open listener instead
+// of opening one itself. This is what lets updater.Supervisor hand the
+// coordinator a socket inherited from a previous process (see
+// updater.RunSlave): the listener keeps accepting connections across a
+// binary swap, so an update doesn't drop in-flight requests or WebSocket
+// connections the way restarting a fresh Start(port) would.
+func StartOnListener(ln net.Listener) error {
+	release, err := AcquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	InstallLogStreaming()
+	loadTokens()
 	reg = newRegistry()
+	StartQuestionReaper()
 	coordinatorPort = port
+	if trustedProxies == nil {
+		SetTrustedProxies(config.DefaultTrustedProxies)
+	}
+	StartCodeRotation()
 
 	mux := http.NewServeMux()
 
-	// API routes
-	mux.HandleFunc("/health", handleHealth)
-	mux.HandleFunc("/machines", handleMachines)
-	mux.HandleFunc("/transcribe", handleTranscribe)
-	mux.HandleFunc("/send-text", handleSendText)
+	// API routes. Every route is wrapped in metrics.Instrument so /metrics
+	// carries a full per-route request count/latency series.
+	mux.HandleFunc("/health", metrics.Instrument("/health", handleHealth))
+	mux.HandleFunc("/healthz", metrics.Instrument("/healthz", handleHealthz))
+	mux.HandleFunc("/metrics", metrics.Default.Handler())
+	mux.HandleFunc("/machines", metrics.Instrument("/machines", handleMachines))
+	mux.HandleFunc("/transcribe", metrics.Instrument("/transcribe", handleTranscribe))
+	mux.HandleFunc("/whip", metrics.Instrument("/whip", handleWHIP))
+	mux.HandleFunc("/whip/", metrics.Instrument("/whip/", func(w http.ResponseWriter, r *http.Request) {
+		handleWHIPResource(w, r, strings.TrimPrefix(r.URL.Path, "/whip/"))
+	}))
+	mux.HandleFunc("/pair", metrics.Instrument("/pair", rateLimitByIP(handlePair)))
+	mux.HandleFunc("/paired-devices", metrics.Instrument("/paired-devices", requireLoopbackOrAdmin(handlePairedDevices)))
+	mux.HandleFunc("/code/", metrics.Instrument("/code/", rateLimitByIP(func(w http.ResponseWriter, r *http.Request) {
+		handleCode(w, r, strings.TrimPrefix(r.URL.Path, "/code/"))
+	})))
+	mux.HandleFunc("/send-text", metrics.Instrument("/send-text", handleSendText))
+	mux.HandleFunc("/pty/input", metrics.Instrument("/pty/input", handlePTYInput))
+	mux.HandleFunc("/pty/resize", metrics.Instrument("/pty/resize", handlePTYResize))
 	mux.HandleFunc("/ws", handleWebSocket)
-	mux.HandleFunc("/connect", handleConnect)
-	mux.HandleFunc("/connect-info", handleConnectInfo)
-	mux.HandleFunc("/tts-voice", handleTTSVoice)
-	mux.HandleFunc("/tts-preview", handleTTSPreview)
-	mux.HandleFunc("/notifications", handleNotifications)
-	mux.HandleFunc("/notifications/dismiss", handleNotifDismiss)
-	mux.HandleFunc("/notifications/dismiss-all", handleNotifDismissAll)
-	mux.HandleFunc("/notifications/test", handleNotifTest)
-	mux.HandleFunc("/notifications/submit", handleNotifSubmit)
-	mux.HandleFunc("/hooks/status", handleHookStatus)
-	mux.HandleFunc("/hooks/install", handleHookInstall)
-	mux.HandleFunc("/hooks/uninstall", handleHookUninstall)
-	mux.HandleFunc("/hooks/question", handleHookQuestion)
-	mux.HandleFunc("/question/answer", handleQuestionAnswer)
-	mux.HandleFunc("/questions", handleListQuestions)
+	mux.HandleFunc("/logs", handleLogsWebSocket)
+	mux.HandleFunc("/events", metrics.Instrument("/events", func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(w, r, "")
+	}))
+	mux.HandleFunc("/events/", metrics.Instrument("/events/", func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(w, r, strings.TrimPrefix(r.URL.Path, "/events/"))
+	}))
+	mux.HandleFunc("/connect", metrics.Instrument("/connect", handleConnect))
+	mux.HandleFunc("/connect-info", metrics.Instrument("/connect-info", handleConnectInfo))
+	mux.HandleFunc("/tts-voice", metrics.Instrument("/tts-voice", handleTTSVoice))
+	mux.HandleFunc("/tts-preview", metrics.Instrument("/tts-preview", requireScope(ScopeTTSPreview, handleTTSPreview)))
+	mux.HandleFunc("/api/voices", metrics.Instrument("/api/voices", handleVoices))
+	mux.HandleFunc("/tts/stream", metrics.Instrument("/tts/stream", requireScope(ScopeTTSPreview, handleTTSStream)))
+	mux.HandleFunc("/tts/cancel", metrics.Instrument("/tts/cancel", requireScope(ScopeTTSPreview, handleTTSCancel)))
+	mux.HandleFunc("/notifications", metrics.Instrument("/notifications", handleNotifications))
+	mux.HandleFunc("/notifications/dismiss", metrics.Instrument("/notifications/dismiss", requireScope(ScopeNotifDismiss, handleNotifDismiss)))
+	mux.HandleFunc("/notifications/dismiss-all", metrics.Instrument("/notifications/dismiss-all", requireScope(ScopeNotifDismiss, handleNotifDismissAll)))
+	mux.HandleFunc("/notifications/test", metrics.Instrument("/notifications/test", handleNotifTest))
+	mux.HandleFunc("/notifications/submit", metrics.Instrument("/notifications/submit", requireScope(ScopeNotifSubmit, handleNotifSubmit)))
+	mux.HandleFunc("/notifications/summarize", metrics.Instrument("/notifications/summarize", requireScope(ScopeNotifSubmit, handleNotifSummarize)))
+	mux.HandleFunc("/notifications/routes", metrics.Instrument("/notifications/routes", handleNotifRoutes))
+	mux.HandleFunc("/hooks/status", metrics.Instrument("/hooks/status", handleHookStatus))
+	mux.HandleFunc("/hooks/install", metrics.Instrument("/hooks/install", requireLoopbackOrAdmin(handleHookInstall)))
+	mux.HandleFunc("/hooks/uninstall", metrics.Instrument("/hooks/uninstall", requireLoopbackOrAdmin(handleHookUninstall)))
+	mux.HandleFunc("/hooks/install-project", metrics.Instrument("/hooks/install-project", requireLoopbackOrAdmin(handleHookInstallProject)))
+	mux.HandleFunc("/hooks/uninstall-project", metrics.Instrument("/hooks/uninstall-project", requireLoopbackOrAdmin(handleHookUninstallProject)))
+	mux.HandleFunc("/hooks/stop", metrics.Instrument("/hooks/stop", requireLoopbackOrAdmin(handleHookStop)))
+	mux.HandleFunc("/hooks/question", metrics.Instrument("/hooks/question", requireLoopbackOrAdmin(handleHookQuestion)))
+	mux.HandleFunc("/hooks/notification", metrics.Instrument("/hooks/notification", requireLoopbackOrAdmin(handleHookNotification)))
+	mux.HandleFunc("/hooks/subagent-stop", metrics.Instrument("/hooks/subagent-stop", requireLoopbackOrAdmin(handleHookSubagentStop)))
+	mux.HandleFunc("/hooks/pre-compact", metrics.Instrument("/hooks/pre-compact", requireLoopbackOrAdmin(handleHookPreCompact)))
+	mux.HandleFunc("/hooks/user-prompt-submit", metrics.Instrument("/hooks/user-prompt-submit", requireLoopbackOrAdmin(handleHookUserPromptSubmit)))
+	mux.HandleFunc("/question/answer", metrics.Instrument("/question/answer", requireScope(ScopeQuestionsAnswer, handleQuestionAnswer)))
+	mux.HandleFunc("/questions", metrics.Instrument("/questions", handleListQuestions))
+	mux.HandleFunc("/auth/tokens", metrics.Instrument("/auth/tokens", requireLoopbackOrAdmin(handleMintToken)))
 
 	// Serve PWA static files (fallback for all other routes)
 	mux.Handle("/", pwaHandler())
 
-	addr := fmt.Sprintf(":%d", port)
 	log.Printf("Coordinator running on port %d", port)
 	log.Printf("PWA available at http://localhost:%d", port)
 	log.Printf("WebSocket endpoint: ws://localhost:%d/ws", port)
 
-	return http.ListenAndServe(addr, corsMiddleware(mux))
+	return http.Serve(ln, corsMiddleware(mux))
 }
 
 func corsMiddleware(next http.Handler) http.Handler {
@@ -279,6 +573,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
+		log.Printf("%s %s %s", clientIP(r), r.Method, r.URL.Path)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -287,6 +582,26 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]string{"status": "ok"})
 }
 
+// handleHealthz is a richer liveness check than /health, surfacing whether
+// the pieces an operator would alert on (TTS, pending questions piling up)
+// are actually functional, not just that the process is up.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	funcMu.RLock()
+	ttsReady := ttsFunc != nil
+	funcMu.RUnlock()
+
+	pendingQuestionsMu.RLock()
+	pendingCount := len(pendingQuestions)
+	pendingQuestionsMu.RUnlock()
+
+	writeJSON(w, map[string]interface{}{
+		"status":             "ok",
+		"machines_connected": len(reg.list()),
+		"tts_ready":          ttsReady,
+		"pending_questions":  pendingCount,
+	})
+}
+
 func handleMachines(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, reg.list())
 }
@@ -323,6 +638,67 @@ func handleSendText(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handlePTYInput delivers base64-encoded keystrokes from a remote Terminal
+// viewer to a named cc-wrapper device's PTY.
+func handlePTYInput(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Target string `json:"target"`
+		Data   string `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if body.Target == "" || body.Data == "" {
+		writeJSONError(w, "Missing target or data", http.StatusBadRequest)
+		return
+	}
+
+	if !reg.sendPTYInput(body.Target, body.Data) {
+		writeJSONError(w, fmt.Sprintf("Target machine '%s' not connected", body.Target), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true, "target": body.Target})
+}
+
+// handlePTYResize tells a named cc-wrapper device's PTY to adopt a remote
+// Terminal viewer's window size.
+func handlePTYResize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Target string `json:"target"`
+		Cols   uint16 `json:"cols"`
+		Rows   uint16 `json:"rows"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if body.Target == "" || body.Cols == 0 || body.Rows == 0 {
+		writeJSONError(w, "Missing target, cols, or rows", http.StatusBadRequest)
+		return
+	}
+
+	if !reg.sendPTYResize(body.Target, body.Cols, body.Rows) {
+		writeJSONError(w, fmt.Sprintf("Target machine '%s' not connected", body.Target), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"success": true, "target": body.Target})
+}
+
 func handleTranscribe(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -405,13 +781,32 @@ func handleTranscribe(w http.ResponseWriter, r *http.Request) {
 	// --- Relay mode (existing behavior) ---
 	funcMu.RLock()
 	cleanupFn := llmFunc
+	streamFn := llmStreamFunc
 	funcMu.RUnlock()
 
-	// 2. Clean up text with LLM
+	// 2. Clean up text with LLM, streaming partial results to the requesting
+	// session's WebSocket as "llm_delta" events when a streaming backend is
+	// available.
 	cleanedText := rawText
 	summary := ""
 	var llmMs int64
-	if cleanupFn != nil {
+	if streamFn != nil {
+		llmStart := time.Now()
+		cleaned, sum, err := streamFn(rawText, func(deltaCleaned, deltaSummary string) {
+			reg.sendToSession(sessionId, map[string]interface{}{
+				"type":    "llm_delta",
+				"cleaned": deltaCleaned,
+				"summary": deltaSummary,
+			})
+		})
+		llmMs = time.Since(llmStart).Milliseconds()
+		if err != nil {
+			log.Printf("LLM cleanup failed (%dms), using raw text: %v", llmMs, err)
+		} else {
+			cleanedText = cleaned
+			summary = sum
+		}
+	} else if cleanupFn != nil {
 		llmStart := time.Now()
 		cleaned, sum, err := cleanupFn(rawText)
 		llmMs = time.Since(llmStart).Milliseconds()
@@ -475,17 +870,35 @@ func handleTranscribe(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, resp)
 }
 
-// handleTalkMode runs the agent on transcribed text and returns the response with TTS audio.
-// Progress events (searching, interim audio) are pushed via WebSocket to observers.
+// handleTalkMode runs the agent on transcribed text and writes the response with TTS
+// audio to w. Progress events (searching, interim audio) are pushed via WebSocket to
+// observers regardless of how the utterance arrived (HTTP upload or a WHIP stream).
 func handleTalkMode(w http.ResponseWriter, rawText string, sttMs int64, sessionId string) {
+	resp, errMsg := runTalkMode(rawText, sttMs, sessionId)
+	if errMsg != "" {
+		writeJSONError(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+	if resp == nil {
+		writeJSONError(w, "Talk mode not available (agent not initialized)", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// runTalkMode runs the agent on transcribed text and returns the JSON-able response
+// map, or a non-empty errMsg on failure. It returns a nil resp (no error) if talk mode
+// isn't configured. Callers that aren't servicing an HTTP request — the WHIP ingestion
+// loop, for instance — can push resp straight to the session's WebSocket instead of
+// writing it to a ResponseWriter.
+func runTalkMode(rawText string, sttMs int64, sessionId string) (resp map[string]interface{}, errMsg string) {
 	funcMu.RLock()
 	agentFn := agentFunc
 	speakFn := ttsFunc
 	funcMu.RUnlock()
 
 	if agentFn == nil {
-		writeJSONError(w, "Talk mode not available (agent not initialized)", http.StatusServiceUnavailable)
-		return
+		return nil, ""
 	}
 
 	// Send transcription text to the requesting session only
@@ -563,8 +976,7 @@ func handleTalkMode(w http.ResponseWriter, rawText string, sttMs int64, sessionI
 	agentMs := time.Since(agentStart).Milliseconds()
 	if err != nil {
 		log.Printf("Agent error (%dms): %v", agentMs, err)
-		writeJSONError(w, fmt.Sprintf("Agent error: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Sprintf("Agent error: %v", err)
 	}
 	log.Printf("Agent response (%dms): %s", agentMs, agentResponse)
 
@@ -588,7 +1000,7 @@ func handleTalkMode(w http.ResponseWriter, rawText string, sttMs int64, sessionI
 	}
 	timings = append(timings, timingEntry{"TTS", ttsMs})
 
-	resp := map[string]interface{}{
+	resp = map[string]interface{}{
 		"success":       true,
 		"mode":          "talk",
 		"rawText":       rawText,
@@ -601,7 +1013,7 @@ func handleTalkMode(w http.ResponseWriter, rawText string, sttMs int64, sessionI
 	if ttsB64 != "" {
 		resp["ttsAudio"] = ttsB64
 	}
-	writeJSON(w, resp)
+	return resp, ""
 }
 
 func handleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -611,10 +1023,12 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Println("New WebSocket connection")
+	rpcMode := isRPC(conn)
+	log.Printf("New WebSocket connection (rpc=%v)", rpcMode)
 
 	go func() {
 		defer conn.Close()
+		deviceName := "" // set once this conn registers as a cc-wrapper device
 
 		for {
 			_, data, err := conn.ReadMessage()
@@ -624,17 +1038,31 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if rpcMode {
+				dispatchRPC(conn, data)
+				continue
+			}
+
 			var msg struct {
 				Type      string `json:"type"`
 				Name      string `json:"name"`
 				SessionId string `json:"sessionId"`
+				Data      string `json:"data"`
+				PeerKey   string `json:"peerKey"`
+				Tag       string `json:"tag"`
+				Epoch     int    `json:"epoch"`
 			}
 			if err := json.Unmarshal(data, &msg); err != nil {
 				log.Printf("Invalid WebSocket message: %v", err)
 				continue
 			}
 
-			if msg.Type == "register" && msg.Name != "" {
+			switch msg.Type {
+			case "register":
+				if msg.Name == "" {
+					continue
+				}
+				deviceName = msg.Name
 				sessionNum := reg.register(msg.Name, conn)
 				resp, _ := json.Marshal(map[string]interface{}{
 					"type":    "registered",
@@ -642,8 +1070,21 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 					"session": sessionNum,
 				})
 				conn.WriteMessage(websocket.TextMessage, resp)
-			} else if msg.Type == "observe" {
+			case "handshake":
+				if deviceName != "" {
+					handleE2EHandshake(conn, deviceName, msg.PeerKey, msg.Tag, msg.Epoch)
+				}
+			case "observe":
 				reg.addObserver(conn, msg.SessionId)
+			case "pty_output":
+				// Forwarded by a cc-wrapper device; fan out to the Terminal
+				// topic so any subscribed PWA viewer can render it via xterm.js.
+				if deviceName != "" {
+					publishEvent(TopicTerminal, "pty_output", map[string]string{
+						"name": deviceName,
+						"data": msg.Data,
+					})
+				}
 			}
 		}
 	}()
@@ -822,6 +1263,30 @@ func handleTTSVoice(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleVoices serves the piper-voices catalog (see tts.ListVoices), turning
+// the hardcoded default voice into a user-browseable picker in the PWA.
+func handleVoices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	funcMu.RLock()
+	listFn := voiceListFunc
+	funcMu.RUnlock()
+	if listFn == nil {
+		writeJSONError(w, "Voice catalog not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	voices, err := listFn()
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Failed to list voices: %v", err), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, voices)
+}
+
 func handleTTSPreview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -852,17 +1317,125 @@ func handleTTSPreview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	start := time.Now()
 	audioData, err := previewFn(text, voice)
 	if err != nil {
+		metrics.TTSFailuresTotal.WithLabelValues(voice).Inc()
 		writeJSONError(w, fmt.Sprintf("TTS failed: %v", err), http.StatusInternalServerError)
 		return
 	}
+	metrics.TTSSynthDuration.WithLabelValues(voice).ObserveDuration(start)
 
 	w.Header().Set("Content-Type", "audio/wav")
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(audioData)))
 	w.Write(audioData)
 }
 
+// handleTTSStream is the streaming counterpart to handleTTSPreview: it
+// flushes each encoded chunk as it arrives so playback can start before the
+// whole utterance is synthesized. Pass "id" to let a later POST
+// /tts/cancel?id=… abort it mid-utterance for barge-in. Pass "format" to
+// negotiate a container other than the default WAV — "opus" for an
+// Ogg-Opus stream at roughly half the bytes, or "flac" for lossless — see
+// internal/tts.Encoder.
+func handleTTSStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		ID     string `json:"id"`
+		Text   string `json:"text"`
+		Voice  string `json:"voice"`
+		Format string `json:"format"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	text := body.Text
+	if text == "" {
+		text = "Hello, this is a preview of my voice."
+	}
+	voice := body.Voice
+	if voice == "" {
+		voice = ttsVoice
+	}
+
+	funcMu.RLock()
+	streamFn := ttsStreamFunc
+	funcMu.RUnlock()
+	if streamFn == nil {
+		writeJSONError(w, "Streaming TTS not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if body.ID != "" {
+		activeStreamsMu.Lock()
+		activeStreams[body.ID] = cancel
+		activeStreamsMu.Unlock()
+		defer func() {
+			activeStreamsMu.Lock()
+			delete(activeStreams, body.ID)
+			activeStreamsMu.Unlock()
+		}()
+	}
+
+	chunks, contentType, err := streamFn(ctx, text, voice, body.Format)
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("TTS failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			log.Printf("tts stream: %v", chunk.Err)
+			break
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return // client disconnected
+		}
+		flusher.Flush()
+	}
+}
+
+// handleTTSCancel aborts an in-flight /tts/stream synthesis started with a
+// matching id, e.g. because the user started speaking over playback.
+func handleTTSCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeJSONError(w, "Missing id", http.StatusBadRequest)
+		return
+	}
+
+	activeStreamsMu.Lock()
+	cancel, ok := activeStreams[id]
+	activeStreamsMu.Unlock()
+	if !ok {
+		writeJSONError(w, "No active stream with that id", http.StatusNotFound)
+		return
+	}
+
+	cancel()
+	writeJSON(w, map[string]bool{"cancelled": true})
+}
+
 func handleNotifications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -891,10 +1464,12 @@ func handleNotifDismiss(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Missing id", http.StatusBadRequest)
 		return
 	}
-	if err := notifWatcher.Dismiss(body.ID); err != nil {
+	n, err := notifWatcher.Dismiss(body.ID)
+	if err != nil {
 		writeJSONError(w, fmt.Sprintf("Dismiss failed: %v", err), http.StatusInternalServerError)
 		return
 	}
+	metrics.NotificationsDismissedTotal.WithLabelValues(n.Source).Inc()
 	BroadcastNotificationsReady()
 	writeJSON(w, map[string]bool{"ok": true})
 }
@@ -908,10 +1483,14 @@ func handleNotifDismissAll(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Notifications not available", http.StatusServiceUnavailable)
 		return
 	}
-	if err := notifWatcher.DismissAll(); err != nil {
+	dismissed, err := notifWatcher.DismissAll()
+	if err != nil {
 		writeJSONError(w, fmt.Sprintf("Dismiss all failed: %v", err), http.StatusInternalServerError)
 		return
 	}
+	for _, n := range dismissed {
+		metrics.NotificationsDismissedTotal.WithLabelValues(n.Source).Inc()
+	}
 	BroadcastNotificationsReady()
 	writeJSON(w, map[string]bool{"ok": true})
 }
@@ -932,10 +1511,8 @@ func handleNotifSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate JSON and extract ID
-	var parsed struct {
-		ID string `json:"id"`
-	}
+	// Validate JSON and extract the fields routing rules match against
+	var parsed notifications.Notification
 	if err := json.Unmarshal(body, &parsed); err != nil {
 		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
 		return
@@ -951,10 +1528,46 @@ func handleNotifSubmit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if routeStore != nil {
+		parsed.ID = id
+		routeStore.Dispatch(parsed)
+	}
+
 	log.Printf("Received forwarded notification: %s", id)
 	writeJSON(w, map[string]interface{}{"ok": true, "id": id})
 }
 
+// handleNotifRoutes manages routing rules that fan submitted notifications
+// out to external sinks (webhook, email, ntfy, FCM) in addition to the local
+// PWA. GET lists the current rules; POST appends a new one, e.g.
+// {"match": {"priority": ">=high", "tag": "build"}, "sinks": ["webhook:https://…"]}.
+func handleNotifRoutes(w http.ResponseWriter, r *http.Request) {
+	if routeStore == nil {
+		writeJSONError(w, "Notifications not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, routeStore.Rules())
+	case "POST":
+		var rule notifications.Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if len(rule.Sinks) == 0 {
+			writeJSONError(w, "At least one sink is required", http.StatusBadRequest)
+			return
+		}
+		routeStore.AddRule(rule)
+		log.Printf("Notification route added: %+v -> %v", rule.Match, rule.Sinks)
+		writeJSON(w, map[string]bool{"ok": true})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
 func handleNotifTest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -984,18 +1597,229 @@ func handleNotifTest(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]interface{}{"ok": true, "title": fields["title"]})
 }
 
-// handleHookQuestion receives an AskUserQuestion from a PreToolUse hook script.
-// It stores the question and broadcasts it to all PWA observers.
-func handleHookQuestion(w http.ResponseWriter, r *http.Request) {
+// handleNotifSummarize turns a Claude Code user request/assistant response
+// pair into a notification via the LLM: it submits the result through
+// notifWatcher (so it shows up in the PWA's notification list with audio
+// attached once the watcher processes it) and also synthesizes the summary
+// inline, returning it right away for a caller that wants spoken feedback
+// without waiting on that background pass — the same "returned inline"
+// pattern handleTranscribe uses for its TTS feedback.
+func handleNotifSummarize(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-
-	var body struct {
-		ID          string         `json:"id"`
-		ReplyTarget string         `json:"reply_target"`
-		Questions   []QuestionItem `json:"questions"`
+	if notifWatcher == nil {
+		writeJSONError(w, "Notifications not available", http.StatusServiceUnavailable)
+		return
+	}
+	if summarizeNotifFunc == nil {
+		writeJSONError(w, "LLM not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		UserText      string `json:"user_text"`
+		AssistantText string `json:"assistant_text"`
+		Source        string `json:"source"`
+		Tag           string `json:"tag"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	title, summary, details, err := summarizeNotifFunc(body.UserText, body.AssistantText)
+	if err != nil {
+		writeJSONError(w, fmt.Sprintf("Summarize failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	fields := map[string]string{
+		"title":   title,
+		"summary": summary,
+		"details": details,
+		"source":  body.Source,
+		"tag":     body.Tag,
+	}
+	if err := notifWatcher.Submit(fields); err != nil {
+		writeJSONError(w, fmt.Sprintf("Submit failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{"ok": true, "title": title, "summary": summary, "details": details}
+
+	funcMu.RLock()
+	speakFn := ttsFunc
+	funcMu.RUnlock()
+	if speakFn != nil {
+		voice := ttsVoice
+		if voice == "" {
+			voice = "default"
+		}
+		if audio, err := speakFn(summary, voice, "English"); err != nil {
+			log.Printf("Notification summary TTS failed: %v", err)
+		} else {
+			resp["summary_audio"] = base64.StdEncoding.EncodeToString(audio)
+		}
+	}
+
+	log.Printf("Notification summarized: %s", title)
+	writeJSON(w, resp)
+}
+
+// stopDebounceEntry tracks the in-flight notification for one Stop-hook
+// debounce key (see stopDebounceKey), so a run of quick back-to-back Stops
+// (tool loops, /compact) merges into a single notification instead of
+// firing overlapping TTS. Held in-process rather than in RunStop, since
+// RunStop is a short-lived CLI invocation run fresh for every Stop and can't
+// hold state across invocations itself.
+type stopDebounceEntry struct {
+	notificationID string
+	assistantText  string
+	expiresAt      time.Time
+}
+
+var (
+	stopDebounceMu    sync.Mutex
+	stopDebounceByKey = map[string]*stopDebounceEntry{}
+)
+
+// stopDebounceKey identifies which Stop hook invocations belong to the same
+// conversation, preferring session over reply_target. An empty key means "no
+// identity available" and must never match itself, since merging unrelated
+// Stops into one notification would be worse than not debouncing at all.
+func stopDebounceKey(session, replyTarget string) string {
+	if session != "" {
+		return "session:" + session
+	}
+	if replyTarget != "" {
+		return "target:" + replyTarget
+	}
+	return ""
+}
+
+// stopAssistantTextMaxChars bounds a debounced Stop notification's merged
+// assistant text, mirroring hookrunner.RunStop's own per-Stop truncation so a
+// long run of debounced Stops can't grow the notification without bound.
+const stopAssistantTextMaxChars = 4000
+
+// truncateMerged appends next to existing (if any) and re-truncates to
+// stopAssistantTextMaxChars worth of runes.
+func truncateMerged(existing, next string) string {
+	merged := strings.TrimSpace(existing + "\n" + next)
+	r := []rune(merged)
+	if len(r) <= stopAssistantTextMaxChars {
+		return merged
+	}
+	return string(r[:stopAssistantTextMaxChars])
+}
+
+// handleHookStop receives a Stop hook payload (the user's last message and
+// Claude's reply) and submits it through notifWatcher as a notification, the
+// same SubmitRaw path handleNotifSubmit uses — summarizing the turn via the
+// LLM when summarizeNotifFunc is configured, or falling back to the raw
+// assistant text otherwise, since "tell me when Claude is done" shouldn't
+// depend on an LLM being available. A Stop arriving within
+// debounce_window_ms of the last one for the same session/reply_target is
+// merged into that notification (by id) instead of submitted as a new one.
+func handleHookStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if notifWatcher == nil {
+		writeJSONError(w, "Notifications not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		UserText         string `json:"user_text"`
+		AssistantText    string `json:"assistant_text"`
+		Source           string `json:"source"`
+		Sound            string `json:"sound"`
+		Session          string `json:"session"`
+		ReplyTarget      string `json:"reply_target"`
+		DebounceWindowMS int64  `json:"debounce_window_ms"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.AssistantText == "" {
+		writeJSONError(w, "assistant_text is required", http.StatusBadRequest)
+		return
+	}
+
+	key := stopDebounceKey(body.Session, body.ReplyTarget)
+	window := time.Duration(body.DebounceWindowMS) * time.Millisecond
+
+	stopDebounceMu.Lock()
+	entry := stopDebounceByKey[key]
+	if key == "" || window <= 0 || entry == nil || time.Now().After(entry.expiresAt) {
+		entry = &stopDebounceEntry{notificationID: fmt.Sprintf("claude-%d", time.Now().UnixMilli())}
+	}
+	entry.assistantText = truncateMerged(entry.assistantText, body.AssistantText)
+	entry.expiresAt = time.Now().Add(window)
+	if key != "" && window > 0 {
+		stopDebounceByKey[key] = entry
+	}
+	id, assistantText := entry.notificationID, entry.assistantText
+	stopDebounceMu.Unlock()
+
+	title, summary, details := "Claude finished", assistantText, ""
+	if summarizeNotifFunc != nil {
+		if t, s, d, err := summarizeNotifFunc(body.UserText, assistantText); err == nil {
+			title, summary, details = t, s, d
+		} else {
+			log.Printf("Stop hook: LLM summarize failed, falling back to raw text: %v", err)
+		}
+	}
+
+	n := notifications.Notification{
+		ID:       id,
+		Title:    title,
+		Summary:  summary,
+		Details:  details,
+		Priority: "normal",
+		Tag:      "stop",
+		Source:   body.Source,
+	}
+	data, err := json.Marshal(n)
+	if err != nil {
+		writeJSONError(w, "Encoding failed", http.StatusInternalServerError)
+		return
+	}
+	if err := notifWatcher.SubmitRaw(id, data); err != nil {
+		writeJSONError(w, fmt.Sprintf("Submit failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if body.Sound != "" {
+		publishEvent(TopicTTS, "cue", map[string]interface{}{"id": id, "sound": body.Sound})
+	}
+
+	log.Printf("Stop hook notification submitted: %s", id)
+	writeJSON(w, map[string]interface{}{"ok": true, "id": id})
+}
+
+// handleHookQuestion receives an AskUserQuestion from a PreToolUse hook script.
+// It stores the question and broadcasts it to all PWA observers.
+func handleHookQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		ID                     string         `json:"id"`
+		ReplyTarget            string         `json:"reply_target"`
+		Questions              []QuestionItem `json:"questions"`
+		Priority               string         `json:"priority"`
+		TTLSeconds             int            `json:"ttl_seconds"`
+		DefaultIndex           int            `json:"default_index"`
+		AutoAnswerAfterSeconds int            `json:"auto_answer_after_seconds"`
+		Sound                  string         `json:"sound"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
@@ -1006,16 +1830,24 @@ func handleHookQuestion(w http.ResponseWriter, r *http.Request) {
 		body.ID = fmt.Sprintf("q-%d", time.Now().UnixMilli())
 	}
 
+	now := time.Now()
 	pq := &PendingQuestion{
-		ID:          body.ID,
-		ReplyTarget: body.ReplyTarget,
-		Questions:   body.Questions,
-		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		ID:                     body.ID,
+		ReplyTarget:            body.ReplyTarget,
+		Questions:              body.Questions,
+		CreatedAt:              now.UTC().Format(time.RFC3339),
+		Priority:               body.Priority,
+		TTLSeconds:             body.TTLSeconds,
+		DefaultIndex:           body.DefaultIndex,
+		AutoAnswerAfterSeconds: body.AutoAnswerAfterSeconds,
+		Sound:                  body.Sound,
+		createdAt:              now,
 	}
 
 	pendingQuestionsMu.Lock()
 	pendingQuestions[pq.ID] = pq
 	pendingQuestionsMu.Unlock()
+	metrics.PendingQuestionsGauge.Inc()
 
 	log.Printf("Question received: %s (target=%s, %d questions)", pq.ID, pq.ReplyTarget, len(pq.Questions))
 
@@ -1026,10 +1858,172 @@ func handleHookQuestion(w http.ResponseWriter, r *http.Request) {
 			"question": pq,
 		})
 	}
+	publishEvent(TopicQuestions, "question", pq)
 
 	writeJSON(w, map[string]interface{}{"ok": true, "id": pq.ID})
 }
 
+// speakHookEvent synthesizes text via the configured TTS backend and
+// broadcasts it as a single-chunk "tts" event under id, the same
+// synthesize-and-publish step handleNotifSummarize and
+// PreCacheInterimPhrases already do. If sound names a cue file (resolved by
+// internal/sounds — see the hookrunner callers), that's published first so
+// the PWA's player can fire the cue before the spoken body arrives. It's all
+// best-effort: hook handlers never fail the request just because speech or
+// a cue file isn't available.
+func speakHookEvent(id, text, sound string) {
+	if sound != "" {
+		publishEvent(TopicTTS, "cue", map[string]interface{}{
+			"id":    id,
+			"sound": sound,
+		})
+	}
+
+	funcMu.RLock()
+	speakFn := ttsFunc
+	funcMu.RUnlock()
+	if speakFn == nil || text == "" {
+		return
+	}
+
+	voice := ttsVoice
+	if voice == "" {
+		voice = "default"
+	}
+
+	audio, err := speakFn(text, voice, "English")
+	if err != nil {
+		log.Printf("hook event TTS failed (%s): %v", id, err)
+		return
+	}
+	PublishAudioChunk(id, 0, audio, true)
+}
+
+// handleHookNotification receives a Claude Code Notification hook payload
+// (e.g. "waiting for your input") and speaks it as a short mic-cue so the
+// user knows to pay attention without having to look at a screen.
+func handleHookNotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+		Sound   string `json:"sound"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.Message == "" {
+		writeJSONError(w, "message is required", http.StatusBadRequest)
+		return
+	}
+
+	id := fmt.Sprintf("notif-%d", time.Now().UnixMilli())
+	log.Printf("Hook notification received: %s", body.Message)
+	speakHookEvent(id, body.Message, body.Sound)
+
+	writeJSON(w, map[string]interface{}{"ok": true, "id": id})
+}
+
+// handleHookSubagentStop receives a SubagentStop hook payload and speaks the
+// subagent's final reply, so a user running talk-mode with subagents hears
+// each one finish instead of only the top-level agent's Stop hook.
+func handleHookSubagentStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Text  string `json:"text"`
+		Sound string `json:"sound"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.Text == "" {
+		writeJSONError(w, "text is required", http.StatusBadRequest)
+		return
+	}
+
+	id := fmt.Sprintf("subagent-%d", time.Now().UnixMilli())
+	log.Printf("Subagent stop received (%d chars)", len(body.Text))
+	speakHookEvent(id, body.Text, body.Sound)
+
+	writeJSON(w, map[string]interface{}{"ok": true, "id": id})
+}
+
+// handleHookPreCompact receives a PreCompact hook payload and announces that
+// the transcript is about to be compacted, since that's a moment where
+// earlier context can quietly fall out of scope.
+func handleHookPreCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		Trigger string `json:"trigger"`
+		Sound   string `json:"sound"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	id := fmt.Sprintf("precompact-%d", time.Now().UnixMilli())
+	text := "Compacting the conversation now."
+	if body.Trigger == "manual" {
+		text = "Compacting the conversation now, as requested."
+	}
+	log.Printf("Pre-compact received (trigger=%s)", body.Trigger)
+	speakHookEvent(id, text, body.Sound)
+
+	writeJSON(w, map[string]interface{}{"ok": true, "id": id})
+}
+
+// handleHookUserPromptSubmit receives a UserPromptSubmit hook payload and
+// records the user's prompt through notifWatcher, the same submission path
+// handleNotifSummarize uses, so it shows up in the PWA's notification
+// history tagged as a user prompt rather than an agent-generated summary.
+func handleHookUserPromptSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if notifWatcher == nil {
+		writeJSONError(w, "Notifications not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.Prompt == "" {
+		writeJSONError(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	fields := map[string]string{
+		"title":   "User prompt",
+		"summary": body.Prompt,
+		"source":  "claude-code",
+		"tag":     "user_prompt",
+	}
+	if err := notifWatcher.Submit(fields); err != nil {
+		writeJSONError(w, fmt.Sprintf("Submit failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{"ok": true})
+}
+
 // handleQuestionAnswer receives an answer from the PWA and routes it to the cc-wrapper.
 func handleQuestionAnswer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -1040,7 +2034,7 @@ func handleQuestionAnswer(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		QuestionID string `json:"question_id"`
 		Index      int    `json:"index"`      // option index to select
-		OtherText  string `json:"other_text"`  // if "Other" was chosen
+		OtherText  string `json:"other_text"` // if "Other" was chosen
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
@@ -1071,24 +2065,90 @@ func handleQuestionAnswer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Question %s answered: index=%d other=%q -> %s", body.QuestionID, body.Index, body.OtherText, pq.ReplyTarget)
+	finishQuestion(pq)
+
+	writeJSON(w, map[string]interface{}{"ok": true})
+}
+
+// finishQuestion broadcasts question_answered so PWA observers drop the card,
+// then schedules pq's removal from pendingQuestions after a short grace
+// period so late-connecting observers still see the dismissal. Shared by
+// handleQuestionAnswer (a human answered) and the reaper below (auto-answered
+// after AutoAnswerAfterSeconds).
+func finishQuestion(pq *PendingQuestion) {
+	metrics.PendingQuestionsGauge.Dec()
+	metrics.QuestionAnswerLatency.Observe(time.Since(pq.createdAt).Seconds())
 
-	// Broadcast dismissal so PWA removes the question card
 	if reg != nil {
 		reg.broadcastEvent(map[string]interface{}{
 			"type":        "question_answered",
-			"question_id": body.QuestionID,
+			"question_id": pq.ID,
 		})
 	}
+	publishEvent(TopicQuestions, "question_answered", map[string]interface{}{
+		"question_id": pq.ID,
+	})
 
-	// Clean up after a short delay (keep it around briefly for late observers)
 	go func() {
 		time.Sleep(5 * time.Second)
 		pendingQuestionsMu.Lock()
-		delete(pendingQuestions, body.QuestionID)
+		delete(pendingQuestions, pq.ID)
 		pendingQuestionsMu.Unlock()
 	}()
+}
 
-	writeJSON(w, map[string]interface{}{"ok": true})
+// questionReaperInterval is how often the reaper scans pendingQuestions for
+// expired TTLs and elapsed auto-answer windows.
+const questionReaperInterval = 1 * time.Second
+
+// StartQuestionReaper begins a background loop that auto-answers questions
+// whose AutoAnswerAfterSeconds has elapsed (picking DefaultIndex) and discards
+// questions past their TTLSeconds outright, the way ntfy expires cached
+// messages. Call once; it runs until the process exits.
+func StartQuestionReaper() {
+	go func() {
+		ticker := time.NewTicker(questionReaperInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reapQuestions()
+		}
+	}()
+}
+
+func reapQuestions() {
+	now := time.Now()
+
+	pendingQuestionsMu.Lock()
+	var toAutoAnswer, toExpire []*PendingQuestion
+	for _, pq := range pendingQuestions {
+		if pq.Answered {
+			continue
+		}
+		age := now.Sub(pq.createdAt)
+		if pq.TTLSeconds > 0 && age >= time.Duration(pq.TTLSeconds)*time.Second {
+			pq.Answered = true
+			toExpire = append(toExpire, pq)
+			continue
+		}
+		if pq.AutoAnswerAfterSeconds > 0 && age >= time.Duration(pq.AutoAnswerAfterSeconds)*time.Second {
+			pq.Answered = true
+			toAutoAnswer = append(toAutoAnswer, pq)
+		}
+	}
+	pendingQuestionsMu.Unlock()
+
+	for _, pq := range toExpire {
+		log.Printf("Question %s expired after %ds with no answer", pq.ID, pq.TTLSeconds)
+		finishQuestion(pq)
+	}
+
+	for _, pq := range toAutoAnswer {
+		log.Printf("Question %s auto-answered with index=%d after %ds", pq.ID, pq.DefaultIndex, pq.AutoAnswerAfterSeconds)
+		if pq.ReplyTarget != "" {
+			reg.sendSelect(pq.ReplyTarget, pq.DefaultIndex, "")
+		}
+		finishQuestion(pq)
+	}
 }
 
 // handleListQuestions returns all pending (unanswered) questions.
@@ -1119,10 +2179,9 @@ func handleHookStatus(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	installed, scriptPath := hooks.Status()
 	writeJSON(w, map[string]interface{}{
-		"installed":  installed,
-		"scriptPath": scriptPath,
+		"hooks":  hooks.Status(),
+		"sounds": sounds.Status(),
 	})
 }
 
@@ -1135,11 +2194,17 @@ func handleHookInstall(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, "Notification directory not configured", http.StatusServiceUnavailable)
 		return
 	}
-	if err := hooks.Install(notifDir); err != nil {
+	if err := hooks.Install(); err != nil {
+		writeJSONError(w, fmt.Sprintf("Install failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := sounds.Install(); err != nil {
 		writeJSONError(w, fmt.Sprintf("Install failed: %v", err), http.StatusInternalServerError)
 		return
 	}
 	log.Printf("Claude Code hook installed")
+	metrics.HookInstallStatus.WithLabelValues(config.DefaultName()).Set(1)
+	publishEvent(TopicHooks, "hook_installed", map[string]interface{}{})
 	writeJSON(w, map[string]bool{"ok": true})
 }
 
@@ -1153,5 +2218,67 @@ func handleHookUninstall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Printf("Claude Code hook uninstalled")
+	metrics.HookInstallStatus.WithLabelValues(config.DefaultName()).Set(0)
+	publishEvent(TopicHooks, "hook_uninstalled", map[string]interface{}{})
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// handleHookInstallProject installs voice-relay's hooks into a repo's own
+// .claude/settings.json (see hooks.InstallProject) instead of the
+// user-global one, so the integration can be checked in for teammates.
+func handleHookInstallProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		RepoRoot string `json:"repo_root"`
+		NotifDir string `json:"notif_dir"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.RepoRoot == "" {
+		writeJSONError(w, "repo_root is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := hooks.InstallProject(body.RepoRoot, body.NotifDir); err != nil {
+		writeJSONError(w, fmt.Sprintf("Install failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Claude Code hook installed into %s", body.RepoRoot)
+	publishEvent(TopicHooks, "hook_installed", map[string]interface{}{"repo_root": body.RepoRoot})
+	writeJSON(w, map[string]bool{"ok": true})
+}
+
+// handleHookUninstallProject removes voice-relay's hooks from a repo's
+// .claude/settings.json (see hooks.UninstallProject).
+func handleHookUninstallProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		RepoRoot string `json:"repo_root"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSONError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if body.RepoRoot == "" {
+		writeJSONError(w, "repo_root is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := hooks.UninstallProject(body.RepoRoot); err != nil {
+		writeJSONError(w, fmt.Sprintf("Uninstall failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Claude Code hook uninstalled from %s", body.RepoRoot)
+	publishEvent(TopicHooks, "hook_uninstalled", map[string]interface{}{"repo_root": body.RepoRoot})
 	writeJSON(w, map[string]bool{"ok": true})
 }
@@ -0,0 +1,239 @@
+package coordinator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media/oggwriter"
+)
+
+// whipFlushInterval is how much audio is buffered before it's handed to the STT
+// engine as one utterance. Real silence-based VAD segmentation would cut more
+// naturally, but a fixed window keeps the ingestion pipeline simple and still
+// gives talk mode continuous, low-latency transcription instead of waiting for
+// a whole recording to be uploaded.
+const whipFlushInterval = 2500 * time.Millisecond
+
+// whipSession is one live WHIP (WebRTC-HTTP Ingestion Protocol) publish from a
+// client streaming microphone audio in talk mode instead of uploading a file per
+// utterance to /transcribe.
+type whipSession struct {
+	id         string
+	sessionId  string // WebSocket session this audio's transcriptions are routed to
+	pc         *webrtc.PeerConnection
+	resourceID string
+}
+
+var (
+	whipSessions   = map[string]*whipSession{}
+	whipSessionsMu sync.Mutex
+)
+
+// handleWHIP accepts a WHIP offer (an SDP POST body) and begins streaming talk-mode
+// transcription for the audio track it carries. See
+// https://www.ietf.org/archive/id/draft-ietf-wish-whip-01.html.
+func handleWHIP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	offerSDP, err := io.ReadAll(r.Body)
+	if err != nil || len(offerSDP) == 0 {
+		writeJSONError(w, "Missing SDP offer body", http.StatusBadRequest)
+		return
+	}
+
+	sessionId := r.URL.Query().Get("session")
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		writeJSONError(w, "Failed to create peer connection", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := pc.AddTransceiverFromKind(webrtc.RTPCodecTypeAudio, webrtc.RTPTransceiverInit{
+		Direction: webrtc.RTPTransceiverDirectionRecvonly,
+	}); err != nil {
+		pc.Close()
+		writeJSONError(w, "Failed to add audio transceiver", http.StatusInternalServerError)
+		return
+	}
+
+	id := randomID()
+	sess := &whipSession{id: id, sessionId: sessionId, pc: pc, resourceID: "/whip/" + id}
+
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		go sess.consumeTrack(track)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			sess.close()
+		}
+	})
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  string(offerSDP),
+	}); err != nil {
+		pc.Close()
+		writeJSONError(w, "Invalid SDP offer", http.StatusBadRequest)
+		return
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		pc.Close()
+		writeJSONError(w, "Failed to create SDP answer", http.StatusInternalServerError)
+		return
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		pc.Close()
+		writeJSONError(w, "Failed to set local description", http.StatusInternalServerError)
+		return
+	}
+	<-gatherComplete
+
+	whipSessionsMu.Lock()
+	whipSessions[id] = sess
+	whipSessionsMu.Unlock()
+
+	log.Printf("WHIP: started publish %s for session %s", id, sessionId)
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", sess.resourceID)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(pc.LocalDescription().SDP))
+}
+
+// handleWHIPResource handles DELETE on a WHIP resource URL to end the publish.
+func handleWHIPResource(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	whipSessionsMu.Lock()
+	sess, ok := whipSessions[id]
+	whipSessionsMu.Unlock()
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	sess.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// consumeTrack reads RTP packets off an incoming audio track, buffers them into
+// Ogg/Opus chunks, and periodically runs each chunk through the existing STT/agent
+// pipeline just like an uploaded /transcribe request.
+func (s *whipSession) consumeTrack(track *webrtc.TrackRemote) {
+	var buf bytes.Buffer
+	ogg, err := oggwriter.NewWith(&buf, track.Codec().ClockRate, uint16(track.Codec().Channels))
+	if err != nil {
+		log.Printf("WHIP: failed to create ogg writer for %s: %v", s.id, err)
+		return
+	}
+
+	flush := func() {
+		if err := ogg.Close(); err != nil {
+			log.Printf("WHIP: failed to close ogg chunk for %s: %v", s.id, err)
+			return
+		}
+		if buf.Len() == 0 {
+			return
+		}
+		chunk := append([]byte(nil), buf.Bytes()...)
+		buf.Reset()
+		if ogg, err = oggwriter.NewWith(&buf, track.Codec().ClockRate, uint16(track.Codec().Channels)); err != nil {
+			log.Printf("WHIP: failed to reopen ogg writer for %s: %v", s.id, err)
+			return
+		}
+		go s.transcribeChunk(chunk)
+	}
+
+	ticker := time.NewTicker(whipFlushInterval)
+	defer ticker.Stop()
+
+	packets := make(chan struct{})
+	go func() {
+		defer close(packets)
+		for {
+			packet, _, err := track.ReadRTP()
+			if err != nil {
+				return
+			}
+			if err := ogg.WriteRTP(packet); err != nil {
+				log.Printf("WHIP: failed to write RTP packet for %s: %v", s.id, err)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			flush()
+		case _, ok := <-packets:
+			if !ok {
+				flush()
+				return
+			}
+		}
+	}
+}
+
+// transcribeChunk runs one buffered audio chunk through the STT/agent pipeline and
+// pushes the result to the originating WebSocket session.
+func (s *whipSession) transcribeChunk(chunk []byte) {
+	funcMu.RLock()
+	transcribeFn := sttFunc
+	funcMu.RUnlock()
+	if transcribeFn == nil {
+		return
+	}
+
+	sttStart := time.Now()
+	rawText, err := transcribeFn(chunk, "whip-chunk.ogg")
+	sttMs := time.Since(sttStart).Milliseconds()
+	if err != nil {
+		log.Printf("WHIP: transcription error for %s: %v", s.id, err)
+		return
+	}
+	if isBlankTranscription(rawText) {
+		return
+	}
+
+	resp, errMsg := runTalkMode(rawText, sttMs, s.sessionId)
+	if errMsg != "" {
+		log.Printf("WHIP: talk mode error for %s: %v", s.id, errMsg)
+		return
+	}
+	if resp == nil {
+		return
+	}
+	reg.sendToSession(s.sessionId, resp)
+}
+
+func (s *whipSession) close() {
+	whipSessionsMu.Lock()
+	delete(whipSessions, s.id)
+	whipSessionsMu.Unlock()
+	s.pc.Close()
+	log.Printf("WHIP: ended publish %s", s.id)
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
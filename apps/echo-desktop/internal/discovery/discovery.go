@@ -0,0 +1,208 @@
+// Package discovery lets a client find a coordinator on the local network
+// without being told its address up front, and lets a coordinator advertise
+// itself so it can be found. It speaks just enough mDNS/DNS-SD (RFC 6762/6763)
+// to publish and browse a PTR/SRV/A record chain for a single service type —
+// no third-party mDNS/zeroconf library is vendored anywhere in this module,
+// so rather than add one this is a minimal, stdlib-only (net package)
+// responder and querier, in the same spirit as the hand-rolled DNS TXT
+// lookups config.ShortURLProviders already does for short-URL resolution.
+package discovery
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// serviceType is the DNS-SD service instance name voice-relay coordinators
+// advertise under, analogous to "_http._tcp.local." for web servers.
+const serviceType = "_voicerelay._tcp.local."
+
+const (
+	mdnsAddrStr = "224.0.0.251:5353"
+	mdnsPort    = 5353
+)
+
+// Instance describes one coordinator found (or advertised) on the network.
+type Instance struct {
+	Name string // instance name, e.g. the machine's configured client name
+	Host string
+	Port int
+}
+
+func (i Instance) target() string {
+	return sanitizeLabel(i.Name) + "." + serviceType
+}
+
+// Advertiser answers mDNS queries for serviceType with this instance's
+// host/port until Stop is called.
+type Advertiser struct {
+	conn   *net.UDPConn
+	stopCh chan struct{}
+}
+
+// Advertise starts responding to mDNS browse queries for the coordinator
+// service, advertising it as name at this host's address and port. Callers
+// should Stop the returned Advertiser on shutdown.
+func Advertise(port int, name string) (*Advertiser, error) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddrStr)
+	if err != nil {
+		return nil, fmt.Errorf("resolve mdns group: %w", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, fmt.Errorf("listen mdns: %w", err)
+	}
+
+	a := &Advertiser{conn: conn, stopCh: make(chan struct{})}
+	go a.serve(port, name)
+	return a, nil
+}
+
+// Stop stops answering queries and releases the multicast socket.
+func (a *Advertiser) Stop() {
+	close(a.stopCh)
+	a.conn.Close()
+}
+
+func (a *Advertiser) serve(port int, name string) {
+	inst := Instance{Name: name, Port: port}
+	buf := make([]byte, 2048)
+	for {
+		a.conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, src, err := a.conn.ReadFromUDP(buf)
+		select {
+		case <-a.stopCh:
+			return
+		default:
+		}
+		if err != nil {
+			continue
+		}
+
+		msg, err := parseMessage(buf[:n])
+		if err != nil || !msg.isQuery() {
+			continue
+		}
+		if !msg.asksFor(serviceType) {
+			continue
+		}
+
+		resp := buildResponse(msg.id, inst, localAddr(a.conn))
+		if _, err := a.conn.WriteToUDP(resp, src); err != nil {
+			log.Printf("discovery: mdns reply failed: %v", err)
+		}
+	}
+}
+
+// Browse sends one mDNS query for the coordinator service and collects
+// replies for the given window, returning whatever instances answered.
+func Browse(window time.Duration) ([]Instance, error) {
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddrStr)
+	if err != nil {
+		return nil, fmt.Errorf("resolve mdns group: %w", err)
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, fmt.Errorf("listen mdns: %w", err)
+	}
+	defer conn.Close()
+
+	query := buildQuery()
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, fmt.Errorf("send mdns query: %w", err)
+	}
+
+	var found []Instance
+	seen := map[string]bool{}
+	deadline := time.Now().Add(window)
+	buf := make([]byte, 2048)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(remaining))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		msg, err := parseMessage(buf[:n])
+		if err != nil || msg.isQuery() {
+			continue
+		}
+		for _, inst := range msg.instances() {
+			key := fmt.Sprintf("%s@%s:%d", inst.Name, inst.Host, inst.Port)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			found = append(found, inst)
+		}
+	}
+	return found, nil
+}
+
+// Watcher periodically re-browses for coordinator instances so a client can
+// notice when one moves to a new address.
+type Watcher struct {
+	stopCh chan struct{}
+}
+
+// Watch browses every interval and calls onUpdate with the current instance
+// list after each pass. It's the "resolver loop" a client runs for as long
+// as it's relying on discovery instead of a fixed CoordinatorURL.
+func Watch(interval time.Duration, onUpdate func([]Instance)) *Watcher {
+	w := &Watcher{stopCh: make(chan struct{})}
+	go func() {
+		for {
+			if instances, err := Browse(interval / 2); err == nil {
+				onUpdate(instances)
+			}
+			select {
+			case <-w.stopCh:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+	return w
+}
+
+// Stop ends the browse loop.
+func (w *Watcher) Stop() {
+	close(w.stopCh)
+}
+
+func localAddr(conn *net.UDPConn) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+func sanitizeLabel(name string) string {
+	if name == "" {
+		return "coordinator"
+	}
+	return strings.ReplaceAll(name, ".", "-")
+}
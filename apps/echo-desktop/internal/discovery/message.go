@@ -0,0 +1,300 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// This file hand-rolls just enough of RFC 1035's DNS wire format to build and
+// parse the handful of mDNS packets discovery needs: a PTR query, and a
+// PTR+SRV+A response chain. It intentionally does not support compression on
+// write (nothing we build is big enough to need it) but does follow
+// compression pointers on read, since other mDNS responders on the network
+// may use them.
+
+const (
+	typePTR = 12
+	typeA   = 1
+	typeSRV = 33
+	typeTXT = 16
+	classIN = 1
+
+	flagResponse = 1 << 15
+)
+
+type dnsHeader struct {
+	id      uint16
+	flags   uint16
+	qdCount uint16
+	anCount uint16
+}
+
+type dnsRecord struct {
+	name  string
+	typ   uint16
+	class uint16
+	ttl   uint32
+	data  []byte
+}
+
+type dnsMessage struct {
+	id        uint16
+	flags     uint16
+	questions []string
+	answers   []dnsRecord
+}
+
+func (m dnsMessage) isQuery() bool {
+	return m.flags&flagResponse == 0
+}
+
+func (m dnsMessage) asksFor(name string) bool {
+	for _, q := range m.questions {
+		if q == name {
+			return true
+		}
+	}
+	return false
+}
+
+// instances reassembles PTR/SRV/A answers into Instance values. mDNS spreads
+// one logical "service instance" across three records (PTR -> instance name,
+// SRV -> host/port, A -> host's address), so this joins them back up by name.
+func (m dnsMessage) instances() []Instance {
+	var names []string
+	for _, rec := range m.answers {
+		if rec.typ == typePTR && rec.name == serviceType {
+			if target, _, err := decodeName(rec.data, 0); err == nil {
+				names = append(names, target)
+			}
+		}
+	}
+
+	srvByName := map[string]struct {
+		host string
+		port int
+	}{}
+	ipByHost := map[string]string{}
+	for _, rec := range m.answers {
+		switch rec.typ {
+		case typeSRV:
+			if len(rec.data) < 6 {
+				continue
+			}
+			port := int(binary.BigEndian.Uint16(rec.data[4:6]))
+			host, _, err := decodeName(rec.data, 6)
+			if err != nil {
+				continue
+			}
+			srvByName[rec.name] = struct {
+				host string
+				port int
+			}{host: host, port: port}
+		case typeA:
+			if len(rec.data) == 4 {
+				ipByHost[rec.name] = net.IP(rec.data).String()
+			}
+		}
+	}
+
+	var out []Instance
+	for _, name := range names {
+		srv, ok := srvByName[name]
+		if !ok {
+			continue
+		}
+		host := ipByHost[srv.host]
+		if host == "" {
+			host = srv.host
+		}
+		out = append(out, Instance{
+			Name: instanceLabel(name),
+			Host: host,
+			Port: srv.port,
+		})
+	}
+	return out
+}
+
+func instanceLabel(target string) string {
+	suffix := "." + serviceType
+	if len(target) > len(suffix) && target[len(target)-len(suffix):] == suffix {
+		return target[:len(target)-len(suffix)]
+	}
+	return target
+}
+
+func buildQuery() []byte {
+	buf := encodeHeader(dnsHeader{qdCount: 1})
+	buf = append(buf, encodeName(serviceType)...)
+	buf = binary.BigEndian.AppendUint16(buf, typePTR)
+	buf = binary.BigEndian.AppendUint16(buf, classIN)
+	return buf
+}
+
+func buildResponse(id uint16, inst Instance, host string) []byte {
+	target := inst.target()
+
+	ptrData := encodeName(target)
+	srvData := binary.BigEndian.AppendUint16(nil, 0)    // priority
+	srvData = binary.BigEndian.AppendUint16(srvData, 0) // weight
+	srvData = binary.BigEndian.AppendUint16(srvData, uint16(inst.Port))
+	srvData = append(srvData, encodeName(host)...)
+	aData := net.ParseIP(host).To4()
+	if aData == nil {
+		aData = []byte{0, 0, 0, 0}
+	}
+
+	buf := encodeHeader(dnsHeader{id: id, flags: flagResponse, anCount: 3})
+	buf = append(buf, encodeRecord(serviceType, typePTR, ptrData)...)
+	buf = append(buf, encodeRecord(target, typeSRV, srvData)...)
+	buf = append(buf, encodeRecord(host, typeA, aData)...)
+	return buf
+}
+
+func encodeHeader(h dnsHeader) []byte {
+	buf := make([]byte, 12)
+	binary.BigEndian.PutUint16(buf[0:2], h.id)
+	binary.BigEndian.PutUint16(buf[2:4], h.flags)
+	binary.BigEndian.PutUint16(buf[4:6], h.qdCount)
+	binary.BigEndian.PutUint16(buf[6:8], h.anCount)
+	return buf
+}
+
+func encodeRecord(name string, typ uint16, data []byte) []byte {
+	buf := encodeName(name)
+	buf = binary.BigEndian.AppendUint16(buf, typ)
+	buf = binary.BigEndian.AppendUint16(buf, classIN)
+	buf = binary.BigEndian.AppendUint32(buf, 120) // TTL seconds
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(data)))
+	return append(buf, data...)
+}
+
+func encodeName(name string) []byte {
+	var buf []byte
+	for _, label := range splitLabels(name) {
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	return append(buf, 0)
+}
+
+func splitLabels(name string) []string {
+	if name == "" {
+		return nil
+	}
+	var labels []string
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			if i > start {
+				labels = append(labels, name[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return labels
+}
+
+func parseMessage(data []byte) (dnsMessage, error) {
+	if len(data) < 12 {
+		return dnsMessage{}, fmt.Errorf("mdns packet too short (%d bytes)", len(data))
+	}
+	msg := dnsMessage{
+		id:    binary.BigEndian.Uint16(data[0:2]),
+		flags: binary.BigEndian.Uint16(data[2:4]),
+	}
+	qdCount := binary.BigEndian.Uint16(data[4:6])
+	anCount := binary.BigEndian.Uint16(data[6:8])
+
+	offset := 12
+	for i := 0; i < int(qdCount); i++ {
+		name, next, err := decodeName(data, offset)
+		if err != nil {
+			return dnsMessage{}, err
+		}
+		if next+4 > len(data) {
+			return dnsMessage{}, fmt.Errorf("mdns question truncated")
+		}
+		msg.questions = append(msg.questions, name)
+		offset = next + 4 // type + class
+	}
+
+	for i := 0; i < int(anCount); i++ {
+		name, next, err := decodeName(data, offset)
+		if err != nil {
+			return dnsMessage{}, err
+		}
+		if next+10 > len(data) {
+			return dnsMessage{}, fmt.Errorf("mdns answer truncated")
+		}
+		typ := binary.BigEndian.Uint16(data[next : next+2])
+		class := binary.BigEndian.Uint16(data[next+2 : next+4])
+		ttl := binary.BigEndian.Uint32(data[next+4 : next+8])
+		rdLen := int(binary.BigEndian.Uint16(data[next+8 : next+10]))
+		start := next + 10
+		if start+rdLen > len(data) {
+			return dnsMessage{}, fmt.Errorf("mdns record data truncated")
+		}
+		rec := dnsRecord{
+			name:  name,
+			typ:   typ,
+			class: class & 0x7fff, // mask the mDNS cache-flush bit
+			ttl:   ttl,
+			data:  data[start : start+rdLen],
+		}
+		msg.answers = append(msg.answers, rec)
+		offset = start + rdLen
+	}
+
+	return msg, nil
+}
+
+// decodeName reads a (possibly compressed) DNS name starting at offset and
+// returns it plus the offset immediately after it in the original message.
+func decodeName(data []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+	pos := offset
+	for hops := 0; ; hops++ {
+		if hops > 128 {
+			return "", 0, fmt.Errorf("mdns name has too many compression hops")
+		}
+		if pos >= len(data) {
+			return "", 0, fmt.Errorf("mdns name runs past end of packet")
+		}
+		length := int(data[pos])
+		switch {
+		case length == 0:
+			pos++
+			if end == -1 {
+				end = pos
+			}
+			return joinLabels(labels), end, nil
+		case length&0xC0 == 0xC0:
+			if pos+1 >= len(data) {
+				return "", 0, fmt.Errorf("mdns name pointer truncated")
+			}
+			if end == -1 {
+				end = pos + 2
+			}
+			pos = int(length&0x3F)<<8 | int(data[pos+1])
+		default:
+			start := pos + 1
+			if start+length > len(data) {
+				return "", 0, fmt.Errorf("mdns name label truncated")
+			}
+			labels = append(labels, string(data[start:start+length]))
+			pos = start + length
+		}
+	}
+}
+
+func joinLabels(labels []string) string {
+	name := ""
+	for _, l := range labels {
+		name += l + "."
+	}
+	return name
+}
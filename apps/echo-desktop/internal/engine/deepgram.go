@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Deepgram calls Deepgram's prerecorded transcription API (POST /v1/listen),
+// which has its own request/response shape rather than the OpenAI one
+// OpenAICompatible already covers. It only implements Recognizer — Deepgram
+// has no synthesis API.
+type Deepgram struct {
+	// BaseURL defaults to Deepgram's own API when empty; overridable for a
+	// self-hosted Deepgram deployment.
+	BaseURL string
+	APIKey  string // sent as "Authorization: Token <key>"
+	Model   string // e.g. "nova-2"; empty uses the account default
+
+	HTTPClient *http.Client // defaults to a 60s-timeout client if nil
+}
+
+func (d *Deepgram) client() *http.Client {
+	if d.HTTPClient != nil {
+		return d.HTTPClient
+	}
+	return &http.Client{Timeout: 60 * time.Second}
+}
+
+func (d *Deepgram) baseURL() string {
+	if d.BaseURL != "" {
+		return strings.TrimRight(d.BaseURL, "/")
+	}
+	return "https://api.deepgram.com"
+}
+
+type deepgramResponse struct {
+	Results struct {
+		Channels []struct {
+			Alternatives []struct {
+				Transcript string `json:"transcript"`
+			} `json:"alternatives"`
+		} `json:"channels"`
+	} `json:"results"`
+}
+
+// Recognize posts raw audio bytes to /v1/listen and returns the top
+// alternative of its first channel.
+func (d *Deepgram) Recognize(ctx context.Context, audio io.Reader, opts RecognizeOptions) (Transcript, error) {
+	q := url.Values{}
+	if d.Model != "" {
+		q.Set("model", d.Model)
+	}
+	if opts.Language != "" {
+		q.Set("language", opts.Language)
+	}
+
+	reqURL := d.baseURL() + "/v1/listen"
+	if encoded := q.Encode(); encoded != "" {
+		reqURL += "?" + encoded
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, audio)
+	if err != nil {
+		return Transcript{}, err
+	}
+	req.Header.Set("Authorization", "Token "+d.APIKey)
+	req.Header.Set("Content-Type", "audio/wav")
+
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return Transcript{}, fmt.Errorf("deepgram request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Transcript{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Transcript{}, fmt.Errorf("deepgram request returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed deepgramResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return Transcript{}, fmt.Errorf("parsing deepgram response: %w", err)
+	}
+	if len(parsed.Results.Channels) == 0 || len(parsed.Results.Channels[0].Alternatives) == 0 {
+		return Transcript{}, fmt.Errorf("deepgram response had no transcript")
+	}
+
+	return Transcript{Text: strings.TrimSpace(parsed.Results.Channels[0].Alternatives[0].Transcript)}, nil
+}
@@ -0,0 +1,53 @@
+// Package engine defines the pluggable TTS/STT backend interfaces and a
+// registry that looks one up by name. internal/tts and internal/stt own the
+// default Piper and whisper-server implementations (wrapped here as
+// adapters so this package stays the single place that knows about every
+// backend); internal/engine also ships an OpenAI-compatible HTTP backend and
+// a Kokoro ONNX backend for callers who want a different latency/quality
+// trade-off than the local defaults.
+package engine
+
+import (
+	"context"
+	"io"
+)
+
+// Format describes the audio a Synthesizer produced or a Recognizer
+// expects, so callers don't have to hard-code WAV.
+type Format struct {
+	Container  string // "wav", "raw", "mp3"
+	SampleRate int
+	Channels   int
+}
+
+// SynthesizeOptions carries the per-request knobs a Synthesizer may use.
+type SynthesizeOptions struct {
+	Voice string
+}
+
+// Synthesizer converts text to speech. Implementations may shell out to a
+// local binary (Piper, Kokoro) or call a remote HTTP API (an
+// OpenAI-compatible server); the Registry lets callers pick one by name.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string, opts SynthesizeOptions) (io.ReadCloser, Format, error)
+}
+
+// RecognizeOptions carries the per-request knobs a Recognizer may use.
+type RecognizeOptions struct {
+	Language string
+
+	// Backend, if non-empty, names a Registry entry a Router must use for
+	// this request instead of evaluating its rules — an explicit override.
+	// Plain Recognizer implementations ignore it.
+	Backend string
+}
+
+// Transcript is the result of a Recognize call.
+type Transcript struct {
+	Text string
+}
+
+// Recognizer converts speech audio to text.
+type Recognizer interface {
+	Recognize(ctx context.Context, audio io.Reader, opts RecognizeOptions) (Transcript, error)
+}
@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+// Kokoro synthesizes speech via a Kokoro ONNX voice model run through
+// onnxruntime, invoked as a one-shot subprocess per request rather than the
+// persistent process internal/tts.Engine keeps for Piper — Kokoro's ONNX
+// session load is cheap enough (and the ONNX Runtime GPU/CoreML provider
+// does its own caching) that reuse isn't worth the extra process-management
+// code. It gives users on Apple Silicon GPU-accelerated synthesis without
+// installing Piper.
+type Kokoro struct {
+	// BinPath is the kokoro-onnx CLI binary (invokes onnxruntime under the
+	// hood; see internal/tts.EnsureServer for the equivalent Piper download).
+	BinPath string
+	// ModelPath is the .onnx voice model file.
+	ModelPath string
+	// VoicesPath is the voices.bin/json file Kokoro pairs with ModelPath.
+	VoicesPath string
+	// Voice is the default Kokoro voice name (e.g. "af_bella") used when
+	// SynthesizeOptions.Voice is empty.
+	Voice string
+}
+
+// Synthesize runs the Kokoro CLI with the requested text on stdin and reads
+// a complete WAV file back from stdout.
+func (k *Kokoro) Synthesize(ctx context.Context, text string, opts SynthesizeOptions) (io.ReadCloser, Format, error) {
+	voice := opts.Voice
+	if voice == "" {
+		voice = k.Voice
+	}
+
+	args := []string{
+		"--model", k.ModelPath,
+		"--voices", k.VoicesPath,
+		"--voice", voice,
+		"--output", "-",
+	}
+
+	cmd := exec.CommandContext(ctx, k.BinPath, args...)
+	cmd.Stdin = bytes.NewReader([]byte(text))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, Format{}, fmt.Errorf("kokoro synthesis failed: %w: %s", err, stderr.String())
+	}
+
+	return io.NopCloser(bytes.NewReader(stdout.Bytes())), Format{Container: "wav"}, nil
+}
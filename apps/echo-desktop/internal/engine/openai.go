@@ -0,0 +1,138 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAICompatible calls any server exposing the OpenAI audio API shape —
+// POST /v1/audio/speech and POST /v1/audio/transcriptions — which covers
+// OpenAI itself, local servers like faster-whisper-server, and
+// ElevenLabs-compatible proxies. It implements both Synthesizer and
+// Recognizer since most such servers offer both endpoints.
+type OpenAICompatible struct {
+	// BaseURL is the server root, e.g. "https://api.openai.com" or
+	// "http://127.0.0.1:8000".
+	BaseURL string
+	APIKey  string // sent as "Authorization: Bearer <key>" when non-empty
+	Model   string // model name passed in each request body
+
+	HTTPClient *http.Client // defaults to a 60s-timeout client if nil
+}
+
+func (o *OpenAICompatible) client() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return &http.Client{Timeout: 60 * time.Second}
+}
+
+func (o *OpenAICompatible) newRequest(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(o.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+	return req, nil
+}
+
+// Synthesize calls POST /v1/audio/speech, which returns raw audio bytes
+// (default format "wav" is requested explicitly so Format is known without
+// sniffing).
+func (o *OpenAICompatible) Synthesize(ctx context.Context, text string, opts SynthesizeOptions) (io.ReadCloser, Format, error) {
+	reqBody, err := json.Marshal(struct {
+		Model          string `json:"model"`
+		Input          string `json:"input"`
+		Voice          string `json:"voice,omitempty"`
+		ResponseFormat string `json:"response_format"`
+	}{
+		Model:          o.Model,
+		Input:          text,
+		Voice:          opts.Voice,
+		ResponseFormat: "wav",
+	})
+	if err != nil {
+		return nil, Format{}, err
+	}
+
+	req, err := o.newRequest(ctx, http.MethodPost, "/v1/audio/speech", "application/json", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, Format{}, err
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, Format{}, fmt.Errorf("openai-compatible speech request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, Format{}, fmt.Errorf("openai-compatible speech request returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp.Body, Format{Container: "wav"}, nil
+}
+
+// Recognize calls POST /v1/audio/transcriptions with the audio as a
+// multipart file upload, requesting a plain-text response.
+func (o *OpenAICompatible) Recognize(ctx context.Context, audio io.Reader, opts RecognizeOptions) (Transcript, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	part, err := w.CreateFormFile("file", "audio.wav")
+	if err != nil {
+		return Transcript{}, err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return Transcript{}, err
+	}
+	if o.Model != "" {
+		if err := w.WriteField("model", o.Model); err != nil {
+			return Transcript{}, err
+		}
+	}
+	if opts.Language != "" {
+		if err := w.WriteField("language", opts.Language); err != nil {
+			return Transcript{}, err
+		}
+	}
+	if err := w.WriteField("response_format", "text"); err != nil {
+		return Transcript{}, err
+	}
+	if err := w.Close(); err != nil {
+		return Transcript{}, err
+	}
+
+	req, err := o.newRequest(ctx, http.MethodPost, "/v1/audio/transcriptions", w.FormDataContentType(), &buf)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return Transcript{}, fmt.Errorf("openai-compatible transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Transcript{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Transcript{}, fmt.Errorf("openai-compatible transcription request returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return Transcript{Text: strings.TrimSpace(string(body))}, nil
+}
@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/voice-relay/echo-desktop/internal/tts"
+)
+
+// piperSynthesizer adapts a *tts.Engine (one fixed voice) to Synthesizer.
+type piperSynthesizer struct {
+	engine *tts.Engine
+}
+
+// NewPiperSynthesizer wraps a running Piper tts.Engine as a Synthesizer.
+// opts.Voice is ignored — the engine is already bound to one voice; callers
+// that need per-request voice selection should register one piperSynthesizer
+// per voice under distinct registry names.
+func NewPiperSynthesizer(e *tts.Engine) Synthesizer {
+	return piperSynthesizer{engine: e}
+}
+
+func (p piperSynthesizer) Synthesize(ctx context.Context, text string, opts SynthesizeOptions) (io.ReadCloser, Format, error) {
+	wav, err := p.engine.Synthesize(text)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	return io.NopCloser(bytes.NewReader(wav)), Format{
+		Container:  "wav",
+		SampleRate: p.engine.SampleRate(),
+		Channels:   1,
+	}, nil
+}
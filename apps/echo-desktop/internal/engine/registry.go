@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registry holds named Synthesizer/Recognizer backends (e.g. "piper",
+// "whisper", "openai", "kokoro"), so a request can pick one by name — short
+// phrases route to a low-latency local engine, long narration to a
+// higher-quality remote one.
+type Registry struct {
+	mu           sync.RWMutex
+	synthesizers map[string]Synthesizer
+	recognizers  map[string]Recognizer
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		synthesizers: map[string]Synthesizer{},
+		recognizers:  map[string]Recognizer{},
+	}
+}
+
+// RegisterSynthesizer adds (or replaces) the Synthesizer backend named name.
+func (r *Registry) RegisterSynthesizer(name string, s Synthesizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.synthesizers[name] = s
+}
+
+// RegisterRecognizer adds (or replaces) the Recognizer backend named name.
+func (r *Registry) RegisterRecognizer(name string, rec Recognizer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recognizers[name] = rec
+}
+
+// Synthesizer returns the backend named name.
+func (r *Registry) Synthesizer(name string) (Synthesizer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.synthesizers[name]
+	if !ok {
+		return nil, fmt.Errorf("no synthesizer engine named %q", name)
+	}
+	return s, nil
+}
+
+// Recognizer returns the backend named name.
+func (r *Registry) Recognizer(name string) (Recognizer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.recognizers[name]
+	if !ok {
+		return nil, fmt.Errorf("no recognizer engine named %q", name)
+	}
+	return rec, nil
+}
+
+// SynthesizerNames lists every registered Synthesizer name.
+func (r *Registry) SynthesizerNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.synthesizers))
+	for name := range r.synthesizers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// RecognizerNames lists every registered Recognizer name.
+func (r *Registry) RecognizerNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.recognizers))
+	for name := range r.recognizers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SplitVoiceSpec parses a voice string as accepted by TTS requests: a bare
+// name like "en_US-lessac-high" addresses the default Piper engine, while an
+// "<engine>:<voice>" spec selects a different backend registered under
+// engine, e.g. "elevenlabs:Rachel" or "kokoro:af_bella". The empty engine
+// name signals "use the caller's default engine".
+func SplitVoiceSpec(voice string) (engineName, voiceName string) {
+	if name, rest, ok := strings.Cut(voice, ":"); ok {
+		return name, rest
+	}
+	return "", voice
+}
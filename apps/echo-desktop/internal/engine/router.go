@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+)
+
+// RouterRule picks a backend for requests matching a condition. Rules are
+// evaluated in order and the first match wins — the same override-beats-
+// default shape as SplitVoiceSpec — rather than a weighted scorer. An empty
+// Language and zero MaxAudioBytes both mean "any", so a rule with neither
+// set matches everything after it (a catch-all, usually last).
+type RouterRule struct {
+	// Backend names a Registry entry. Empty means "use Router.Local", so a
+	// rule can pin certain requests to the local engine ahead of a later
+	// catch-all that sends everything else to a cloud backend.
+	Backend string
+
+	Language      string // matches RecognizeOptions.Language case-insensitively; empty = any
+	MaxAudioBytes int64  // matches only audio no larger than this; 0 = any
+}
+
+// Router implements Recognizer by choosing another Recognizer per request —
+// by RecognizeOptions.Backend if the caller set an explicit override,
+// otherwise by the first matching Rule — and falls back to Local if the
+// chosen backend isn't registered or its Recognize call fails. That fallback
+// is what keeps a cloud backend's rate limit, outage, or a simply offline
+// network from failing dictation outright.
+type Router struct {
+	Registry *Registry
+	Rules    []RouterRule
+	Local    Recognizer
+}
+
+func (rt *Router) Recognize(ctx context.Context, audio io.Reader, opts RecognizeOptions) (Transcript, error) {
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	if backend := rt.pick(opts, int64(len(data))); backend != nil {
+		t, err := backend.Recognize(ctx, bytes.NewReader(data), opts)
+		if err == nil {
+			return t, nil
+		}
+		log.Printf("engine: router backend failed, falling back to local: %v", err)
+	}
+
+	if rt.Local == nil {
+		return Transcript{}, fmt.Errorf("router: no backend matched and no local fallback is configured")
+	}
+	return rt.Local.Recognize(ctx, bytes.NewReader(data), opts)
+}
+
+// pick resolves the Recognizer a request should try first, or nil if it
+// should go straight to Local (no rule matched, or the matching rule names
+// Local explicitly).
+func (rt *Router) pick(opts RecognizeOptions, audioLen int64) Recognizer {
+	if opts.Backend != "" {
+		backend, err := rt.Registry.Recognizer(opts.Backend)
+		if err != nil {
+			log.Printf("engine: router override named unknown backend %q", opts.Backend)
+			return nil
+		}
+		return backend
+	}
+
+	for _, rule := range rt.Rules {
+		if rule.Language != "" && !strings.EqualFold(rule.Language, opts.Language) {
+			continue
+		}
+		if rule.MaxAudioBytes > 0 && audioLen > rule.MaxAudioBytes {
+			continue
+		}
+		if rule.Backend == "" {
+			return nil
+		}
+		backend, err := rt.Registry.Recognizer(rule.Backend)
+		if err != nil {
+			log.Printf("engine: router rule references unknown backend %q", rule.Backend)
+			continue
+		}
+		return backend
+	}
+	return nil
+}
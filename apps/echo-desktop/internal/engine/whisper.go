@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"context"
+	"io"
+
+	"github.com/voice-relay/echo-desktop/internal/stt"
+)
+
+// whisperRecognizer adapts a *stt.Engine to Recognizer.
+type whisperRecognizer struct {
+	engine *stt.Engine
+}
+
+// NewWhisperRecognizer wraps a running whisper-server stt.Engine as a
+// Recognizer. opts.Language is ignored — whisper-server auto-detects the
+// spoken language from the audio itself.
+func NewWhisperRecognizer(e *stt.Engine) Recognizer {
+	return whisperRecognizer{engine: e}
+}
+
+func (w whisperRecognizer) Recognize(ctx context.Context, audio io.Reader, opts RecognizeOptions) (Transcript, error) {
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return Transcript{}, err
+	}
+	text, err := w.engine.Transcribe(data, "audio.wav")
+	if err != nil {
+		return Transcript{}, err
+	}
+	return Transcript{Text: text}, nil
+}
@@ -0,0 +1,460 @@
+// Package hookrunner implements the Claude Code hook payloads voice-relay
+// installs into ~/.claude/settings.json (see internal/hooks). The installed
+// scripts are tiny shims that pipe stdin into "voice-relay hook stop" or
+// "voice-relay hook ask-intercept" (see internal/cli); this package holds
+// all the actual transcript parsing, tag stripping, truncation, git metadata
+// capture, and I/O that used to be embedded as inline Python/PowerShell.
+package hookrunner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/voice-relay/echo-desktop/internal/sounds"
+)
+
+const (
+	// transcriptTailLines bounds how much of a (potentially long-running)
+	// session transcript gets scanned — only the Stop hook's own turn and a
+	// little context before it matter.
+	transcriptTailLines = 200
+	maxAssistantChars   = 4000
+	maxUserChars        = 1000
+
+	noUserTextCaptured = "(no user text captured)"
+)
+
+// stripTagsRe strips the system/command scaffolding Claude Code wraps around
+// slash-command and tool-result input, so a notification's "user said" text
+// reads like what the person actually typed.
+var stripTagsRe = regexp.MustCompile(`(?s)<(system-reminder|local-command-caveat|command-name|command-message|command-args|local-command-stdout)>.*?</(?:system-reminder|local-command-caveat|command-name|command-message|command-args|local-command-stdout)>`)
+
+// stopHookInput is the subset of Claude Code's Stop hook stdin payload RunStop cares about.
+type stopHookInput struct {
+	StopHookActive bool   `json:"stop_hook_active"`
+	TranscriptPath string `json:"transcript_path"`
+}
+
+// transcriptEntry is one JSONL line of a Claude Code transcript.
+type transcriptEntry struct {
+	Type    string `json:"type"`
+	Message struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is one element of a transcript message's content array.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// RunStop implements the Stop hook: it reads the transcript named by stdin's
+// transcript_path, extracts the last user turn and the assistant's reply,
+// and forwards them to the coordinator's /hooks/stop endpoint, same
+// fire-and-forget postJSON pattern as its siblings below. debounceWindow is
+// passed through as debounce_window_ms so the coordinator (not this
+// short-lived process) can merge a run of quick back-to-back Stops for the
+// same session/reply_target into one notification — see handleHookStop in
+// internal/coordinator/server.go.
+func RunStop(stdin io.Reader, notifDir, coordinatorURL string, debounceWindow time.Duration) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("reading hook input: %w", err)
+	}
+	if len(raw) == 0 {
+		debugLog(notifDir, "no stdin received, exiting")
+		return nil
+	}
+
+	var in stopHookInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return fmt.Errorf("parsing hook input: %w", err)
+	}
+	if in.StopHookActive {
+		debugLog(notifDir, "stop_hook_active=true, skipping")
+		return nil
+	}
+	if in.TranscriptPath == "" {
+		debugLog(notifDir, "no transcript_path, exiting")
+		return nil
+	}
+
+	lastUser, rawAssistant, err := extractTurn(in.TranscriptPath)
+	if err != nil {
+		debugLog(notifDir, "transcript not found, exiting: %v", err)
+		return nil
+	}
+	if rawAssistant == "" {
+		debugLog(notifDir, "no assistant text found, exiting")
+		return nil
+	}
+	if lastUser == "" {
+		lastUser = noUserTextCaptured
+	}
+	rawAssistant = truncateRunes(rawAssistant, maxAssistantChars)
+	lastUser = truncateRunes(lastUser, maxUserChars)
+
+	repo, _ := gitMetadata()
+	payload := map[string]interface{}{
+		"user_text":          lastUser,
+		"assistant_text":     rawAssistant,
+		"source":             "claude-code",
+		"session":            os.Getenv("CC_SESSION"),
+		"reply_target":       os.Getenv("CC_WRAPPER_NAME"),
+		"debounce_window_ms": debounceWindow.Milliseconds(),
+	}
+	if sp := sounds.Resolve(sounds.Event{Kind: "stop", Priority: "normal", Repo: repo}); sp != "" {
+		payload["sound"] = sp
+	}
+
+	if err := postJSON(coordinatorURL, "/hooks/stop", payload); err != nil {
+		return fmt.Errorf("posting stop notification: %w", err)
+	}
+	debugLog(notifDir, "posted stop notification (user: %d chars, assistant: %d chars)", len(lastUser), len(rawAssistant))
+	return nil
+}
+
+// askInterceptInput is the subset of Claude Code's PreToolUse hook stdin
+// payload RunAskIntercept cares about.
+type askInterceptInput struct {
+	ToolName  string `json:"tool_name"`
+	ToolInput struct {
+		Questions json.RawMessage `json:"questions"`
+	} `json:"tool_input"`
+}
+
+// RunAskIntercept implements the PreToolUse hook for AskUserQuestion: on a
+// match it forwards the tool's questions to the coordinator's
+// /hooks/question endpoint (fire-and-forget, same as the old
+// ask-intercept.sh/.ps1 scripts) so the PWA can prompt the user instead of
+// waiting on Claude Code's own terminal prompt.
+func RunAskIntercept(stdin io.Reader, coordinatorURL string) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var in askInterceptInput
+	if err := json.Unmarshal(raw, &in); err != nil || in.ToolName != "AskUserQuestion" {
+		return nil
+	}
+	if len(in.ToolInput.Questions) == 0 {
+		return nil
+	}
+
+	repo, _ := gitMetadata()
+	payload := map[string]interface{}{
+		"id":           fmt.Sprintf("ask-%d", time.Now().UnixMilli()),
+		"reply_target": os.Getenv("CC_WRAPPER_NAME"),
+		"questions":    in.ToolInput.Questions,
+	}
+	if sp := sounds.Resolve(sounds.Event{Kind: "ask", Matcher: in.ToolName, Repo: repo}); sp != "" {
+		payload["sound"] = sp
+	}
+	return postJSON(coordinatorURL, "/hooks/question", payload)
+}
+
+// notificationInput is the subset of Claude Code's Notification hook stdin
+// payload RunNotification cares about.
+type notificationInput struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// RunNotification implements the Notification hook: it forwards the
+// notification's title/message to the coordinator's /hooks/notification
+// endpoint, which speaks it as a mic-cue so the user knows Claude is waiting
+// on them.
+func RunNotification(stdin io.Reader, coordinatorURL string) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var in notificationInput
+	if err := json.Unmarshal(raw, &in); err != nil || in.Message == "" {
+		return nil
+	}
+
+	repo, _ := gitMetadata()
+	payload := map[string]interface{}{
+		"title":   in.Title,
+		"message": in.Message,
+	}
+	if sp := sounds.Resolve(sounds.Event{Kind: "notification", Repo: repo}); sp != "" {
+		payload["sound"] = sp
+	}
+	return postJSON(coordinatorURL, "/hooks/notification", payload)
+}
+
+// subagentStopInput is the subset of Claude Code's SubagentStop hook stdin
+// payload RunSubagentStop cares about.
+type subagentStopInput struct {
+	StopHookActive bool   `json:"stop_hook_active"`
+	TranscriptPath string `json:"transcript_path"`
+}
+
+// RunSubagentStop implements the SubagentStop hook: it extracts the
+// subagent's final reply from its own transcript and forwards it to the
+// coordinator's /hooks/subagent-stop endpoint, which speaks it — a voice
+// reply for a subagent task the user isn't watching scroll by.
+func RunSubagentStop(stdin io.Reader, coordinatorURL string) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var in subagentStopInput
+	if err := json.Unmarshal(raw, &in); err != nil || in.StopHookActive || in.TranscriptPath == "" {
+		return nil
+	}
+
+	_, rawAssistant, err := extractTurn(in.TranscriptPath)
+	if err != nil || rawAssistant == "" {
+		return nil
+	}
+
+	repo, _ := gitMetadata()
+	payload := map[string]interface{}{
+		"text": truncateRunes(rawAssistant, maxAssistantChars),
+	}
+	if sp := sounds.Resolve(sounds.Event{Kind: "subagent-stop", Repo: repo}); sp != "" {
+		payload["sound"] = sp
+	}
+	return postJSON(coordinatorURL, "/hooks/subagent-stop", payload)
+}
+
+// preCompactInput is the subset of Claude Code's PreCompact hook stdin
+// payload RunPreCompact cares about.
+type preCompactInput struct {
+	Trigger string `json:"trigger"` // "manual" or "auto"
+}
+
+// RunPreCompact implements the PreCompact hook: it forwards the compaction
+// trigger to the coordinator's /hooks/pre-compact endpoint, which
+// announces the compaction so the user isn't left wondering why Claude went
+// quiet for a moment.
+func RunPreCompact(stdin io.Reader, coordinatorURL string) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var in preCompactInput
+	json.Unmarshal(raw, &in) // best-effort; an empty/unparsed trigger is still worth announcing
+
+	repo, _ := gitMetadata()
+	payload := map[string]interface{}{
+		"trigger": in.Trigger,
+	}
+	if sp := sounds.Resolve(sounds.Event{Kind: "pre-compact", Matcher: in.Trigger, Repo: repo}); sp != "" {
+		payload["sound"] = sp
+	}
+	return postJSON(coordinatorURL, "/hooks/pre-compact", payload)
+}
+
+// userPromptSubmitInput is the subset of Claude Code's UserPromptSubmit hook
+// stdin payload RunUserPromptSubmit cares about.
+type userPromptSubmitInput struct {
+	Prompt string `json:"prompt"`
+}
+
+// RunUserPromptSubmit implements the UserPromptSubmit hook: it forwards the
+// user's prompt to the coordinator's /hooks/user-prompt-submit endpoint,
+// which records it as a notification so the PWA's history shows what was
+// asked, not just how Claude replied.
+func RunUserPromptSubmit(stdin io.Reader, coordinatorURL string) error {
+	raw, err := io.ReadAll(stdin)
+	if err != nil || len(raw) == 0 {
+		return nil
+	}
+
+	var in userPromptSubmitInput
+	if err := json.Unmarshal(raw, &in); err != nil || in.Prompt == "" {
+		return nil
+	}
+
+	return postJSON(coordinatorURL, "/hooks/user-prompt-submit", map[string]interface{}{
+		"prompt": in.Prompt,
+	})
+}
+
+// postJSON POSTs payload as JSON to coordinatorURL+path. Fire-and-forget,
+// matching the old ask-intercept scripts' `|| true`: a slow or unreachable
+// coordinator must never block or fail the hook Claude Code is waiting on.
+func postJSON(coordinatorURL, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(coordinatorURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// extractTurn scans the last transcriptTailLines lines of the transcript at
+// path for the most recent user message and the full text of the assistant
+// turn that followed it.
+func extractTurn(path string) (lastUser, rawAssistant string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if len(lines) > transcriptTailLines {
+		lines = lines[len(lines)-transcriptTailLines:]
+	}
+
+	var assistantTexts []string
+	for _, ln := range lines {
+		ln = strings.TrimSpace(ln)
+		if ln == "" {
+			continue
+		}
+		var entry transcriptEntry
+		if err := json.Unmarshal([]byte(ln), &entry); err != nil {
+			continue
+		}
+		if entry.Type == "" || len(entry.Message.Content) == 0 {
+			continue
+		}
+
+		switch entry.Type {
+		case "user":
+			if cleaned := extractUserText(entry.Message.Content); cleaned != "" {
+				lastUser = cleaned
+			}
+			assistantTexts = nil
+		case "assistant":
+			assistantTexts = append(assistantTexts, extractAssistantTexts(entry.Message.Content)...)
+		}
+	}
+
+	rawAssistant = strings.TrimSpace(strings.Join(assistantTexts, "\n"))
+	return lastUser, rawAssistant, nil
+}
+
+// extractUserText pulls the cleaned text out of a user message's content,
+// which is either a bare string or an array of content blocks, returning the
+// last text block that survives cleanUserText.
+func extractUserText(content json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return cleanUserText(s)
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var last string
+	for _, b := range blocks {
+		if b.Type != "text" {
+			continue
+		}
+		if cleaned := cleanUserText(b.Text); cleaned != "" {
+			last = cleaned
+		}
+	}
+	return last
+}
+
+// cleanUserText strips tool-result/tool-use turns and system-reminder
+// scaffolding, and rejects anything empty or implausibly long to have been
+// typed by hand (a pasted file, for instance) — mirroring the old scripts'
+// 0 < len < 2000 check.
+func cleanUserText(text string) string {
+	if text == "" {
+		return ""
+	}
+	if strings.Contains(text, "<tool_result") || strings.Contains(text, "<tool_use") {
+		return ""
+	}
+	cleaned := strings.TrimSpace(stripTagsRe.ReplaceAllString(text, ""))
+	if n := utf8.RuneCountInString(cleaned); n == 0 || n >= 2000 {
+		return ""
+	}
+	return cleaned
+}
+
+// extractAssistantTexts pulls every text block out of an assistant message's
+// content, which is either a bare string or an array of content blocks.
+func extractAssistantTexts(content json.RawMessage) []string {
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+
+	var blocks []contentBlock
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil
+	}
+	var texts []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return texts
+}
+
+// truncateRunes truncates s to at most n runes, leaving shorter strings untouched.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// gitMetadata returns the current repo's directory name and branch, if the
+// transcript's working directory happens to be inside a git checkout.
+// Failures (not a repo, git not on PATH, detached HEAD) are silent — this is
+// best-effort enrichment, not something a notification should fail over.
+func gitMetadata() (repo, branch string) {
+	if out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		repo = filepath.Base(strings.TrimSpace(string(out)))
+	}
+	if out, err := exec.Command("git", "branch", "--show-current").Output(); err == nil {
+		branch = strings.TrimSpace(string(out))
+	}
+	return repo, branch
+}
+
+// debugLog appends a timestamped line to notifDir/hook-debug.log, mirroring
+// the old scripts' own log() helper. Best-effort: a logging failure must
+// never stop the hook from doing its job.
+func debugLog(notifDir, format string, args ...interface{}) {
+	f, err := os.OpenFile(filepath.Join(notifDir, "hook-debug.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "[%s] "+format+"\n", append([]interface{}{time.Now().Format("15:04:05")}, args...)...)
+}
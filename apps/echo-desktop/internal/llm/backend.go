@@ -0,0 +1,37 @@
+package llm
+
+import "context"
+
+// Backend is anything that can perform voice-relay's three LLM tasks:
+// cleaning up a raw transcript, summarizing a notification, and generating a
+// random one. Engine (local llama-server), RemoteBackend (a remote
+// OpenAI-compatible server), and GRPCBackend (a third-party engine speaking
+// llmserver.proto) all implement it, so the rest of the app can depend on
+// Backend instead of a concrete engine type. ctx lets a caller (or, for
+// Engine, the request queue) cancel a request already in flight.
+type Backend interface {
+	// CleanupText sends raw transcribed text through the LLM for cleanup.
+	// Returns (cleaned text, summary, error). Implementations should degrade
+	// gracefully to the raw text on failure rather than blocking dictation.
+	CleanupText(ctx context.Context, rawText string) (string, string, error)
+
+	// SummarizeNotification generates title/summary/details from a
+	// user request and assistant response, for a voice notification.
+	SummarizeNotification(ctx context.Context, userText, assistantText string) (title, summary, details string, err error)
+
+	// GenerateNotification asks the LLM to produce a random notification.
+	GenerateNotification(ctx context.Context) (map[string]string, error)
+
+	// Close releases any resources (subprocess, connection) held by the backend.
+	Close()
+}
+
+// StreamingBackend is implemented by backends that can deliver CleanupText's
+// result incrementally as the model generates it, instead of blocking until
+// the full response arrives. Engine and RemoteBackend both implement it via
+// the embedded chatClient; GRPCBackend doesn't, since llmserver.proto's
+// Complete RPC is unary only.
+type StreamingBackend interface {
+	Backend
+	CleanupTextStream(ctx context.Context, rawText string, onDelta func(Delta)) (string, string, error)
+}
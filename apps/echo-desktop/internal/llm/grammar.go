@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// cleanupResult is CleanupText's JSON shape.
+type cleanupResult struct {
+	Cleaned string `json:"cleaned"`
+	Summary string `json:"summary"`
+}
+
+// notifSummaryResult is SummarizeNotification's JSON shape.
+type notifSummaryResult struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Details string `json:"details"`
+}
+
+// notifGenResult is GenerateNotification's JSON shape. Priority is the one
+// enum-constrained field across all three prompts. Voice lets the model
+// request a specific TTS voice for this notification instead of whatever the
+// user currently has selected (see notifications.Notification.Voice).
+type notifGenResult struct {
+	Title    string `json:"title"`
+	Summary  string `json:"summary"`
+	Details  string `json:"details"`
+	Priority string `json:"priority"`
+	Source   string `json:"source"`
+	Voice    string `json:"voice"`
+}
+
+var priorityEnum = []string{"low", "normal", "high"}
+
+// jsonFieldsOf returns shape's JSON field names in declaration order, via its
+// `json:"..."` tags. shape must be a struct value, not a pointer.
+func jsonFieldsOf(shape any) []string {
+	t := reflect.TypeOf(shape)
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// gbnfGrammar auto-derives a minimal GBNF grammar that accepts exactly one
+// JSON shape: a flat object with shape's fields, in order, each a quoted
+// string except where enums overrides it to an alternation of quoted
+// literals (e.g. notifGenResult's "priority"). This is sent as the request's
+// `grammar` field so llama-server can't sample anything else, rather than
+// relying on the prompt alone to keep the model in line.
+func gbnfGrammar(shape any, enums map[string][]string) string {
+	var rules []string
+	var root strings.Builder
+	root.WriteString(`root ::= "{" ws `)
+	for i, field := range jsonFieldsOf(shape) {
+		if i > 0 {
+			root.WriteString(`"," ws `)
+		}
+		valueRule := "string"
+		if values, ok := enums[field]; ok {
+			ruleName := field + "-enum"
+			valueRule = ruleName
+			rules = append(rules, ruleName+" ::= "+quotedAlternation(values))
+		}
+		fmt.Fprintf(&root, `"\"%s\"" ws ":" ws %s ws `, field, valueRule)
+	}
+	root.WriteString(`"}"`)
+
+	grammar := append([]string{root.String()}, rules...)
+	grammar = append(grammar,
+		`string ::= "\"" ( [^"\\] | "\\" . )* "\""`,
+		`ws ::= [ \t\n]*`,
+	)
+	return strings.Join(grammar, "\n")
+}
+
+// quotedAlternation renders values as a GBNF alternation of quoted literals,
+// e.g. []string{"low","normal"} -> `"\"low\"" | "\"normal\""`.
+func quotedAlternation(values []string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf(`"\"%s\""`, v)
+	}
+	return strings.Join(parts, " | ")
+}
+
+// jsonSchemaForShape builds the `response_format.json_schema.schema` object
+// paired with gbnfGrammar's output: a plain JSON Schema with a string
+// property per field (restricted to an enum where enums overrides it) and
+// every field required, since Grammar already guarantees the model can't
+// omit one.
+func jsonSchemaForShape(shape any, enums map[string][]string) map[string]any {
+	fields := jsonFieldsOf(shape)
+	properties := make(map[string]any, len(fields))
+	for _, field := range fields {
+		prop := map[string]any{"type": "string"}
+		if values, ok := enums[field]; ok {
+			prop["enum"] = values
+		}
+		properties[field] = prop
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   fields,
+	}
+}
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/voice-relay/echo-desktop/internal/llm/llmserverpb"
+)
+
+// GRPCBackend talks to a third-party inference server over the LLMServer
+// gRPC service (see llmserver.proto), for engines that aren't an
+// OpenAI-compatible HTTP server — a GPT4All, falcon-ggml, or HuggingFace
+// process someone wired up as an external subprocess without touching this
+// repo.
+type GRPCBackend struct {
+	conn   *grpc.ClientConn
+	client llmserverpb.LLMServerClient
+	model  string
+
+	// cmd is set when NewSupervisedGRPCBackend spawned the worker process
+	// itself, so Close can stop it; nil when the backend just dialed an
+	// already-running external server (the original NewGRPCBackend path).
+	cmd *exec.Cmd
+}
+
+// NewGRPCBackend dials target (e.g. "localhost:50051") and returns a Backend
+// backed by its LLMServer service. The connection is plaintext; put it
+// behind Tailscale or another trusted transport the way the rest of
+// voice-relay's coordinator-to-coordinator traffic is.
+func NewGRPCBackend(target, model string) (*GRPCBackend, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", target, err)
+	}
+	return &GRPCBackend{
+		conn:   conn,
+		client: llmserverpb.NewLLMServerClient(conn),
+		model:  model,
+	}, nil
+}
+
+func (g *GRPCBackend) complete(ctx context.Context, messages []chatMessage, maxTokens int, temperature float64) (string, error) {
+	msgs := make([]any, len(messages))
+	for i, m := range messages {
+		msgs[i] = map[string]any{"role": m.Role, "content": m.Content}
+	}
+	req, err := structpb.NewStruct(map[string]any{
+		"model":       g.model,
+		"messages":    msgs,
+		"max_tokens":  float64(maxTokens),
+		"temperature": temperature,
+	})
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := g.client.Complete(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("llmserver Complete: %w", err)
+	}
+	return resp.Fields["content"].GetStringValue(), nil
+}
+
+// CleanupText sends raw transcribed text through the gRPC backend for
+// cleanup. Returns (cleaned text, summary, error); like Engine, it degrades
+// to the raw text on failure rather than blocking dictation.
+func (g *GRPCBackend) CleanupText(ctx context.Context, rawText string) (string, string, error) {
+	result, err := g.complete(ctx, []chatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: rawText + " /no_think"},
+	}, 512, 0.1)
+	if err != nil {
+		return rawText, "", nil
+	}
+
+	if idx := strings.Index(result, "</think>"); idx >= 0 {
+		result = result[idx+len("</think>"):]
+	}
+	result = strings.TrimSpace(result)
+
+	var parsed struct {
+		Cleaned string `json:"cleaned"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(result), &parsed); err == nil && parsed.Cleaned != "" {
+		return parsed.Cleaned, parsed.Summary, nil
+	}
+
+	result = strings.Trim(result, "\"'")
+	if result == "" {
+		return rawText, "", nil
+	}
+	return result, "", nil
+}
+
+// SummarizeNotification generates title/summary/details via the gRPC backend.
+func (g *GRPCBackend) SummarizeNotification(ctx context.Context, userText, assistantText string) (string, string, string, error) {
+	if len(assistantText) > 3000 {
+		assistantText = assistantText[:3000] + "\n..."
+	}
+	userContent := fmt.Sprintf("USER REQUEST:\n%s\n\nASSISTANT RESPONSE:\n%s /no_think", userText, assistantText)
+
+	result, err := g.complete(ctx, []chatMessage{
+		{Role: "system", Content: notifSummarizePrompt},
+		{Role: "user", Content: userContent},
+	}, 512, 0.3)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if idx := strings.Index(result, "</think>"); idx >= 0 {
+		result = result[idx+len("</think>"):]
+	}
+	result = strings.TrimSpace(result)
+
+	var parsed struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+		Details string `json:"details"`
+	}
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		return "", "", "", fmt.Errorf("invalid JSON from LLM: %s", result)
+	}
+	if parsed.Title == "" || parsed.Summary == "" {
+		return "", "", "", fmt.Errorf("missing title or summary: %s", result)
+	}
+	return parsed.Title, parsed.Summary, parsed.Details, nil
+}
+
+// GenerateNotification asks the gRPC backend to produce a random notification.
+func (g *GRPCBackend) GenerateNotification(ctx context.Context) (map[string]string, error) {
+	result, err := g.complete(ctx, []chatMessage{
+		{Role: "system", Content: notifGenPrompt},
+		{Role: "user", Content: "Generate one notification. /no_think"},
+	}, 256, 1.0)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx := strings.Index(result, "</think>"); idx >= 0 {
+		result = result[idx+len("</think>"):]
+	}
+	result = strings.TrimSpace(result)
+
+	var notif map[string]string
+	if err := json.Unmarshal([]byte(result), &notif); err != nil {
+		return nil, fmt.Errorf("invalid JSON from LLM: %s", result)
+	}
+	if notif["title"] == "" || notif["summary"] == "" {
+		return nil, fmt.Errorf("missing title or summary: %s", result)
+	}
+	return notif, nil
+}
+
+// Health reports whether the worker behind this connection considers itself
+// ready to serve requests, via llmserver.proto's Health RPC.
+func (g *GRPCBackend) Health(ctx context.Context) (bool, error) {
+	resp, err := g.client.Health(ctx, &structpb.Struct{})
+	if err != nil {
+		return false, err
+	}
+	return resp.Fields["ready"].GetBoolValue(), nil
+}
+
+// LoadModel tells the worker to load modelPath, via llmserver.proto's
+// LoadModel RPC. Used once, right after a freshly spawned worker reports
+// healthy, for runtimes that can't take a model path on their own command
+// line the way llama-server's --model flag does.
+func (g *GRPCBackend) LoadModel(ctx context.Context, modelPath string) error {
+	req, err := structpb.NewStruct(map[string]any{"model_path": modelPath})
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	resp, err := g.client.LoadModel(ctx, req)
+	if err != nil {
+		return fmt.Errorf("llmserver LoadModel: %w", err)
+	}
+	if !resp.Fields["loaded"].GetBoolValue() {
+		return fmt.Errorf("llmserver LoadModel: worker reported failure loading %s", modelPath)
+	}
+	return nil
+}
+
+// Close tears down the gRPC connection and, if this backend spawned its own
+// worker process (via NewSupervisedGRPCBackend), stops it too.
+func (g *GRPCBackend) Close() {
+	g.conn.Close()
+	if g.cmd != nil && g.cmd.Process != nil {
+		g.cmd.Process.Kill()
+	}
+}
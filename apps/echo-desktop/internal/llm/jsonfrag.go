@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// fieldRegexes matches a top-level `"field": "value"` pair in a streaming
+// chat completion's accumulated-so-far text, even while the rest of the JSON
+// object is still incomplete. A field only matches once its closing quote
+// has arrived, which is what "well-formed enough to surface" means here —
+// there's no attempt to parse partial/unterminated string values.
+var fieldRegexes = map[string]*regexp.Regexp{
+	"cleaned": regexp.MustCompile(`"cleaned"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+	"summary": regexp.MustCompile(`"summary"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+	"title":   regexp.MustCompile(`"title"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+	"details": regexp.MustCompile(`"details"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+}
+
+// partialJSONField extracts field's string value out of s, which may be an
+// incomplete JSON object still streaming in. ok is false until the field's
+// value has fully arrived (or the field never appears).
+func partialJSONField(s, field string) (value string, ok bool) {
+	m := fieldRegexes[field].FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	return unescapeJSONString(m[1]), true
+}
+
+// unescapeJSONString decodes JSON string escapes (\n, \", \uXXXX, ...) in raw,
+// the captured contents of a matched field's quotes, by round-tripping it
+// through json.Unmarshal rather than reimplementing the escape grammar.
+func unescapeJSONString(raw string) string {
+	var s string
+	if err := json.Unmarshal([]byte(`"`+raw+`"`), &s); err == nil {
+		return s
+	}
+	return raw
+}
@@ -1,7 +1,9 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -46,6 +49,29 @@ type chatRequest struct {
 	Messages    []chatMessage `json:"messages"`
 	MaxTokens   int           `json:"max_tokens"`
 	Temperature float64       `json:"temperature"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	// ResponseFormat and Grammar together pin the model to an exact JSON
+	// shape instead of relying on the system prompt alone: ResponseFormat is
+	// the OpenAI-style schema hint, Grammar is the GBNF that llama-server
+	// actually samples against. Both are derived from a Go struct by
+	// gbnfGrammar/jsonSchemaForShape and omitted once the backend has shown
+	// it doesn't support them (see chatClient.grammarSupported).
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Grammar        string          `json:"grammar,omitempty"`
+}
+
+// responseFormat is the OpenAI-compatible `response_format` field; paired
+// with Grammar it tells llama-server (and any OpenAI-compatible server that
+// honors json_schema) the exact object shape to return.
+type responseFormat struct {
+	Type       string      `json:"type"` // "json_schema"
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string `json:"name"`
+	Schema any    `json:"schema"`
 }
 
 // chatResponse is a response from the chat completions API.
@@ -57,149 +83,206 @@ type chatResponse struct {
 	} `json:"choices"`
 }
 
-// Engine manages llama-server as a subprocess for text cleanup.
-type Engine struct {
-	modelPath  string
-	serverPath string
-	port       int
-	cmd        *exec.Cmd
-	apiURL     string
+// streamChunk is one frame of a `"stream": true` chat completions response,
+// in the OpenAI/llama-server SSE shape: `data: {"choices":[{"delta":{"content":"..."}}]}`.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
 }
 
-// NewEngine creates a new LLM engine using llama-server.
-func NewEngine(modelPath, serverPath string, port int) (*Engine, error) {
-	if _, err := os.Stat(modelPath); err != nil {
-		return nil, fmt.Errorf("model not found: %s", modelPath)
-	}
-	if _, err := os.Stat(serverPath); err != nil {
-		return nil, fmt.Errorf("llama-server not found: %s", serverPath)
-	}
-
-	e := &Engine{
-		modelPath:  modelPath,
-		serverPath: serverPath,
-		port:       port,
-		apiURL:     fmt.Sprintf("http://127.0.0.1:%d", port),
-	}
+// Delta is one incremental update from a streaming completion, delivered as
+// the model's tokens arrive rather than all at once. Done is true on the
+// final delta, whether the stream succeeded or failed; Err is set only then.
+type Delta struct {
+	Cleaned string // CleanupTextStream only
+	Summary string
+	Title   string // SummarizeNotificationStream only
+	Details string // SummarizeNotificationStream only
+	Done    bool
+	Err     error
+}
 
-	if err := e.start(); err != nil {
-		return nil, fmt.Errorf("failed to start llama-server: %w", err)
+// stripThink removes a Qwen3 <think>...</think> reasoning block the model
+// may emit before its actual JSON answer.
+func stripThink(s string) string {
+	if idx := strings.Index(s, "</think>"); idx >= 0 {
+		s = s[idx+len("</think>"):]
 	}
+	return strings.TrimSpace(s)
+}
 
-	return e, nil
+// streamChat posts a streaming chat-completions request and calls onToken
+// with the response content accumulated so far after every SSE frame,
+// returning the full accumulated content once the stream ends. Cancelling
+// ctx closes the underlying connection, which llama-server detects as a
+// client disconnect and aborts generation for. schemaName/shape/enums are
+// passed to withGrammar to constrain the model's output; if the backend
+// rejects the grammar-constrained request, grammar support is disabled and
+// the request is retried once without it.
+func (c *chatClient) streamChat(ctx context.Context, messages []chatMessage, maxTokens int, temperature float64, schemaName string, shape any, enums map[string][]string, onToken func(accumulated string)) (string, error) {
+	req := c.withGrammar(chatRequest{
+		Model:       c.model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		Stream:      true,
+	}, schemaName, shape, enums)
+
+	result, rejected, err := c.doStreamChat(ctx, req, onToken)
+	if err != nil && rejected {
+		c.disableGrammar()
+		req.Grammar = ""
+		req.ResponseFormat = nil
+		result, _, err = c.doStreamChat(ctx, req, onToken)
+	}
+	return result, err
 }
 
-func (e *Engine) start() error {
-	// Use half the available CPU threads (min 4) to avoid saturating all cores
-	threads := runtime.NumCPU() / 2
-	if threads < 4 {
-		threads = 4
-	}
+// doStreamChat posts req and streams the response. rejected is true only
+// when req carried a grammar and the server returned a non-2xx status,
+// which streamChat uses to decide whether to disable grammar support and
+// retry without it.
+func (c *chatClient) doStreamChat(ctx context.Context, req chatRequest, onToken func(accumulated string)) (result string, rejected bool, err error) {
+	reqBody, _ := json.Marshal(req)
 
-	args := []string{
-		"--model", e.modelPath,
-		"--port", fmt.Sprintf("%d", e.port),
-		"--host", "127.0.0.1",
-		"--ctx-size", "4096",
-		"--cache-ram", "0",
-		"--jinja",
-		"--threads", fmt.Sprintf("%d", threads),
+	resp, err := c.post(ctx, "/v1/chat/completions", &http.Client{}, reqBody)
+	if err != nil {
+		return "", false, err
 	}
+	defer resp.Body.Close()
 
-	// Offload all layers to GPU when NVIDIA GPU is available
-	if HasNvidiaGPU() {
-		args = append(args, "--n-gpu-layers", "99")
-		log.Printf("llama-server: GPU offloading enabled (all layers)")
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", req.Grammar != "", fmt.Errorf("LLM error %d: %s", resp.StatusCode, string(body))
 	}
 
-	e.cmd = exec.Command(e.serverPath, args...)
-	e.cmd.Stdout = os.Stdout
-	e.cmd.Stderr = os.Stderr
-
-	setSysProcAttr(e.cmd)
-
-	if err := e.cmd.Start(); err != nil {
-		return err
+	var accumulated strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		accumulated.WriteString(chunk.Choices[0].Delta.Content)
+		onToken(accumulated.String())
 	}
+	return accumulated.String(), false, scanner.Err()
+}
 
-	log.Printf("llama-server starting on port %d (pid %d)", e.port, e.cmd.Process.Pid)
-
-	if err := e.waitReady(60 * time.Second); err != nil {
-		e.Close()
-		return err
-	}
+// chatClient speaks the OpenAI chat-completions API against apiURL, shared by
+// Engine (a local llama-server subprocess) and RemoteBackend (an already
+// running Ollama/vLLM/LM Studio/LocalAI/llama-server endpoint) so the
+// CleanupText/SummarizeNotification/GenerateNotification logic — prompts,
+// <think> stripping, JSON parsing — is written once.
+type chatClient struct {
+	apiURL string
+	apiKey string // bearer token; empty for a local, unauthenticated server
+	model  string
+
+	// grammarSupported starts optimistic (every request tries
+	// grammar-constrained decoding) and latches to false the first time the
+	// backend rejects one, so later requests fall back to the old
+	// prompt-only JSON mode instead of paying for a failed round trip every
+	// time.
+	grammarSupported atomic.Bool
+}
 
-	log.Printf("llama-server ready")
-	return nil
+// newChatClient builds a chatClient optimistic about grammar support; both
+// Engine (local llama-server, which supports it) and RemoteBackend (which
+// may or may not, depending what's actually listening) start out the same
+// way and let disableGrammar correct course.
+func newChatClient(apiURL, apiKey, model string) *chatClient {
+	c := &chatClient{apiURL: apiURL, apiKey: apiKey, model: model}
+	c.grammarSupported.Store(true)
+	return c
 }
 
-func (e *Engine) waitReady(timeout time.Duration) error {
-	deadline := time.Now().Add(timeout)
-	for time.Now().Before(deadline) {
-		resp, err := http.Get(e.apiURL + "/health")
-		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return nil
-			}
-		}
-		time.Sleep(500 * time.Millisecond)
+// disableGrammar remembers that this backend rejected a grammar-constrained
+// request, so subsequent requests omit Grammar/ResponseFormat entirely.
+func (c *chatClient) disableGrammar() {
+	if c.grammarSupported.CompareAndSwap(true, false) {
+		log.Printf("LLM backend %s rejected grammar-constrained decoding; falling back to prompt-only JSON", c.apiURL)
 	}
-	return fmt.Errorf("llama-server did not become ready within %v", timeout)
 }
 
-// CleanupText sends raw transcribed text through the LLM for cleanup.
-// Returns (cleaned text, summary, error).
-func (e *Engine) CleanupText(rawText string) (string, string, error) {
-	reqBody, _ := json.Marshal(chatRequest{
-		Model: "qwen3",
-		Messages: []chatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: rawText + " /no_think"},
-		},
-		MaxTokens:   512,
-		Temperature: 0.1,
-	})
-
-	resp, err := http.Post(e.apiURL+"/v1/chat/completions", "application/json", bytes.NewReader(reqBody))
-	if err != nil {
-		log.Printf("LLM request failed, returning raw text: %v", err)
-		return rawText, "", nil
+// withGrammar augments req with Grammar/ResponseFormat derived from shape
+// (an instance of cleanupResult, notifSummaryResult, or notifGenResult) when
+// the backend is still believed to support it; enums overrides individual
+// fields to an enum, e.g. notifGenResult's "priority".
+func (c *chatClient) withGrammar(req chatRequest, schemaName string, shape any, enums map[string][]string) chatRequest {
+	if shape == nil || !c.grammarSupported.Load() {
+		return req
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("LLM error %d: %s, returning raw text", resp.StatusCode, string(body))
-		return rawText, "", nil
+	req.Grammar = gbnfGrammar(shape, enums)
+	req.ResponseFormat = &responseFormat{
+		Type:       "json_schema",
+		JSONSchema: &jsonSchema{Name: schemaName, Schema: jsonSchemaForShape(shape, enums)},
 	}
+	return req
+}
 
-	var data chatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		log.Printf("Failed to decode LLM response: %v, returning raw text", err)
-		return rawText, "", nil
+func (c *chatClient) post(ctx context.Context, path string, client *http.Client, reqBody []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
 	}
-
-	if len(data.Choices) == 0 {
-		return rawText, "", nil
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
+	return client.Do(req)
+}
 
-	result := data.Choices[0].Message.Content
+// CleanupText sends raw transcribed text through the LLM for cleanup and
+// waits for the full response. Returns (cleaned text, summary, error).
+func (c *chatClient) CleanupText(ctx context.Context, rawText string) (string, string, error) {
+	return c.CleanupTextStream(ctx, rawText, func(Delta) {})
+}
 
-	// Strip Qwen3 thinking blocks: <think>...</think>
-	if idx := strings.Index(result, "</think>"); idx >= 0 {
-		result = result[idx+len("</think>"):]
+// CleanupTextStream is CleanupText's streaming counterpart: onDelta is called
+// with the {cleaned, summary} fields as soon as each becomes well-formed in
+// the model's still-arriving output (see partialJSONField), and once more
+// with Done set to true when the response finishes. The final return values
+// match what the non-streaming CleanupText would have returned.
+func (c *chatClient) CleanupTextStream(ctx context.Context, rawText string, onDelta func(Delta)) (string, string, error) {
+	p := profile("cleanup")
+	raw, err := c.streamChat(ctx, []chatMessage{
+		{Role: "system", Content: p.SystemPrompt},
+		{Role: "user", Content: rawText + " /no_think"},
+	}, p.MaxTokens, p.Temperature, "cleanup", cleanupResult{}, nil, func(accumulated string) {
+		text := stripThink(accumulated)
+		cleaned, cleanedOK := partialJSONField(text, "cleaned")
+		summary, _ := partialJSONField(text, "summary")
+		if cleanedOK {
+			onDelta(Delta{Cleaned: cleaned, Summary: summary})
+		}
+	})
+	if err != nil {
+		log.Printf("LLM request failed, returning raw text: %v", err)
+		onDelta(Delta{Cleaned: rawText, Done: true})
+		return rawText, "", nil
 	}
 
-	result = strings.TrimSpace(result)
+	result := stripThink(raw)
 
-	// Try to parse as JSON {"cleaned": "...", "summary": "..."}
-	var parsed struct {
-		Cleaned string `json:"cleaned"`
-		Summary string `json:"summary"`
-	}
+	var parsed cleanupResult
 	if err := json.Unmarshal([]byte(result), &parsed); err == nil && parsed.Cleaned != "" {
 		log.Printf("LLM cleanup: %q -> %q (summary: %q)", rawText, parsed.Cleaned, parsed.Summary)
+		onDelta(Delta{Cleaned: parsed.Cleaned, Summary: parsed.Summary, Done: true})
 		return parsed.Cleaned, parsed.Summary, nil
 	}
 
@@ -208,8 +291,10 @@ func (e *Engine) CleanupText(rawText string) (string, string, error) {
 	log.Printf("LLM cleanup (no JSON): %q -> %q", rawText, result)
 
 	if result == "" {
+		onDelta(Delta{Cleaned: rawText, Done: true})
 		return rawText, "", nil
 	}
+	onDelta(Delta{Cleaned: result, Done: true})
 	return result, "", nil
 }
 
@@ -222,8 +307,19 @@ Given the user's request and the assistant's response, generate a JSON object wi
 
 Reply with ONLY the JSON object, no other text.`
 
-// SummarizeNotification uses the LLM to generate title/summary/details from raw transcript text.
-func (e *Engine) SummarizeNotification(userText, assistantText string) (string, string, string, error) {
+// SummarizeNotification uses the LLM to generate title/summary/details from
+// raw transcript text, waiting for the full response.
+func (c *chatClient) SummarizeNotification(ctx context.Context, userText, assistantText string) (string, string, string, error) {
+	return c.SummarizeNotificationStream(ctx, userText, assistantText, func(Delta) {})
+}
+
+// SummarizeNotificationStream is SummarizeNotification's streaming
+// counterpart: onDelta is called with the {title, summary, details} fields
+// as each becomes well-formed in the model's still-arriving output, and once
+// more with Done set to true when the response finishes (Err set on
+// failure). Unlike CleanupText, a failure here is a real error rather than a
+// silent fallback — there's no "raw text" to degrade to for a notification.
+func (c *chatClient) SummarizeNotificationStream(ctx context.Context, userText, assistantText string, onDelta func(Delta)) (string, string, string, error) {
 	// Truncate assistant text to avoid overwhelming the context
 	if len(assistantText) > 3000 {
 		assistantText = assistantText[:3000] + "\n..."
@@ -231,55 +327,41 @@ func (e *Engine) SummarizeNotification(userText, assistantText string) (string,
 
 	userContent := fmt.Sprintf("USER REQUEST:\n%s\n\nASSISTANT RESPONSE:\n%s /no_think", userText, assistantText)
 
-	reqBody, _ := json.Marshal(chatRequest{
-		Model: "qwen3",
-		Messages: []chatMessage{
-			{Role: "system", Content: notifSummarizePrompt},
-			{Role: "user", Content: userContent},
-		},
-		MaxTokens:   512,
-		Temperature: 0.3,
+	p := profile("notification_summary")
+	raw, err := c.streamChat(ctx, []chatMessage{
+		{Role: "system", Content: p.SystemPrompt},
+		{Role: "user", Content: userContent},
+	}, p.MaxTokens, p.Temperature, "notification_summary", notifSummaryResult{}, nil, func(accumulated string) {
+		text := stripThink(accumulated)
+		title, titleOK := partialJSONField(text, "title")
+		summary, summaryOK := partialJSONField(text, "summary")
+		details, _ := partialJSONField(text, "details")
+		if titleOK && summaryOK {
+			onDelta(Delta{Title: title, Summary: summary, Details: details})
+		}
 	})
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Post(e.apiURL+"/v1/chat/completions", "application/json", bytes.NewReader(reqBody))
 	if err != nil {
-		return "", "", "", fmt.Errorf("LLM request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", "", "", fmt.Errorf("LLM error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var data chatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", "", "", fmt.Errorf("decode failed: %w", err)
-	}
-	if len(data.Choices) == 0 {
-		return "", "", "", fmt.Errorf("no choices returned")
+		err = fmt.Errorf("LLM request failed: %w", err)
+		onDelta(Delta{Done: true, Err: err})
+		return "", "", "", err
 	}
 
-	result := data.Choices[0].Message.Content
-	if idx := strings.Index(result, "</think>"); idx >= 0 {
-		result = result[idx+len("</think>"):]
-	}
-	result = strings.TrimSpace(result)
+	result := stripThink(raw)
 
-	var parsed struct {
-		Title   string `json:"title"`
-		Summary string `json:"summary"`
-		Details string `json:"details"`
-	}
+	var parsed notifSummaryResult
 	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
-		return "", "", "", fmt.Errorf("invalid JSON from LLM: %s", result)
+		err = fmt.Errorf("invalid JSON from LLM: %s", result)
+		onDelta(Delta{Done: true, Err: err})
+		return "", "", "", err
 	}
 	if parsed.Title == "" || parsed.Summary == "" {
-		return "", "", "", fmt.Errorf("missing title or summary: %s", result)
+		err := fmt.Errorf("missing title or summary: %s", result)
+		onDelta(Delta{Done: true, Err: err})
+		return "", "", "", err
 	}
 
 	log.Printf("LLM summarized notification: %q / %q", parsed.Title, parsed.Summary)
+	onDelta(Delta{Title: parsed.Title, Summary: parsed.Summary, Details: parsed.Details, Done: true})
 	return parsed.Title, parsed.Summary, parsed.Details, nil
 }
 
@@ -295,39 +377,60 @@ const notifGenPrompt = `Generate a realistic random notification. You MUST pick
 - Message/social (e.g. "2 new messages in #engineering", "Mom shared a photo")
 
 Reply with ONLY a JSON object, no other text:
-{"title": "short title", "summary": "1-2 sentence summary to read aloud", "details": "optional extra context, or empty string", "priority": "low|normal|high", "source": "source app or system name"}
+{"title": "short title", "summary": "1-2 sentence summary to read aloud", "details": "optional extra context, or empty string", "priority": "low|normal|high", "source": "source app or system name", "voice": "a specific TTS voice name to read this one aloud with, or empty string to use whatever's currently selected"}
 
 Be specific with names, numbers, times. Make it feel like a real notification.`
 
+// notifGenEnums overrides notifGenResult's "priority" field to a three-way
+// enum in the grammar/schema sent with GenerateNotification's request.
+var notifGenEnums = map[string][]string{"priority": priorityEnum}
+
 // GenerateNotification asks the LLM to produce a random notification JSON.
-func (e *Engine) GenerateNotification() (map[string]string, error) {
-	reqBody, _ := json.Marshal(chatRequest{
-		Model: "qwen3",
+func (c *chatClient) GenerateNotification(ctx context.Context) (map[string]string, error) {
+	p := profile("notification")
+	req := c.withGrammar(chatRequest{
+		Model: c.model,
 		Messages: []chatMessage{
-			{Role: "system", Content: notifGenPrompt},
+			{Role: "system", Content: p.SystemPrompt},
 			{Role: "user", Content: "Generate one notification. /no_think"},
 		},
-		MaxTokens:   256,
-		Temperature: 1.0,
-	})
+		MaxTokens:   p.MaxTokens,
+		Temperature: p.Temperature,
+	}, "notification", notifGenResult{}, notifGenEnums)
+
+	notif, rejected, err := c.doChat(ctx, req)
+	if err != nil && rejected {
+		c.disableGrammar()
+		req.Grammar = ""
+		req.ResponseFormat = nil
+		notif, _, err = c.doChat(ctx, req)
+	}
+	return notif, err
+}
+
+// doChat posts a non-streaming chat-completions request and parses the
+// result as a flat string map. rejected mirrors doStreamChat's: true only
+// when req carried a grammar and the server rejected the request outright.
+func (c *chatClient) doChat(ctx context.Context, req chatRequest) (notif map[string]string, rejected bool, err error) {
+	reqBody, _ := json.Marshal(req)
 
-	resp, err := http.Post(e.apiURL+"/v1/chat/completions", "application/json", bytes.NewReader(reqBody))
+	resp, err := c.post(ctx, "/v1/chat/completions", http.DefaultClient, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("LLM request failed: %w", err)
+		return nil, false, fmt.Errorf("LLM request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("LLM error %d: %s", resp.StatusCode, string(body))
+		return nil, req.Grammar != "", fmt.Errorf("LLM error %d: %s", resp.StatusCode, string(body))
 	}
 
 	var data chatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("decode failed: %w", err)
+		return nil, false, fmt.Errorf("decode failed: %w", err)
 	}
 	if len(data.Choices) == 0 {
-		return nil, fmt.Errorf("no choices returned")
+		return nil, false, fmt.Errorf("no choices returned")
 	}
 
 	result := data.Choices[0].Message.Content
@@ -336,14 +439,232 @@ func (e *Engine) GenerateNotification() (map[string]string, error) {
 	}
 	result = strings.TrimSpace(result)
 
-	var notif map[string]string
 	if err := json.Unmarshal([]byte(result), &notif); err != nil {
-		return nil, fmt.Errorf("invalid JSON from LLM: %s", result)
+		return nil, false, fmt.Errorf("invalid JSON from LLM: %s", result)
 	}
 	if notif["title"] == "" || notif["summary"] == "" {
-		return nil, fmt.Errorf("missing title or summary: %s", result)
+		return nil, false, fmt.Errorf("missing title or summary: %s", result)
+	}
+	return notif, false, nil
+}
+
+// RunProfile sends userText through the named profile's system prompt and
+// sampling parameters (see profiles.go, profiles_config.go, and plugins.go
+// for where profiles come from) and returns the result as a flat string
+// map: the profile's own post-processor if a plugin registered one, or a
+// plain JSON-object parse otherwise — the same shape
+// CleanupText/SummarizeNotification/GenerateNotification each hand-parse
+// into, generalized for a profile with no fixed Go struct to
+// grammar-constrain against.
+func (c *chatClient) RunProfile(ctx context.Context, name, userText string) (map[string]string, error) {
+	p := profile(name)
+	if p.SystemPrompt == "" {
+		return nil, fmt.Errorf("unknown LLM profile: %s", name)
+	}
+
+	raw, err := c.streamChat(ctx, []chatMessage{
+		{Role: "system", Content: p.SystemPrompt},
+		{Role: "user", Content: userText + " /no_think"},
+	}, p.MaxTokens, p.Temperature, name, nil, nil, func(string) {})
+	if err != nil {
+		return nil, fmt.Errorf("LLM request failed: %w", err)
+	}
+
+	result := stripThink(raw)
+
+	if postProcess := postProcessorFor(name); postProcess != nil {
+		return postProcess(result)
 	}
-	return notif, nil
+
+	var out map[string]string
+	if err := json.Unmarshal([]byte(result), &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON from LLM: %s", result)
+	}
+	return out, nil
+}
+
+// Engine manages llama-server as a local subprocess, speaking the same
+// chat-completions API as any other Backend over loopback. Requests are
+// serialized behind a priority queue (see queue.go) rather than calling
+// straight through to chatClient, since llama-server degrades badly under
+// concurrent requests on a CPU-only host.
+type Engine struct {
+	*chatClient
+	modelPath  string
+	serverPath string
+	port       int
+	cmd        *exec.Cmd
+	queue      *taskQueue
+}
+
+// NewEngine creates a new LLM engine using llama-server. workers bounds how
+// many chat-completions requests are sent to it at once; values below 1 are
+// treated as 1, since that's the only setting known to be safe on a
+// CPU-only host.
+func NewEngine(modelPath, serverPath string, port, workers int) (*Engine, error) {
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("model not found: %s", modelPath)
+	}
+	if _, err := os.Stat(serverPath); err != nil {
+		return nil, fmt.Errorf("llama-server not found: %s", serverPath)
+	}
+
+	e := &Engine{
+		chatClient: newChatClient(fmt.Sprintf("http://127.0.0.1:%d", port), "", "qwen3"),
+		modelPath:  modelPath,
+		serverPath: serverPath,
+		port:       port,
+	}
+	e.queue = newTaskQueue(workers, func(t *task) { e.cancelSlot() })
+
+	if err := e.start(); err != nil {
+		return nil, fmt.Errorf("failed to start llama-server: %w", err)
+	}
+
+	return e, nil
+}
+
+// cancelSlotTimeout bounds cancelSlot's request so a slow or wedged
+// llama-server can't block it indefinitely — it runs off taskQueue.submit's
+// onPreempt hook, and stalling there would freeze every other submit and
+// workerLoop's dequeue across the whole engine.
+const cancelSlotTimeout = 5 * time.Second
+
+// cancelSlot asks llama-server to free a generation slot after a request's
+// connection has already been closed client-side (see streamChat/post's use
+// of ctx). Closing the connection is what actually stops llama-server from
+// generating further tokens; this is best-effort server-side cleanup on top
+// of that, via llama-server's optional /slots endpoint, and is silently
+// ignored on older builds that don't expose it.
+func (e *Engine) cancelSlot() {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelSlotTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/slots/0?action=erase", e.apiURL), nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+// CleanupText runs the interactive-priority cleanup task: it blocks the
+// user's dictation, so it preempts any queued or in-flight background/idle
+// work.
+func (e *Engine) CleanupText(ctx context.Context, rawText string) (string, string, error) {
+	var cleaned, summary string
+	var err error
+	e.queue.submit(ctx, priorityInteractive, func(ctx context.Context) {
+		cleaned, summary, err = e.chatClient.CleanupText(ctx, rawText)
+	})
+	return cleaned, summary, err
+}
+
+// CleanupTextStream is CleanupText's streaming counterpart, queued at the
+// same interactive priority.
+func (e *Engine) CleanupTextStream(ctx context.Context, rawText string, onDelta func(Delta)) (string, string, error) {
+	var cleaned, summary string
+	var err error
+	e.queue.submit(ctx, priorityInteractive, func(ctx context.Context) {
+		cleaned, summary, err = e.chatClient.CleanupTextStream(ctx, rawText, onDelta)
+	})
+	return cleaned, summary, err
+}
+
+// SummarizeNotification runs at background priority: it can wait behind an
+// interactive cleanup, but preempts idle notification generation.
+func (e *Engine) SummarizeNotification(ctx context.Context, userText, assistantText string) (string, string, string, error) {
+	var title, summary, details string
+	var err error
+	e.queue.submit(ctx, priorityBackground, func(ctx context.Context) {
+		title, summary, details, err = e.chatClient.SummarizeNotification(ctx, userText, assistantText)
+	})
+	return title, summary, details, err
+}
+
+// GenerateNotification runs at idle priority: it's the first thing
+// preempted when an interactive or background request shows up.
+func (e *Engine) GenerateNotification(ctx context.Context) (map[string]string, error) {
+	var notif map[string]string
+	var err error
+	e.queue.submit(ctx, priorityIdle, func(ctx context.Context) {
+		notif, err = e.chatClient.GenerateNotification(ctx)
+	})
+	return notif, err
+}
+
+// RunProfile runs a named prompt/parameter profile at background priority:
+// the same queue slot as SummarizeNotification, since like that call it's an
+// on-demand request rather than idle filler.
+func (e *Engine) RunProfile(ctx context.Context, name, userText string) (map[string]string, error) {
+	var out map[string]string
+	var err error
+	e.queue.submit(ctx, priorityBackground, func(ctx context.Context) {
+		out, err = e.chatClient.RunProfile(ctx, name, userText)
+	})
+	return out, err
+}
+
+func (e *Engine) start() error {
+	// Use half the available CPU threads (min 4) to avoid saturating all cores
+	threads := runtime.NumCPU() / 2
+	if threads < 4 {
+		threads = 4
+	}
+
+	args := []string{
+		"--model", e.modelPath,
+		"--port", fmt.Sprintf("%d", e.port),
+		"--host", "127.0.0.1",
+		"--ctx-size", "4096",
+		"--cache-ram", "0",
+		"--jinja",
+		"--threads", fmt.Sprintf("%d", threads),
+	}
+
+	// Offload all layers to GPU when NVIDIA GPU is available
+	if HasNvidiaGPU() {
+		args = append(args, "--n-gpu-layers", "99")
+		log.Printf("llama-server: GPU offloading enabled (all layers)")
+	}
+
+	e.cmd = exec.Command(e.serverPath, args...)
+	e.cmd.Stdout = os.Stdout
+	e.cmd.Stderr = os.Stderr
+
+	setSysProcAttr(e.cmd)
+
+	if err := e.cmd.Start(); err != nil {
+		return err
+	}
+
+	log.Printf("llama-server starting on port %d (pid %d)", e.port, e.cmd.Process.Pid)
+
+	if err := e.waitReady(60 * time.Second); err != nil {
+		e.Close()
+		return err
+	}
+
+	log.Printf("llama-server ready")
+	return nil
+}
+
+func (e *Engine) waitReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(e.apiURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("llama-server did not become ready within %v", timeout)
 }
 
 // Close stops the llama-server subprocess.
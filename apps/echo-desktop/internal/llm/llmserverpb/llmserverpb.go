@@ -0,0 +1,112 @@
+// Package llmserverpb is the generated-style client stub for the LLMServer
+// service defined in ../llmserver.proto. Because that service's request and
+// response are google.protobuf.Struct (see the .proto for why), the message
+// types need no protoc-generated code at all — only structpb, which already
+// ships as part of google.golang.org/protobuf — leaving just this thin
+// client wrapper to hand-maintain, matching what protoc-gen-go-grpc would
+// emit for a multi-method service.
+package llmserverpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// serviceName is the fully-qualified service name from llmserver.proto.
+const serviceName = "llmserver.LLMServer"
+
+// LLMServerClient is the client API for the LLMServer service.
+type LLMServerClient interface {
+	Complete(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	Predict(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (LLMServer_PredictClient, error)
+	LoadModel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	Embeddings(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	TokenCount(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+	Health(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error)
+}
+
+type llmServerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewLLMServerClient creates a client for the LLMServer service reachable
+// through cc.
+func NewLLMServerClient(cc grpc.ClientConnInterface) LLMServerClient {
+	return &llmServerClient{cc}
+}
+
+func (c *llmServerClient) Complete(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Complete", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LLMServer_PredictClient is the client-side stream handle for Predict,
+// matching the shape protoc-gen-go-grpc emits for a server-streaming RPC.
+type LLMServer_PredictClient interface {
+	Recv() (*structpb.Struct, error)
+	grpc.ClientStream
+}
+
+type llmServerPredictClient struct {
+	grpc.ClientStream
+}
+
+func (c *llmServerClient) Predict(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (LLMServer_PredictClient, error) {
+	stream, err := c.cc.NewStream(ctx, &grpc.StreamDesc{StreamName: "Predict", ServerStreams: true}, "/"+serviceName+"/Predict", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &llmServerPredictClient{stream}
+	if err := x.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *llmServerPredictClient) Recv() (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := x.ClientStream.RecvMsg(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *llmServerClient) LoadModel(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/LoadModel", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *llmServerClient) Embeddings(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Embeddings", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *llmServerClient) TokenCount(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/TokenCount", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *llmServerClient) Health(ctx context.Context, in *structpb.Struct, opts ...grpc.CallOption) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Health", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
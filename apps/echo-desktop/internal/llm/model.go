@@ -3,6 +3,9 @@ package llm
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,18 +15,134 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/voice-relay/echo-desktop/internal/releasefetch"
 )
 
 const (
 	llamaRepoAPI = "https://api.github.com/repos/ggml-org/llama.cpp/releases/latest"
 )
 
-// EnsureModel checks if the LLM model (GGUF) exists and downloads it if missing.
+//go:embed default_models.json
+var defaultModelsJSON []byte
+
+// ModelSpec describes one entry in the model catalog: enough for EnsureModel
+// to resolve a name to a download, verify its integrity once the weights
+// land on disk, and tell the rest of the app what backend/prompt settings
+// they expect. Replaces the old two-entry getModelURL/getModelFilename maps
+// with a catalog that ListModels/InstallModel/RemoveModel can present as a
+// browsable gallery instead of one hardcoded model.
+type ModelSpec struct {
+	Name           string   `json:"name"`
+	Family         string   `json:"family,omitempty"`
+	Backend        string   `json:"backend,omitempty"` // "local" (llama-server/.gguf) today; any other value names a WorkerSpec-style runtime
+	PromptTemplate string   `json:"prompt_template,omitempty"`
+	ContextSize    int      `json:"context_size,omitempty"`
+	Quantization   string   `json:"quantization,omitempty"`
+	Filename       string   `json:"filename"`
+	Mirrors        []string `json:"mirrors"` // plain HTTPS URLs, or "oci://host/repo:tag" for a registry-distribution pull (see ociregistry.go)
+	SHA256         string   `json:"sha256,omitempty"`
+	License        string   `json:"license,omitempty"`
+	SizeBytes      int64    `json:"size_bytes,omitempty"`
+}
+
+var (
+	modelCatalogMu sync.Mutex
+	modelCatalog   = map[string]ModelSpec{}
+)
+
+func init() {
+	var specs []ModelSpec
+	if err := json.Unmarshal(defaultModelsJSON, &specs); err != nil {
+		log.Printf("llm: invalid built-in model manifest: %v", err)
+		return
+	}
+	for _, s := range specs {
+		modelCatalog[s.Name] = s
+	}
+}
+
+// ManifestPath returns the path to the hot-editable model catalog file,
+// alongside the downloaded weights in modelsDir.
+func ManifestPath(modelsDir string) string {
+	return filepath.Join(modelsDir, "models.json")
+}
+
+// LoadModelManifest reads modelsDir/models.json and registers its entries,
+// overriding or adding to the built-ins — the same override semantics
+// LoadProfiles uses for llm-profiles.yaml. A missing file is not an error;
+// it just means every lookup keeps using the built-in catalog.
+func LoadModelManifest(modelsDir string) error {
+	data, err := os.ReadFile(ManifestPath(modelsDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading model manifest: %w", err)
+	}
+
+	var specs []ModelSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return fmt.Errorf("parsing model manifest: %w", err)
+	}
+
+	modelCatalogMu.Lock()
+	defer modelCatalogMu.Unlock()
+	for _, s := range specs {
+		modelCatalog[s.Name] = s
+	}
+	return nil
+}
+
+// ListModels returns every registered model catalog entry, sorted by name,
+// so a UI can present a browsable gallery instead of a single hardcoded
+// model.
+func ListModels() []ModelSpec {
+	modelCatalogMu.Lock()
+	defer modelCatalogMu.Unlock()
+	out := make([]ModelSpec, 0, len(modelCatalog))
+	for _, s := range modelCatalog {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func lookupModel(name string) (ModelSpec, error) {
+	modelCatalogMu.Lock()
+	defer modelCatalogMu.Unlock()
+	spec, ok := modelCatalog[name]
+	if !ok {
+		return ModelSpec{}, fmt.Errorf("unknown model: %s", name)
+	}
+	return spec, nil
+}
+
+// EnsureModel checks if the named model's weights exist on disk and
+// downloads them if not. See InstallModel for the download details.
 func EnsureModel(modelsDir, name string) (string, error) {
-	filename := getModelFilename(name)
-	modelPath := filepath.Join(modelsDir, filename)
+	return InstallModel(modelsDir, name, nil)
+}
 
+// InstallModel is EnsureModel with a progress callback invoked as the
+// (often multi-GB) weights download, so a model catalog browser can show a
+// progress bar the way tts.EnsureVoiceWithProgress already does for voices.
+// progress may be nil.
+//
+// It resolves name against the model catalog (see ListModels), falls back
+// across spec.Mirrors in order if one host is unreachable, verifies
+// spec.SHA256 once the download completes, and resumes a previous partial
+// download via HTTP Range rather than restarting from zero.
+func InstallModel(modelsDir, name string, progress func(bytes, total int64)) (string, error) {
+	spec, err := lookupModel(name)
+	if err != nil {
+		return "", err
+	}
+
+	modelPath := filepath.Join(modelsDir, spec.Filename)
 	if _, err := os.Stat(modelPath); err == nil {
 		log.Printf("LLM model found: %s", modelPath)
 		return modelPath, nil
@@ -33,19 +152,82 @@ func EnsureModel(modelsDir, name string) (string, error) {
 		return "", fmt.Errorf("failed to create models directory: %w", err)
 	}
 
-	url := getModelURL(name)
-	if url == "" {
-		return "", fmt.Errorf("unknown model: %s", name)
+	if len(spec.Mirrors) == 0 {
+		return "", fmt.Errorf("model %s has no download mirrors configured", name)
 	}
 
-	log.Printf("Downloading LLM model: %s", url)
+	var lastErr error
+	for _, mirror := range spec.Mirrors {
+		log.Printf("Downloading LLM model %s from %s", name, mirror)
 
-	if err := downloadFile(modelPath, url); err != nil {
-		return "", fmt.Errorf("failed to download model: %w", err)
+		if strings.HasPrefix(mirror, "oci://") {
+			templatePath, paramsPath, err := pullOCIModel(modelPath, mirror, progress)
+			if err != nil {
+				lastErr = fmt.Errorf("%s: %w", mirror, err)
+				log.Printf("Mirror failed for %s: %v", name, err)
+				continue
+			}
+			if templatePath != "" {
+				log.Printf("LLM model %s: wrote prompt template to %s", name, templatePath)
+			}
+			if paramsPath != "" {
+				log.Printf("LLM model %s: wrote sampler params to %s", name, paramsPath)
+			}
+			log.Printf("LLM model downloaded: %s", modelPath)
+			return modelPath, nil
+		}
+
+		if err := releasefetch.DownloadFileResumable(modelPath, mirror, progress); err != nil {
+			lastErr = fmt.Errorf("%s: %w", mirror, err)
+			log.Printf("Mirror failed for %s: %v", name, err)
+			continue
+		}
+
+		if spec.SHA256 != "" {
+			if err := verifyModelChecksum(modelPath, spec.SHA256); err != nil {
+				os.Remove(modelPath)
+				lastErr = fmt.Errorf("%s: %w", mirror, err)
+				log.Printf("Checksum mismatch for %s from %s: %v", name, mirror, err)
+				continue
+			}
+		}
+
+		log.Printf("LLM model downloaded: %s", modelPath)
+		return modelPath, nil
 	}
 
-	log.Printf("LLM model downloaded: %s", modelPath)
-	return modelPath, nil
+	return "", fmt.Errorf("failed to download model %s from any mirror: %w", name, lastErr)
+}
+
+// RemoveModel deletes a previously installed model's weights from disk. It
+// is not an error if the model was never downloaded.
+func RemoveModel(modelsDir, name string) error {
+	spec, err := lookupModel(name)
+	if err != nil {
+		return err
+	}
+	modelPath := filepath.Join(modelsDir, spec.Filename)
+	if err := os.Remove(modelPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing model %s: %w", name, err)
+	}
+	return nil
+}
+
+func verifyModelChecksum(path, wantHex string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != wantHex {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", wantHex, got)
+	}
+	return nil
 }
 
 // EnsureServer checks if llama-server binary exists and downloads it if not.
@@ -87,23 +269,6 @@ func EnsureServer(binDir string) (string, error) {
 	return serverPath, nil
 }
 
-func getModelURL(name string) string {
-	urls := map[string]string{
-		"qwen3-4b": "https://huggingface.co/bartowski/Qwen_Qwen3-4B-GGUF/resolve/main/Qwen_Qwen3-4B-Q4_K_M.gguf",
-	}
-	return urls[name]
-}
-
-func getModelFilename(name string) string {
-	filenames := map[string]string{
-		"qwen3-4b": "Qwen_Qwen3-4B-Q4_K_M.gguf",
-	}
-	if fn, ok := filenames[name]; ok {
-		return fn
-	}
-	return name + ".gguf"
-}
-
 // ServerBinaryName returns the platform-specific llama-server binary name.
 func ServerBinaryName() string {
 	if runtime.GOOS == "windows" {
@@ -215,20 +380,6 @@ func extractServerFromZip(zipData []byte, destDir string) error {
 	return nil
 }
 
-func downloadFile(dest, url string) error {
-	data, err := downloadBytes(url)
-	if err != nil {
-		return err
-	}
-
-	tmpPath := dest + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
-		return err
-	}
-
-	return os.Rename(tmpPath, dest)
-}
-
 func downloadBytes(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
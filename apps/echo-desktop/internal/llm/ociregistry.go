@@ -0,0 +1,372 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// OCIModelLayerMediaTypes lists the manifest layer mediaTypes pullOCIModel
+// treats as the GGUF payload to download as the model's weights. The
+// default covers Ollama's registry; a registry using a different mediaType
+// for the same purpose can be supported by appending to this list rather
+// than forking pullOCIModel.
+var OCIModelLayerMediaTypes = []string{"application/vnd.ollama.image.model"}
+
+const (
+	ociModelTemplateMediaType = "application/vnd.ollama.image.template"
+	ociModelParamsMediaType   = "application/vnd.ollama.image.params"
+
+	ociManifestAccept = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// ociRef is a parsed "oci://host/repository:reference" model mirror, per
+// the OCI distribution spec (the same addressing ollama pull and `docker
+// pull` use).
+type ociRef struct {
+	Host       string
+	Repository string
+	Reference  string
+}
+
+// parseOCIRef parses a mirror URL of the form
+// "oci://registry.example.com/library/qwen3-4b:q4_k_m". A reference with no
+// ":tag" defaults to "latest", matching every other OCI client.
+func parseOCIRef(ref string) (ociRef, error) {
+	trimmed := strings.TrimPrefix(ref, "oci://")
+	slash := strings.Index(trimmed, "/")
+	if slash < 0 {
+		return ociRef{}, fmt.Errorf("invalid oci ref %q: missing repository", ref)
+	}
+	host := trimmed[:slash]
+	rest := trimmed[slash+1:]
+
+	repository := rest
+	reference := "latest"
+	if colon := strings.LastIndex(rest, ":"); colon >= 0 {
+		repository = rest[:colon]
+		reference = rest[colon+1:]
+	}
+	if repository == "" {
+		return ociRef{}, fmt.Errorf("invalid oci ref %q: missing repository", ref)
+	}
+	return ociRef{Host: host, Repository: repository, Reference: reference}, nil
+}
+
+// ociLayer is one entry of an OCI image manifest's "layers" array.
+type ociLayer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type ociManifest struct {
+	Layers []ociLayer `json:"layers"`
+}
+
+// ociAuthChallenge is the parsed form of a 401 response's WWW-Authenticate
+// header, e.g. `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/qwen3-4b:pull"`.
+type ociAuthChallenge struct {
+	Realm   string
+	Service string
+	Scope   string
+}
+
+func parseAuthChallenge(header string) (ociAuthChallenge, bool) {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return ociAuthChallenge{}, false
+	}
+	var c ociAuthChallenge
+	for _, part := range splitAuthParams(strings.TrimPrefix(header, prefix)) {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		val := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		switch key {
+		case "realm":
+			c.Realm = val
+		case "service":
+			c.Service = val
+		case "scope":
+			c.Scope = val
+		}
+	}
+	return c, c.Realm != ""
+}
+
+// splitAuthParams splits a comma-separated "key=\"value\"" list on commas
+// that aren't inside a quoted value.
+func splitAuthParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, c := range s {
+		switch c {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+// ociBearerToken exchanges an auth challenge for a short-lived bearer token,
+// the flow every OCI-distribution-spec registry (Docker Hub, GHCR, ollama's
+// own registry) uses for anonymous or scoped pulls.
+func ociBearerToken(challenge ociAuthChallenge) (string, error) {
+	u, err := url.Parse(challenge.Realm)
+	if err != nil {
+		return "", fmt.Errorf("parsing auth realm: %w", err)
+	}
+	q := u.Query()
+	if challenge.Service != "" {
+		q.Set("service", challenge.Service)
+	}
+	if challenge.Scope != "" {
+		q.Set("scope", challenge.Scope)
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return "", fmt.Errorf("requesting auth token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("auth server returned %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("parsing auth token response: %w", err)
+	}
+	if parsed.Token != "" {
+		return parsed.Token, nil
+	}
+	return parsed.AccessToken, nil
+}
+
+// ociRequest performs req, transparently completing the Bearer token
+// challenge flow on a 401 and retrying once with the resulting token.
+func ociRequest(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge, ok := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	resp.Body.Close()
+	if !ok {
+		return nil, fmt.Errorf("registry returned 401 with no Bearer challenge")
+	}
+
+	token, err := ociBearerToken(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("oci auth: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultClient.Do(req)
+}
+
+// fetchOCIManifest resolves ref's image manifest.
+func fetchOCIManifest(ref ociRef) (ociManifest, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Host, ref.Repository, ref.Reference)
+	req, err := http.NewRequest(http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return ociManifest{}, err
+	}
+	req.Header.Set("Accept", ociManifestAccept)
+
+	resp, err := ociRequest(req)
+	if err != nil {
+		return ociManifest{}, fmt.Errorf("fetching manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ociManifest{}, fmt.Errorf("manifest request returned %d", resp.StatusCode)
+	}
+
+	var manifest ociManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return ociManifest{}, fmt.Errorf("parsing manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// isOCIModelLayer reports whether mediaType is one of OCIModelLayerMediaTypes.
+func isOCIModelLayer(mediaType string) bool {
+	for _, t := range OCIModelLayerMediaTypes {
+		if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadOCIBlob fetches layer's blob to dest, resuming a previous partial
+// download via Range the same way releasefetch.DownloadFileResumable does,
+// and verifying the result against layer.Digest (a "sha256:<hex>" string)
+// rather than a separately-fetched checksum, since the manifest already
+// carries it.
+func downloadOCIBlob(dest string, ref ociRef, layer ociLayer, progress func(bytes, total int64)) error {
+	wantHex := strings.TrimPrefix(layer.Digest, "sha256:")
+	if wantHex == layer.Digest {
+		return fmt.Errorf("unsupported digest algorithm in %q", layer.Digest)
+	}
+
+	tmpPath := dest + ".tmp"
+	var startAt int64
+	if fi, err := os.Stat(tmpPath); err == nil {
+		startAt = fi.Size()
+	}
+
+	blobURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", ref.Host, ref.Repository, layer.Digest)
+	req, err := http.NewRequest(http.MethodGet, blobURL, nil)
+	if err != nil {
+		return err
+	}
+	if startAt > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startAt))
+	}
+
+	resp, err := ociRequest(req)
+	if err != nil {
+		return fmt.Errorf("fetching blob %s: %w", layer.Digest, err)
+	}
+	defer resp.Body.Close()
+
+	var f *os.File
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		f, err = os.OpenFile(tmpPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case http.StatusOK:
+		startAt = 0
+		f, err = os.Create(tmpPath)
+	default:
+		return fmt.Errorf("blob request for %s returned %d", layer.Digest, resp.StatusCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	if startAt > 0 {
+		if existing, err := os.Open(tmpPath); err == nil {
+			io.Copy(h, io.LimitReader(existing, startAt))
+			existing.Close()
+		}
+	}
+
+	var reader io.Reader = resp.Body
+	if progress != nil {
+		reader = &countingReader{r: resp.Body, read: startAt, total: layer.Size, progress: progress}
+	}
+
+	_, copyErr := io.Copy(io.MultiWriter(f, h), reader)
+	closeErr := f.Close()
+	if copyErr != nil {
+		return copyErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != wantHex {
+		return fmt.Errorf("blob %s checksum mismatch: got %s", layer.Digest, got)
+	}
+	return os.Rename(tmpPath, dest)
+}
+
+// countingReader wraps an io.Reader, reporting cumulative bytes read via
+// progress as the caller consumes it — the same shape releasefetch uses for
+// download progress.
+type countingReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress func(bytes, total int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.read += int64(n)
+		c.progress(c.read, c.total)
+	}
+	return n, err
+}
+
+// pullOCIModel resolves ref (an "oci://host/repo:tag" mirror URL) against
+// the OCI distribution API, downloads the layer matching
+// OCIModelLayerMediaTypes to modelPath, and writes any template/params
+// layers alongside it as modelPath+".template"/".params" sidecar files —
+// the prompt template and sampler defaults an ollama-packaged model ships
+// with. Returns their paths (empty if the manifest had none) so the caller
+// can log or, eventually, feed them into the server launch flags.
+func pullOCIModel(modelPath, ref string, progress func(bytes, total int64)) (templatePath, paramsPath string, err error) {
+	parsed, err := parseOCIRef(ref)
+	if err != nil {
+		return "", "", err
+	}
+
+	manifest, err := fetchOCIManifest(parsed)
+	if err != nil {
+		return "", "", err
+	}
+
+	var modelLayer *ociLayer
+	var templateLayer, paramsLayer *ociLayer
+	for i, l := range manifest.Layers {
+		switch {
+		case isOCIModelLayer(l.MediaType):
+			modelLayer = &manifest.Layers[i]
+		case l.MediaType == ociModelTemplateMediaType:
+			templateLayer = &manifest.Layers[i]
+		case l.MediaType == ociModelParamsMediaType:
+			paramsLayer = &manifest.Layers[i]
+		}
+	}
+	if modelLayer == nil {
+		return "", "", fmt.Errorf("manifest for %s has no model layer (looked for %v)", ref, OCIModelLayerMediaTypes)
+	}
+
+	if err := downloadOCIBlob(modelPath, parsed, *modelLayer, progress); err != nil {
+		return "", "", err
+	}
+
+	if templateLayer != nil {
+		templatePath = modelPath + ".template"
+		if err := downloadOCIBlob(templatePath, parsed, *templateLayer, nil); err != nil {
+			return "", "", fmt.Errorf("downloading prompt template layer: %w", err)
+		}
+	}
+	if paramsLayer != nil {
+		paramsPath = modelPath + ".params"
+		if err := downloadOCIBlob(paramsPath, parsed, *paramsLayer, nil); err != nil {
+			return "", "", fmt.Errorf("downloading params layer: %w", err)
+		}
+	}
+
+	return templatePath, paramsPath, nil
+}
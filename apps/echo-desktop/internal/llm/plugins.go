@@ -0,0 +1,100 @@
+//go:build !windows
+
+package llm
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"plugin"
+	"time"
+)
+
+// PluginRegister is the symbol name a plugins/*.so file must export. See
+// plugins_windows.go for why this extension point doesn't exist there.
+const PluginRegister = "Register"
+
+// PluginRegisterFunc is the signature PluginRegister must have: a niladic
+// function returning the profile name to register it under, the Profile
+// itself, and an optional post-processor for responses that don't fit the
+// default flat-JSON-object parse (nil to use that default).
+type PluginRegisterFunc func() (name string, profile Profile, postProcess func(raw string) (map[string]string, error))
+
+// loadedPlugins tracks plugin paths already opened. Go plugins can't be
+// unloaded or re-opened with new code once loaded, so there's no reason to
+// look up and re-run Register for a path twice.
+var loadedPlugins = map[string]bool{}
+
+// ScanPlugins opens every plugins/*.so in dir not already loaded and calls
+// its Register symbol to add a named profile (and optional post-processor)
+// to the registry — e.g. a "code-review-summary" profile a power user wrote
+// without rebuilding voice-relay.
+func ScanPlugins(dir string) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return
+	}
+	for _, path := range matches {
+		if loadedPlugins[path] {
+			continue
+		}
+		if err := loadPlugin(path); err != nil {
+			log.Printf("Loading LLM plugin %s: %v", path, err)
+			continue
+		}
+		loadedPlugins[path] = true
+	}
+}
+
+func loadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup(PluginRegister)
+	if err != nil {
+		return err
+	}
+	register, ok := sym.(PluginRegisterFunc)
+	if !ok {
+		return fmt.Errorf("%s has the wrong signature", PluginRegister)
+	}
+
+	name, prof, postProcess := register()
+	registerProfile(name, prof)
+	if postProcess != nil {
+		registerPostProcessor(name, postProcess)
+	}
+	log.Printf("LLM plugin loaded: %s (profile %q)", path, name)
+	return nil
+}
+
+// pluginScanInterval is how often WatchPlugins re-scans dir for new .so
+// files. Unlike WatchProfiles, this can't be event-driven off the plugin
+// file itself: Go plugins can't be unloaded or reloaded once opened, so
+// replacing an already-loaded plugin's file takes effect only on restart —
+// the periodic scan just picks up files that weren't there before.
+const pluginScanInterval = 30 * time.Second
+
+// WatchPlugins scans dir immediately and then every pluginScanInterval,
+// returning a stop function that ends the periodic scan; callers should
+// defer it.
+func WatchPlugins(dir string) (stop func()) {
+	ScanPlugins(dir)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pluginScanInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ScanPlugins(dir)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
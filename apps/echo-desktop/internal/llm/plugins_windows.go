@@ -0,0 +1,14 @@
+//go:build windows
+
+package llm
+
+// ScanPlugins is a no-op on Windows: the standard library's plugin package
+// only supports linux/darwin, so there's no plugins/*.so extension point
+// here. Built-in profiles and the YAML profiles config (see
+// profiles_config.go) still work.
+func ScanPlugins(dir string) {}
+
+// WatchPlugins is a no-op on Windows, for the same reason as ScanPlugins.
+func WatchPlugins(dir string) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,80 @@
+package llm
+
+import "sync"
+
+// Profile bundles everything a call site needs to send one kind of chat
+// request: its system prompt and default sampling parameters. Built-in
+// profiles below are registered under the same names already used as
+// withGrammar's schemaName ("cleanup", "notification_summary",
+// "notification"), so a config file or plugin can override one by reusing
+// that name, or add an entirely new one a future call site can ask for by
+// name via RunProfile.
+type Profile struct {
+	SystemPrompt string
+	MaxTokens    int
+	Temperature  float64
+}
+
+var (
+	profilesMu sync.RWMutex
+
+	// profiles holds every registered profile, seeded with voice-relay's
+	// built-in prompts so the engine behaves exactly as before when no
+	// profiles config file or plugin overrides anything.
+	profiles = map[string]Profile{
+		"cleanup": {
+			SystemPrompt: systemPrompt,
+			MaxTokens:    512,
+			Temperature:  0.1,
+		},
+		"notification_summary": {
+			SystemPrompt: notifSummarizePrompt,
+			MaxTokens:    512,
+			Temperature:  0.3,
+		},
+		"notification": {
+			SystemPrompt: notifGenPrompt,
+			MaxTokens:    256,
+			Temperature:  1.0,
+		},
+	}
+
+	// postProcessors holds custom response parsers for profiles that don't
+	// use one of the built-in Go shapes (cleanupResult, notifSummaryResult,
+	// notifGenResult), keyed by profile name. Only plugins register these
+	// (see plugins.go); config-file profiles always parse as a flat JSON
+	// object of strings.
+	postProcessors = map[string]func(raw string) (map[string]string, error){}
+)
+
+// profile returns the named profile, or its zero value (an empty
+// SystemPrompt) if name isn't registered.
+func profile(name string) Profile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	return profiles[name]
+}
+
+// registerProfile adds or replaces a named profile. Called by LoadProfiles
+// for config-file profiles and by plugins for Go-plugin-registered ones.
+func registerProfile(name string, p Profile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[name] = p
+}
+
+// registerPostProcessor records a custom response parser for name, used by
+// RunProfile instead of the default flat-JSON-object parse.
+func registerPostProcessor(name string, fn func(raw string) (map[string]string, error)) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	postProcessors[name] = fn
+}
+
+// postProcessorFor returns the registered post-processor for name, or nil if
+// none was registered.
+func postProcessorFor(name string) func(raw string) (map[string]string, error) {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	return postProcessors[name]
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// profileYAML is one profile's on-disk shape in the profiles config file.
+type profileYAML struct {
+	SystemPrompt string  `yaml:"system_prompt"`
+	MaxTokens    int     `yaml:"max_tokens"`
+	Temperature  float64 `yaml:"temperature"`
+}
+
+// profilesFile is the on-disk shape of the profiles config: a map from
+// profile name (e.g. "cleanup", or a custom name like "code-review-summary")
+// to its prompt/parameters. An entry named after a built-in profile
+// overrides it; any other name registers a new one RunProfile can look up.
+type profilesFile struct {
+	Profiles map[string]profileYAML `yaml:"profiles"`
+}
+
+// ProfilesPath returns the path to the hot-reloadable prompt/parameter
+// config file, alongside voice-relay's main config in configDir (see
+// config.Dir).
+func ProfilesPath(configDir string) string {
+	return filepath.Join(configDir, "llm-profiles.yaml")
+}
+
+// LoadProfiles reads path and registers its profiles, overriding or adding
+// to the built-ins. A missing file is not an error — it just means every
+// call site keeps using its built-in default.
+func LoadProfiles(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading profiles config: %w", err)
+	}
+
+	var f profilesFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parsing profiles config: %w", err)
+	}
+
+	for name, p := range f.Profiles {
+		registerProfile(name, Profile{
+			SystemPrompt: p.SystemPrompt,
+			MaxTokens:    p.MaxTokens,
+			Temperature:  p.Temperature,
+		})
+	}
+	return nil
+}
+
+// profilesWatchDebounce mirrors config.watchDebounce: coalesce the burst of
+// write/rename/create events a single save can produce into one reload.
+const profilesWatchDebounce = 250 * time.Millisecond
+
+// WatchProfiles reloads path with LoadProfiles whenever it changes, so a
+// power user can edit prompts/parameters (or add a new profile) while
+// voice-relay is running, without restarting llama-server. It watches
+// path's containing directory rather than the file itself, for the same
+// reason config.Watch does (survives editors that save via rename). Returns
+// a stop function that shuts the watcher down; callers should defer it.
+func WatchProfiles(path string) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go watchProfilesLoop(watcher, path, done)
+
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+func watchProfilesLoop(watcher *fsnotify.Watcher, path string, done chan struct{}) {
+	var debounce *time.Timer
+
+	reload := func() {
+		if err := LoadProfiles(path); err != nil {
+			log.Printf("Reloading LLM profiles: %v", err)
+			return
+		}
+		log.Printf("LLM profiles reloaded from %s", path)
+	}
+
+	for {
+		select {
+		case <-done:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Name != path {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.AfterFunc(profilesWatchDebounce, reload)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("LLM profiles watcher error: %v", err)
+		}
+	}
+}
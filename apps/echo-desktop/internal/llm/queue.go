@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/metrics"
+)
+
+// priority ranks the three kinds of request Engine serializes in front of
+// llama-server. Higher values run first; a task enqueued at a higher
+// priority than the one currently running preempts it.
+type priority int
+
+const (
+	priorityIdle priority = iota
+	priorityBackground
+	priorityInteractive
+)
+
+func (p priority) label() string {
+	switch p {
+	case priorityInteractive:
+		return "interactive"
+	case priorityBackground:
+		return "background"
+	default:
+		return "idle"
+	}
+}
+
+// task is one unit of queued work. run is given a context that's cancelled
+// if a higher-priority task preempts it while running, which in turn
+// cancels run's outbound HTTP request and lets llama-server stop generating.
+type task struct {
+	priority priority
+	run      func(ctx context.Context)
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// taskQueue serializes requests to llama-server behind a bounded worker
+// pool, since llama-server degrades badly under concurrent requests on a
+// CPU-only host. Work is dequeued by priority class rather than arrival
+// order, and submitting a higher-priority task preempts whichever running
+// task currently has the lowest priority by cancelling its context.
+type taskQueue struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queues    [3][]*task
+	running   []*task // one slot per worker; nil entries are idle
+	onPreempt func(*task)
+}
+
+// newTaskQueue starts workers goroutines pulling from the three priority
+// queues. workers < 1 is treated as 1. onPreempt, if non-nil, runs whenever
+// a task is cancelled to make room for a higher-priority one; Engine uses it
+// to also ask llama-server to free the generation slot.
+func newTaskQueue(workers int, onPreempt func(*task)) *taskQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	q := &taskQueue{
+		running:   make([]*task, workers),
+		onPreempt: onPreempt,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	for i := 0; i < workers; i++ {
+		go q.workerLoop(i)
+	}
+	return q
+}
+
+func (q *taskQueue) workerLoop(slot int) {
+	for {
+		q.mu.Lock()
+		t := q.popHighestLocked()
+		for t == nil {
+			q.cond.Wait()
+			t = q.popHighestLocked()
+		}
+		q.running[slot] = t
+		q.mu.Unlock()
+
+		metrics.LLMQueueDepth.WithLabelValues(t.priority.label()).Dec()
+		start := time.Now()
+		t.run(t.ctx)
+		metrics.LLMRequestDuration.WithLabelValues(t.priority.label()).Observe(time.Since(start).Seconds())
+		close(t.done)
+
+		q.mu.Lock()
+		q.running[slot] = nil
+		q.mu.Unlock()
+	}
+}
+
+// popHighestLocked removes and returns the oldest task in the
+// highest-nonempty priority queue. Callers must hold q.mu.
+func (q *taskQueue) popHighestLocked() *task {
+	for p := priorityInteractive; p >= priorityIdle; p-- {
+		if len(q.queues[p]) > 0 {
+			t := q.queues[p][0]
+			q.queues[p] = q.queues[p][1:]
+			return t
+		}
+	}
+	return nil
+}
+
+// lowestRunningLocked returns the slot index of the lowest-priority running
+// task, or -1 if every worker is idle. Callers must hold q.mu.
+func (q *taskQueue) lowestRunningLocked() int {
+	lowest := -1
+	for i, t := range q.running {
+		if t == nil {
+			continue
+		}
+		if lowest == -1 || t.priority < q.running[lowest].priority {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
+// submit enqueues run at priority pr and blocks until it has executed. If
+// every worker is busy with lower-priority work, the lowest-priority running
+// task is preempted (its context cancelled) to make room sooner; preempted
+// work isn't resumed, since a cancelled chat-completions request can't pick
+// up mid-generation — it simply runs again from the top next time it's
+// submitted.
+func (q *taskQueue) submit(ctx context.Context, pr priority, run func(ctx context.Context)) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	t := &task{priority: pr, run: run, ctx: taskCtx, cancel: cancel, done: make(chan struct{})}
+
+	q.mu.Lock()
+	var preempted *task
+	if slot := q.lowestRunningLocked(); slot != -1 && pr > q.running[slot].priority {
+		preempted = q.running[slot]
+		preempted.cancel()
+	}
+	q.queues[pr] = append(q.queues[pr], t)
+	metrics.LLMQueueDepth.WithLabelValues(pr.label()).Inc()
+	q.cond.Signal()
+	q.mu.Unlock()
+
+	// onPreempt (Engine.cancelSlot) makes a synchronous HTTP call to
+	// llama-server; running it while still holding q.mu would stall every
+	// other submit and workerLoop's dequeue if llama-server were slow or
+	// wedged, so it runs only after the lock is released.
+	if preempted != nil && q.onPreempt != nil {
+		q.onPreempt(preempted)
+	}
+
+	<-t.done
+}
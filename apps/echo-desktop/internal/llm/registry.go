@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// BackendSpec configures one named LLM backend for Registry, mirroring how
+// config.EngineSpec configures a TTS/STT backend: a type tag plus the
+// handful of fields that type needs. Type may be left empty, in which case
+// Registry auto-selects it from the other fields — see selectBackendType.
+type BackendSpec struct {
+	Type string // "local", "openai", or "grpc"
+
+	// local: run llama-server as a subprocess (see NewEngine). Workers
+	// bounds how many requests are sent to it concurrently; 0 means the
+	// NewEngine default of 1.
+	ModelPath  string
+	ServerPath string
+	Port       int
+	Workers    int
+
+	// openai: an already-running OpenAI-compatible chat completions server
+	// (Ollama, vLLM, LM Studio, LocalAI, or a shared llama-server elsewhere).
+	BaseURL string
+	APIKey  string
+
+	// grpc: a third-party engine speaking llmserver.proto. Target alone
+	// dials an already-running external server. Setting Command (and
+	// optionally Args) instead tells Registry to spawn and supervise the
+	// worker itself via NewSupervisedGRPCBackend — the path for wiring in a
+	// whisper/rwkv/gpt4all/etc runtime that isn't llama-server, without
+	// forking this repo.
+	Target  string
+	Command string
+	Args    []string
+
+	// Model is the model name sent in chat-completions requests (openai,
+	// grpc) or used as the in-process label (local, where llama-server
+	// only ever hosts the one model it was started with).
+	Model string
+}
+
+// modelExtBackends maps a model file extension to the backend type Registry
+// auto-selects when a spec omits Type: .gguf models run through the bundled
+// llama-server worker, the only runtime this repo downloads and supervises
+// itself. Anything else is expected to be served by an external or spawned
+// worker speaking llmserver.proto (see WorkerSpec / GRPCBackend) — add an
+// entry here once a given extension reliably maps to one runtime.
+var modelExtBackends = map[string]string{
+	".gguf": "local",
+}
+
+// selectBackendType returns the backend type spec should use when Type is
+// unset: "local" for a recognized ModelPath extension, "grpc" if a Target or
+// Command is already configured (an external/spawned worker for
+// whisper/rwkv/gpt4all/etc), and "local" as a last resort since that's the
+// only runtime this repo bundles and downloads on its own.
+func selectBackendType(spec BackendSpec) string {
+	if spec.Type != "" {
+		return spec.Type
+	}
+	if ext := strings.ToLower(filepath.Ext(spec.ModelPath)); ext != "" {
+		if t, ok := modelExtBackends[ext]; ok {
+			return t
+		}
+	}
+	if spec.Target != "" || spec.Command != "" {
+		return "grpc"
+	}
+	return "local"
+}
+
+// Registry holds named Backend instances so the task that picks one —
+// cleanup, summarization, or notification generation — can be routed to a
+// different model/backend per task, the way cfg.Engines lets a TTS request
+// pick a non-default voice engine by name.
+type Registry struct {
+	backends map[string]Backend
+}
+
+// NewRegistry builds a Registry from specs, dialing/starting each backend.
+// A spec that fails to start is logged by the caller (main.go already logs
+// this way for the local engine) and simply omitted rather than aborting the
+// whole registry, so one misconfigured remote doesn't take down the others.
+func NewRegistry(specs map[string]BackendSpec) (*Registry, []error) {
+	reg := &Registry{backends: map[string]Backend{}}
+	var errs []error
+	for name, spec := range specs {
+		backend, err := newBackend(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backend %q: %w", name, err))
+			continue
+		}
+		reg.backends[name] = backend
+	}
+	return reg, errs
+}
+
+func newBackend(spec BackendSpec) (Backend, error) {
+	switch selectBackendType(spec) {
+	case "local":
+		return NewEngine(spec.ModelPath, spec.ServerPath, spec.Port, spec.Workers)
+	case "openai":
+		return NewRemoteBackend(spec.BaseURL, spec.APIKey, spec.Model), nil
+	case "grpc":
+		if spec.Command != "" {
+			return NewSupervisedGRPCBackend(WorkerSpec{
+				Command:   spec.Command,
+				Args:      spec.Args,
+				Target:    spec.Target,
+				ModelPath: spec.ModelPath,
+			})
+		}
+		return NewGRPCBackend(spec.Target, spec.Model)
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", spec.Type)
+	}
+}
+
+// Backend returns the backend named name.
+func (r *Registry) Backend(name string) (Backend, error) {
+	b, ok := r.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("no LLM backend named %q", name)
+	}
+	return b, nil
+}
+
+// Names lists every registered backend name.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.backends))
+	for name := range r.backends {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Close shuts down every backend in the registry.
+func (r *Registry) Close() {
+	for _, b := range r.backends {
+		b.Close()
+	}
+}
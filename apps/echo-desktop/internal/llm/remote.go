@@ -0,0 +1,25 @@
+package llm
+
+import "strings"
+
+// RemoteBackend talks to an already-running OpenAI-compatible chat
+// completions server — Ollama, vLLM, LM Studio, LocalAI, or a llama-server
+// voice-relay itself started on another host — instead of managing a local
+// subprocess. It shares CleanupText/SummarizeNotification/GenerateNotification
+// with Engine via the embedded chatClient; only Close differs, since there's
+// no subprocess here to stop.
+type RemoteBackend struct {
+	*chatClient
+}
+
+// NewRemoteBackend creates a backend that sends chat-completions requests to
+// baseURL (e.g. "http://192.168.1.50:11434" for Ollama). apiKey is sent as a
+// Bearer token when non-empty; model selects which model the server loads.
+func NewRemoteBackend(baseURL, apiKey, model string) *RemoteBackend {
+	return &RemoteBackend{
+		chatClient: newChatClient(strings.TrimSuffix(baseURL, "/"), apiKey, model),
+	}
+}
+
+// Close is a no-op: RemoteBackend doesn't own the remote server's lifecycle.
+func (r *RemoteBackend) Close() {}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// WorkerSpec configures an external LLM runtime process that Registry spawns
+// and supervises, then talks to over llmserver.proto (see GRPCBackend) — the
+// generic path for wiring in a whisper-derived text model, rwkv, gpt4all, or
+// any other non-GGUF runtime without forking this repo or teaching it a new
+// wire format. This mirrors how Engine already supervises llama-server for
+// GGUF models, just one layer further out: the worker speaks llmserver.proto
+// instead of llama-server's HTTP API.
+type WorkerSpec struct {
+	// Command and Args launch the worker binary. It's expected to start an
+	// llmserver.proto gRPC listener on Target and answer Health once ready.
+	Command string
+	Args    []string
+
+	// Target is the gRPC address (e.g. "127.0.0.1:50061") Registry dials
+	// once the worker reports healthy.
+	Target string
+
+	// ModelPath is sent via LoadModel once the worker is healthy, for
+	// runtimes that load a model after starting rather than taking one on
+	// their command line.
+	ModelPath string
+
+	// ReadyTimeout bounds how long to poll Health before giving up; zero
+	// means workerReadyTimeoutDefault.
+	ReadyTimeout time.Duration
+}
+
+const (
+	workerReadyTimeoutDefault = 60 * time.Second
+	workerHealthPollInterval  = 500 * time.Millisecond
+)
+
+// NewSupervisedGRPCBackend starts spec.Command, waits for it to report
+// healthy over llmserver.proto, loads spec.ModelPath into it if set, and
+// returns a GRPCBackend wrapping the now-ready connection. The subprocess is
+// killed if it fails to come up in time or LoadModel fails.
+func NewSupervisedGRPCBackend(spec WorkerSpec) (*GRPCBackend, error) {
+	cmd := exec.Command(spec.Command, spec.Args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	setSysProcAttr(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting worker %s: %w", spec.Command, err)
+	}
+
+	backend, err := NewGRPCBackend(spec.Target, "")
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, fmt.Errorf("dialing worker: %w", err)
+	}
+	backend.cmd = cmd
+
+	timeout := spec.ReadyTimeout
+	if timeout <= 0 {
+		timeout = workerReadyTimeoutDefault
+	}
+	if err := waitWorkerHealthy(backend, timeout); err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	if spec.ModelPath != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := backend.LoadModel(ctx, spec.ModelPath); err != nil {
+			backend.Close()
+			return nil, fmt.Errorf("loading model into worker: %w", err)
+		}
+	}
+
+	return backend, nil
+}
+
+func waitWorkerHealthy(backend *GRPCBackend, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		ready, err := backend.Health(ctx)
+		cancel()
+		if err == nil && ready {
+			return nil
+		}
+		time.Sleep(workerHealthPollInterval)
+	}
+	return fmt.Errorf("worker did not become healthy within %v", timeout)
+}
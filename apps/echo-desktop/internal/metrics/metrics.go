@@ -0,0 +1,359 @@
+// Package metrics is a small, dependency-free Prometheus exposition
+// format writer for the coordinator's counters, gauges, and histograms. It
+// covers only what the coordinator needs (no summaries, no push gateway) —
+// if that grows, switch to github.com/prometheus/client_golang instead of
+// extending this by hand.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counter is a monotonically increasing value, e.g. a count of events.
+type Counter struct {
+	value atomic.Int64
+}
+
+func (c *Counter) Inc()         { c.value.Add(1) }
+func (c *Counter) Add(n int64)  { c.value.Add(n) }
+func (c *Counter) Value() int64 { return c.value.Load() }
+
+// Gauge is a value that can move up or down, e.g. a count of pending items.
+type Gauge struct {
+	value atomic.Int64
+}
+
+func (g *Gauge) Set(n int64)  { g.value.Store(n) }
+func (g *Gauge) Inc()         { g.value.Add(1) }
+func (g *Gauge) Dec()         { g.value.Add(-1) }
+func (g *Gauge) Value() int64 { return g.value.Load() }
+
+// defaultBuckets are latency/duration buckets in seconds, the same default
+// set the official Prometheus client libraries ship with.
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram tracks the distribution of a value (typically a duration in
+// seconds) across defaultBuckets, plus a running sum and count.
+type Histogram struct {
+	mu     sync.Mutex
+	counts []int64
+	sum    float64
+	count  int64
+}
+
+func newHistogram() *Histogram {
+	return &Histogram{counts: make([]int64, len(defaultBuckets))}
+}
+
+// Observe records v, taking the time.Since(start).Seconds() idiom so callers
+// can write `defer h.ObserveDuration(time.Now())`.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sum += v
+	h.count++
+	for i, b := range defaultBuckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// labelString renders label keys/values as Prometheus's "{k="v",k2="v2"}"
+// suffix, or "" when there are no labels.
+func labelString(keys, values []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", k, values[i])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func labelKey(values []string) string {
+	return strings.Join(values, "\x1f")
+}
+
+// collector is anything that can render itself in Prometheus text format.
+type collector interface {
+	writeTo(buf *bytes.Buffer)
+}
+
+// Registry holds every metric the coordinator has registered and can render
+// all of them in Prometheus exposition format for /metrics.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// Default is the coordinator's single process-wide registry — metrics in
+// this package are always registered here, mirroring how the standard
+// library's expvar and the Prometheus client's DefaultRegisterer work.
+var Default = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo renders every registered metric in Prometheus text exposition
+// format.
+func (r *Registry) WriteTo(buf *bytes.Buffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.collectors {
+		c.writeTo(buf)
+	}
+}
+
+// Handler serves the registry in Prometheus text exposition format.
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		r.WriteTo(&buf)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(buf.Bytes())
+	}
+}
+
+// CounterVec is a Counter keyed by one or more label values, e.g. a "source"
+// or "voice" dimension on an otherwise single counter.
+type CounterVec struct {
+	mu        sync.Mutex
+	name      string
+	help      string
+	labelKeys []string
+	children  map[string]*Counter
+	labels    map[string][]string
+}
+
+// NewCounter creates and registers an unlabeled counter.
+func NewCounter(name, help string) *Counter {
+	cv := NewCounterVec(name, help)
+	return cv.WithLabelValues()
+}
+
+// NewCounterVec creates and registers a labeled counter family.
+func NewCounterVec(name, help string, labelKeys ...string) *CounterVec {
+	cv := &CounterVec{name: name, help: help, labelKeys: labelKeys, children: map[string]*Counter{}, labels: map[string][]string{}}
+	Default.register(cv)
+	return cv
+}
+
+// WithLabelValues returns the Counter for this label combination, creating
+// it on first use. Pass values in the same order as labelKeys.
+func (cv *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := labelKey(values)
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	c, ok := cv.children[key]
+	if !ok {
+		c = &Counter{}
+		cv.children[key] = c
+		cv.labels[key] = values
+	}
+	return c
+}
+
+func (cv *CounterVec) writeTo(buf *bytes.Buffer) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s counter\n", cv.name, cv.help, cv.name)
+	for _, key := range sortedKeys(cv.children) {
+		fmt.Fprintf(buf, "%s%s %d\n", cv.name, labelString(cv.labelKeys, cv.labels[key]), cv.children[key].Value())
+	}
+}
+
+// GaugeVec is a Gauge keyed by one or more label values.
+type GaugeVec struct {
+	mu        sync.Mutex
+	name      string
+	help      string
+	labelKeys []string
+	children  map[string]*Gauge
+	labels    map[string][]string
+}
+
+// NewGauge creates and registers an unlabeled gauge.
+func NewGauge(name, help string) *Gauge {
+	gv := NewGaugeVec(name, help)
+	return gv.WithLabelValues()
+}
+
+// NewGaugeVec creates and registers a labeled gauge family.
+func NewGaugeVec(name, help string, labelKeys ...string) *GaugeVec {
+	gv := &GaugeVec{name: name, help: help, labelKeys: labelKeys, children: map[string]*Gauge{}, labels: map[string][]string{}}
+	Default.register(gv)
+	return gv
+}
+
+// WithLabelValues returns the Gauge for this label combination, creating it
+// on first use. Pass values in the same order as labelKeys.
+func (gv *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	key := labelKey(values)
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	g, ok := gv.children[key]
+	if !ok {
+		g = &Gauge{}
+		gv.children[key] = g
+		gv.labels[key] = values
+	}
+	return g
+}
+
+func (gv *GaugeVec) writeTo(buf *bytes.Buffer) {
+	gv.mu.Lock()
+	defer gv.mu.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", gv.name, gv.help, gv.name)
+	for _, key := range sortedKeys(gv.children) {
+		fmt.Fprintf(buf, "%s%s %d\n", gv.name, labelString(gv.labelKeys, gv.labels[key]), gv.children[key].Value())
+	}
+}
+
+// HistogramVec is a Histogram keyed by one or more label values.
+type HistogramVec struct {
+	mu        sync.Mutex
+	name      string
+	help      string
+	labelKeys []string
+	children  map[string]*Histogram
+	labels    map[string][]string
+}
+
+// NewHistogramVec creates and registers a labeled histogram family.
+func NewHistogramVec(name, help string, labelKeys ...string) *HistogramVec {
+	hv := &HistogramVec{name: name, help: help, labelKeys: labelKeys, children: map[string]*Histogram{}, labels: map[string][]string{}}
+	Default.register(hv)
+	return hv
+}
+
+// WithLabelValues returns the Histogram for this label combination, creating
+// it on first use. Pass values in the same order as labelKeys.
+func (hv *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := labelKey(values)
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	h, ok := hv.children[key]
+	if !ok {
+		h = newHistogram()
+		hv.children[key] = h
+		hv.labels[key] = values
+	}
+	return h
+}
+
+func (hv *HistogramVec) writeTo(buf *bytes.Buffer) {
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s histogram\n", hv.name, hv.help, hv.name)
+	for _, key := range sortedKeys(hv.children) {
+		h := hv.children[key]
+		values := hv.labels[key]
+
+		h.mu.Lock()
+		cumulative := int64(0)
+		for i, b := range defaultBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", hv.name, labelString(append(append([]string{}, hv.labelKeys...), "le"), append(append([]string{}, values...), fmt.Sprintf("%g", b))), cumulative)
+		}
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", hv.name, labelString(append(append([]string{}, hv.labelKeys...), "le"), append(append([]string{}, values...), "+Inf")), h.count)
+		fmt.Fprintf(buf, "%s_sum%s %g\n", hv.name, labelString(hv.labelKeys, values), h.sum)
+		fmt.Fprintf(buf, "%s_count%s %d\n", hv.name, labelString(hv.labelKeys, values), h.count)
+		h.mu.Unlock()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Instrument wraps handler so every request through it increments
+// http_requests_total{route,method,status} and observes
+// http_request_duration_seconds{route,method}. route is a caller-supplied
+// label (the mux pattern), not the raw path, so it doesn't explode into one
+// series per distinct URL.
+func Instrument(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		handler(sw, r)
+		HTTPRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+		HTTPRequestsTotal.WithLabelValues(route, r.Method, fmt.Sprintf("%d", sw.status)).Inc()
+	}
+}
+
+// statusWriter captures the status code passed to WriteHeader so Instrument
+// can label requests that never call it explicitly (default 200) the same
+// as those that do.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// Coordinator-wide metrics. Declared here rather than per-package globals so
+// /metrics has one place to look for the full list.
+var (
+	HTTPRequestsTotal   = NewCounterVec("voicerelay_http_requests_total", "Total HTTP requests by route, method, and status", "route", "method", "status")
+	HTTPRequestDuration = NewHistogramVec("voicerelay_http_request_duration_seconds", "HTTP request latency by route and method", "route", "method")
+
+	TTSSynthDuration    = NewHistogramVec("voicerelay_tts_synth_duration_seconds", "TTS synthesis latency by voice", "voice")
+	TTSFailuresTotal    = NewCounterVec("voicerelay_tts_failures_total", "TTS synthesis failures by voice", "voice")
+	TTSTimeToFirstAudio = NewHistogramVec("voicerelay_tts_time_to_first_audio_seconds", "Time from stream request to the first PCM frame, by voice", "voice")
+
+	NotificationsSubmittedTotal = NewCounterVec("voicerelay_notifications_submitted_total", "Notifications submitted by source", "source")
+	NotificationsDismissedTotal = NewCounterVec("voicerelay_notifications_dismissed_total", "Notifications dismissed by source", "source")
+	NotificationsExpiredTotal   = NewCounterVec("voicerelay_notifications_expired_total", "Notifications auto-expired via TTL by source", "source")
+	NotificationsQueueGauge     = NewGaugeVec("voicerelay_notifications_queue", "Notifications currently in each pipeline state", "state")
+
+	PendingQuestionsGauge = NewGauge("voicerelay_pending_questions", "Number of AskUserQuestion prompts awaiting an answer")
+	QuestionAnswerLatency = NewHistogram("voicerelay_question_answer_latency_seconds", "Time from a question being asked to it being answered")
+	HookInstallStatus     = NewGaugeVec("voicerelay_hook_install_status", "1 if Claude Code hooks are installed, 0 otherwise", "machine")
+
+	LLMQueueDepth      = NewGaugeVec("voicerelay_llm_queue_depth", "Number of LLM requests waiting behind in-flight work, by priority class", "priority")
+	LLMRequestDuration = NewHistogramVec("voicerelay_llm_request_duration_seconds", "LLM backend request latency by priority class", "priority")
+
+	// Agent.callLLM health — separate from the llama-server task queue
+	// above, since the talk-mode agent can also be pointed at a remote
+	// backend with its own retry/circuit-breaker needs.
+	LLMCallAttemptsTotal         = NewCounter("voicerelay_llm_call_attempts_total", "Agent.callLLM attempts, including retries")
+	LLMCallSuccessesTotal        = NewCounter("voicerelay_llm_call_successes_total", "Agent.callLLM attempts that got a 200 response")
+	LLMCallFailuresTotal         = NewCounter("voicerelay_llm_call_failures_total", "Agent.callLLM attempts that failed (network error or non-2xx)")
+	LLMBreakerTripsTotal         = NewCounter("voicerelay_llm_breaker_trips_total", "Times the agent's LLM circuit breaker has opened")
+	LLMBreakerShortCircuitsTotal = NewCounter("voicerelay_llm_breaker_short_circuits_total", "Calls rejected immediately because the LLM circuit breaker was open")
+)
+
+// NewHistogram creates and registers an unlabeled histogram.
+func NewHistogram(name, help string) *Histogram {
+	hv := NewHistogramVec(name, help)
+	return hv.WithLabelValues()
+}
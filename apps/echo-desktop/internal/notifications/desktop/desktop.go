@@ -0,0 +1,14 @@
+// Package desktop posts voice-relay notifications to the host OS's native
+// notification center, alongside the PWA broadcast the coordinator already
+// does. See NewSink for the platform-specific implementations.
+package desktop
+
+// Sink posts notifications to the host OS and reports back when the user
+// acts on one, so the coordinator can mark the underlying row read.
+type Sink interface {
+	// Notify posts a native notification for id with the given title/body.
+	Notify(id, title, body string) error
+	// Close releases any OS resources the sink holds open (e.g. a D-Bus
+	// connection). Safe to call even if NewSink never fully succeeded.
+	Close() error
+}
@@ -0,0 +1,51 @@
+//go:build darwin
+
+package desktop
+
+/*
+#cgo LDFLAGS: -framework Foundation
+#include <stdlib.h>
+#import <Foundation/Foundation.h>
+
+// postUserNotification posts a banner via the deprecated-but-still-working
+// NSUserNotificationCenter API. UNUserNotificationCenter (the modern
+// replacement) requires a signed app bundle with a proper bundle identifier
+// to authorize, which an unsigned dev build of voice-relay doesn't have —
+// NSUserNotificationCenter works unconditionally for an unbundled binary.
+static void postUserNotification(const char *title, const char *body) {
+	@autoreleasepool {
+		NSUserNotification *n = [[NSUserNotification alloc] init];
+		n.title = [NSString stringWithUTF8String:title];
+		n.informativeText = [NSString stringWithUTF8String:body];
+		[[NSUserNotificationCenter defaultUserNotificationCenter] deliverNotification:n];
+	}
+}
+*/
+import "C"
+
+import "unsafe"
+
+// darwinSink posts notifications via NSUserNotificationCenter. Unlike the
+// Linux D-Bus sink, there's no click-to-Go callback here: wiring
+// NSUserNotificationCenterDelegate's didActivateNotification up to Go
+// requires an Objective-C class registered via cgo's //export, which is
+// more machinery than a best-effort toast warrants — onAction is accepted
+// for interface parity with the other sinks but never called.
+type darwinSink struct{}
+
+// NewSink returns a sink that posts native banners but never reports clicks
+// back (see darwinSink).
+func NewSink(onAction func(id string)) (Sink, error) {
+	return &darwinSink{}, nil
+}
+
+func (s *darwinSink) Notify(id, title, body string) error {
+	cTitle := C.CString(title)
+	defer C.free(unsafe.Pointer(cTitle))
+	cBody := C.CString(body)
+	defer C.free(unsafe.Pointer(cBody))
+	C.postUserNotification(cTitle, cBody)
+	return nil
+}
+
+func (s *darwinSink) Close() error { return nil }
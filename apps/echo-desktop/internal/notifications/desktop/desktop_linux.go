@@ -0,0 +1,152 @@
+//go:build linux
+
+package desktop
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	dbusDest      = "org.freedesktop.Notifications"
+	dbusPath      = "/org/freedesktop/Notifications"
+	dbusInterface = "org.freedesktop.Notifications"
+	appName       = "Voice Relay"
+)
+
+// linuxSink posts notifications via org.freedesktop.Notifications.Notify
+// over a single long-lived session D-Bus connection, and listens for that
+// connection's NotificationClosed/ActionInvoked signals so a click can be
+// correlated back to the notification id that produced it.
+type linuxSink struct {
+	conn     *dbus.Conn
+	onAction func(id string)
+	signals  chan *dbus.Signal
+
+	mu       sync.Mutex
+	byDBusID map[uint32]string // D-Bus notification id -> our notification id
+}
+
+// NewSink connects to the session bus and starts listening for
+// NotificationClosed/ActionInvoked signals. onAction is called with the
+// voice-relay notification id when the user clicks a notification or its
+// default action; it may be nil.
+func NewSink(onAction func(id string)) (Sink, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to session bus: %w", err)
+	}
+
+	s := &linuxSink{
+		conn:     conn,
+		onAction: onAction,
+		byDBusID: make(map[uint32]string),
+	}
+
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface(dbusInterface),
+		dbus.WithMatchMember("ActionInvoked"),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to ActionInvoked: %w", err)
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface(dbusInterface),
+		dbus.WithMatchMember("NotificationClosed"),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("subscribing to NotificationClosed: %w", err)
+	}
+
+	s.signals = make(chan *dbus.Signal, 16)
+	conn.Signal(s.signals)
+	go s.watchSignals(s.signals)
+
+	return s, nil
+}
+
+// Notify calls Notify over the already-open connection, with a "default"
+// action hint so clicking the notification body itself (not just a button)
+// fires ActionInvoked.
+func (s *linuxSink) Notify(id, title, body string) error {
+	call := s.conn.Object(dbusDest, dbus.ObjectPath(dbusPath)).Call(
+		dbusInterface+".Notify", 0,
+		appName,
+		uint32(0),
+		"",
+		title,
+		body,
+		[]string{"default", "Open"},
+		map[string]dbus.Variant{},
+		int32(-1),
+	)
+	if call.Err != nil {
+		return fmt.Errorf("Notify: %w", call.Err)
+	}
+
+	var dbusID uint32
+	if err := call.Store(&dbusID); err != nil {
+		return fmt.Errorf("decoding Notify reply: %w", err)
+	}
+
+	s.mu.Lock()
+	s.byDBusID[dbusID] = id
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *linuxSink) watchSignals(signals chan *dbus.Signal) {
+	for sig := range signals {
+		switch sig.Name {
+		case dbusInterface + ".ActionInvoked":
+			if len(sig.Body) < 1 {
+				continue
+			}
+			dbusID, ok := sig.Body[0].(uint32)
+			if !ok {
+				continue
+			}
+			s.handleAction(dbusID)
+
+		case dbusInterface + ".NotificationClosed":
+			// Drop the mapping — the notification is gone either way, and
+			// there's nothing further to correlate once it's closed without
+			// being clicked (reason 1 = expired, 2 = dismissed, 3 = closed
+			// by a CloseNotification call, 4 = undefined/reserved).
+			if len(sig.Body) < 1 {
+				continue
+			}
+			if dbusID, ok := sig.Body[0].(uint32); ok {
+				s.mu.Lock()
+				delete(s.byDBusID, dbusID)
+				s.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (s *linuxSink) handleAction(dbusID uint32) {
+	s.mu.Lock()
+	id, ok := s.byDBusID[dbusID]
+	delete(s.byDBusID, dbusID)
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	if s.onAction != nil {
+		s.onAction(id)
+	}
+}
+
+func (s *linuxSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	if s.signals != nil {
+		s.conn.RemoveSignal(s.signals)
+	}
+	return s.conn.Close()
+}
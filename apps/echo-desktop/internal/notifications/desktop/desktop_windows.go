@@ -0,0 +1,54 @@
+//go:build windows
+
+package desktop
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// toastScriptTemplate builds a toast via the WinRT Windows.UI.Notifications
+// APIs through PowerShell, the same approach internal/hooks/install.go uses
+// for running the hook script itself — it avoids a cgo/WinRT binding just
+// for this one call.
+const toastScriptTemplate = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName("text")
+$texts.Item(0).AppendChild($template.CreateTextNode("%s")) | Out-Null
+$texts.Item(1).AppendChild($template.CreateTextNode("%s")) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($template)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier("Voice Relay").Show($toast)
+`
+
+// windowsSink posts toasts via a one-shot PowerShell invocation per
+// notification. Unlike the Linux D-Bus sink, there's no long-lived listener
+// here, so — as with darwinSink — onAction is accepted for interface parity
+// but never called: correlating a toast click back to this process would
+// need a registered AppUserModelID and a COM activator, which is more than
+// a best-effort toast warrants.
+type windowsSink struct{}
+
+// NewSink returns a sink that posts native toasts but never reports clicks
+// back (see windowsSink).
+func NewSink(onAction func(id string)) (Sink, error) {
+	return &windowsSink{}, nil
+}
+
+func (s *windowsSink) Notify(id, title, body string) error {
+	script := fmt.Sprintf(toastScriptTemplate, psEscape(title), psEscape(body))
+	cmd := exec.Command("powershell", "-ExecutionPolicy", "Bypass", "-NoProfile", "-Command", script)
+	return cmd.Run()
+}
+
+func (s *windowsSink) Close() error { return nil }
+
+// psEscape escapes a string for embedding inside a PowerShell double-quoted
+// string literal.
+func psEscape(s string) string {
+	s = strings.ReplaceAll(s, "`", "``")
+	s = strings.ReplaceAll(s, "\"", "`\"")
+	s = strings.ReplaceAll(s, "$", "`$")
+	return s
+}
@@ -0,0 +1,404 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dispatchTimeout bounds how long any single sink may take to deliver a
+// notification before it's considered failed.
+const dispatchTimeout = 10 * time.Second
+
+// Sink delivers a notification to some external destination (a webhook, an
+// inbox, an ntfy topic, a phone via FCM) in addition to the local PWA.
+type Sink interface {
+	// Name identifies the sink for logging, e.g. "webhook:https://example.com/hook".
+	Name() string
+	// Send delivers n, returning an error if delivery failed.
+	Send(n Notification) error
+}
+
+// SinkConfig holds the credentials sinks need beyond a rule's "kind:target"
+// spec, set once from the app config rather than repeated in every rule.
+type SinkConfig struct {
+	WebhookSecret string // HMAC key for the X-Voicerelay-Signature header
+	SMTPRelay     string // host:port of an SMTP relay, e.g. "smtp.gmail.com:587"
+	SMTPFrom      string
+	SMTPUser      string
+	SMTPPassword  string
+	FCMServerKey  string // legacy FCM HTTP server key
+}
+
+// BuildSink parses a "<kind>:<target>" spec — "webhook:https://…",
+// "email:me@example.com", "ntfy:https://ntfy.sh/mytopic", or
+// "fcm:<device-token>" — into a Sink.
+func BuildSink(spec string, cfg SinkConfig) (Sink, error) {
+	kind, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("sink %q must be \"kind:target\"", spec)
+	}
+
+	switch kind {
+	case "webhook":
+		return webhookSink{url: target, secret: cfg.WebhookSecret}, nil
+	case "email":
+		return emailSink{to: target, cfg: cfg}, nil
+	case "ntfy":
+		return ntfySink{url: target}, nil
+	case "fcm":
+		return fcmSink{token: target, serverKey: cfg.FCMServerKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink kind %q", kind)
+	}
+}
+
+// webhookSink POSTs the notification as JSON, signing the body with
+// HMAC-SHA256 so a receiver can verify it came from this coordinator.
+type webhookSink struct {
+	url    string
+	secret string
+}
+
+func (s webhookSink) Name() string { return "webhook:" + s.url }
+
+func (s webhookSink) Send(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write(body)
+		req.Header.Set("X-Voicerelay-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := &http.Client{Timeout: dispatchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// emailSink sends the notification as a plain-text email through cfg's SMTP
+// relay. Auth is skipped when no user/password is configured, for relays that
+// only accept connections from trusted hosts.
+type emailSink struct {
+	to  string
+	cfg SinkConfig
+}
+
+func (s emailSink) Name() string { return "email:" + s.to }
+
+func (s emailSink) Send(n Notification) error {
+	if s.cfg.SMTPRelay == "" {
+		return fmt.Errorf("no SMTP relay configured")
+	}
+	from := s.cfg.SMTPFrom
+	if from == "" {
+		from = "voice-relay@localhost"
+	}
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		host, _, err := net.SplitHostPort(s.cfg.SMTPRelay)
+		if err != nil {
+			host = s.cfg.SMTPRelay
+		}
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n\r\n%s\r\n",
+		from, s.to, sanitizeSMTPField(n.Title), sanitizeSMTPField(n.Summary), sanitizeSMTPField(n.Details))
+	return smtp.SendMail(s.cfg.SMTPRelay, auth, from, []string{s.to}, []byte(msg))
+}
+
+// sanitizeSMTPField strips CR, LF, and other control characters from s before
+// it's interpolated into this hand-built message: n.Title/n.Summary/n.Details
+// can be attacker-controlled (e.g. via /notifications/submit), and an
+// embedded CRLF would otherwise let a notification inject arbitrary extra
+// SMTP headers — Bcc, another Subject, etc. — into the Subject line it's
+// meant to be confined to (classic CRLF/header injection, CWE-93).
+func sanitizeSMTPField(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r < 0x20 {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// ntfySink publishes to an ntfy-compatible topic URL (ntfy.sh or self-hosted).
+type ntfySink struct {
+	url string
+}
+
+func (s ntfySink) Name() string { return "ntfy:" + s.url }
+
+func (s ntfySink) Send(n Notification) error {
+	body := n.Summary
+	if n.Details != "" {
+		body += "\n\n" + n.Details
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", n.Title)
+	if p := ntfyPriority(n.Priority); p != "" {
+		req.Header.Set("Priority", p)
+	}
+
+	client := &http.Client{Timeout: dispatchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ntfyPriority maps voice-relay's low/normal/high scale to ntfy's 1-5 scale.
+func ntfyPriority(priority string) string {
+	switch priority {
+	case "low":
+		return "2"
+	case "normal":
+		return "3"
+	case "high":
+		return "4"
+	default:
+		return ""
+	}
+}
+
+// fcmSink pushes to a single device token via the legacy FCM HTTP API.
+type fcmSink struct {
+	token     string
+	serverKey string
+}
+
+func (s fcmSink) Name() string { return "fcm:" + s.token }
+
+func (s fcmSink) Send(n Notification) error {
+	if s.serverKey == "" {
+		return fmt.Errorf("no FCM server key configured")
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"to": s.token,
+		"notification": map[string]string{
+			"title": n.Title,
+			"body":  n.Summary,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://fcm.googleapis.com/fcm/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+s.serverKey)
+
+	client := &http.Client{Timeout: dispatchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("FCM returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// priorityRank orders voice-relay's priority scale so RuleMatch can evaluate
+// comparisons like ">=high".
+var priorityRank = map[string]int{"low": 0, "normal": 1, "high": 2}
+
+// RuleMatch selects which notifications a Rule applies to. An empty field
+// matches anything; Priority supports an optional comparison prefix
+// (">=high", ">normal", "high" alone means "=high").
+type RuleMatch struct {
+	Priority string `json:"priority,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+}
+
+// Matches reports whether n satisfies every non-empty field of m.
+func (m RuleMatch) Matches(n Notification) bool {
+	if m.Tag != "" && m.Tag != n.Tag {
+		return false
+	}
+	if m.Priority != "" && !matchesPriority(m.Priority, n.Priority) {
+		return false
+	}
+	return true
+}
+
+// matchesPriority evaluates an expression like ">=high" against a
+// notification's priority. An unrecognized operator or priority name fails
+// the match rather than erroring, since one bad rule shouldn't block delivery
+// of notifications unrelated to it.
+func matchesPriority(expr, priority string) bool {
+	op, level := "=", expr
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(expr, candidate) {
+			op, level = candidate, strings.TrimPrefix(expr, candidate)
+			break
+		}
+	}
+
+	want, ok := priorityRank[level]
+	if !ok {
+		return false
+	}
+	have, ok := priorityRank[priority]
+	if !ok {
+		return false
+	}
+
+	switch op {
+	case ">=":
+		return have >= want
+	case "<=":
+		return have <= want
+	case ">":
+		return have > want
+	case "<":
+		return have < want
+	default:
+		return have == want
+	}
+}
+
+// Rule routes notifications matching Match to Sinks in addition to the local
+// PWA, e.g. forwarding high-priority build failures to a phone via ntfy.
+type Rule struct {
+	Match RuleMatch `json:"match"`
+	Sinks []string  `json:"sinks"`
+}
+
+// RouteStore persists routing rules to a small JSON file and dispatches
+// incoming notifications to whichever sinks their rules match, the same way
+// coordinator/pairing.go persists paired devices.
+type RouteStore struct {
+	mu    sync.Mutex
+	path  string
+	rules []Rule
+	cfg   SinkConfig
+}
+
+// NewRouteStore creates a RouteStore backed by routes.json in dir, loading
+// any rules already persisted there.
+func NewRouteStore(dir string, cfg SinkConfig) *RouteStore {
+	s := &RouteStore{path: filepath.Join(dir, "routes.json"), cfg: cfg}
+	s.load()
+	return s
+}
+
+func (s *RouteStore) load() {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		log.Printf("notifications: failed to parse routing rules: %v", err)
+		return
+	}
+	s.mu.Lock()
+	s.rules = rules
+	s.mu.Unlock()
+}
+
+func (s *RouteStore) saveLocked() {
+	data, err := json.MarshalIndent(s.rules, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(s.path, data, 0644)
+}
+
+// AddRule appends a new routing rule and persists the updated set.
+func (s *RouteStore) AddRule(rule Rule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rule)
+	s.saveLocked()
+}
+
+// Rules returns the current routing rules.
+func (s *RouteStore) Rules() []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Rule(nil), s.rules...)
+}
+
+// Dispatch evaluates n against every rule and sends it to every sink any
+// matching rule names, deduplicated, each in its own goroutine so a slow or
+// unreachable sink doesn't hold up notification ingest. Delivery failures are
+// logged rather than surfaced, since a bad external sink shouldn't block the
+// local PWA from seeing the notification.
+func (s *RouteStore) Dispatch(n Notification) {
+	s.mu.Lock()
+	rules := append([]Rule(nil), s.rules...)
+	cfg := s.cfg
+	s.mu.Unlock()
+
+	sent := make(map[string]bool)
+	for _, rule := range rules {
+		if !rule.Match.Matches(n) {
+			continue
+		}
+		for _, spec := range rule.Sinks {
+			if sent[spec] {
+				continue
+			}
+			sent[spec] = true
+
+			sink, err := BuildSink(spec, cfg)
+			if err != nil {
+				log.Printf("notifications: skipping invalid sink %q: %v", spec, err)
+				continue
+			}
+			go func(sink Sink) {
+				if err := sink.Send(n); err != nil {
+					log.Printf("notifications: dispatch to %s failed: %v", sink.Name(), err)
+				}
+			}(sink)
+		}
+	}
+}
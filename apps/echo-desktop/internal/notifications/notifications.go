@@ -1,16 +1,18 @@
 package notifications
 
 import (
+	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/metrics"
 )
 
 // Notification represents a notification with optional TTS audio.
@@ -20,48 +22,130 @@ type Notification struct {
 	Summary      string `json:"summary"`
 	Details      string `json:"details,omitempty"`
 	Priority     string `json:"priority,omitempty"`
+	Tag          string `json:"tag,omitempty"` // e.g. "build", for routing rules (see dispatch.go)
 	Source       string `json:"source,omitempty"`
+	Voice        string `json:"voice,omitempty"` // TTS voice to read Summary/Details with; falls back to the watcher's configured voice when empty
 	CreatedAt    string `json:"created_at,omitempty"`
 	ProcessedAt  string `json:"processed_at,omitempty"`
 	SummaryAudio string `json:"summary_audio,omitempty"`
 	DetailsAudio string `json:"details_audio,omitempty"`
+	// TTLSeconds, if non-zero, has the watcher auto-archive this notification
+	// this many seconds after it was processed, so stale build notifications
+	// don't accumulate in the PWA's list.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// Status, Attempts, and LastError mirror the notifications table's own
+	// bookkeeping columns, exposed so the PWA can tell a "failed" TTS render
+	// apart from a normal "processed" one.
+	Status    string `json:"status,omitempty"`
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// DefaultMaxAttempts is how many times processPending retries a notification
+// whose TTS synthesis fails before giving up and marking it StatusFailed.
+const DefaultMaxAttempts = 5
+
+// Stats counts notifications by pipeline state, for Watcher.Stats().
+type Stats struct {
+	Pending   int
+	Retrying  int
+	Failed    int
+	Processed int
 }
 
 // TTSFunc synthesizes text to WAV audio bytes.
 type TTSFunc func(text, voice, language string) ([]byte, error)
 
-// Watcher polls a pending directory for notification JSON files and processes them.
+// AudioChunk is one piece of streamed TTS audio, or a terminal error,
+// mirroring tts.AudioChunk without this package depending on the tts package
+// directly — the same decoupling TTSFunc/TTSStreamFunc itself is for.
+type AudioChunk struct {
+	PCM []byte
+	Err error
+}
+
+// TTSStreamFunc synthesizes text incrementally, delivering encoded audio
+// chunks as they're produced instead of making the caller wait for the whole
+// utterance. When set via SetTTSStreamFunc, it's used for Details (typically
+// the longest, most multi-sentence text a notification carries) in place of
+// TTSFunc.
+type TTSStreamFunc func(ctx context.Context, text, voice, language string) (<-chan AudioChunk, error)
+
+// Watcher polls the notifications database for pending rows and processes
+// them. It replaced an earlier pending/processing/processed/archived
+// directory-of-JSON-files pipeline; EnsureDirs migrates any files left over
+// from that pipeline into the database the first time it runs.
 type Watcher struct {
-	baseDir    string
-	ttsFunc    TTSFunc
-	ttsVoice   func() string // returns current voice name
-	onReady    func()        // called after processing a notification
-	stopCh     chan struct{}
-	mu         sync.Mutex
+	baseDir       string
+	db            *sql.DB
+	ttsFunc       TTSFunc
+	ttsStreamFunc TTSStreamFunc
+	ttsVoice      func() string                                          // returns current voice name
+	onReady       func()                                                 // called after processing a notification
+	onAudioChunk  func(notifID string, seq int, data []byte, final bool) // called per streamed chunk, if set
+	onNotify      func(id, title, body string)                           // called once per processed notification, if set (see SetNotifyFunc)
+	maxAttempts   int
+	stopCh        chan struct{}
+	mu            sync.Mutex
 }
 
-// NewWatcher creates a new notification watcher.
+// NewWatcher creates a new notification watcher. The database isn't opened
+// until EnsureDirs is called.
 func NewWatcher(baseDir string, tts TTSFunc, voiceFn func() string, onReady func()) *Watcher {
 	return &Watcher{
-		baseDir:  baseDir,
-		ttsFunc:  tts,
-		ttsVoice: voiceFn,
-		onReady:  onReady,
-		stopCh:   make(chan struct{}),
+		baseDir:     baseDir,
+		ttsFunc:     tts,
+		ttsVoice:    voiceFn,
+		onReady:     onReady,
+		maxAttempts: DefaultMaxAttempts,
+		stopCh:      make(chan struct{}),
 	}
 }
 
-// EnsureDirs creates the notification pipeline directories.
+// SetMaxAttempts overrides how many times a notification's TTS synthesis is
+// retried before it's marked StatusFailed. Must be called before Start.
+func (w *Watcher) SetMaxAttempts(n int) {
+	w.maxAttempts = n
+}
+
+// SetTTSStreamFunc enables streaming synthesis for Details text, so a long,
+// multi-sentence notification can start playback before the whole utterance
+// has rendered. Must be called before Start; without it, processPending
+// falls back to TTSFunc for everything.
+func (w *Watcher) SetTTSStreamFunc(fn TTSStreamFunc) {
+	w.ttsStreamFunc = fn
+}
+
+// SetAudioChunkFunc registers a callback invoked with each chunk of a
+// streamed Details render as it's produced, so the coordinator can fan it
+// out to the PWA (see coordinator.PublishAudioChunk) for near-real-time
+// playback. Must be called before Start.
+func (w *Watcher) SetAudioChunkFunc(fn func(notifID string, seq int, data []byte, final bool)) {
+	w.onAudioChunk = fn
+}
+
+// SetNotifyFunc registers a callback invoked once a notification finishes
+// processing successfully, so a caller (the coordinator's desktop
+// notification sink) can surface it as a native OS toast alongside the PWA
+// broadcast onReady already triggers. Must be called before Start.
+func (w *Watcher) SetNotifyFunc(fn func(id, title, body string)) {
+	w.onNotify = fn
+}
+
+// EnsureDirs opens (creating if necessary) the notifications SQLite
+// database, ensures its schema exists, and imports any notifications left
+// over from the old directory-based pipeline. The name predates the move to
+// SQLite but is kept so callers don't need to change.
 func (w *Watcher) EnsureDirs() error {
-	for _, dir := range []string{"pending", "processing", "processed", "archived"} {
-		if err := os.MkdirAll(filepath.Join(w.baseDir, dir), 0755); err != nil {
-			return err
-		}
+	db, err := openStore(w.baseDir)
+	if err != nil {
+		return err
 	}
+	w.db = db
 	return nil
 }
 
-// Submit writes a notification JSON into the pending directory for processing.
+// Submit inserts a pending notification built from fields.
 func (w *Watcher) Submit(fields map[string]string) error {
 	id := fmt.Sprintf("test-%d", time.Now().UnixMilli())
 	n := Notification{
@@ -70,20 +154,40 @@ func (w *Watcher) Submit(fields map[string]string) error {
 		Summary:   fields["summary"],
 		Details:   fields["details"],
 		Priority:  fields["priority"],
+		Tag:       fields["tag"],
 		Source:    fields["source"],
+		Voice:     fields["voice"],
 		CreatedAt: time.Now().UTC().Format(time.RFC3339),
 	}
-	data, err := json.Marshal(n)
-	if err != nil {
+	if err := insertNotification(w.db, n, StatusPending); err != nil {
+		return err
+	}
+	metrics.NotificationsSubmittedTotal.WithLabelValues(n.Source).Inc()
+	return nil
+}
+
+// SubmitRaw inserts an already-decoded notification under the given id, for
+// callers (like the coordinator's forwarding endpoint) that receive a
+// notification pre-serialized rather than as discrete fields.
+func (w *Watcher) SubmitRaw(id string, body []byte) error {
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return fmt.Errorf("invalid notification JSON: %w", err)
+	}
+	n.ID = id
+
+	if err := insertNotification(w.db, n, StatusPending); err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(w.baseDir, "pending", id+".json"), data, 0644)
+	metrics.NotificationsSubmittedTotal.WithLabelValues(n.Source).Inc()
+	return nil
 }
 
 // Start begins the polling loop. Call in a goroutine.
 func (w *Watcher) Start() {
-	// Recover any stale files in processing/ back to pending/
-	w.recoverStale()
+	if err := resetStaleProcessing(w.db); err != nil {
+		log.Printf("notifications: failed to recover stale processing rows: %v", err)
+	}
 
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -94,91 +198,67 @@ func (w *Watcher) Start() {
 			return
 		case <-ticker.C:
 			w.processPending()
+			w.expireStale()
+			w.publishStats()
 		}
 	}
 }
 
-// Stop halts the polling loop.
-func (w *Watcher) Stop() {
-	close(w.stopCh)
-}
-
-// recoverStale moves files from processing/ back to pending/.
-func (w *Watcher) recoverStale() {
-	processingDir := filepath.Join(w.baseDir, "processing")
-	pendingDir := filepath.Join(w.baseDir, "pending")
-
-	entries, err := os.ReadDir(processingDir)
-	if err != nil {
-		return
-	}
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
-			continue
-		}
-		src := filepath.Join(processingDir, e.Name())
-		dst := filepath.Join(pendingDir, e.Name())
-		if err := os.Rename(src, dst); err != nil {
-			log.Printf("notifications: failed to recover %s: %v", e.Name(), err)
-		} else {
-			log.Printf("notifications: recovered stale %s to pending", e.Name())
-		}
-	}
-}
-
-// processPending scans the pending directory and processes files serially.
-func (w *Watcher) processPending() {
-	pendingDir := filepath.Join(w.baseDir, "pending")
-	processingDir := filepath.Join(w.baseDir, "processing")
-	processedDir := filepath.Join(w.baseDir, "processed")
-
-	entries, err := os.ReadDir(pendingDir)
+// expireStale archives processed notifications whose TTLSeconds has elapsed
+// since they were processed, so a hook that sets a short TTL on a transient
+// build notification doesn't leave it cluttering the PWA's list forever.
+func (w *Watcher) expireStale() {
+	expired, err := selectNotifications(w.db, `SELECT `+notificationColumns+` FROM notifications
+		WHERE status = ? AND ttl_seconds > 0`, StatusProcessed)
 	if err != nil {
+		log.Printf("notifications: failed to scan for expired notifications: %v", err)
 		return
 	}
 
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+	now := time.Now()
+	for _, n := range expired {
+		processedAt, err := time.Parse(time.RFC3339, n.ProcessedAt)
+		if err != nil {
 			continue
 		}
-
-		src := filepath.Join(pendingDir, e.Name())
-		mid := filepath.Join(processingDir, e.Name())
-
-		// Move to processing
-		if err := os.Rename(src, mid); err != nil {
-			log.Printf("notifications: failed to move %s to processing: %v", e.Name(), err)
+		if now.Sub(processedAt) < time.Duration(n.TTLSeconds)*time.Second {
 			continue
 		}
 
-		// Read and parse
-		data, err := os.ReadFile(mid)
-		if err != nil {
-			log.Printf("notifications: failed to read %s: %v", e.Name(), err)
+		if _, err := w.db.Exec(`UPDATE notifications SET status = ? WHERE id = ?`, StatusArchived, n.ID); err != nil {
+			log.Printf("notifications: failed to archive expired %s: %v", n.ID, err)
 			continue
 		}
+		log.Printf("notifications: archived %s after %ds TTL", n.ID, n.TTLSeconds)
+		metrics.NotificationsExpiredTotal.WithLabelValues(n.Source).Inc()
+	}
+}
 
-		var notif Notification
-		if err := json.Unmarshal(data, &notif); err != nil {
-			log.Printf("notifications: invalid JSON in %s: %v", e.Name(), err)
-			// Move bad file to archived so it doesn't block the pipeline
-			os.Rename(mid, filepath.Join(w.baseDir, "archived", e.Name()))
-			continue
-		}
+// Stop halts the polling loop.
+func (w *Watcher) Stop() {
+	close(w.stopCh)
+}
 
-		// Default ID from filename stem
-		if notif.ID == "" {
-			notif.ID = strings.TrimSuffix(e.Name(), ".json")
+// processPending claims and processes pending rows one at a time until none
+// remain, synthesizing TTS audio for each.
+func (w *Watcher) processPending() {
+	for {
+		notif, ok, err := claimPending(w.db)
+		if err != nil {
+			log.Printf("notifications: failed to claim a pending row: %v", err)
+			return
+		}
+		if !ok {
+			return
 		}
 
-		// Validate required fields
 		if notif.Title == "" || notif.Summary == "" {
-			log.Printf("notifications: %s missing title or summary, archiving", e.Name())
-			os.Rename(mid, filepath.Join(w.baseDir, "archived", e.Name()))
+			log.Printf("notifications: %s missing title or summary, archiving", notif.ID)
+			finishProcessing(w.db, notif.ID, StatusArchived, "missing title or summary")
 			continue
 		}
 
-		// Generate TTS audio
+		var lastErr string
 		if w.ttsFunc != nil {
 			voice := "default"
 			if w.ttsVoice != nil {
@@ -186,145 +266,186 @@ func (w *Watcher) processPending() {
 					voice = v
 				}
 			}
+			if notif.Voice != "" {
+				voice = notif.Voice
+			}
 
 			if audio, err := w.ttsFunc(notif.Summary, voice, "English"); err == nil {
-				notif.SummaryAudio = base64.StdEncoding.EncodeToString(audio)
+				saveAudio(w.db, notif.ID, audioSummary, audio)
 			} else {
 				log.Printf("notifications: TTS failed for summary: %v", err)
+				lastErr = err.Error()
 			}
 
 			if notif.Details != "" {
-				if audio, err := w.ttsFunc(notif.Details, voice, "English"); err == nil {
-					notif.DetailsAudio = base64.StdEncoding.EncodeToString(audio)
+				if w.ttsStreamFunc != nil {
+					if err := w.streamDetails(notif.ID, notif.Details, voice); err != nil {
+						log.Printf("notifications: streamed TTS failed for details: %v", err)
+						lastErr = err.Error()
+					}
+				} else if audio, err := w.ttsFunc(notif.Details, voice, "English"); err == nil {
+					saveAudio(w.db, notif.ID, audioDetails, audio)
 				} else {
 					log.Printf("notifications: TTS failed for details: %v", err)
+					lastErr = err.Error()
 				}
 			}
 		}
 
-		notif.ProcessedAt = time.Now().UTC().Format(time.RFC3339)
-
-		// Write processed file
-		out, err := json.Marshal(notif)
-		if err != nil {
-			log.Printf("notifications: failed to marshal %s: %v", e.Name(), err)
-			continue
-		}
-
-		dst := filepath.Join(processedDir, e.Name())
-		if err := os.WriteFile(dst, out, 0644); err != nil {
-			log.Printf("notifications: failed to write processed %s: %v", e.Name(), err)
+		if lastErr == "" {
+			if err := finishProcessing(w.db, notif.ID, StatusProcessed, ""); err != nil {
+				log.Printf("notifications: failed to mark %s processed: %v", notif.ID, err)
+				continue
+			}
+			log.Printf("notifications: processed %s (%s)", notif.ID, notif.Title)
+			if w.onNotify != nil {
+				w.onNotify(notif.ID, notif.Title, notif.Summary)
+			}
+		} else if notif.Attempts+1 >= w.maxAttempts {
+			if err := failPermanently(w.db, notif.ID, lastErr); err != nil {
+				log.Printf("notifications: failed to mark %s failed: %v", notif.ID, err)
+				continue
+			}
+			log.Printf("notifications: %s failed after %d attempts, giving up: %s", notif.ID, notif.Attempts+1, lastErr)
+		} else {
+			delay := backoffDelay(notif.Attempts)
+			if err := scheduleRetry(w.db, notif.ID, lastErr, delay); err != nil {
+				log.Printf("notifications: failed to schedule retry for %s: %v", notif.ID, err)
+				continue
+			}
+			log.Printf("notifications: %s TTS failed (attempt %d/%d), retrying in %s", notif.ID, notif.Attempts+1, w.maxAttempts, delay)
+			// Not terminal: ListProcessed won't show this row yet, so skip onReady
+			// rather than prompting the PWA to refresh for nothing.
 			continue
 		}
 
-		// Remove from processing
-		os.Remove(mid)
-		log.Printf("notifications: processed %s (%s)", e.Name(), notif.Title)
-
-		// Notify listeners
 		if w.onReady != nil {
 			w.onReady()
 		}
 	}
 }
 
-// ListProcessed returns all processed notifications sorted newest-first.
-func (w *Watcher) ListProcessed() []Notification {
-	processedDir := filepath.Join(w.baseDir, "processed")
+// streamDetails renders id's Details text via ttsStreamFunc, writing raw
+// chunks to baseDir/<id>.pcm as they arrive and publishing each one through
+// onAudioChunk (if set) so a subscribed PWA can begin playback within
+// ~200ms. The same bytes are also saved as DetailsAudio (base64), same as
+// the non-streaming path, so clients that don't subscribe to the stream
+// still get a working fallback.
+func (w *Watcher) streamDetails(id, text, voice string) error {
+	ch, err := w.ttsStreamFunc(context.Background(), text, voice, "English")
+	if err != nil {
+		return err
+	}
 
-	entries, err := os.ReadDir(processedDir)
+	f, err := os.Create(filepath.Join(w.baseDir, id+".pcm"))
 	if err != nil {
-		return nil
+		return err
 	}
+	defer f.Close()
 
-	var notifs []Notification
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
-			continue
+	var all []byte
+	seq := 0
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return chunk.Err
 		}
-
-		data, err := os.ReadFile(filepath.Join(processedDir, e.Name()))
-		if err != nil {
-			continue
+		if _, err := f.Write(chunk.PCM); err != nil {
+			return err
 		}
-
-		var n Notification
-		if err := json.Unmarshal(data, &n); err != nil {
-			continue
+		all = append(all, chunk.PCM...)
+		if w.onAudioChunk != nil {
+			w.onAudioChunk(id, seq, chunk.PCM, false)
 		}
-		notifs = append(notifs, n)
+		seq++
 	}
+	if w.onAudioChunk != nil {
+		w.onAudioChunk(id, seq, nil, true)
+	}
+
+	return saveAudio(w.db, id, audioDetails, all)
+}
 
-	// Sort newest first by processed_at
-	sort.Slice(notifs, func(i, j int) bool {
-		return notifs[i].ProcessedAt > notifs[j].ProcessedAt
-	})
+// Stats counts notifications currently in each pipeline state.
+func (w *Watcher) Stats() (Stats, error) {
+	counts, err := queueStats(w.db)
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		Pending:   counts[StatusPending],
+		Retrying:  counts[StatusRetrying],
+		Failed:    counts[StatusFailed],
+		Processed: counts[StatusProcessed],
+	}, nil
+}
 
+// publishStats refreshes the voicerelay_notifications_queue gauge so the
+// coordinator's /metrics endpoint reflects the queue without every caller
+// needing to poll Stats() itself.
+func (w *Watcher) publishStats() {
+	stats, err := w.Stats()
+	if err != nil {
+		log.Printf("notifications: failed to gather stats: %v", err)
+		return
+	}
+	metrics.NotificationsQueueGauge.WithLabelValues(StatusPending).Set(int64(stats.Pending))
+	metrics.NotificationsQueueGauge.WithLabelValues(StatusRetrying).Set(int64(stats.Retrying))
+	metrics.NotificationsQueueGauge.WithLabelValues(StatusFailed).Set(int64(stats.Failed))
+	metrics.NotificationsQueueGauge.WithLabelValues(StatusProcessed).Set(int64(stats.Processed))
+}
+
+// ListProcessed returns all processed notifications, plus any that gave up
+// retrying (StatusFailed) so the PWA can surface those as dead letters
+// rather than silently dropping them, sorted newest-first.
+func (w *Watcher) ListProcessed() []Notification {
+	notifs, err := selectNotifications(w.db, `SELECT `+notificationColumns+` FROM notifications
+		WHERE status = ? OR status = ? ORDER BY processed_at DESC`, StatusProcessed, StatusFailed)
+	if err != nil {
+		log.Printf("notifications: failed to list processed notifications: %v", err)
+		return nil
+	}
 	return notifs
 }
 
-// Dismiss moves a notification from processed to archived.
-func (w *Watcher) Dismiss(id string) error {
+// Dismiss moves a processed or failed notification to archived, returning it
+// (so callers can label metrics by its Source) or an error if none matched.
+func (w *Watcher) Dismiss(id string) (Notification, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	processedDir := filepath.Join(w.baseDir, "processed")
-	archivedDir := filepath.Join(w.baseDir, "archived")
-
-	// Find the file with matching ID
-	entries, err := os.ReadDir(processedDir)
+	notifs, err := selectNotifications(w.db, `SELECT `+notificationColumns+` FROM notifications
+		WHERE id = ? AND (status = ? OR status = ?)`, id, StatusProcessed, StatusFailed)
 	if err != nil {
-		return err
+		return Notification{}, err
 	}
-
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
-			continue
-		}
-
-		data, err := os.ReadFile(filepath.Join(processedDir, e.Name()))
-		if err != nil {
-			continue
-		}
-
-		var n Notification
-		if err := json.Unmarshal(data, &n); err != nil {
-			continue
-		}
-
-		if n.ID == id {
-			return os.Rename(
-				filepath.Join(processedDir, e.Name()),
-				filepath.Join(archivedDir, e.Name()),
-			)
-		}
+	if len(notifs) == 0 {
+		return Notification{}, nil
 	}
 
-	return nil
+	if _, err := w.db.Exec(`UPDATE notifications SET status = ? WHERE id = ?`, StatusArchived, id); err != nil {
+		return Notification{}, err
+	}
+	return notifs[0], nil
 }
 
-// DismissAll moves all processed notifications to archived.
-func (w *Watcher) DismissAll() error {
+// DismissAll moves all processed and failed notifications to archived,
+// returning the ones it moved (so callers can label metrics by each one's
+// Source).
+func (w *Watcher) DismissAll() ([]Notification, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
-	processedDir := filepath.Join(w.baseDir, "processed")
-	archivedDir := filepath.Join(w.baseDir, "archived")
-
-	entries, err := os.ReadDir(processedDir)
+	notifs, err := selectNotifications(w.db, `SELECT `+notificationColumns+` FROM notifications WHERE status = ? OR status = ?`, StatusProcessed, StatusFailed)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
-			continue
-		}
-		os.Rename(
-			filepath.Join(processedDir, e.Name()),
-			filepath.Join(archivedDir, e.Name()),
-		)
+	if _, err := w.db.Exec(`UPDATE notifications SET status = ? WHERE status = ? OR status = ?`, StatusArchived, StatusProcessed, StatusFailed); err != nil {
+		return nil, err
 	}
+	return notifs, nil
+}
 
-	return nil
+func encodeAudio(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
 }
@@ -0,0 +1,333 @@
+package notifications
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Status values a notification row moves through. "processing" only ever
+// exists while a single processPending pass is claiming a row — a restart
+// mid-claim resets it back to "pending" (see resetStaleProcessing), so
+// unlike the old directory pipeline there's no file left in limbo to
+// recover by hand.
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusRetrying   = "retrying" // TTS failed; waiting for next_attempt_at before being claimed again
+	StatusProcessed  = "processed"
+	StatusArchived   = "archived"
+	StatusFailed     = "failed" // TTS failed MaxAttempts times; a dead letter the PWA can surface
+)
+
+// audioKind distinguishes the two TTS renders a notification can carry.
+type audioKind string
+
+const (
+	audioSummary audioKind = "summary"
+	audioDetails audioKind = "details"
+)
+
+// openStore opens (creating if necessary) the notifications SQLite database
+// at baseDir/notifications.db, ensures its schema exists, and imports any
+// leftover JSON files from a pre-SQLite install.
+func openStore(baseDir string) (*sql.DB, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, err
+	}
+
+	dbPath := filepath.Join(baseDir, "notifications.db")
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(wal)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // modernc.org/sqlite connections aren't safe to share across goroutines
+
+	if _, err := db.Exec(schemaSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+
+	if err := migrateLegacyJSON(db, baseDir); err != nil {
+		log.Printf("notifications: legacy JSON migration: %v", err)
+	}
+
+	return db, nil
+}
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS notifications (
+	id           TEXT PRIMARY KEY,
+	title        TEXT NOT NULL,
+	summary      TEXT NOT NULL,
+	details      TEXT NOT NULL DEFAULT '',
+	priority     TEXT NOT NULL DEFAULT '',
+	tag          TEXT NOT NULL DEFAULT '',
+	source       TEXT NOT NULL DEFAULT '',
+	voice        TEXT NOT NULL DEFAULT '',
+	ttl_seconds  INTEGER NOT NULL DEFAULT 0,
+	status          TEXT NOT NULL DEFAULT 'pending',
+	attempts        INTEGER NOT NULL DEFAULT 0,
+	last_error      TEXT NOT NULL DEFAULT '',
+	created_at      TEXT NOT NULL,
+	processed_at    TEXT NOT NULL DEFAULT '',
+	next_attempt_at TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
+CREATE INDEX IF NOT EXISTS idx_notifications_processed_at ON notifications(processed_at);
+
+CREATE TABLE IF NOT EXISTS audio_blobs (
+	notification_id TEXT NOT NULL,
+	kind            TEXT NOT NULL,
+	data            BLOB NOT NULL,
+	PRIMARY KEY (notification_id, kind)
+);
+`
+
+// insertNotification inserts n with the given status, ignoring any of n's
+// audio fields (those are stored separately via saveAudio).
+func insertNotification(db *sql.DB, n Notification, status string) error {
+	if n.CreatedAt == "" {
+		n.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	}
+	_, err := db.Exec(`
+		INSERT INTO notifications (id, title, summary, details, priority, tag, source, voice, ttl_seconds, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			title=excluded.title, summary=excluded.summary, details=excluded.details,
+			priority=excluded.priority, tag=excluded.tag, source=excluded.source,
+			voice=excluded.voice, ttl_seconds=excluded.ttl_seconds`,
+		n.ID, n.Title, n.Summary, n.Details, n.Priority, n.Tag, n.Source, n.Voice, n.TTLSeconds, status, n.CreatedAt)
+	return err
+}
+
+// resetStaleProcessing reverts any row left in "processing" by a previous
+// run that didn't shut down cleanly, back to "pending" — the SQLite
+// equivalent of the old recoverStale directory rename.
+func resetStaleProcessing(db *sql.DB) error {
+	_, err := db.Exec(`UPDATE notifications SET status = ? WHERE status = ?`, StatusPending, StatusProcessing)
+	return err
+}
+
+// claimPending atomically claims the oldest row ready to (re)process —
+// either newly pending, or retrying with an elapsed next_attempt_at —
+// returning (Notification{}, false, nil) if none is waiting.
+func claimPending(db *sql.DB) (Notification, bool, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	row := db.QueryRow(`
+		UPDATE notifications SET status = ?
+		WHERE id = (
+			SELECT id FROM notifications
+			WHERE status = ? OR (status = ? AND next_attempt_at <= ?)
+			ORDER BY created_at LIMIT 1
+		)
+		RETURNING id, title, summary, details, priority, tag, source, voice, ttl_seconds, attempts, created_at`,
+		StatusProcessing, StatusPending, StatusRetrying, now)
+
+	var n Notification
+	err := row.Scan(&n.ID, &n.Title, &n.Summary, &n.Details, &n.Priority, &n.Tag, &n.Source, &n.Voice, &n.TTLSeconds, &n.Attempts, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Notification{}, false, nil
+	}
+	if err != nil {
+		return Notification{}, false, err
+	}
+	return n, true, nil
+}
+
+// finishProcessing records a terminal outcome (StatusProcessed or
+// StatusArchived) for a claimed notification — used for success and for the
+// "missing title/summary" early-archive case, neither of which retries.
+func finishProcessing(db *sql.DB, id, status, lastErr string) error {
+	_, err := db.Exec(`
+		UPDATE notifications SET status = ?, last_error = ?, processed_at = ?, attempts = attempts + 1, next_attempt_at = ''
+		WHERE id = ?`,
+		status, lastErr, time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// scheduleRetry records a failed TTS attempt and reschedules the
+// notification for another try after delay, leaving it in StatusRetrying.
+func scheduleRetry(db *sql.DB, id, lastErr string, delay time.Duration) error {
+	_, err := db.Exec(`
+		UPDATE notifications SET status = ?, last_error = ?, attempts = attempts + 1, next_attempt_at = ?
+		WHERE id = ?`,
+		StatusRetrying, lastErr, time.Now().UTC().Add(delay).Format(time.RFC3339), id)
+	return err
+}
+
+// failPermanently moves a notification that has exhausted MaxAttempts to
+// StatusFailed — a dead letter the PWA can surface instead of retrying
+// forever.
+func failPermanently(db *sql.DB, id, lastErr string) error {
+	_, err := db.Exec(`
+		UPDATE notifications SET status = ?, last_error = ?, processed_at = ?, attempts = attempts + 1, next_attempt_at = ''
+		WHERE id = ?`,
+		StatusFailed, lastErr, time.Now().UTC().Format(time.RFC3339), id)
+	return err
+}
+
+// backoffDelay returns how long to wait before retrying a notification that
+// has failed attempts times: 30s * 2^attempts, capped at 1h, with ±20%
+// jitter so a burst of simultaneous failures doesn't retry in lockstep.
+func backoffDelay(attempts int) time.Duration {
+	const (
+		base     = 30 * time.Second
+		maxDelay = time.Hour
+		jitter   = 0.2
+	)
+
+	delay := base
+	for i := 0; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitterRange := float64(delay) * jitter
+	delay += time.Duration(jitterRange*2*rand.Float64() - jitterRange)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// queueStats counts notifications grouped by status, for Watcher.Stats().
+func queueStats(db *sql.DB) (map[string]int, error) {
+	rows, err := db.Query(`SELECT status, COUNT(*) FROM notifications GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// saveAudio stores synthesized WAV bytes for a notification out-of-line from
+// the notifications table, so a long Details render doesn't bloat every row
+// scan.
+func saveAudio(db *sql.DB, id string, kind audioKind, data []byte) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO audio_blobs (notification_id, kind, data) VALUES (?, ?, ?)`, id, string(kind), data)
+	return err
+}
+
+func loadAudio(db *sql.DB, id string, kind audioKind) ([]byte, error) {
+	var data []byte
+	err := db.QueryRow(`SELECT data FROM audio_blobs WHERE notification_id = ? AND kind = ?`, id, string(kind)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
+
+// selectNotifications runs a query expected to return full notification rows
+// (the same column list as claimPending, plus status/last_error/processed_at)
+// and attaches each row's audio.
+func selectNotifications(db *sql.DB, query string, args ...any) ([]Notification, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.Title, &n.Summary, &n.Details, &n.Priority, &n.Tag, &n.Source, &n.Voice,
+			&n.TTLSeconds, &n.Status, &n.Attempts, &n.LastError, &n.CreatedAt, &n.ProcessedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		if audio, err := loadAudio(db, out[i].ID, audioSummary); err == nil && audio != nil {
+			out[i].SummaryAudio = encodeAudio(audio)
+		}
+		if audio, err := loadAudio(db, out[i].ID, audioDetails); err == nil && audio != nil {
+			out[i].DetailsAudio = encodeAudio(audio)
+		}
+	}
+	return out, nil
+}
+
+const notificationColumns = `id, title, summary, details, priority, tag, source, voice, ttl_seconds, status, attempts, last_error, created_at, processed_at`
+
+// legacyNotifDirs are the four directories the pre-SQLite pipeline used,
+// in the status each one implied.
+var legacyNotifDirs = []struct {
+	dir    string
+	status string
+}{
+	{"pending", StatusPending},
+	{"processing", StatusPending}, // a stale in-flight file; treat like recoverStale did
+	{"processed", StatusProcessed},
+	{"archived", StatusArchived},
+}
+
+// migrateLegacyJSON imports any *.json files left over from the old
+// pending/processing/processed/archived directory pipeline into the
+// database, then renames each directory to "<dir>.migrated" so this only
+// ever runs once per install.
+func migrateLegacyJSON(db *sql.DB, baseDir string) error {
+	for _, legacy := range legacyNotifDirs {
+		dir := filepath.Join(baseDir, legacy.dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // already migrated, or never existed
+		}
+
+		imported := 0
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			var n Notification
+			if err := json.Unmarshal(data, &n); err != nil {
+				continue
+			}
+			if n.ID == "" {
+				n.ID = strings.TrimSuffix(e.Name(), ".json")
+			}
+			if err := insertNotification(db, n, legacy.status); err != nil {
+				log.Printf("notifications: importing legacy %s: %v", e.Name(), err)
+				continue
+			}
+			if n.ProcessedAt != "" {
+				db.Exec(`UPDATE notifications SET processed_at = ? WHERE id = ?`, n.ProcessedAt, n.ID)
+			}
+			imported++
+		}
+
+		if imported > 0 {
+			log.Printf("notifications: imported %d legacy notification(s) from %s/", imported, legacy.dir)
+		}
+		os.Rename(dir, dir+".migrated")
+	}
+	return nil
+}
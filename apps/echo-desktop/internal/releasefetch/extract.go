@@ -0,0 +1,282 @@
+package releasefetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// extract writes assetName's archive contents (read from the file at path)
+// into destDir, flattening any single top-level directory in the archive
+// (e.g. "piper/piper" -> "piper"). If wantGlobs is non-empty, only files
+// whose base name matches one of the globs are written. maxBytes caps the
+// total decompressed size written, guarding against zip/tar bombs. It
+// returns the destination paths of every file written.
+func extract(path, assetName, destDir string, wantGlobs []string, maxBytes int64) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	limiter := newExtractLimiter(maxBytes)
+
+	switch {
+	case strings.HasSuffix(assetName, ".zip"):
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return extractZip(f, info.Size(), destDir, wantGlobs, limiter)
+	case strings.HasSuffix(assetName, ".tar.gz") || strings.HasSuffix(assetName, ".tgz"):
+		return extractTarGz(f, destDir, wantGlobs, limiter)
+	case strings.HasSuffix(assetName, ".tar.xz"):
+		return extractTarXz(f, destDir, wantGlobs, limiter)
+	case strings.HasSuffix(assetName, ".gz"):
+		return extractGz(f, destDir, assetName, wantGlobs, limiter)
+	default:
+		// Not a recognized archive — install the asset itself as a single file.
+		return writeFile(f, destDir, assetName, wantGlobs, limiter)
+	}
+}
+
+func wanted(name string, globs []string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	base := filepath.Base(name)
+	for _, g := range globs {
+		if ok, _ := path.Match(g, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTopLevel removes a single leading "dir/" path component, the way
+// most release archives wrap their payload in one top-level folder.
+func stripTopLevel(name string) string {
+	if idx := strings.IndexByte(name, '/'); idx >= 0 {
+		return name[idx+1:]
+	}
+	return name
+}
+
+// safeJoin resolves name against destDir, rejecting any entry that would
+// escape destDir (zip-slip / tar traversal): an absolute path, a ".." path
+// segment, or a cleaned result that falls outside destDir.
+func safeJoin(destDir, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return "", fmt.Errorf("archive entry %q contains a %q path segment", name, "..")
+		}
+	}
+
+	cleanDest := filepath.Clean(destDir)
+	destPath := filepath.Clean(filepath.Join(cleanDest, name))
+	if destPath != cleanDest && !strings.HasPrefix(destPath, cleanDest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q escapes destination directory", name)
+	}
+	return destPath, nil
+}
+
+// extractLimiter caps the total number of decompressed bytes written across
+// an entire archive, so a small download can't zip-bomb its way into
+// exhausting disk space.
+type extractLimiter struct {
+	max       int64
+	remaining int64
+}
+
+func newExtractLimiter(max int64) *extractLimiter {
+	return &extractLimiter{max: max, remaining: max}
+}
+
+func (l *extractLimiter) wrap(r io.Reader) io.Reader {
+	return &limitedReader{r: r, limiter: l}
+}
+
+type limitedReader struct {
+	r       io.Reader
+	limiter *extractLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		lr.limiter.remaining -= int64(n)
+		if lr.limiter.remaining < 0 {
+			return n, fmt.Errorf("archive decompresses to more than %d bytes, aborting", lr.limiter.max)
+		}
+	}
+	return n, err
+}
+
+func writeExtracted(destDir, name string, r io.Reader, limiter *extractLimiter) (string, error) {
+	destPath, err := safeJoin(destDir, name)
+	if err != nil {
+		return "", err
+	}
+
+	if dir := filepath.Dir(destPath); dir != filepath.Clean(destDir) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, limiter.wrap(r)); err != nil {
+		return "", err
+	}
+
+	log.Printf("releasefetch: extracted %s", name)
+	return destPath, nil
+}
+
+func extractZip(r io.ReaderAt, size int64, destDir string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			log.Printf("releasefetch: skipping symlink entry %q", f.Name)
+			continue
+		}
+
+		name := stripTopLevel(f.Name)
+		if name == "" || !wanted(name, wantGlobs) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		destPath, err := writeExtracted(destDir, name, rc, limiter)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		installed = append(installed, destPath)
+	}
+
+	return installed, nil
+}
+
+func extractTarGz(r io.Reader, destDir string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return extractTar(gz, destDir, wantGlobs, limiter)
+}
+
+func extractTarXz(r io.Reader, destDir string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	xr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractTar(xr, destDir, wantGlobs, limiter)
+}
+
+func extractTar(r io.Reader, destDir string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	var installed []string
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeSymlink, tar.TypeLink:
+			log.Printf("releasefetch: skipping symlink entry %q", header.Name)
+			continue
+		case tar.TypeReg:
+			// handled below
+		default:
+			return nil, fmt.Errorf("unsupported tar entry type %q for %s", string(header.Typeflag), header.Name)
+		}
+
+		name := stripTopLevel(header.Name)
+		if name == "" || !wanted(name, wantGlobs) {
+			continue
+		}
+
+		destPath, err := writeExtracted(destDir, name, tr, limiter)
+		if err != nil {
+			return nil, err
+		}
+		installed = append(installed, destPath)
+	}
+
+	return installed, nil
+}
+
+// extractGz handles a raw .gz asset that isn't a tarball, e.g. a single
+// compressed binary. The decompressed file takes the asset's name with the
+// ".gz" suffix stripped.
+func extractGz(r io.Reader, destDir string, assetName string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	name := strings.TrimSuffix(filepath.Base(assetName), ".gz")
+	if !wanted(name, wantGlobs) {
+		return nil, nil
+	}
+
+	destPath, err := writeExtracted(destDir, name, gz, limiter)
+	if err != nil {
+		return nil, err
+	}
+	return []string{destPath}, nil
+}
+
+// writeFile installs a non-archive asset as-is.
+func writeFile(r io.Reader, destDir string, assetName string, wantGlobs []string, limiter *extractLimiter) ([]string, error) {
+	name := filepath.Base(assetName)
+	if !wanted(name, wantGlobs) {
+		return nil, fmt.Errorf("asset %s excluded by wantGlobs", assetName)
+	}
+
+	destPath, err := writeExtracted(destDir, name, r, limiter)
+	if err != nil {
+		return nil, err
+	}
+	return []string{destPath}, nil
+}
@@ -0,0 +1,542 @@
+// Package releasefetch fetches the latest non-prerelease GitHub release for
+// a repo, picks the asset matching the current platform, and installs it to
+// disk with checksum verification and archive extraction. It backs the
+// whisper-server, piper, and voice-model auto-installers, which previously
+// each hard-coded their own asset name and extractor.
+package releasefetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Asset is a single downloadable file attached to a GitHub release.
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Release is the subset of the GitHub releases API response we care about.
+type Release struct {
+	Tag        string  `json:"tag_name"`
+	Assets     []Asset `json:"assets"`
+	Fetched    time.Time
+	Prerelease bool `json:"prerelease"`
+}
+
+// Matcher reports whether an asset name is suitable for the current
+// platform. DefaultMatcher covers the common GOOS/GOARCH naming schemes;
+// callers with unusual asset names (e.g. whisper.cpp's "whisper-bin-x64.zip",
+// which carries no OS token) can supply their own.
+type Matcher func(assetName string) bool
+
+var goosAliases = map[string][]string{
+	"darwin":  {"darwin", "macos", "osx", "mac"},
+	"windows": {"windows", "win"},
+	"linux":   {"linux"},
+}
+
+var archAliases = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64"},
+	"arm64": {"arm64", "aarch64"},
+	"386":   {"386", "x86", "i386"},
+	"arm":   {"arm"},
+}
+
+// DefaultMatcher returns a Matcher that requires the asset name to contain
+// both a GOOS alias (e.g. "darwin" or "macos") and a GOARCH alias (e.g.
+// "amd64" or "x86_64") for the running platform.
+func DefaultMatcher() Matcher {
+	goos := aliasesFor(goosAliases, runtime.GOOS)
+	goarch := aliasesFor(archAliases, runtime.GOARCH)
+
+	return func(assetName string) bool {
+		name := strings.ToLower(assetName)
+		return containsAny(name, goos) && containsAny(name, goarch)
+	}
+}
+
+func aliasesFor(table map[string][]string, key string) []string {
+	if aliases, ok := table[key]; ok {
+		return aliases
+	}
+	return []string{key}
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultMaxExtractedBytes caps how many decompressed bytes Install will
+// write for a Fetcher that leaves MaxExtractedBytes unset, guarding against
+// zip/tar bombs in a compromised or malicious release asset.
+const DefaultMaxExtractedBytes = 500 * 1024 * 1024 // 500 MiB
+
+// Fetcher resolves and installs releases for a single GitHub repo.
+type Fetcher struct {
+	Owner string
+	Repo  string
+
+	// CacheDir overrides where the resolved-release cache is stored.
+	// Defaults to "~/.cache/voice-relay".
+	CacheDir string
+
+	// Interval, if non-zero, is how often Poll re-checks for a new
+	// release in the background.
+	Interval time.Duration
+
+	// MaxExtractedBytes caps the total decompressed size Install will write
+	// for this Fetcher. Zero uses DefaultMaxExtractedBytes.
+	MaxExtractedBytes int64
+}
+
+func (f *Fetcher) maxExtractedBytes() int64 {
+	if f.MaxExtractedBytes > 0 {
+		return f.MaxExtractedBytes
+	}
+	return DefaultMaxExtractedBytes
+}
+
+// New creates a Fetcher for the given GitHub "owner/repo".
+func New(owner, repo string) *Fetcher {
+	return &Fetcher{Owner: owner, Repo: repo}
+}
+
+func (f *Fetcher) apiURL() string {
+	return fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", f.Owner, f.Repo)
+}
+
+func (f *Fetcher) cacheDir() string {
+	if f.CacheDir != "" {
+		return f.CacheDir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".cache/voice-relay"
+	}
+	return filepath.Join(home, ".cache", "voice-relay")
+}
+
+func (f *Fetcher) cacheKey() string {
+	return f.Owner + "/" + f.Repo
+}
+
+type releaseCache struct {
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	ETag      string    `json:"etag"`
+	Release   Release   `json:"release"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (f *Fetcher) cachePath() string {
+	return filepath.Join(f.cacheDir(), "releases.json")
+}
+
+func (f *Fetcher) loadCache() releaseCache {
+	cache := releaseCache{Entries: map[string]cacheEntry{}}
+	data, err := os.ReadFile(f.cachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return releaseCache{Entries: map[string]cacheEntry{}}
+	}
+	if cache.Entries == nil {
+		cache.Entries = map[string]cacheEntry{}
+	}
+	return cache
+}
+
+func (f *Fetcher) saveCache(cache releaseCache) {
+	if err := os.MkdirAll(f.cacheDir(), 0755); err != nil {
+		return
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(f.cachePath(), data, 0644)
+}
+
+// LatestRelease returns the latest non-prerelease release, using the local
+// cache (keyed on ETag) to avoid burning GitHub's API rate limit on every
+// startup. A cache hit (304 Not Modified) returns the cached release as-is.
+func (f *Fetcher) LatestRelease() (*Release, error) {
+	cache := f.loadCache()
+	entry, cached := cache.Entries[f.cacheKey()]
+
+	req, err := http.NewRequest(http.MethodGet, f.apiURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if cached && entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if cached {
+			log.Printf("releasefetch: %s/%s unreachable, using cached release %s: %v", f.Owner, f.Repo, entry.Release.Tag, err)
+			return &entry.Release, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached {
+		return &entry.Release, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		if cached {
+			log.Printf("releasefetch: %s/%s returned %d, using cached release %s", f.Owner, f.Repo, resp.StatusCode, entry.Release.Tag)
+			return &entry.Release, nil
+		}
+		return nil, fmt.Errorf("GitHub API returned %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	release.Fetched = time.Now()
+
+	cache.Entries[f.cacheKey()] = cacheEntry{
+		ETag:      resp.Header.Get("ETag"),
+		Release:   release,
+		FetchedAt: release.Fetched,
+	}
+	f.saveCache(cache)
+
+	return &release, nil
+}
+
+// Poll calls onNewRelease whenever LatestRelease resolves to a different tag
+// than last seen, checking every f.Interval until ctx is cancelled. It is
+// meant to back a future self-update path; it does not install anything
+// itself.
+func (f *Fetcher) Poll(stop <-chan struct{}, onNewRelease func(*Release)) {
+	if f.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	var lastTag string
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			release, err := f.LatestRelease()
+			if err != nil {
+				log.Printf("releasefetch: background check for %s/%s failed: %v", f.Owner, f.Repo, err)
+				continue
+			}
+			if release.Tag != lastTag {
+				lastTag = release.Tag
+				onNewRelease(release)
+			}
+		}
+	}
+}
+
+// pickAsset returns the first asset accepted by matcher, preferring assets
+// that aren't themselves checksum files.
+func pickAsset(assets []Asset, matcher Matcher) (Asset, bool) {
+	for _, a := range assets {
+		if strings.HasSuffix(a.Name, ".sha256") {
+			continue
+		}
+		if matcher(a.Name) {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// sha256Asset finds the sibling "<assetName>.sha256" checksum asset, if any.
+func sha256Asset(assets []Asset, assetName string) (Asset, bool) {
+	for _, a := range assets {
+		if a.Name == assetName+".sha256" {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Install resolves the latest release, downloads the asset matcher selects,
+// verifies it against a sibling "*.sha256" asset when present, and extracts
+// it into destDir. wantGlobs, if non-empty, filters which extracted files
+// are kept (matched against the base name, via path.Match semantics);
+// a nil/empty wantGlobs keeps everything. It returns the paths of the files
+// written into destDir.
+func (f *Fetcher) Install(destDir string, matcher Matcher, wantGlobs []string) ([]string, error) {
+	release, err := f.LatestRelease()
+	if err != nil {
+		return nil, fmt.Errorf("releasefetch: failed to resolve %s/%s release: %w", f.Owner, f.Repo, err)
+	}
+
+	asset, ok := pickAsset(release.Assets, matcher)
+	if !ok {
+		return nil, fmt.Errorf("releasefetch: no asset in %s/%s %s matches this platform", f.Owner, f.Repo, release.Tag)
+	}
+
+	log.Printf("releasefetch: downloading %s %s", asset.Name, release.Tag)
+	tmpPath, gotSum, err := downloadToTempFile(asset.BrowserDownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("releasefetch: failed to download %s: %w", asset.Name, err)
+	}
+	defer os.Remove(tmpPath)
+
+	if sumAsset, ok := sha256Asset(release.Assets, asset.Name); ok {
+		if err := verifySHA256Hex(gotSum, sumAsset.BrowserDownloadURL); err != nil {
+			return nil, fmt.Errorf("releasefetch: checksum mismatch for %s: %w", asset.Name, err)
+		}
+		log.Printf("releasefetch: verified sha256 for %s", asset.Name)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("releasefetch: failed to create %s: %w", destDir, err)
+	}
+
+	installed, err := extract(tmpPath, asset.Name, destDir, wantGlobs, f.maxExtractedBytes())
+	if err != nil {
+		return nil, fmt.Errorf("releasefetch: failed to extract %s: %w", asset.Name, err)
+	}
+
+	return installed, nil
+}
+
+// wantSum downloads a sha256sum-style checksum file (lines of
+// "<hex>  <filename>", or a bare hex digest) and returns the lowercase hex
+// digest it names.
+func wantSum(sumURL string) (string, error) {
+	sumData, err := DownloadBytes(sumURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download checksum: %w", err)
+	}
+	return strings.ToLower(strings.TrimSpace(strings.Fields(strings.TrimSpace(string(sumData)))[0])), nil
+}
+
+// VerifySHA256 downloads a sha256sum-style checksum file and compares it
+// against data's own digest.
+func VerifySHA256(data []byte, sumURL string) error {
+	sum := sha256.Sum256(data)
+	return verifySHA256Hex(hex.EncodeToString(sum[:]), sumURL)
+}
+
+// verifySHA256Hex is VerifySHA256 for a digest that's already been computed
+// (e.g. while streaming a download to disk, where buffering the data again
+// just to hash it would defeat the point).
+func verifySHA256Hex(gotHex, sumURL string) error {
+	want, err := wantSum(sumURL)
+	if err != nil {
+		return err
+	}
+	if gotHex != want {
+		return fmt.Errorf("expected %s, got %s", want, gotHex)
+	}
+	return nil
+}
+
+// DownloadBytes fetches url and returns its full body.
+func DownloadBytes(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("releasefetch: downloaded %d bytes", len(data))
+	return data, nil
+}
+
+// downloadToTempFile streams url into a new temp file rather than buffering
+// the whole body in memory the way DownloadBytes does — release archives run
+// tens of MB, and io.ReadAll there would double RSS during install. It
+// returns the temp file's path (the caller must os.Remove it) and the
+// lowercase hex sha256 digest computed while streaming.
+func downloadToTempFile(url string) (path string, sha256Hex string, err error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp("", "releasefetch-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer tmp.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+
+	log.Printf("releasefetch: downloaded %d bytes", n)
+	return tmp.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DownloadFile fetches url and atomically writes it to dest.
+func DownloadFile(dest, url string) error {
+	return DownloadFileWithProgress(dest, url, nil)
+}
+
+// DownloadFileWithProgress is DownloadFile with a progress callback invoked
+// after every read as bytes arrive — total is the response's Content-Length,
+// or 0 if the server didn't send one. progress may be nil, in which case
+// this behaves exactly like DownloadFile.
+func DownloadFileWithProgress(dest, url string, progress func(bytes, total int64)) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	tmpPath := dest + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader = resp.Body
+	if progress != nil {
+		reader = &countingReader{r: resp.Body, total: resp.ContentLength, progress: progress}
+	}
+
+	n, copyErr := io.Copy(f, reader)
+	closeErr := f.Close()
+	if copyErr != nil {
+		os.Remove(tmpPath)
+		return copyErr
+	}
+	if closeErr != nil {
+		os.Remove(tmpPath)
+		return closeErr
+	}
+
+	log.Printf("releasefetch: downloaded %d bytes", n)
+	return os.Rename(tmpPath, dest)
+}
+
+// DownloadFileResumable is DownloadFileWithProgress, but if dest+".tmp"
+// already exists from an earlier interrupted attempt, resumes it via an
+// HTTP Range request instead of restarting from byte zero — useful for the
+// multi-GB model weights internal/llm's manifest-driven downloads fetch,
+// where losing an almost-complete download to a dropped connection is
+// expensive. Falls back to a full download if the server ignores Range.
+func DownloadFileResumable(dest, url string, progress func(bytes, total int64)) error {
+	tmpPath := dest + ".tmp"
+	var startAt int64
+	if fi, err := os.Stat(tmpPath); err == nil {
+		startAt = fi.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if startAt > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", startAt))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var f *os.File
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		f, err = os.OpenFile(tmpPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case http.StatusOK:
+		// Server ignored Range (or there was nothing to resume): start over.
+		startAt = 0
+		f, err = os.Create(tmpPath)
+	default:
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	total := resp.ContentLength
+	if total > 0 {
+		total += startAt
+	}
+
+	var reader io.Reader = resp.Body
+	if progress != nil {
+		reader = &countingReader{r: resp.Body, read: startAt, total: total, progress: progress}
+	}
+
+	n, copyErr := io.Copy(f, reader)
+	closeErr := f.Close()
+	if copyErr != nil {
+		return copyErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	log.Printf("releasefetch: downloaded %d bytes (resumed at %d)", n, startAt)
+	return os.Rename(tmpPath, dest)
+}
+
+// countingReader wraps an io.Reader, invoking progress with the running byte
+// count (and the response's total, 0 if unknown) after every read.
+type countingReader struct {
+	r        io.Reader
+	read     int64
+	total    int64
+	progress func(bytes, total int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if n > 0 {
+		c.progress(c.read, c.total)
+	}
+	return n, err
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package setup
+
+import "os/exec"
+
+// hideWindow is a no-op outside Windows, which is the only platform that pops a
+// console window for subprocesses started from a GUI app.
+func hideWindow(cmd *exec.Cmd) {}
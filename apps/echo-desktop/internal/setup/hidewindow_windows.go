@@ -0,0 +1,14 @@
+//go:build windows
+
+package setup
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow suppresses the console window Windows would otherwise flash when
+// running tailscale.exe as a subprocess from a GUI app.
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
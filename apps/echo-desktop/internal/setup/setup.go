@@ -65,7 +65,7 @@ func RunWizard(cfg *config.Config) error {
 		if err != nil {
 			log.Printf("Entry dialog cancelled")
 		} else if code != "" {
-			wsURL, err := ResolveCoordinatorURL(code)
+			wsURL, err := ResolveCoordinatorURLWithProviders(code, cfg.ShortURLProviders)
 			if err != nil {
 				_ = zenity.Warning(
 					fmt.Sprintf("Could not connect: %v\n\nYou can edit the config file later.", err),
@@ -102,8 +102,16 @@ func RunWizard(cfg *config.Config) error {
 }
 
 // ResolveCoordinatorURL takes a user-provided input (connection code, short URL, HTTPS URL, or ws:// URL)
-// and resolves it to a WebSocket URL. Returns the URL and an error message if resolution failed.
+// and resolves it to a WebSocket URL using the default provider chain. Returns the URL
+// and an error message if resolution failed.
 func ResolveCoordinatorURL(input string) (string, error) {
+	return ResolveCoordinatorURLWithProviders(input, nil)
+}
+
+// ResolveCoordinatorURLWithProviders is like ResolveCoordinatorURL but resolves bare
+// connection codes using the given ordered provider chain (see config.ShortURLProviders).
+// A nil or empty chain falls back to the built-in is.gd/tinyurl default.
+func ResolveCoordinatorURLWithProviders(input string, providers []string) (string, error) {
 	input = strings.TrimSpace(input)
 
 	// If already a WebSocket URL, use as-is
@@ -114,13 +122,10 @@ func ResolveCoordinatorURL(input string) (string, error) {
 	// If it looks like a bare code (no dots, no slashes, no scheme), treat as short URL code
 	if !strings.Contains(input, ".") && !strings.Contains(input, "/") && !strings.Contains(input, ":") {
 		log.Printf("Treating input as short URL code: %s", input)
-		// Try is.gd first (current provider), then tinyurl (legacy)
-		resolved, err := resolveShortURL("https://is.gd/" + input)
-		if err != nil {
-			resolved, err = resolveShortURL("https://tinyurl.com/" + input)
-		}
+		chain := buildResolverChain(providers)
+		resolved, err := chain.resolve(input)
 		if err != nil {
-			return "", fmt.Errorf("could not resolve code '%s': %v", input, err)
+			return "", err
 		}
 		input = resolved
 	}
@@ -0,0 +1,185 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// shortURLTimeout bounds how long any single provider may take before the chain
+// moves on to the next one.
+const shortURLTimeout = 10 * time.Second
+
+// ShortURLResolver resolves a bare connection code to a coordinator URL (either an
+// http(s) base URL or a fully-formed ws(s):// URL). Implementations should return a
+// descriptive error so failures can be aggregated across the chain.
+type ShortURLResolver interface {
+	// Name identifies the provider for logging and config matching.
+	Name() string
+	// Resolve returns the coordinator URL for code, or an error if this provider
+	// could not resolve it.
+	Resolve(code string) (string, error)
+}
+
+// resolverChain tries each resolver in order and returns the first success.
+type resolverChain struct {
+	resolvers []ShortURLResolver
+}
+
+// resolve runs the chain, short-circuiting on the first resolver that succeeds.
+// If every resolver fails, the returned error aggregates each provider's failure.
+func (c resolverChain) resolve(code string) (string, error) {
+	var errs []string
+	for _, r := range c.resolvers {
+		resolved, err := r.Resolve(code)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", r.Name(), err))
+			continue
+		}
+		if resolved != "" {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("could not resolve code '%s': %s", code, strings.Join(errs, "; "))
+}
+
+// buildResolverChain constructs a resolverChain from config-style provider names.
+// Unknown names are skipped. An empty or nil list falls back to config.DefaultShortURLProviders.
+func buildResolverChain(providers []string) resolverChain {
+	if len(providers) == 0 {
+		providers = []string{"is.gd", "tinyurl"}
+	}
+
+	var chain resolverChain
+	for _, name := range providers {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "is.gd":
+			chain.resolvers = append(chain.resolvers, redirectResolver{name: "is.gd", baseURL: "https://is.gd/"})
+		case "tinyurl":
+			chain.resolvers = append(chain.resolvers, redirectResolver{name: "tinyurl", baseURL: "https://tinyurl.com/"})
+		case "self-hosted":
+			chain.resolvers = append(chain.resolvers, selfHostedResolver{})
+		case "dns":
+			chain.resolvers = append(chain.resolvers, dnsTXTResolver{domain: "voicerelay.dev"})
+		default:
+			// Anything else is treated as a generic "GET <prefix><code> -> {wsUrl}" provider,
+			// e.g. "https://codes.example.com/" for a team-run resolver.
+			chain.resolvers = append(chain.resolvers, genericJSONResolver{name: name, baseURL: name})
+		}
+	}
+	return chain
+}
+
+// redirectResolver resolves a code via a public URL shortener that 301/302s to the
+// real destination (is.gd, tinyurl, ...).
+type redirectResolver struct {
+	name    string
+	baseURL string
+}
+
+func (r redirectResolver) Name() string { return r.name }
+
+func (r redirectResolver) Resolve(code string) (string, error) {
+	return resolveShortURL(r.baseURL + code)
+}
+
+// genericJSONResolver resolves a code by GETting "<baseURL><code>" and expecting a
+// JSON body of the form {"wsUrl": "wss://..."}.
+type genericJSONResolver struct {
+	name    string
+	baseURL string
+}
+
+func (r genericJSONResolver) Name() string { return r.name }
+
+func (r genericJSONResolver) Resolve(code string) (string, error) {
+	client := &http.Client{Timeout: shortURLTimeout}
+	resp, err := client.Get(strings.TrimRight(r.baseURL, "/") + "/" + code)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		WebSocketURL string `json:"wsUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid response: %w", err)
+	}
+	if body.WebSocketURL == "" {
+		return "", fmt.Errorf("response missing wsUrl")
+	}
+	return body.WebSocketURL, nil
+}
+
+// selfHostedResolver asks a coordinator directly for the URL behind a code via its
+// own /code/{code} endpoint, so teams can avoid third-party URL shorteners entirely
+// when the coordinator is reachable over Tailscale or a Funnel URL.
+type selfHostedResolver struct{}
+
+func (selfHostedResolver) Name() string { return "self-hosted" }
+
+func (selfHostedResolver) Resolve(code string) (string, error) {
+	ts := DetectTailscale()
+	if !ts.Available || ts.FunnelURL == "" {
+		return "", fmt.Errorf("no Tailscale Funnel URL available")
+	}
+
+	client := &http.Client{Timeout: shortURLTimeout}
+	resp, err := client.Get(strings.TrimRight(ts.FunnelURL, "/") + "/code/" + code)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		WebSocketURL string `json:"wsUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid response: %w", err)
+	}
+	if body.WebSocketURL == "" {
+		return "", fmt.Errorf("response missing wsUrl")
+	}
+	return body.WebSocketURL, nil
+}
+
+// dnsTXTResolver resolves a code by looking up a _voicerelay.<code>.<domain> TXT
+// record, letting teams publish codes through their own DNS instead of a shortener.
+type dnsTXTResolver struct {
+	domain string
+}
+
+func (dnsTXTResolver) Name() string { return "dns" }
+
+func (r dnsTXTResolver) Resolve(code string) (string, error) {
+	name := fmt.Sprintf("_voicerelay.%s.%s", code, r.domain)
+
+	resolver := net.Resolver{}
+	ctx, cancel := context.WithTimeout(context.Background(), shortURLTimeout)
+	defer cancel()
+
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("TXT lookup failed: %w", err)
+	}
+	for _, rec := range records {
+		rec = strings.TrimSpace(rec)
+		if rec != "" {
+			return rec, nil
+		}
+	}
+	return "", fmt.Errorf("no TXT record found for %s", name)
+}
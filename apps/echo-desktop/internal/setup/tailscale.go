@@ -1,6 +1,7 @@
 package setup
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,8 +10,15 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"tailscale.com/client/tailscale"
 )
 
+// localClientTimeout bounds calls to the local Tailscale daemon's LocalAPI
+// socket, so we fall back to the CLI promptly when tailscaled isn't running
+// or the socket isn't reachable.
+const localClientTimeout = 2 * time.Second
+
 // TailscaleInfo holds detected Tailscale network information.
 type TailscaleInfo struct {
 	Available bool
@@ -35,8 +43,33 @@ type funnelStatus struct {
 	} `json:"Web"`
 }
 
-// DetectTailscale runs `tailscale status --json` and extracts the local Tailscale IP and DNS name.
+// DetectTailscale queries the local Tailscale daemon over its LocalAPI
+// socket for the local Tailscale IP and DNS name, falling back to
+// `tailscale status --json` if the socket can't be reached.
 func DetectTailscale() TailscaleInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), localClientTimeout)
+	defer cancel()
+
+	var lc tailscale.LocalClient
+	st, err := lc.Status(ctx)
+	if err != nil {
+		return detectTailscaleCLI()
+	}
+
+	info := TailscaleInfo{Available: true}
+	if st.Self != nil {
+		if len(st.Self.TailscaleIPs) > 0 {
+			info.IP = st.Self.TailscaleIPs[0].String()
+		}
+		info.DNSName = strings.TrimSuffix(st.Self.DNSName, ".")
+	}
+
+	return info
+}
+
+// detectTailscaleCLI is the legacy fallback used when the LocalAPI socket
+// isn't reachable.
+func detectTailscaleCLI() TailscaleInfo {
 	info := TailscaleInfo{}
 
 	cmd := exec.Command("tailscale", "status", "--json")
@@ -60,8 +93,38 @@ func DetectTailscale() TailscaleInfo {
 	return info
 }
 
-// DetectFunnel checks if Tailscale Funnel/Serve is configured and returns the URL.
+// DetectFunnel checks if Tailscale Funnel/Serve is configured and returns the
+// URL, preferring the LocalAPI serve config and falling back to the CLI.
 func DetectFunnel() string {
+	ctx, cancel := context.WithTimeout(context.Background(), localClientTimeout)
+	defer cancel()
+
+	var lc tailscale.LocalClient
+	cfg, err := lc.GetServeConfig(ctx)
+	if err != nil {
+		return detectFunnelCLI()
+	}
+	if cfg == nil {
+		return ""
+	}
+
+	for hostPort, on := range cfg.AllowFunnel {
+		if !on {
+			continue
+		}
+		host := string(hostPort)
+		if idx := strings.LastIndex(host, ":"); idx > 0 {
+			host = host[:idx]
+		}
+		return fmt.Sprintf("https://%s", host)
+	}
+
+	return ""
+}
+
+// detectFunnelCLI is the legacy fallback used when the LocalAPI serve config
+// can't be read.
+func detectFunnelCLI() string {
 	cmd := exec.Command("tailscale", "funnel", "status", "--json")
 	hideWindow(cmd)
 	out, err := cmd.Output()
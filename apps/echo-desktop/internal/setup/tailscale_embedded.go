@@ -0,0 +1,67 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+
+	"tailscale.com/tsnet"
+)
+
+// EmbeddedNode wraps a tsnet.Server, letting the coordinator join the
+// tailnet directly (no system tailscaled required) for headless or CI
+// environments where the Tailscale client isn't installed.
+type EmbeddedNode struct {
+	srv *tsnet.Server
+}
+
+// NewEmbeddedNode brings up an in-process Tailscale node under hostname,
+// authenticating with authKey. stateDir is passed through as the node's
+// state directory (tsnet.Server.Dir); an empty stateDir lets tsnet pick its
+// default under os.UserConfigDir.
+func NewEmbeddedNode(ctx context.Context, hostname, authKey, stateDir string) (*EmbeddedNode, error) {
+	srv := &tsnet.Server{
+		Dir:       stateDir,
+		Hostname:  hostname,
+		AuthKey:   authKey,
+		Ephemeral: true,
+	}
+
+	if _, err := srv.Up(ctx); err != nil {
+		srv.Close()
+		return nil, fmt.Errorf("tsnet: failed to join tailnet: %w", err)
+	}
+
+	return &EmbeddedNode{srv: srv}, nil
+}
+
+// Info reports the embedded node's IP and DNS name in the same shape as
+// DetectTailscale, so callers can treat an embedded node like any other
+// Tailscale connection.
+func (n *EmbeddedNode) Info(ctx context.Context) TailscaleInfo {
+	info := TailscaleInfo{}
+
+	lc, err := n.srv.LocalClient()
+	if err != nil {
+		return info
+	}
+
+	st, err := lc.Status(ctx)
+	if err != nil {
+		return info
+	}
+
+	info.Available = true
+	if st.Self != nil {
+		if len(st.Self.TailscaleIPs) > 0 {
+			info.IP = st.Self.TailscaleIPs[0].String()
+		}
+		info.DNSName = st.Self.DNSName
+	}
+
+	return info
+}
+
+// Close shuts down the embedded node and releases its tsnet state.
+func (n *EmbeddedNode) Close() error {
+	return n.srv.Close()
+}
@@ -0,0 +1,167 @@
+// Package sounds resolves which cue file, if any, should play for a hook
+// event before its spoken body. It's a dunst-style routing table — rules in
+// sounds.yaml match on (event, matcher, priority, repo, tool_name) the same
+// way dunst's notification rules match on stack tag and urgency — instead of
+// voice-relay hard-coding a sound file per hook in the shim scripts.
+package sounds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/voice-relay/echo-desktop/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule maps one combination of hook metadata to a sound file. Every match
+// field is optional and acts as a wildcard when empty; Resolve tries rules
+// in order and the first full match wins.
+type Rule struct {
+	// Event is the hook kind, e.g. "stop", "ask", "notification".
+	Event    string `yaml:"event,omitempty"`
+	Matcher  string `yaml:"matcher,omitempty"`
+	Priority string `yaml:"priority,omitempty"`
+	Repo     string `yaml:"repo,omitempty"`
+	ToolName string `yaml:"tool_name,omitempty"`
+	// Sound is a filename resolved against the sounds directory, or an
+	// absolute path.
+	Sound string `yaml:"sound"`
+}
+
+// Table is the parsed contents of sounds.yaml.
+type Table struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Event describes one hook firing — the dimensions a Rule can match on.
+type Event struct {
+	Kind     string
+	Matcher  string
+	Priority string
+	Repo     string
+	ToolName string
+}
+
+// defaultTable seeds sounds.yaml the first time hooks are installed.
+var defaultTable = Table{
+	Rules: []Rule{
+		{Event: "stop", Sound: "chime.wav"},
+		{Event: "ask", Sound: "ask.wav"},
+		{Event: "notification", Sound: "ding.wav"},
+		{Priority: "error", Sound: "error.wav"},
+	},
+}
+
+// tablePath returns the path to sounds.yaml.
+func tablePath() string {
+	return filepath.Join(config.Dir(), "sounds.yaml")
+}
+
+// soundsDir returns the directory relative Sound filenames resolve against.
+func soundsDir() string {
+	return filepath.Join(config.Dir(), "sounds")
+}
+
+// Install creates the sounds directory and seeds sounds.yaml with
+// defaultTable the first time it's called. It never overwrites an existing
+// table, so user edits survive a hook reinstall.
+func Install() error {
+	if err := os.MkdirAll(soundsDir(), 0755); err != nil {
+		return fmt.Errorf("create sounds dir: %w", err)
+	}
+
+	path := tablePath()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	data, err := yaml.Marshal(defaultTable)
+	if err != nil {
+		return fmt.Errorf("marshal default sounds table: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadTable reads sounds.yaml, falling back to defaultTable if it hasn't
+// been seeded yet.
+func loadTable() (Table, error) {
+	data, err := os.ReadFile(tablePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultTable, nil
+		}
+		return Table{}, fmt.Errorf("read sounds table: %w", err)
+	}
+
+	var t Table
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return Table{}, fmt.Errorf("parse sounds table: %w", err)
+	}
+	return t, nil
+}
+
+// Resolve returns the absolute path of the sound that should play for ev, or
+// "" if no rule matches or every matching rule's sound file is missing.
+func Resolve(ev Event) string {
+	table, err := loadTable()
+	if err != nil {
+		return ""
+	}
+
+	dir := soundsDir()
+	for _, rule := range table.Rules {
+		if rule.Sound == "" || !ruleMatches(rule, ev) {
+			continue
+		}
+		sp := rule.Sound
+		if !filepath.IsAbs(sp) {
+			sp = filepath.Join(dir, sp)
+		}
+		if _, err := os.Stat(sp); err != nil {
+			continue
+		}
+		return sp
+	}
+	return ""
+}
+
+// Status reports, per sound file named in the table, whether it exists on
+// disk — so the UI can flag a rule that references a file nobody dropped
+// into the sounds directory yet.
+func Status() map[string]bool {
+	table, err := loadTable()
+	if err != nil {
+		return map[string]bool{}
+	}
+
+	dir := soundsDir()
+	status := make(map[string]bool, len(table.Rules))
+	for _, rule := range table.Rules {
+		if rule.Sound == "" {
+			continue
+		}
+		sp := rule.Sound
+		if !filepath.IsAbs(sp) {
+			sp = filepath.Join(dir, sp)
+		}
+		_, err := os.Stat(sp)
+		status[rule.Sound] = err == nil
+	}
+	return status
+}
+
+func ruleMatches(rule Rule, ev Event) bool {
+	return fieldMatches(rule.Event, ev.Kind) &&
+		fieldMatches(rule.Matcher, ev.Matcher) &&
+		fieldMatches(rule.Priority, ev.Priority) &&
+		fieldMatches(rule.Repo, ev.Repo) &&
+		fieldMatches(rule.ToolName, ev.ToolName)
+}
+
+// fieldMatches treats an empty rule field as a wildcard and compares
+// case-insensitively otherwise.
+func fieldMatches(ruleField, eventField string) bool {
+	return ruleField == "" || strings.EqualFold(ruleField, eventField)
+}
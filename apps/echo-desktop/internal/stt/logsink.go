@@ -0,0 +1,256 @@
+package stt
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogSink receives whisper-server's subprocess stdout/stderr, one Write per
+// line (see Engine.start's teeOutput). Stdout and stderr are teed to the
+// same sink from two goroutines, so implementations must be safe for
+// concurrent use.
+type LogSink interface {
+	io.Writer
+}
+
+// ConsoleSink writes straight through to an underlying writer, matching the
+// old cmd.Stdout = os.Stdout / cmd.Stderr = os.Stderr behavior.
+type ConsoleSink struct {
+	io.Writer
+}
+
+// NewConsoleSink returns a LogSink that writes to w.
+func NewConsoleSink(w io.Writer) ConsoleSink {
+	return ConsoleSink{Writer: w}
+}
+
+// MultiSink fans every write out to all of its sinks, like io.MultiWriter
+// but keeping LogSink's identity so it can be passed wherever a LogSink is
+// expected. A failed write to one sink is logged and doesn't stop the others.
+type MultiSink struct {
+	sinks []LogSink
+}
+
+// NewMultiSink returns a LogSink that writes to every one of sinks.
+func NewMultiSink(sinks ...LogSink) MultiSink {
+	return MultiSink{sinks: sinks}
+}
+
+func (m MultiSink) Write(p []byte) (int, error) {
+	for _, s := range m.sinks {
+		if _, err := s.Write(p); err != nil {
+			log.Printf("stt: log sink write failed: %v", err)
+		}
+	}
+	return len(p), nil
+}
+
+// FileSink writes whisper-server's logs to a rotating file, à la lumberjack:
+// once the current file reaches MaxSizeMB it's renamed aside, gzip
+// compressed, and a fresh file started in its place; backups past
+// MaxBackups, or older than MaxAgeDays, are pruned on each rotation. A zero
+// value for MaxSizeMB, MaxBackups, or MaxAgeDays disables that limit.
+type FileSink struct {
+	Dir        string // directory the log file and its rotated backups live in
+	Name       string // base log file name, e.g. "whisper-server.log"
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink returns a FileSink writing dir/name, rotating per maxSizeMB
+// and pruning backups per maxBackups/maxAgeDays.
+func NewFileSink(dir, name string, maxSizeMB, maxBackups, maxAgeDays int) *FileSink {
+	return &FileSink{Dir: dir, Name: name, MaxSizeMB: maxSizeMB, MaxBackups: maxBackups, MaxAgeDays: maxAgeDays}
+}
+
+func (f *FileSink) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.openLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxSizeMB > 0 && f.size+int64(len(p)) > int64(f.MaxSizeMB)*1024*1024 {
+		if err := f.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *FileSink) path() string {
+	return filepath.Join(f.Dir, f.Name)
+}
+
+// openLocked opens (or creates) the live log file. Caller must hold f.mu.
+func (f *FileSink) openLocked() error {
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotateLocked closes the current file, renames it aside with a timestamp
+// suffix, gzips the renamed copy, prunes old backups, and opens a fresh file
+// in its place. Caller must hold f.mu.
+func (f *FileSink) rotateLocked() error {
+	f.file.Close()
+	f.file = nil
+
+	backupPath := fmt.Sprintf("%s.%s", f.path(), time.Now().UTC().Format("20060102T150405"))
+	if err := os.Rename(f.path(), backupPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := gzipAndRemove(backupPath); err != nil {
+		log.Printf("stt: failed to compress rotated log %s: %v", backupPath, err)
+	}
+
+	f.prune()
+
+	return f.openLocked()
+}
+
+// gzipAndRemove compresses path to path+".gz" and removes the uncompressed
+// copy. A missing path (nothing to rotate yet) is not an error.
+func gzipAndRemove(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune removes rotated backups past MaxBackups (oldest first) or older
+// than MaxAgeDays. Caller must hold f.mu.
+func (f *FileSink) prune() {
+	if f.MaxBackups <= 0 && f.MaxAgeDays <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(f.path() + ".*.gz")
+	if err != nil {
+		return
+	}
+	sort.Strings(matches) // the timestamp suffix sorts chronologically
+
+	if f.MaxAgeDays > 0 {
+		cutoff := time.Now().Add(-time.Duration(f.MaxAgeDays) * 24 * time.Hour)
+		kept := matches[:0]
+		for _, m := range matches {
+			if backupTime(m).Before(cutoff) {
+				os.Remove(m)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		matches = kept
+	}
+
+	if f.MaxBackups > 0 && len(matches) > f.MaxBackups {
+		for _, m := range matches[:len(matches)-f.MaxBackups] {
+			os.Remove(m)
+		}
+	}
+}
+
+// backupTime parses the "<name>.<timestamp>.gz" suffix rotateLocked writes.
+// A name that doesn't match (shouldn't happen) sorts as the zero time, i.e.
+// oldest, so it's the first pruned.
+func backupTime(path string) time.Time {
+	base := strings.TrimSuffix(filepath.Base(path), ".gz")
+	parts := strings.Split(base, ".")
+	if len(parts) < 2 {
+		return time.Time{}
+	}
+	t, err := time.Parse("20060102T150405", parts[len(parts)-1])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// LogEntry is one parsed whisper-server log line.
+type LogEntry struct {
+	Time    time.Time // zero if the line didn't carry a recognizable timestamp
+	Level   string    // "info", "warn", or "error"
+	Message string
+}
+
+// parseLogLine does a best-effort parse of a whisper-server log line, which
+// looks like "[12:34:56.789] some message" with no level marker of its own
+// (unlike llama-server's "ERROR:"-prefixed lines) — so Level is inferred
+// from the message text instead. Lines that don't start with a timestamp
+// are returned with a zero Time and the line verbatim as Message.
+func parseLogLine(line string) LogEntry {
+	entry := LogEntry{Level: "info", Message: line}
+
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 {
+			if t, err := time.Parse("15:04:05.000", line[1:end]); err == nil {
+				entry.Time = t
+				entry.Message = strings.TrimSpace(line[end+1:])
+			}
+		}
+	}
+
+	switch lower := strings.ToLower(entry.Message); {
+	case strings.Contains(lower, "error"), strings.Contains(lower, "failed"):
+		entry.Level = "error"
+	case strings.Contains(lower, "warn"):
+		entry.Level = "warn"
+	}
+
+	return entry
+}
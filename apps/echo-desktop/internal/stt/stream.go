@@ -0,0 +1,117 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log"
+	"time"
+)
+
+// Partial is one hypothesis TranscribeStream emits. Interim partials may
+// still change as more audio arrives; the last one sent (IsFinal true)
+// won't.
+type Partial struct {
+	Text    string
+	IsFinal bool
+}
+
+// streamWindow is how often TranscribeStream re-transcribes the buffered
+// audio so far. whisper-server has no incremental protocol of its own, so
+// this is the VAD-less sliding-window approach: repost the growing tail and
+// let whisper.cpp's own decoder figure out the stable prefix each time.
+const streamWindow = 300 * time.Millisecond
+
+// Streamed audio is assumed to be raw 16kHz mono 16-bit PCM, matching what
+// whisper.cpp expects without a resample step.
+const (
+	streamSampleRate    = 16000
+	streamChannels      = 1
+	streamBitsPerSample = 16
+)
+
+// TranscribeStream transcribes audio incrementally instead of waiting for a
+// complete clip: each frame pushed onto audio is appended to a growing
+// buffer, which gets re-sent to whisper-server roughly every streamWindow.
+// Every re-transcription whose text differs from the last one emitted is
+// sent as an interim Partial; once audio is closed (or ctx is canceled) the
+// last hypothesis is sent once more with IsFinal set.
+//
+// Callers are expected to range over the returned channel until it closes.
+func (e *Engine) TranscribeStream(ctx context.Context, audio <-chan []byte) (<-chan Partial, error) {
+	out := make(chan Partial)
+	go e.runStream(ctx, audio, out)
+	return out, nil
+}
+
+func (e *Engine) runStream(ctx context.Context, audio <-chan []byte, out chan<- Partial) {
+	defer close(out)
+
+	var buf bytes.Buffer
+	var lastText string
+
+	ticker := time.NewTicker(streamWindow)
+	defer ticker.Stop()
+
+	transcribeBuffered := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		wav := pcmToWav(buf.Bytes())
+		text, err := e.Transcribe(wav, "stream.wav")
+		if err != nil {
+			log.Printf("stt: interim transcription failed: %v", err)
+			return
+		}
+		if text == lastText {
+			return
+		}
+		lastText = text
+		select {
+		case out <- Partial{Text: text}:
+		case <-ctx.Done():
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			out <- Partial{Text: lastText, IsFinal: true}
+			return
+		case frame, ok := <-audio:
+			if !ok {
+				transcribeBuffered()
+				out <- Partial{Text: lastText, IsFinal: true}
+				return
+			}
+			buf.Write(frame)
+		case <-ticker.C:
+			transcribeBuffered()
+		}
+	}
+}
+
+// pcmToWav wraps raw 16kHz mono 16-bit PCM in a minimal WAV header so
+// whisper-server's /inference endpoint, which expects a decodable container
+// rather than bare samples, can read it.
+func pcmToWav(pcm []byte) []byte {
+	const byteRate = streamSampleRate * streamChannels * streamBitsPerSample / 8
+	const blockAlign = streamChannels * streamBitsPerSample / 8
+
+	buf := make([]byte, 44+len(pcm))
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+len(pcm)))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1)
+	binary.LittleEndian.PutUint16(buf[22:24], streamChannels)
+	binary.LittleEndian.PutUint32(buf[24:28], streamSampleRate)
+	binary.LittleEndian.PutUint32(buf[28:32], byteRate)
+	binary.LittleEndian.PutUint16(buf[32:34], blockAlign)
+	binary.LittleEndian.PutUint16(buf[34:36], streamBitsPerSample)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(len(pcm)))
+	copy(buf[44:], pcm)
+	return buf
+}
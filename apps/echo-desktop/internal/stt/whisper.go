@@ -1,6 +1,7 @@
 package stt
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -22,11 +23,20 @@ type Engine struct {
 	port       int
 	cmd        *exec.Cmd
 	apiURL     string
+
+	sink    LogSink
+	onError func(LogEntry)
 }
 
-// NewEngine creates a new STT engine. It expects the paths to the whisper model
-// and the whisper-server binary.
-func NewEngine(modelPath, serverPath string, port int) (*Engine, error) {
+// NewEngine creates a new STT engine. It expects the paths to the whisper
+// model and the whisper-server binary. Subprocess stdout/stderr are teed
+// line-by-line into sink (a nil sink falls back to a ConsoleSink on
+// os.Stderr, matching the old cmd.Stdout/Stderr = os.Stdout/os.Stderr
+// behavior); each line is also parsed into a LogEntry, and onError, if
+// non-nil, is called for lines that parse as level "error" so a caller can
+// surface whisper-server failures to the UI instead of them only ever
+// reaching a log file.
+func NewEngine(modelPath, serverPath string, port int, sink LogSink, onError func(LogEntry)) (*Engine, error) {
 	if _, err := os.Stat(modelPath); err != nil {
 		return nil, fmt.Errorf("model not found: %s", modelPath)
 	}
@@ -34,11 +44,17 @@ func NewEngine(modelPath, serverPath string, port int) (*Engine, error) {
 		return nil, fmt.Errorf("whisper-server not found: %s", serverPath)
 	}
 
+	if sink == nil {
+		sink = NewConsoleSink(os.Stderr)
+	}
+
 	e := &Engine{
 		modelPath:  modelPath,
 		serverPath: serverPath,
 		port:       port,
 		apiURL:     fmt.Sprintf("http://127.0.0.1:%d", port),
+		sink:       sink,
+		onError:    onError,
 	}
 
 	if err := e.start(); err != nil {
@@ -56,8 +72,15 @@ func (e *Engine) start() error {
 	}
 
 	e.cmd = exec.Command(e.serverPath, args...)
-	e.cmd.Stdout = os.Stdout
-	e.cmd.Stderr = os.Stderr
+
+	stdout, err := e.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := e.cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
 
 	// On Windows, hide the console window
 	setSysProcAttr(e.cmd)
@@ -66,6 +89,9 @@ func (e *Engine) start() error {
 		return err
 	}
 
+	go e.teeOutput(stdout)
+	go e.teeOutput(stderr)
+
 	log.Printf("whisper-server starting on port %d (pid %d)", e.port, e.cmd.Process.Pid)
 
 	// Wait for server to be ready
@@ -78,6 +104,22 @@ func (e *Engine) start() error {
 	return nil
 }
 
+// teeOutput reads r line by line, writing each line to e.sink and, for
+// lines that parse as level "error", calling e.onError. It returns once r
+// hits EOF, which happens when the whisper-server subprocess exits.
+func (e *Engine) teeOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Fprintln(e.sink, line)
+
+		entry := parseLogLine(line)
+		if entry.Level == "error" && e.onError != nil {
+			e.onError(entry)
+		}
+	}
+}
+
 func (e *Engine) waitReady(timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
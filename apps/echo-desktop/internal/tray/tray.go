@@ -2,6 +2,7 @@ package tray
 
 import (
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -16,12 +17,15 @@ import (
 
 // Callbacks holds function references for tray actions.
 type Callbacks struct {
-	OnReconnect func()
-	OnQuit      func()
+	OnReconnect               func()
+	OnQuit                    func()
+	OnReloadTools             func() error       // re-scans the talk agent's tools dir; nil hides the menu item
+	OnDesktopNotificationsSet func(enabled bool) // called when the "Desktop Notifications" checkbox is toggled
 }
 
 var (
 	mStatus   *systray.MenuItem
+	mName     *systray.MenuItem
 	connected bool
 )
 
@@ -36,7 +40,7 @@ func SetupMenu(cfg *config.Config, cb Callbacks) {
 
 	systray.AddSeparator()
 
-	mName := systray.AddMenuItem(fmt.Sprintf("Device: %s", cfg.Name), "Device name")
+	mName = systray.AddMenuItem(fmt.Sprintf("Device: %s", cfg.Name), "Device name")
 	mName.Disable()
 
 	// Connection info and QR code
@@ -80,6 +84,12 @@ func SetupMenu(cfg *config.Config, cb Callbacks) {
 
 	mConfig := systray.AddMenuItem("Open Config...", "Open configuration file")
 	mUpdate := systray.AddMenuItem("Check for Updates", "Check for new version")
+	mDesktopNotif := systray.AddMenuItemCheckbox("Desktop Notifications", "Post notifications as native OS toasts", cfg.DesktopNotifications)
+
+	var mReloadTools *systray.MenuItem
+	if cb.OnReloadTools != nil {
+		mReloadTools = systray.AddMenuItem("Reload Tools", "Re-scan the talk agent's tools directory")
+	}
 
 	systray.AddSeparator()
 
@@ -96,6 +106,10 @@ func SetupMenu(cfg *config.Config, cb Callbacks) {
 		if mQR != nil {
 			qrCh = mQR.ClickedCh
 		}
+		var reloadToolsCh <-chan struct{}
+		if mReloadTools != nil {
+			reloadToolsCh = mReloadTools.ClickedCh
+		}
 
 		for {
 			select {
@@ -122,6 +136,26 @@ func SetupMenu(cfg *config.Config, cb Callbacks) {
 					}
 					systray.Quit()
 				})
+			case <-reloadToolsCh:
+				if cb.OnReloadTools != nil {
+					go func() {
+						if err := cb.OnReloadTools(); err != nil {
+							log.Printf("tray: reloading tools: %v", err)
+						}
+					}()
+				}
+			case <-mDesktopNotif.ClickedCh:
+				enabled := !mDesktopNotif.Checked()
+				if enabled {
+					mDesktopNotif.Check()
+				} else {
+					mDesktopNotif.Uncheck()
+				}
+				cfg.DesktopNotifications = enabled
+				cfg.Save()
+				if cb.OnDesktopNotificationsSet != nil {
+					cb.OnDesktopNotificationsSet(enabled)
+				}
 			case <-mQuit.ClickedCh:
 				if cb.OnQuit != nil {
 					cb.OnQuit()
@@ -140,6 +174,15 @@ func wsToHTTP(wsURL string) string {
 	return u
 }
 
+// UpdateConfig refreshes the menu items that mirror config values after a hot
+// reload. Items whose presence depends on config (e.g. the coordinator/QR
+// entries) are left alone since rebuilding the menu layout requires a restart.
+func UpdateConfig(cfg *config.Config) {
+	if mName != nil {
+		mName.SetTitle(fmt.Sprintf("Device: %s", cfg.Name))
+	}
+}
+
 // UpdateStatus updates the systray icon and status text.
 func UpdateStatus(isConnected bool, status string) {
 	connected = isConnected
@@ -0,0 +1,233 @@
+package tts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Encoder converts a stream of raw PCM chunks (as produced by
+// Engine.SynthesizeStream) into an encoded container, delivering the
+// container's bytes on the returned channel as they become available so
+// playback can start before the whole utterance has been encoded.
+type Encoder interface {
+	// Name identifies the format, as negotiated by the "format" field on
+	// /tts/stream — "wav", "opus", or "flac".
+	Name() string
+	// ContentType is the MIME type to send with the encoded stream.
+	ContentType() string
+	// Encode consumes pcm and returns a channel of encoded output chunks.
+	// sampleRate/channels/bitsPerSample describe the PCM in pcm.
+	Encode(ctx context.Context, pcm <-chan AudioChunk, sampleRate, channels, bitsPerSample int) (<-chan AudioChunk, error)
+}
+
+// Encoders is keyed by format name, so a websocket/HTTP handshake's format
+// negotiation can look one up directly.
+var Encoders = map[string]Encoder{
+	"wav":  WAVEncoder{},
+	"opus": OpusEncoder{},
+	"flac": FLACEncoder{},
+}
+
+// EncoderNamed returns the Encoder registered under name, defaulting to WAV
+// (today's only format) for an empty name.
+func EncoderNamed(name string) (Encoder, error) {
+	if name == "" {
+		name = "wav"
+	}
+	enc, ok := Encoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio format %q", name)
+	}
+	return enc, nil
+}
+
+// WAVEncoder is the original behavior: a streaming WAV header with the RIFF
+// and data chunk sizes left at the 0xFFFFFFFF "read until EOF" convention
+// (see streamingWAVHeader), followed by raw PCM as it arrives. It's ~2x the
+// bytes of Opus at comparable quality, but needs no external binary.
+type WAVEncoder struct{}
+
+func (WAVEncoder) Name() string        { return "wav" }
+func (WAVEncoder) ContentType() string { return "audio/wav" }
+
+func (WAVEncoder) Encode(ctx context.Context, pcm <-chan AudioChunk, sampleRate, channels, bitsPerSample int) (<-chan AudioChunk, error) {
+	out := make(chan AudioChunk, 4)
+	go func() {
+		defer close(out)
+
+		header := AudioChunk{PCM: streamingWAVHeader(sampleRate, channels, bitsPerSample)}
+		select {
+		case out <- header:
+		case <-ctx.Done():
+			return
+		}
+
+		for chunk := range pcm {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+			if chunk.Err != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// OpusEncoder pipes raw PCM through a bundled or PATH-resolved `opusenc`
+// binary to produce a proper Ogg-Opus container using Opus's standard 20ms
+// frame size — the frame length that best balances latency against
+// container overhead for voice. This shells out rather than using cgo
+// bindings (gopus, hraban/opus) for the same reason Piper and whisper-server
+// are subprocesses: one fewer cgo toolchain to cross-compile for every
+// platform voice-relay ships on.
+type OpusEncoder struct {
+	// BinPath is the opusenc binary; defaults to "opusenc" on PATH when empty.
+	// Bundling one per-platform would follow the same EnsureServer pattern as
+	// internal/tts.EnsureServer does for Piper.
+	BinPath string
+}
+
+func (OpusEncoder) Name() string        { return "opus" }
+func (OpusEncoder) ContentType() string { return "audio/ogg; codecs=opus" }
+
+func (o OpusEncoder) Encode(ctx context.Context, pcm <-chan AudioChunk, sampleRate, channels, bitsPerSample int) (<-chan AudioChunk, error) {
+	bin := o.BinPath
+	if bin == "" {
+		bin = "opusenc"
+	}
+	cmd := exec.CommandContext(ctx, bin,
+		"--quiet",
+		"--framesize", "20",
+		"--raw",
+		"--raw-bits", fmt.Sprintf("%d", bitsPerSample),
+		"--raw-rate", fmt.Sprintf("%d", sampleRate),
+		"--raw-chan", fmt.Sprintf("%d", channels),
+		"-", "-",
+	)
+	return encodeViaSubprocess(cmd, pcm)
+}
+
+// FLACEncoder pipes raw PCM through a bundled or PATH-resolved `flac` binary
+// for lossless compression, for clients that want exact reproduction rather
+// than Opus's lossy encoding.
+type FLACEncoder struct {
+	// BinPath is the flac binary; defaults to "flac" on PATH when empty.
+	BinPath string
+}
+
+func (FLACEncoder) Name() string        { return "flac" }
+func (FLACEncoder) ContentType() string { return "audio/flac" }
+
+func (f FLACEncoder) Encode(ctx context.Context, pcm <-chan AudioChunk, sampleRate, channels, bitsPerSample int) (<-chan AudioChunk, error) {
+	bin := f.BinPath
+	if bin == "" {
+		bin = "flac"
+	}
+	cmd := exec.CommandContext(ctx, bin,
+		"--silent", "--force",
+		"--endian=little", "--sign=signed",
+		fmt.Sprintf("--channels=%d", channels),
+		fmt.Sprintf("--bps=%d", bitsPerSample),
+		fmt.Sprintf("--sample-rate=%d", sampleRate),
+		"-", "-o", "-",
+	)
+	return encodeViaSubprocess(cmd, pcm)
+}
+
+// encodeViaSubprocess feeds pcm to cmd's stdin and streams its stdout back
+// on the returned channel as it's produced, for the opusenc/flac encoders
+// that expect raw PCM on stdin and write a complete container to stdout.
+func encodeViaSubprocess(cmd *exec.Cmd, pcm <-chan AudioChunk) (<-chan AudioChunk, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%s stdin pipe: %w", cmd.Path, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%s stdout pipe: %w", cmd.Path, err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s failed to start: %w", cmd.Path, err)
+	}
+
+	go func() {
+		defer stdin.Close()
+		for chunk := range pcm {
+			if chunk.Err != nil {
+				return
+			}
+			if _, err := stdin.Write(chunk.PCM); err != nil {
+				return
+			}
+		}
+	}()
+
+	out := make(chan AudioChunk, 4)
+	go func() {
+		defer close(out)
+		r := bufio.NewReaderSize(stdout, streamChunkSize)
+		buf := make([]byte, streamChunkSize)
+		for {
+			n, readErr := r.Read(buf)
+			if n > 0 {
+				chunk := make([]byte, n)
+				copy(chunk, buf[:n])
+				out <- AudioChunk{PCM: chunk}
+			}
+			if readErr != nil {
+				break
+			}
+		}
+		if err := cmd.Wait(); err != nil {
+			out <- AudioChunk{Err: fmt.Errorf("%s: %w: %s", cmd.Path, err, stderr.String())}
+		}
+	}()
+
+	return out, nil
+}
+
+// streamingWAVHeader builds a WAV header whose RIFF and data chunk sizes are
+// left as the streaming convention of 0xFFFFFFFF, since the total length
+// isn't known until synthesis completes. Most players (and all browsers)
+// treat this as "read until EOF" rather than trusting the declared size.
+func streamingWAVHeader(sampleRate, channels, bitsPerSample int) []byte {
+	byteRate := sampleRate * channels * bitsPerSample / 8
+	blockAlign := channels * bitsPerSample / 8
+
+	buf := make([]byte, 44)
+	copy(buf[0:4], "RIFF")
+	putUint32(buf[4:8], 0xFFFFFFFF)
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	putUint32(buf[16:20], 16)
+	putUint16(buf[20:22], 1)
+	putUint16(buf[22:24], uint16(channels))
+	putUint32(buf[24:28], uint32(sampleRate))
+	putUint32(buf[28:32], uint32(byteRate))
+	putUint16(buf[32:34], uint16(blockAlign))
+	putUint16(buf[34:36], uint16(bitsPerSample))
+	copy(buf[36:40], "data")
+	putUint32(buf[40:44], 0xFFFFFFFF)
+	return buf
+}
+
+func putUint32(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
+
+func putUint16(b []byte, v uint16) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+}
@@ -1,30 +1,146 @@
 package tts
 
 import (
-	"archive/tar"
-	"archive/zip"
-	"bytes"
-	"compress/gzip"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/releasefetch"
 )
 
 const (
-	piperRepoAPI = "https://api.github.com/repos/rhasspy/piper/releases/latest"
-
 	// Voice model URLs (HuggingFace)
 	defaultVoiceBaseURL = "https://huggingface.co/rhasspy/piper-voices/resolve/v1.0.0"
 	defaultVoicePath    = "en/en_US/lessac/medium"
 	defaultVoiceName    = "en_US-lessac-high"
+
+	// voicesManifestURL is the piper-voices catalog listing every voice the
+	// HuggingFace repo hosts, keyed by voice name.
+	voicesManifestURL = "https://huggingface.co/rhasspy/piper-voices/resolve/v1.0.0/voices.json"
+
+	// voicesCacheTTL is how long ListVoices serves its cached catalog before
+	// refetching voices.json — the catalog changes rarely enough within a
+	// single run that polling it on every call would just waste a request.
+	voicesCacheTTL = time.Hour
+)
+
+var piperFetcher = releasefetch.New("rhasspy", "piper")
+
+// voiceDownloadLocks serializes concurrent EnsureVoice calls for the same
+// voice name, so a user switching voices while a notification is mid-render
+// can't have two downloads race and corrupt the same .onnx file.
+var (
+	voiceDownloadLocksMu sync.Mutex
+	voiceDownloadLocks   = map[string]*sync.Mutex{}
+)
+
+func voiceDownloadLock(voiceName string) *sync.Mutex {
+	voiceDownloadLocksMu.Lock()
+	defer voiceDownloadLocksMu.Unlock()
+	l, ok := voiceDownloadLocks[voiceName]
+	if !ok {
+		l = &sync.Mutex{}
+		voiceDownloadLocks[voiceName] = l
+	}
+	return l
+}
+
+// VoiceInfo describes one entry in the piper-voices catalog, as returned by
+// ListVoices and the coordinator's GET /api/voices endpoint.
+type VoiceInfo struct {
+	Name       string `json:"name"`
+	Language   string `json:"language"`
+	Quality    string `json:"quality"`
+	SampleRate int    `json:"sample_rate"`
+	SizeBytes  int64  `json:"size_bytes"`
+	SampleURL  string `json:"sample_url,omitempty"`
+}
+
+// rawVoiceEntry mirrors the subset of piper-voices' voices.json this package
+// needs; the real file also carries aliases and a speaker map that no caller
+// here uses.
+type rawVoiceEntry struct {
+	Name     string `json:"name"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Quality string `json:"quality"`
+	Audio   struct {
+		SampleRate int `json:"sample_rate"`
+	} `json:"audio"`
+	Files map[string]struct {
+		SizeBytes int64 `json:"size_bytes"`
+	} `json:"files"`
+}
+
+var (
+	voicesCacheMu      sync.Mutex
+	voicesCache        []VoiceInfo
+	voicesCacheFetched time.Time
 )
 
+// ListVoices fetches and caches the piper-voices catalog, turning the
+// currently hardcoded defaultVoiceName into a user-browseable list. A
+// refetch failure falls back to serving whatever's cached, if anything, so a
+// transient network blip doesn't blank out the PWA's voice picker.
+func ListVoices() ([]VoiceInfo, error) {
+	voicesCacheMu.Lock()
+	defer voicesCacheMu.Unlock()
+
+	if voicesCache != nil && time.Since(voicesCacheFetched) < voicesCacheTTL {
+		return voicesCache, nil
+	}
+
+	data, err := releasefetch.DownloadBytes(voicesManifestURL)
+	if err != nil {
+		if voicesCache != nil {
+			log.Printf("tts: refreshing voice catalog failed, serving cached copy: %v", err)
+			return voicesCache, nil
+		}
+		return nil, fmt.Errorf("fetching voice catalog: %w", err)
+	}
+
+	var raw map[string]rawVoiceEntry
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing voice catalog: %w", err)
+	}
+
+	voices := make([]VoiceInfo, 0, len(raw))
+	for key, entry := range raw {
+		name := entry.Name
+		if name == "" {
+			name = key
+		}
+		info := VoiceInfo{
+			Name:       name,
+			Language:   entry.Language.Code,
+			Quality:    entry.Quality,
+			SampleRate: entry.Audio.SampleRate,
+		}
+		for file, meta := range entry.Files {
+			switch {
+			case strings.HasSuffix(file, ".onnx"):
+				info.SizeBytes = meta.SizeBytes
+			case strings.Contains(file, "sample"):
+				info.SampleURL = defaultVoiceBaseURL + "/" + file
+			}
+		}
+		voices = append(voices, info)
+	}
+	sort.Slice(voices, func(i, j int) bool { return voices[i].Name < voices[j].Name })
+
+	voicesCache = voices
+	voicesCacheFetched = time.Now()
+	return voices, nil
+}
+
 // BinaryName returns the platform-specific piper binary name.
 func BinaryName() string {
 	if runtime.GOOS == "windows" {
@@ -59,31 +175,11 @@ func EnsureServer(binDir string) (string, error) {
 		return serverPath, nil
 	}
 
-	if err := os.MkdirAll(piperDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create piper bin directory: %w", err)
-	}
-
-	downloadURL, err := getPiperDownloadURL()
-	if err != nil {
-		return "", fmt.Errorf("failed to get piper download URL: %w", err)
-	}
-
-	log.Printf("Downloading piper: %s", downloadURL)
-
-	archiveData, err := downloadBytes(downloadURL)
-	if err != nil {
-		return "", fmt.Errorf("failed to download piper: %w", err)
-	}
+	targetAsset := piperAssetName()
+	matcher := func(assetName string) bool { return assetName == targetAsset }
 
-	assetName := piperAssetName()
-	if strings.HasSuffix(assetName, ".zip") {
-		if err := extractZip(archiveData, piperDir); err != nil {
-			return "", fmt.Errorf("failed to extract piper zip: %w", err)
-		}
-	} else {
-		if err := extractTarGz(archiveData, piperDir); err != nil {
-			return "", fmt.Errorf("failed to extract piper tar.gz: %w", err)
-		}
+	if _, err := piperFetcher.Install(piperDir, matcher, nil); err != nil {
+		return "", fmt.Errorf("failed to install piper: %w", err)
 	}
 
 	if _, err := os.Stat(serverPath); err != nil {
@@ -97,10 +193,24 @@ func EnsureServer(binDir string) (string, error) {
 // EnsureVoice checks if a voice model exists and downloads it if not.
 // Returns the path to the .onnx model file.
 func EnsureVoice(modelsDir, voiceName string) (string, error) {
+	return EnsureVoiceWithProgress(modelsDir, voiceName, nil)
+}
+
+// EnsureVoiceWithProgress is EnsureVoice with a progress callback invoked as
+// the (multi-hundred-MB) .onnx model downloads, so the PWA can show a
+// progress bar while a user switches to a voice that isn't cached yet.
+// progress may be nil. Concurrent calls for the same voiceName serialize
+// behind a per-voice lock so two downloads can't write the same .onnx file
+// at once.
+func EnsureVoiceWithProgress(modelsDir, voiceName string, progress func(bytes, total int64)) (string, error) {
 	if voiceName == "" || voiceName == "default" {
 		voiceName = defaultVoiceName
 	}
 
+	lock := voiceDownloadLock(voiceName)
+	lock.Lock()
+	defer lock.Unlock()
+
 	modelPath := filepath.Join(modelsDir, voiceName+".onnx")
 	jsonPath := filepath.Join(modelsDir, voiceName+".onnx.json")
 
@@ -123,13 +233,13 @@ func EnsureVoice(modelsDir, voiceName string) (string, error) {
 
 	// Download .onnx model
 	log.Printf("Downloading Piper voice model: %s", onnxURL)
-	if err := downloadFile(modelPath, onnxURL); err != nil {
+	if err := releasefetch.DownloadFileWithProgress(modelPath, onnxURL, progress); err != nil {
 		return "", fmt.Errorf("failed to download voice model: %w", err)
 	}
 
 	// Download .onnx.json config
 	log.Printf("Downloading Piper voice config: %s", jsonURL)
-	if err := downloadFile(jsonPath, jsonURL); err != nil {
+	if err := releasefetch.DownloadFile(jsonPath, jsonURL); err != nil {
 		return "", fmt.Errorf("failed to download voice config: %w", err)
 	}
 
@@ -155,177 +265,3 @@ func voiceToPath(voiceName string) string {
 
 	return fmt.Sprintf("%s/%s/%s/%s", lang, locale, name, quality)
 }
-
-func getPiperDownloadURL() (string, error) {
-	resp, err := http.Get(piperRepoAPI)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned %d", resp.StatusCode)
-	}
-
-	var release struct {
-		Assets []struct {
-			Name               string `json:"name"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		} `json:"assets"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", err
-	}
-
-	targetAsset := piperAssetName()
-	for _, asset := range release.Assets {
-		if asset.Name == targetAsset {
-			return asset.BrowserDownloadURL, nil
-		}
-	}
-
-	return "", fmt.Errorf("asset %s not found in release", targetAsset)
-}
-
-// extractZip extracts all files from a zip archive into destDir.
-// Files nested inside a top-level directory are flattened into destDir.
-func extractZip(zipData []byte, destDir string) error {
-	r, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
-	if err != nil {
-		return err
-	}
-
-	for _, f := range r.File {
-		if f.FileInfo().IsDir() {
-			continue
-		}
-
-		// Strip the top-level "piper/" directory prefix if present
-		name := f.Name
-		if idx := strings.Index(name, "/"); idx >= 0 {
-			name = name[idx+1:]
-		}
-		if name == "" {
-			continue
-		}
-
-		destPath := filepath.Join(destDir, name)
-
-		// Create subdirectories (e.g. espeak-ng-data/)
-		if dir := filepath.Dir(destPath); dir != destDir {
-			os.MkdirAll(dir, 0755)
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-		if err != nil {
-			rc.Close()
-			return err
-		}
-
-		_, err = io.Copy(out, rc)
-		rc.Close()
-		out.Close()
-		if err != nil {
-			return err
-		}
-
-		log.Printf("Extracted: %s", name)
-	}
-
-	return nil
-}
-
-// extractTarGz extracts all files from a .tar.gz archive into destDir.
-// Files nested inside a top-level directory are flattened into destDir.
-func extractTarGz(data []byte, destDir string) error {
-	gz, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	defer gz.Close()
-
-	tr := tar.NewReader(gz)
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		if header.Typeflag == tar.TypeDir {
-			continue
-		}
-
-		// Strip the top-level "piper/" directory prefix if present
-		name := header.Name
-		if idx := strings.Index(name, "/"); idx >= 0 {
-			name = name[idx+1:]
-		}
-		if name == "" {
-			continue
-		}
-
-		destPath := filepath.Join(destDir, name)
-
-		// Create subdirectories (e.g. espeak-ng-data/)
-		if dir := filepath.Dir(destPath); dir != destDir {
-			os.MkdirAll(dir, 0755)
-		}
-
-		out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
-		if err != nil {
-			return err
-		}
-
-		_, err = io.Copy(out, tr)
-		out.Close()
-		if err != nil {
-			return err
-		}
-
-		log.Printf("Extracted: %s", name)
-	}
-
-	return nil
-}
-
-func downloadFile(dest, url string) error {
-	data, err := downloadBytes(url)
-	if err != nil {
-		return err
-	}
-
-	tmpPath := dest + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
-		return err
-	}
-
-	return os.Rename(tmpPath, dest)
-}
-
-func downloadBytes(url string) ([]byte, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
-	}
-
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Printf("Downloaded %d bytes", len(data))
-	return data, nil
-}
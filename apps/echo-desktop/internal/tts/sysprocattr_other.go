@@ -0,0 +1,9 @@
+//go:build !windows
+
+package tts
+
+import "os/exec"
+
+// setSysProcAttr is a no-op outside Windows, which is the only platform that pops a
+// console window for subprocesses started from a GUI app.
+func setSysProcAttr(cmd *exec.Cmd) {}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package tts
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setSysProcAttr suppresses the console window Windows would otherwise flash when
+// launching this package's subprocess from a GUI app.
+func setSysProcAttr(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
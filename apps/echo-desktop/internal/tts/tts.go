@@ -1,64 +1,359 @@
 package tts
 
 import (
+	"bufio"
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/voice-relay/echo-desktop/internal/metrics"
 )
 
-// Engine wraps the Piper TTS CLI binary for text-to-speech synthesis.
+// Engine wraps the Piper TTS CLI binary for text-to-speech synthesis. A
+// single Engine keeps one piper subprocess running in JSON-input streaming
+// mode, so repeated calls don't pay piper's startup cost — callers that
+// need a different voice should use a different Engine (see
+// tts.EnsureVoice / tts.NewEngine in main.go's per-voice engine cache).
 type Engine struct {
 	piperPath  string // path to piper binary
 	modelPath  string // path to .onnx voice model
 	sampleRate int    // audio sample rate from .onnx.json
+
+	reqCh     chan *ttsRequest
+	stopCh    chan struct{}
+	closeOnce sync.Once
+
+	proc *piperProcess // owned exclusively by dispatchLoop
 }
 
 // NewEngine creates a new TTS engine with the given piper binary and model paths.
-// It reads the sample rate from the .onnx.json config file.
+// It reads the sample rate from the .onnx.json config file and starts the
+// request dispatcher goroutine; the piper subprocess itself is spawned
+// lazily on first use.
 func NewEngine(piperPath, modelPath string) *Engine {
 	sampleRate := readSampleRate(modelPath + ".json")
-	return &Engine{
+	e := &Engine{
 		piperPath:  piperPath,
 		modelPath:  modelPath,
 		sampleRate: sampleRate,
+		reqCh:      make(chan *ttsRequest, 16),
+		stopCh:     make(chan struct{}),
 	}
+	go e.dispatchLoop()
+	return e
 }
 
-// Synthesize converts text to speech, returning WAV audio bytes.
+// Synthesize converts text to speech, returning WAV audio bytes. It's a
+// convenience wrapper over SynthesizeStream for callers that want the whole
+// utterance at once.
 func (e *Engine) Synthesize(text string) ([]byte, error) {
+	ch, err := e.SynthesizeStream(context.Background(), text)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcm []byte
+	for chunk := range ch {
+		if chunk.Err != nil {
+			return nil, chunk.Err
+		}
+		pcm = append(pcm, chunk.PCM...)
+	}
+
+	if len(pcm) == 0 {
+		return nil, fmt.Errorf("piper produced no audio output")
+	}
+
+	log.Printf("Piper produced %d bytes of raw PCM (rate=%d)", len(pcm), e.sampleRate)
+	return pcmToWav(pcm, e.sampleRate, 1, 16), nil
+}
+
+// Close terminates the persistent piper subprocess, if one is running, and
+// stops the request dispatcher. Safe to call more than once.
+func (e *Engine) Close() {
+	e.closeOnce.Do(func() { close(e.stopCh) })
+}
+
+// SampleRate returns the engine's configured output sample rate, needed by
+// callers that wrap streamed PCM in their own container (see
+// coordinator.handleTTSStream).
+func (e *Engine) SampleRate() int {
+	return e.sampleRate
+}
+
+// AudioChunk is one piece of streamed raw PCM audio, or a terminal error
+// delivered in place of a final chunk.
+type AudioChunk struct {
+	PCM []byte
+	Err error
+}
+
+// streamChunkSize is how much raw PCM SynthesizeStream reads at a time —
+// small enough to start playback quickly, large enough not to thrash on
+// syscalls.
+const streamChunkSize = 32 * 1024
+
+// ttsRequest is one sentence queued for the persistent piper process.
+// respCh is closed once the sentence's audio (or a terminal error) has been
+// delivered.
+type ttsRequest struct {
+	sentence    string
+	ctx         context.Context
+	respCh      chan AudioChunk
+	submittedAt time.Time
+}
+
+// sentenceBoundary matches a run of sentence-ending punctuation followed by
+// whitespace or end-of-string.
+var sentenceBoundary = regexp.MustCompile(`(?s)([.!?]+)(\s+|$)`)
+
+// splitSentences breaks text into sentence-sized pieces so SynthesizeStream
+// can start emitting audio for the first sentence while piper is still
+// rendering the rest. Text with no recognizable sentence punctuation is
+// returned as a single piece.
+func splitSentences(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var sentences []string
+	last := 0
+	for _, m := range sentenceBoundary.FindAllStringIndex(text, -1) {
+		if s := strings.TrimSpace(text[last:m[1]]); s != "" {
+			sentences = append(sentences, s)
+		}
+		last = m[1]
+	}
+	if s := strings.TrimSpace(text[last:]); s != "" {
+		sentences = append(sentences, s)
+	}
+
+	if len(sentences) == 0 {
+		sentences = []string{text}
+	}
+	return sentences
+}
+
+// SynthesizeStream converts text to speech, delivering raw PCM on a channel
+// sentence-by-sentence as piper produces it instead of buffering the whole
+// utterance. Cancelling ctx stops delivery (the in-flight sentence is still
+// drained internally so the persistent piper process stays in sync, but no
+// further sentences are queued).
+func (e *Engine) SynthesizeStream(ctx context.Context, text string) (<-chan AudioChunk, error) {
+	sentences := splitSentences(text)
+	out := make(chan AudioChunk, 4)
+
+	go func() {
+		defer close(out)
+
+		for _, sentence := range sentences {
+			if ctx.Err() != nil {
+				return
+			}
+
+			req := &ttsRequest{
+				sentence:    sentence,
+				ctx:         ctx,
+				respCh:      make(chan AudioChunk, 4),
+				submittedAt: time.Now(),
+			}
+
+			select {
+			case e.reqCh <- req:
+			case <-ctx.Done():
+				return
+			case <-e.stopCh:
+				out <- AudioChunk{Err: fmt.Errorf("tts engine closed")}
+				return
+			}
+
+			for chunk := range req.respCh {
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+				}
+				if chunk.Err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// piperProcess is one running `piper --json-input` subprocess. dead is
+// closed by its watchdog goroutine once the process exits, so dispatchLoop
+// can detect a crash and respawn before handling the next request.
+type piperProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *strings.Builder
+	dead   chan struct{}
+}
+
+func (e *Engine) isDead(p *piperProcess) bool {
+	if p == nil {
+		return true
+	}
+	select {
+	case <-p.dead:
+		return true
+	default:
+		return false
+	}
+}
+
+// spawnProcess starts a new persistent piper process in JSON-input mode:
+// each stdin line is a `{"text": "...", "output_file": "-"}` request, and
+// piper writes one complete WAV file to stdout per line, in order. The WAV
+// header's data-chunk size tells handleRequest exactly how many PCM bytes
+// to read, so back-to-back utterances never need an explicit delimiter.
+func (e *Engine) spawnProcess() (*piperProcess, error) {
 	cmd := exec.Command(e.piperPath,
 		"--model", e.modelPath,
-		"--output-raw",
+		"--json-input",
 	)
-	cmd.Stdin = strings.NewReader(text)
 	cmd.Dir = piperDir(e.piperPath)
 	setSysProcAttr(cmd)
 
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("piper stdin pipe: %w", err)
+	}
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("piper stdout pipe: %w", err)
+	}
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 
-	rawPCM, err := cmd.Output()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("piper failed to start: %w", err)
+	}
+
+	p := &piperProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: bufio.NewReaderSize(stdoutPipe, streamChunkSize),
+		stderr: &stderr,
+		dead:   make(chan struct{}),
+	}
+
+	go func() {
+		cmd.Wait()
+		close(p.dead)
+	}()
+
+	log.Printf("Started persistent piper process (pid %d) for %s", cmd.Process.Pid, filepath.Base(e.modelPath))
+	return p, nil
+}
+
+// dispatchLoop is the Engine's single goroutine that owns the piper
+// subprocess, serializing sentence requests onto it one at a time (piper's
+// JSON-input mode only processes one line at a time anyway).
+func (e *Engine) dispatchLoop() {
+	defer func() {
+		if e.proc != nil && !e.isDead(e.proc) {
+			e.proc.stdin.Close()
+			e.proc.cmd.Process.Kill()
+		}
+	}()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case req := <-e.reqCh:
+			e.handleRequest(req)
+		}
+	}
+}
+
+func (e *Engine) handleRequest(req *ttsRequest) {
+	defer close(req.respCh)
+	defer metrics.TTSSynthDuration.WithLabelValues(e.voiceLabel()).ObserveDuration(req.submittedAt)
+
+	if e.isDead(e.proc) {
+		proc, err := e.spawnProcess()
+		if err != nil {
+			req.respCh <- AudioChunk{Err: fmt.Errorf("piper failed to start: %w", err)}
+			return
+		}
+		e.proc = proc
+	}
+	proc := e.proc
+
+	line, err := json.Marshal(struct {
+		Text       string `json:"text"`
+		OutputFile string `json:"output_file"`
+	}{Text: req.sentence, OutputFile: "-"})
 	if err != nil {
-		return nil, fmt.Errorf("piper failed: %w: %s", err, stderr.String())
+		req.respCh <- AudioChunk{Err: fmt.Errorf("encode piper request: %w", err)}
+		return
 	}
+	line = append(line, '\n')
 
-	if len(rawPCM) == 0 {
-		return nil, fmt.Errorf("piper produced no audio output")
+	if _, err := proc.stdin.Write(line); err != nil {
+		e.proc = nil
+		req.respCh <- AudioChunk{Err: fmt.Errorf("piper write failed: %w: %s", err, proc.stderr.String())}
+		return
+	}
+
+	header := make([]byte, 44)
+	if _, err := io.ReadFull(proc.stdout, header); err != nil {
+		e.proc = nil
+		req.respCh <- AudioChunk{Err: fmt.Errorf("piper produced no audio: %w: %s", err, proc.stderr.String())}
+		return
 	}
+	dataSize := int(binary.LittleEndian.Uint32(header[40:44]))
+
+	first := true
+	buf := make([]byte, streamChunkSize)
+	remaining := dataSize
+	for remaining > 0 {
+		n := len(buf)
+		if n > remaining {
+			n = remaining
+		}
 
-	log.Printf("Piper produced %d bytes of raw PCM (rate=%d)", len(rawPCM), e.sampleRate)
+		read, err := io.ReadFull(proc.stdout, buf[:n])
+		remaining -= read
 
-	wav := pcmToWav(rawPCM, e.sampleRate, 1, 16)
-	return wav, nil
+		if read > 0 && req.ctx.Err() == nil {
+			if first {
+				metrics.TTSTimeToFirstAudio.WithLabelValues(e.voiceLabel()).Observe(time.Since(req.submittedAt).Seconds())
+				first = false
+			}
+			chunk := make([]byte, read)
+			copy(chunk, buf[:read])
+			req.respCh <- AudioChunk{PCM: chunk}
+		}
+
+		if err != nil {
+			e.proc = nil
+			req.respCh <- AudioChunk{Err: fmt.Errorf("piper stream ended early: %w: %s", err, proc.stderr.String())}
+			return
+		}
+	}
 }
 
-// Close is a no-op — piper CLI processes exit immediately after synthesis.
-func (e *Engine) Close() {
-	// Nothing to do — no long-running process
+// voiceLabel derives a metrics label from the voice model's file name, e.g.
+// "en_US-lessac-high" from ".../en_US-lessac-high.onnx".
+func (e *Engine) voiceLabel() string {
+	return strings.TrimSuffix(filepath.Base(e.modelPath), ".onnx")
 }
 
 // piperDir returns the directory containing the piper binary,
@@ -115,13 +410,13 @@ func pcmToWav(pcm []byte, sampleRate, channels, bitsPerSample int) []byte {
 
 	// fmt sub-chunk
 	copy(buf[12:16], "fmt ")
-	binary.LittleEndian.PutUint32(buf[16:20], 16)                     // sub-chunk size
-	binary.LittleEndian.PutUint16(buf[20:22], 1)                      // PCM format
-	binary.LittleEndian.PutUint16(buf[22:24], uint16(channels))       // channels
-	binary.LittleEndian.PutUint32(buf[24:28], uint32(sampleRate))     // sample rate
-	binary.LittleEndian.PutUint32(buf[28:32], uint32(byteRate))       // byte rate
-	binary.LittleEndian.PutUint16(buf[32:34], uint16(blockAlign))     // block align
-	binary.LittleEndian.PutUint16(buf[34:36], uint16(bitsPerSample))  // bits per sample
+	binary.LittleEndian.PutUint32(buf[16:20], 16)                    // sub-chunk size
+	binary.LittleEndian.PutUint16(buf[20:22], 1)                     // PCM format
+	binary.LittleEndian.PutUint16(buf[22:24], uint16(channels))      // channels
+	binary.LittleEndian.PutUint32(buf[24:28], uint32(sampleRate))    // sample rate
+	binary.LittleEndian.PutUint32(buf[28:32], uint32(byteRate))      // byte rate
+	binary.LittleEndian.PutUint16(buf[32:34], uint16(blockAlign))    // block align
+	binary.LittleEndian.PutUint16(buf[34:36], uint16(bitsPerSample)) // bits per sample
 
 	// data sub-chunk
 	copy(buf[36:40], "data")
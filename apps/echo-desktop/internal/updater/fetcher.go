@@ -0,0 +1,279 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Fetcher resolves and downloads voice-relay release artifacts from one of
+// several sources — GitHubFetcher (the default), HTTPFetcher (a generic
+// HTTPS URL serving a version manifest), or S3Fetcher (an S3-compatible
+// bucket laid out the same way). Fetch always verifies the downloaded bytes
+// against their detached Ed25519 signature (see VerifyRelease) before
+// returning, so nothing unverified ever reaches a caller regardless of
+// which Fetcher produced it.
+type Fetcher interface {
+	// Init resolves the latest available version using cfg. Current,
+	// Fetch, and Manifest are only valid after Init returns successfully.
+	Init(cfg FetcherConfig) error
+
+	// Current returns the latest version Init discovered.
+	Current() string
+
+	// Fetch downloads and signature-verifies the release asset for the
+	// current platform, returning a reader over the verified bytes.
+	Fetch() (io.Reader, error)
+
+	// Manifest returns the release's signed manifest, already checked
+	// against TrustedKeys (see VerifyManifest) — callers use its per-asset
+	// checksum to verify a download independently of its detached
+	// signature before an installer touches anything on disk.
+	Manifest() (*manifestFile, error)
+}
+
+// FetcherConfig selects and configures a Fetcher. Source picks the
+// implementation; the remaining fields are read by whichever one is
+// selected and ignored by the others.
+type FetcherConfig struct {
+	// Source is "github" (default), "https", or "s3".
+	Source string `yaml:"source,omitempty"`
+
+	// GitHub Releases (Source: "github")
+	RepoOwner string `yaml:"repo_owner,omitempty"`
+	RepoName  string `yaml:"repo_name,omitempty"`
+
+	// Generic HTTPS manifest (Source: "https"): ManifestURL serves a JSON
+	// document in manifestFile's shape.
+	ManifestURL string `yaml:"manifest_url,omitempty"`
+
+	// S3-compatible bucket (Source: "s3"): BucketURL is the bucket's base
+	// URL (e.g. "https://my-bucket.s3.us-west-2.amazonaws.com"), serving
+	// the same manifestFile layout at "<BucketURL>/manifest.json".
+	BucketURL string `yaml:"bucket_url,omitempty"`
+}
+
+// newFetcher constructs the Fetcher named by cfg.Source.
+func newFetcher(cfg FetcherConfig) (Fetcher, error) {
+	switch cfg.Source {
+	case "", "github":
+		return &GitHubFetcher{}, nil
+	case "https":
+		return &HTTPFetcher{}, nil
+	case "s3":
+		return &S3Fetcher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown update fetcher source: %s", cfg.Source)
+	}
+}
+
+// manifestAsset is one platform's entry in a version manifest, shared by
+// HTTPFetcher and S3Fetcher.
+type manifestAsset struct {
+	AssetURL string `json:"asset_url"`
+	SigURL   string `json:"sig_url"`
+	Checksum string `json:"sha256"` // hex SHA-256 of the asset; see verifyAssetChecksum
+}
+
+// manifestFile is the JSON document HTTPFetcher/S3Fetcher expect at their
+// configured URL (and GitHubFetcher expects as a "manifest.json" release
+// asset): a version string, one manifestAsset per platform keyed the same
+// way GitHubFetcher names its release assets (see getAssetName), and one
+// detached Ed25519 signature per signing key in Signatures (key label ->
+// hex signature over signingPayload(), see VerifyManifest). The manifest's
+// per-asset checksum is the independent digest check applyUpdateDarwin and
+// applyUpdateWindows run before touching anything on disk, on top of the
+// detached per-asset signature fetchVerified already checks.
+type manifestFile struct {
+	Version    string                   `json:"version"`
+	Assets     map[string]manifestAsset `json:"assets"`
+	Signatures map[string]string        `json:"signatures"`
+}
+
+// fetchManifest downloads, parses, and signature-verifies a manifestFile
+// from url against TrustedKeys (see VerifyManifest) — the manifest is never
+// handed back to a caller unless it checks out.
+func fetchManifest(url string) (*manifestFile, error) {
+	data, err := fetchAll(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching manifest: %w", err)
+	}
+	var m manifestFile
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if err := VerifyManifest(&m, DefaultKeyRotationPolicy); err != nil {
+		return nil, fmt.Errorf("manifest verification failed: %w", err)
+	}
+	return &m, nil
+}
+
+// fetchVerified downloads assetURL and the detached signature at sigURL,
+// verifies the asset against it and, if manifest is non-nil, against its
+// checksum for assetName too, and returns a reader over the verified bytes.
+func fetchVerified(assetURL, sigURL string, manifest *manifestFile, assetName string) (io.Reader, error) {
+	data, err := fetchAll(assetURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading asset: %w", err)
+	}
+	sig, err := fetchAll(sigURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading signature: %w", err)
+	}
+	if err := VerifyRelease(data, sig); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+	if manifest != nil {
+		if err := verifyAssetChecksum(manifest, assetName, data); err != nil {
+			return nil, fmt.Errorf("manifest verification failed: %w", err)
+		}
+	}
+	return bytes.NewReader(data), nil
+}
+
+// GitHubFetcher is the original, default Fetcher: it resolves releases
+// against the GitHub Releases API for cfg.RepoOwner/cfg.RepoName.
+type GitHubFetcher struct {
+	version   string
+	assetName string
+	assetURL  string
+	sigURL    string
+	manifest  *manifestFile
+}
+
+func (f *GitHubFetcher) Init(cfg FetcherConfig) error {
+	owner, name := cfg.RepoOwner, cfg.RepoName
+	if owner == "" {
+		owner = repoOwner
+	}
+	if name == "" {
+		name = repoName
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", owner, name)
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API returned %d", resp.StatusCode)
+	}
+
+	var release githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return err
+	}
+
+	assetName := getAssetName()
+	var assetURL, manifestURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case assetName:
+			assetURL = asset.BrowserDownloadURL
+		case "manifest.json":
+			manifestURL = asset.BrowserDownloadURL
+		}
+	}
+	if assetURL == "" {
+		return fmt.Errorf("no download available for this platform (%s)", assetName)
+	}
+	if manifestURL == "" {
+		return fmt.Errorf("release %s has no signed manifest.json asset", release.TagName)
+	}
+
+	manifest, err := fetchManifest(manifestURL)
+	if err != nil {
+		return err
+	}
+
+	f.version = strings.TrimPrefix(release.TagName, "v")
+	f.assetName = assetName
+	f.assetURL = assetURL
+	f.sigURL = assetURL + ".sig"
+	f.manifest = manifest
+	return nil
+}
+
+func (f *GitHubFetcher) Current() string { return f.version }
+
+func (f *GitHubFetcher) Fetch() (io.Reader, error) {
+	return fetchVerified(f.assetURL, f.sigURL, f.manifest, f.assetName)
+}
+
+func (f *GitHubFetcher) Manifest() (*manifestFile, error) { return f.manifest, nil }
+
+// HTTPFetcher resolves releases against a version manifest served from a
+// plain HTTPS URL (cfg.ManifestURL) instead of GitHub — for self-hosting
+// releases on an internal server.
+type HTTPFetcher struct {
+	version  string
+	asset    manifestAsset
+	manifest *manifestFile
+}
+
+func (f *HTTPFetcher) Init(cfg FetcherConfig) error {
+	if cfg.ManifestURL == "" {
+		return fmt.Errorf("https fetcher requires manifest_url")
+	}
+	m, err := fetchManifest(cfg.ManifestURL)
+	if err != nil {
+		return err
+	}
+	asset, ok := m.Assets[getAssetName()]
+	if !ok {
+		return fmt.Errorf("manifest has no asset for this platform (%s)", getAssetName())
+	}
+	f.version = m.Version
+	f.asset = asset
+	f.manifest = m
+	return nil
+}
+
+func (f *HTTPFetcher) Current() string { return f.version }
+
+func (f *HTTPFetcher) Fetch() (io.Reader, error) {
+	return fetchVerified(f.asset.AssetURL, f.asset.SigURL, f.manifest, getAssetName())
+}
+
+func (f *HTTPFetcher) Manifest() (*manifestFile, error) { return f.manifest, nil }
+
+// S3Fetcher resolves releases against the same manifestFile layout as
+// HTTPFetcher, published at "<cfg.BucketURL>/manifest.json" in an
+// S3-compatible bucket (AWS S3, MinIO, R2, ...) reachable over plain HTTPS
+// — no AWS SDK/credentials are needed since release buckets are public-read.
+type S3Fetcher struct {
+	version  string
+	asset    manifestAsset
+	manifest *manifestFile
+}
+
+func (f *S3Fetcher) Init(cfg FetcherConfig) error {
+	if cfg.BucketURL == "" {
+		return fmt.Errorf("s3 fetcher requires bucket_url")
+	}
+	m, err := fetchManifest(strings.TrimSuffix(cfg.BucketURL, "/") + "/manifest.json")
+	if err != nil {
+		return err
+	}
+	asset, ok := m.Assets[getAssetName()]
+	if !ok {
+		return fmt.Errorf("manifest has no asset for this platform (%s)", getAssetName())
+	}
+	f.version = m.Version
+	f.asset = asset
+	f.manifest = m
+	return nil
+}
+
+func (f *S3Fetcher) Current() string { return f.version }
+
+func (f *S3Fetcher) Fetch() (io.Reader, error) {
+	return fetchVerified(f.asset.AssetURL, f.asset.SigURL, f.manifest, getAssetName())
+}
+
+func (f *S3Fetcher) Manifest() (*manifestFile, error) { return f.manifest, nil }
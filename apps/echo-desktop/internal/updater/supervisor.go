@@ -0,0 +1,274 @@
+//go:build !windows
+
+package updater
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// supervisor.go implements an overseer-style master/slave restart, used by
+// the `voice-relay serve` subcommand so an update can swap the running
+// binary without dropping in-flight HTTP/WebSocket connections: the master
+// process holds the listening socket open across the swap and only stops
+// the old slave once a newly spawned one reports it's ready to serve.
+//
+// This is Unix-only (see supervisor_windows.go): passing an inherited
+// listener fd across exec.Command's ExtraFiles relies on fork/exec
+// semantics Windows doesn't have, and the existing batch-swap helper (see
+// update_windows.go) already restarts the tray app's single process
+// without that problem applying.
+
+// supervisorSlaveEnv marks a process as a Supervisor-spawned slave; only its
+// presence is checked. listenerFD/readyFD are fixed because ExtraFiles
+// always appends after stdin/stdout/stderr (fds 0-2) in the order given.
+const (
+	supervisorSlaveEnv = "VOICERELAY_SUPERVISOR_SLAVE"
+	listenerFD         = 3
+	readyFD            = 4
+)
+
+// crashLoopWindow is how soon after spawning a slave must exit to be treated
+// as a failed update rather than a normal shutdown. Within this window,
+// Restart reverts to the previous binary instead of keeping the new one.
+const crashLoopWindow = 5 * time.Second
+
+// slaveReadyTimeout bounds how long Run/Restart wait for a freshly spawned
+// slave to report it's serving before giving up on it.
+const slaveReadyTimeout = 60 * time.Second
+
+// IsSlave reports whether the current process was spawned by a Supervisor
+// master to actually serve traffic, as opposed to being the master itself.
+// `voice-relay serve` checks this at startup to decide which role to run.
+func IsSlave() bool {
+	return os.Getenv(supervisorSlaveEnv) != ""
+}
+
+// RunSlave is the slave side of a Supervisor: it reconstructs the listener
+// the master opened before forking, from the well-known fd it inherited,
+// signals the master that it's about to serve, and hands the listener to
+// serve.
+//
+// Restart only sends this slave SIGTERM after the newly spawned one has
+// already reported ready, so by the time onDrain runs a replacement is
+// already accepting connections on the same (shared) socket. onDrain gets a
+// chance to wind down anything this process owns that shouldn't just be
+// killed mid-request — telling already-connected clients to redial now
+// rather than wait out a dead TCP connection, flushing work in flight — and
+// then RunSlave closes the listener so serve stops accepting and returns.
+// onDrain may be nil if the caller has nothing to drain.
+func RunSlave(serve func(ln net.Listener) error, onDrain func()) error {
+	lnFile := os.NewFile(listenerFD, "voicerelay-listener")
+	ln, err := net.FileListener(lnFile)
+	if err != nil {
+		return fmt.Errorf("reconstructing listener from inherited fd: %w", err)
+	}
+	lnFile.Close() // net.FileListener dup'd it; our copy is no longer needed
+
+	readyFile := os.NewFile(readyFD, "voicerelay-ready")
+	fmt.Fprintln(readyFile, "ready")
+	readyFile.Close()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Printf("Slave draining ahead of handoff to the newly spawned slave")
+		if onDrain != nil {
+			onDrain()
+		}
+		ln.Close()
+	}()
+
+	return serve(ln)
+}
+
+// Supervisor is the master side: it owns the listening socket and the slave
+// subprocess currently serving it, and can swap in a new binary via Restart
+// while keeping that socket open the whole time.
+type Supervisor struct {
+	execPath string
+
+	mu   sync.Mutex
+	lnFD *os.File
+	cmd  *exec.Cmd
+}
+
+// NewSupervisor resolves the running executable's path up front, since
+// os.Executable becomes unreliable once Restart has renamed the file it
+// pointed at aside.
+func NewSupervisor() (*Supervisor, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating executable: %w", err)
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return nil, fmt.Errorf("resolving executable path: %w", err)
+	}
+	return &Supervisor{execPath: exe}, nil
+}
+
+// Run listens on addr, spawns a slave to serve it, and blocks until that
+// slave exits (e.g. it received SIGTERM forwarded from this process, or
+// Restart's old-slave teardown killed it after a successful swap).
+func (s *Supervisor) Run(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	lnFD, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		return fmt.Errorf("getting listener fd: %w", err)
+	}
+	s.lnFD = lnFD
+
+	if err := s.spawnSlave(); err != nil {
+		return fmt.Errorf("starting slave: %w", err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		sig := <-sigCh
+		s.mu.Lock()
+		cmd := s.cmd
+		s.mu.Unlock()
+		if cmd != nil && cmd.Process != nil {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	s.mu.Lock()
+	cmd := s.cmd
+	s.mu.Unlock()
+	return cmd.Wait()
+}
+
+// spawnSlave forks execPath as a slave, handing it the listener fd and a
+// pipe it writes to once ready, and waits (up to slaveReadyTimeout) for that
+// signal before considering the slave up.
+func (s *Supervisor) spawnSlave() error {
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(s.execPath, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), supervisorSlaveEnv+"=1")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{s.lnFD, readyW}
+
+	if err := cmd.Start(); err != nil {
+		readyW.Close()
+		return err
+	}
+	readyW.Close()
+
+	startedAt := time.Now()
+	if err := waitReady(readyR, slaveReadyTimeout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	log.Printf("Supervisor: slave ready (pid %d) after %v", cmd.Process.Pid, time.Since(startedAt))
+
+	s.mu.Lock()
+	s.cmd = cmd
+	s.mu.Unlock()
+	return nil
+}
+
+func waitReady(r *os.File, timeout time.Duration) error {
+	r.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	if err != nil {
+		return fmt.Errorf("slave did not report ready within %v: %w", timeout, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("slave closed ready pipe without reporting ready")
+	}
+	return nil
+}
+
+// Restart swaps newExecPath in as the running binary and replaces the slave
+// with one running it, keeping the listening socket open throughout so no
+// connection is dropped. If the new slave crashes within crashLoopWindow,
+// the swap is rolled back: the previous binary (kept momentarily at
+// execPath+".prev") is restored and re-spawned instead.
+func (s *Supervisor) Restart(newExecPath string) error {
+	prevPath := s.execPath + ".prev"
+	os.Remove(prevPath)
+	if err := os.Rename(s.execPath, prevPath); err != nil {
+		return fmt.Errorf("backing up current binary: %w", err)
+	}
+	if err := os.Rename(newExecPath, s.execPath); err != nil {
+		os.Rename(prevPath, s.execPath)
+		return fmt.Errorf("installing new binary: %w", err)
+	}
+
+	s.mu.Lock()
+	oldCmd := s.cmd
+	s.mu.Unlock()
+
+	if err := s.spawnSlave(); err != nil {
+		log.Printf("Supervisor: new slave failed to start, reverting to previous binary: %v", err)
+		os.Remove(s.execPath)
+		os.Rename(prevPath, s.execPath)
+		if revertErr := s.spawnSlave(); revertErr != nil {
+			return fmt.Errorf("restarting previous binary after failed update: %w", revertErr)
+		}
+		return fmt.Errorf("update rolled back: new binary failed to start: %w", err)
+	}
+
+	if oldCmd != nil && oldCmd.Process != nil {
+		oldCmd.Process.Signal(syscall.SIGTERM)
+		go func() {
+			time.Sleep(30 * time.Second)
+			oldCmd.Process.Kill()
+		}()
+	}
+
+	os.Remove(prevPath)
+	return nil
+}
+
+// CheckAndApplyUpdate checks for a newer release and, if one exists, stages
+// and verifies it (same download+signature path as Apply) and swaps it in
+// via Restart. Returns ErrNoUpdate if already current.
+func (s *Supervisor) CheckAndApplyUpdate() error {
+	info, err := checkLatest()
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrNoUpdate
+	}
+
+	staged, err := os.CreateTemp(filepath.Dir(s.execPath), "voicerelay-update-*")
+	if err != nil {
+		return fmt.Errorf("creating staging file: %w", err)
+	}
+	stagedPath := staged.Name()
+	staged.Close()
+	defer os.Remove(stagedPath)
+
+	if err := downloadAsset(info, stagedPath); err != nil {
+		return fmt.Errorf("downloading update: %w", err)
+	}
+
+	log.Printf("Supervisor: applying update to %s", info.Version)
+	return s.Restart(stagedPath)
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package updater
+
+import (
+	"fmt"
+	"net"
+)
+
+// Supervisor's master/slave socket handoff relies on exec.Cmd.ExtraFiles
+// fork/exec semantics that don't exist on Windows (see update_windows.go for
+// how Windows restarts instead, via a staged batch-script swap). The `serve`
+// CLI subcommand checks runtime.GOOS before reaching any of this.
+
+// IsSlave always reports false on Windows: there is no supervised slave role.
+func IsSlave() bool { return false }
+
+// RunSlave is unreachable on Windows.
+func RunSlave(serve func(ln net.Listener) error, onDrain func()) error {
+	return fmt.Errorf("supervised serve is not supported on Windows")
+}
+
+// Supervisor is an unusable stand-in on Windows; NewSupervisor always errors.
+type Supervisor struct{}
+
+// NewSupervisor always fails on Windows.
+func NewSupervisor() (*Supervisor, error) {
+	return nil, fmt.Errorf("supervised serve is not supported on Windows")
+}
+
+func (s *Supervisor) Run(addr string) error            { return fmt.Errorf("not supported on Windows") }
+func (s *Supervisor) Restart(newExecPath string) error { return fmt.Errorf("not supported on Windows") }
+func (s *Supervisor) CheckAndApplyUpdate() error       { return fmt.Errorf("not supported on Windows") }
@@ -16,6 +16,11 @@ import (
 // applyUpdateDarwin downloads the macOS zip, extracts the .app bundle,
 // and replaces the running .app in place. macOS does not lock running
 // binaries so this works without a helper script.
+//
+// downloadAsset never returns bytes that haven't already passed both the
+// detached Ed25519 signature check and the signed manifest's checksum check
+// (see Fetcher.Fetch / fetchVerified), so appPath is never touched with
+// anything that hasn't verified — there's nothing left to verify here.
 func applyUpdateDarwin(info *releaseInfo) error {
 	exe, err := os.Executable()
 	if err != nil {
@@ -41,7 +46,7 @@ func applyUpdateDarwin(info *releaseInfo) error {
 	tmpFile.Close()
 	defer os.Remove(tmpPath)
 
-	if err := downloadAsset(info.release.AssetURL, tmpPath); err != nil {
+	if err := downloadAsset(info, tmpPath); err != nil {
 		return fmt.Errorf("downloading update: %w", err)
 	}
 
@@ -116,6 +121,19 @@ func applyUpdateDarwin(info *releaseInfo) error {
 		return fmt.Errorf("installing new app: %w", err)
 	}
 
+	// exe still resolves to the right on-disk path — appPath was renamed as
+	// a whole directory, so the relative Contents/MacOS/VoiceRelay path into
+	// it is unchanged. Probe it before trusting the swap enough to discard
+	// the only copy of the app that's known to still work.
+	if err := probeHealth(exe); err != nil {
+		log.Printf("Health probe failed for updated app, rolling back: %v", err)
+		os.RemoveAll(appPath)
+		if rbErr := os.Rename(backupPath, appPath); rbErr != nil {
+			return fmt.Errorf("health probe failed (%v) and rollback failed: %w", err, rbErr)
+		}
+		return fmt.Errorf("update health probe failed, rolled back to previous version: %w", err)
+	}
+
 	os.RemoveAll(backupPath)
 	log.Printf("macOS app bundle updated at %s", appPath)
 	return nil
@@ -124,3 +142,9 @@ func applyUpdateDarwin(info *releaseInfo) error {
 func applyUpdateWindows(_ *releaseInfo, _ func()) error {
 	panic("applyUpdateWindows called on macOS")
 }
+
+// RunSwapHelperIfRequested is a no-op on macOS, which replaces its .app bundle
+// in-place without needing a helper process.
+func RunSwapHelperIfRequested() bool {
+	return false
+}
@@ -9,3 +9,9 @@ func applyUpdateWindows(_ *releaseInfo, _ func()) error {
 func applyUpdateDarwin(_ *releaseInfo) error {
 	panic("applyUpdateDarwin called on non-macOS platform")
 }
+
+// RunSwapHelperIfRequested is a no-op outside Windows, which is the only platform
+// that needs a separate process to swap out its own running binary.
+func RunSwapHelperIfRequested() bool {
+	return false
+}
@@ -8,14 +8,26 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
+	"unsafe"
 )
 
-// applyUpdateWindows downloads the new exe to a staging file, writes a helper
-// PowerShell script that waits for our process to exit, swaps the files, and
-// relaunches the app. The caller's quit function is invoked so the current
-// process exits and the script can proceed.
+// swapHelperFlag marks a re-exec of the binary whose only job is to wait for the
+// original process to exit, then atomically swap the staged binary into place and
+// relaunch it. Kept internal (not part of the public CLI surface).
+const swapHelperFlag = "--voicerelay-apply-update"
+
+// applyUpdateWindows downloads the new exe to a staging file next to the running
+// one, then launches a detached copy of this same binary to perform the swap once
+// we exit (Windows won't let a running process overwrite its own image). The
+// caller's quit function is invoked so the current process exits promptly.
+//
+// downloadAsset never returns bytes that haven't already passed both the
+// detached Ed25519 signature check and the signed manifest's checksum check
+// (see Fetcher.Fetch / fetchVerified), so staged is already known-good by the
+// time the swap helper takes over.
 func applyUpdateWindows(info *releaseInfo, quit func()) error {
 	exe, err := os.Executable()
 	if err != nil {
@@ -26,71 +38,25 @@ func applyUpdateWindows(info *releaseInfo, quit func()) error {
 		return fmt.Errorf("resolving executable: %w", err)
 	}
 
-	dir := filepath.Dir(exe)
-	base := filepath.Base(exe)
-	staged := filepath.Join(dir, base+".new")
-	script := filepath.Join(dir, "update.ps1")
+	staged := exe + ".new"
 
 	log.Printf("Downloading update to %s", staged)
-	if err := downloadAsset(info.release.AssetURL, staged); err != nil {
+	if err := downloadAsset(info, staged); err != nil {
 		os.Remove(staged)
 		return fmt.Errorf("downloading update: %w", err)
 	}
 
 	pid := os.Getpid()
-
-	// PowerShell script that:
-	// 1. Waits for our process to fully exit
-	// 2. Retries the move up to 10 times (file lock may linger briefly)
-	// 3. Starts the new exe
-	// 4. Cleans up staged file and script
-	ps := fmt.Sprintf(
-		"try { Wait-Process -Id %d -Timeout 30 -ErrorAction SilentlyContinue } catch {}\r\n"+
-			"Start-Sleep -Seconds 1\r\n"+
-			"$ok = $false\r\n"+
-			"for ($i = 0; $i -lt 10; $i++) {\r\n"+
-			"  try {\r\n"+
-			"    Move-Item -Path '%s' -Destination '%s' -Force\r\n"+
-			"    $ok = $true\r\n"+
-			"    break\r\n"+
-			"  } catch {\r\n"+
-			"    Start-Sleep -Seconds 1\r\n"+
-			"  }\r\n"+
-			"}\r\n"+
-			"if ($ok) {\r\n"+
-			"  Start-Process -FilePath '%s'\r\n"+
-			"}\r\n"+
-			"Remove-Item -Path '%s' -Force -ErrorAction SilentlyContinue\r\n",
-		pid,
-		staged, exe,
-		exe,
-		script,
-	)
-	if err := os.WriteFile(script, []byte(ps), 0755); err != nil {
-		os.Remove(staged)
-		return fmt.Errorf("writing update script: %w", err)
-	}
-
-	log.Printf("Launching update script: %s", script)
-	cmd := exec.Command("powershell.exe",
-		"-NoProfile", "-ExecutionPolicy", "Bypass",
-		"-WindowStyle", "Hidden",
-		"-File", script,
-	)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd := exec.Command(exe, swapHelperFlag, strconv.Itoa(pid), staged, exe)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CreationFlags: createNoWindow | detachedProcess}
 	if err := cmd.Start(); err != nil {
 		os.Remove(staged)
-		os.Remove(script)
-		return fmt.Errorf("launching update script: %w", err)
+		return fmt.Errorf("launching swap helper: %w", err)
 	}
-
 	cmd.Process.Release()
 
-	log.Println("Update staged — exiting for script to swap binary")
+	log.Println("Update staged — exiting for helper process to swap binary")
 
-	// Start graceful shutdown in the background (closes child processes,
-	// systray, etc.) but don't wait for it — hard-exit after a deadline
-	// so the helper script can swap the binary.
 	if quit != nil {
 		go quit()
 	}
@@ -103,3 +69,115 @@ func applyUpdateWindows(info *releaseInfo, quit func()) error {
 func applyUpdateDarwin(_ *releaseInfo) error {
 	panic("applyUpdateDarwin called on Windows")
 }
+
+const (
+	createNoWindow          = 0x08000000
+	detachedProcess         = 0x00000008
+	movefileReplaceExisting = 0x1
+	movefileWriteThrough    = 0x8
+)
+
+// RunSwapHelperIfRequested checks os.Args for the internal swap-helper invocation. If
+// present, it waits for the parent process (by PID) to exit, atomically moves the
+// staged binary over the target with rollback on failure, relaunches the target, and
+// exits the process. It never returns in that case; it returns false otherwise so
+// callers can fall through to normal startup.
+func RunSwapHelperIfRequested() bool {
+	args := os.Args[1:]
+	if len(args) != 4 || args[0] != swapHelperFlag {
+		return false
+	}
+
+	pid, err := strconv.Atoi(args[1])
+	if err != nil {
+		log.Printf("swap helper: invalid pid %q: %v", args[1], err)
+		os.Exit(1)
+	}
+	staged, target := args[2], args[3]
+
+	waitForProcessExit(pid, 30*time.Second)
+
+	backup := target + ".old"
+	os.Remove(backup)
+
+	if err := os.Rename(target, backup); err != nil {
+		log.Printf("swap helper: backing up %s: %v", target, err)
+		os.Exit(1)
+	}
+
+	if err := moveFileReplace(staged, target); err != nil {
+		log.Printf("swap helper: swapping in %s: %v, rolling back", staged, err)
+		os.Rename(backup, target)
+		os.Exit(1)
+	}
+
+	// Smoke-test the swapped-in binary before discarding the backup — a
+	// partially-written or broken exe fails this quickly and gets rolled
+	// back instead of leaving the user on a binary that won't run at all.
+	if err := probeHealth(target); err != nil {
+		log.Printf("swap helper: health probe failed for %s: %v, rolling back", target, err)
+		os.Remove(target)
+		if rbErr := os.Rename(backup, target); rbErr != nil {
+			log.Printf("swap helper: rollback failed: %v", rbErr)
+			os.Exit(1)
+		}
+	} else {
+		os.Remove(backup)
+	}
+
+	cmd := exec.Command(target)
+	if err := cmd.Start(); err != nil {
+		log.Printf("swap helper: relaunching %s: %v", target, err)
+	}
+
+	os.Exit(0)
+	return true // unreachable
+}
+
+// moveFileReplace atomically replaces dst with src using the Win32 MoveFileEx API
+// (MOVEFILE_REPLACE_EXISTING|MOVEFILE_WRITE_THROUGH), which succeeds even when dst
+// was the path of a process that has since exited — unlike os.Rename, it forces the
+// write through to disk before returning so a crash right after can't leave a
+// half-written binary in place.
+func moveFileReplace(src, dst string) error {
+	srcPtr, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	dstPtr, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+
+	moveFileEx := syscall.NewLazyDLL("kernel32.dll").NewProc("MoveFileExW")
+	ret, _, callErr := moveFileEx.Call(
+		uintptr(unsafe.Pointer(srcPtr)),
+		uintptr(unsafe.Pointer(dstPtr)),
+		uintptr(movefileReplaceExisting|movefileWriteThrough),
+	)
+	if ret == 0 {
+		return fmt.Errorf("MoveFileExW failed: %w", callErr)
+	}
+	return nil
+}
+
+const stillActive = 259
+
+// waitForProcessExit polls GetExitCodeProcess for the given PID until it reports the
+// process has exited, up to timeout.
+func waitForProcessExit(pid int, timeout time.Duration) {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return // already gone
+	}
+	defer syscall.CloseHandle(handle)
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		var exitCode uint32
+		if err := syscall.GetExitCodeProcess(handle, &exitCode); err != nil || exitCode != stillActive {
+			return
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+}
@@ -1,26 +1,69 @@
 package updater
 
 import (
-	"archive/zip"
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
+// ErrNoUpdate is returned by Apply when the running binary is already on the
+// latest release.
+var ErrNoUpdate = errors.New("no update available")
+
 const (
 	CurrentVersion = "1.1.0"
 	repoOwner      = "dzearing"
 	repoName       = "voice-relay"
 )
 
-type gitHubRelease struct {
+// Config selects and configures where updates come from. The coordinator (or
+// the `serve` CLI subcommand) calls Configure once at startup; everything
+// else reads activeConfig via newFetcher.
+type Config struct {
+	Fetcher FetcherConfig
+}
+
+var (
+	configMu     sync.RWMutex
+	activeConfig Config
+)
+
+// Configure sets the Fetcher used by subsequent update checks. Uninvoked,
+// updates default to GitHubFetcher against dzearing/voice-relay.
+func Configure(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	activeConfig = cfg
+}
+
+func currentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return activeConfig
+}
+
+// updatePublicKeyHex is the hex-encoded Ed25519 public key used to verify release
+// signatures. It is embedded at build time via:
+//
+//	-ldflags "-X github.com/voice-relay/echo-desktop/internal/updater.updatePublicKeyHex=<hex>"
+//
+// Builds without an embedded key refuse to apply updates rather than silently
+// skipping verification.
+var updatePublicKeyHex string
+
+type githubRelease struct {
 	TagName string `json:"tag_name"`
 	Assets  []struct {
 		Name               string `json:"name"`
@@ -28,186 +71,179 @@ type gitHubRelease struct {
 	} `json:"assets"`
 }
 
-// CheckForUpdates checks GitHub for a newer release and installs it if found.
-func CheckForUpdates() {
-	log.Println("Checking for updates...")
+// releaseInfo describes an available update, bound to the Fetcher that
+// discovered it so downloadAsset can fetch the matching platform asset
+// regardless of which source (GitHub, HTTPS manifest, S3 bucket) it came
+// from.
+type releaseInfo struct {
+	Version string
+	fetcher Fetcher
+}
 
-	release, err := getLatestRelease()
+// CheckForUpdates checks GitHub for a newer release and installs it non-interactively.
+func CheckForUpdates() {
+	info, err := checkLatest()
 	if err != nil {
 		log.Printf("Update check failed: %v", err)
 		return
 	}
-
-	latestVersion := strings.TrimPrefix(release.TagName, "v")
-	if latestVersion == CurrentVersion {
+	if info == nil {
 		log.Printf("Already on latest version (%s)", CurrentVersion)
 		return
 	}
 
-	log.Printf("New version available: %s (current: %s)", latestVersion, CurrentVersion)
-
-	assetName := getAssetName()
-	var downloadURL string
-	for _, asset := range release.Assets {
-		if asset.Name == assetName {
-			downloadURL = asset.BrowserDownloadURL
-			break
-		}
-	}
-
-	if downloadURL == "" {
-		log.Printf("No download available for this platform")
-		return
-	}
-
-	if err := downloadAndInstall(downloadURL, assetName); err != nil {
+	log.Printf("New version available: %s (current: %s)", info.Version, CurrentVersion)
+	if err := applyUpdate(info, nil); err != nil {
 		log.Printf("Update failed: %v", err)
 		return
 	}
-
 	log.Println("Update installed! Please restart the app.")
 }
 
-func getLatestRelease() (*gitHubRelease, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", repoOwner, repoName)
-
-	resp, err := http.Get(url)
+// CheckOnly reports whether a newer release is available without installing it.
+func CheckOnly() (version string, available bool, err error) {
+	info, err := checkLatest()
 	if err != nil {
-		return nil, err
+		return "", false, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("GitHub API returned %d", resp.StatusCode)
+	if info == nil {
+		return "", false, nil
 	}
-
-	var release gitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, err
-	}
-
-	return &release, nil
+	return info.Version, true, nil
 }
 
-func getAssetName() string {
-	if runtime.GOOS == "darwin" {
-		return "VoiceRelay-macOS-arm64.zip"
-	}
-	return "VoiceRelay.exe"
-}
-
-func downloadAndInstall(url, assetName string) error {
-	log.Printf("Downloading %s...", assetName)
-
-	resp, err := http.Get(url)
+// Apply checks for and installs the latest release, returning ErrNoUpdate if the
+// running binary is already current. Used by the `voice-relay update --apply` CLI
+// subcommand, which runs synchronously rather than in the background.
+func Apply() error {
+	info, err := checkLatest()
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+	if info == nil {
+		return ErrNoUpdate
 	}
+	return applyUpdate(info, nil)
+}
 
-	data, err := io.ReadAll(resp.Body)
+// checkLatest resolves the latest release via the configured Fetcher (see
+// Configure) and returns a releaseInfo if it is newer than CurrentVersion, or
+// (nil, nil) if already up to date.
+func checkLatest() (*releaseInfo, error) {
+	fetcher, err := newFetcher(currentConfig().Fetcher)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	log.Printf("Downloaded %d bytes", len(data))
-
-	execPath, err := os.Executable()
-	if err != nil {
-		return err
+	if err := fetcher.Init(currentConfig().Fetcher); err != nil {
+		return nil, err
 	}
 
-	if runtime.GOOS == "darwin" {
-		return installMacOS(data, execPath)
+	version := fetcher.Current()
+	if version == CurrentVersion {
+		return nil, nil
 	}
 
-	return installWindows(data, execPath)
+	return &releaseInfo{Version: version, fetcher: fetcher}, nil
 }
 
-func installMacOS(zipData []byte, execPath string) error {
-	appPath := execPath
-	for i := 0; i < 3; i++ {
-		appPath = filepath.Dir(appPath)
+func getAssetName() string {
+	if runtime.GOOS == "darwin" {
+		return "VoiceRelay-macOS-arm64.zip"
 	}
+	return fmt.Sprintf("VoiceRelay-windows-%s.exe", runtime.GOARCH)
+}
 
-	if !strings.HasSuffix(appPath, ".app") {
-		return fmt.Errorf("not running from .app bundle")
+// applyUpdate dispatches to the platform-specific installer.
+func applyUpdate(info *releaseInfo, quit func()) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return applyUpdateDarwin(info)
+	case "windows":
+		return applyUpdateWindows(info, quit)
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
+}
 
-	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+// downloadAsset fetches and signature-verifies info's release asset via its
+// Fetcher, then writes the verified bytes to destPath. The file is never
+// written to disk unverified.
+func downloadAsset(info *releaseInfo, destPath string) error {
+	r, err := info.fetcher.Fetch()
 	if err != nil {
 		return err
 	}
 
-	tempDir, err := os.MkdirTemp("", "voicerelay-update")
+	f, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	for _, file := range zipReader.File {
-		destPath := filepath.Join(tempDir, file.Name)
-
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(destPath, file.Mode())
-			continue
-		}
-
-		os.MkdirAll(filepath.Dir(destPath), 0755)
-
-		destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			return err
-		}
-
-		srcFile, err := file.Open()
-		if err != nil {
-			destFile.Close()
-			return err
-		}
-
-		_, err = io.Copy(destFile, srcFile)
-		srcFile.Close()
-		destFile.Close()
-		if err != nil {
-			return err
-		}
-	}
-
-	backupPath := appPath + ".backup"
-	os.RemoveAll(backupPath)
-
-	if err := os.Rename(appPath, backupPath); err != nil {
+	if _, err := io.Copy(f, r); err != nil {
 		return err
 	}
+	return f.Sync()
+}
 
-	newAppPath := filepath.Join(tempDir, "VoiceRelay.app")
-	if err := os.Rename(newAppPath, appPath); err != nil {
-		os.Rename(backupPath, appPath)
-		return err
+func fetchAll(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	os.RemoveAll(backupPath)
-	os.RemoveAll(tempDir)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d fetching %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(resp.Body)
+}
 
+// healthProbeTimeout bounds how long installMacOS/installWindows wait for a
+// freshly installed binary to prove it isn't corrupt or crash-looping before
+// discarding the backup they staged it alongside.
+const healthProbeTimeout = 5 * time.Second
+
+// probeHealth runs execPath's `status` subcommand as a smoke test: it loads
+// config and exits, so a binary that's partially written, missing a shared
+// library, or crashing on startup fails it quickly without having to drive
+// the full app. It isn't a substitute for the `serve` subcommand's
+// crash-loop detection (see Supervisor.Restart) — that's for the headless
+// path where a listener is already open — just enough to catch a bad swap
+// on the desktop tray app before discarding the only copy that still works.
+func probeHealth(execPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), healthProbeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, execPath, "status").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("health probe failed: %w (%s)", err, strings.TrimSpace(string(out)))
+	}
 	return nil
 }
 
-func installWindows(exeData []byte, execPath string) error {
-	oldPath := execPath + ".old"
-	os.Remove(oldPath)
-
-	if err := os.Rename(execPath, oldPath); err != nil {
-		return err
+// VerifyRelease checks a detached Ed25519 signature over the SHA-256 digest
+// of data against TrustedKeys, succeeding if any one of them matches — see
+// TrustedKeys for why that's what makes key rotation possible. It is
+// exported so tests can exercise the verification path with fixture keys.
+func VerifyRelease(data, sig []byte) error {
+	if len(TrustedKeys) == 0 {
+		return fmt.Errorf("no trusted update keys embedded in this build")
 	}
-
-	if err := os.WriteFile(execPath, exeData, 0755); err != nil {
-		os.Rename(oldPath, execPath)
-		return err
+	if len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("signature has wrong length (%d bytes)", len(sig))
 	}
 
-	return nil
+	sum := sha256.Sum256(data)
+	for _, key := range TrustedKeys {
+		pubKey, err := hex.DecodeString(key.PublicKeyHex)
+		if err != nil || len(pubKey) != ed25519.PublicKeySize {
+			continue
+		}
+		if ed25519.Verify(ed25519.PublicKey(pubKey), sum[:], sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match any trusted update key")
 }
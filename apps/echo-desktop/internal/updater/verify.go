@@ -0,0 +1,151 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TrustedKey is one Ed25519 public key a release manifest or asset signature
+// may be checked against. Label identifies which signing key produced a
+// given manifest signature (see manifestFile.Signatures) so a manifest
+// signed by several keys during a rotation can be matched up without
+// guessing which signature belongs to which key.
+type TrustedKey struct {
+	Label        string
+	PublicKeyHex string
+}
+
+// TrustedKeys is the hard-coded set of public keys this build accepts
+// release signatures from. It starts with whichever single key was embedded
+// at build time via updatePublicKeyHex (labelled "embedded", for backward
+// compatibility with existing release pipelines); additional keys are
+// appended here ahead of a rotation. Both VerifyRelease and VerifyManifest
+// accept a signature from any key in this slice, so a release signed only
+// with the incoming key already verifies for builds that have picked it up,
+// while a release signed with both keys (see KeyRotationPolicy) keeps
+// verifying for builds that still only trust the outgoing one. Once every
+// build in the field has picked up the new key, the outgoing TrustedKey
+// entry can be deleted from source in a later release.
+var TrustedKeys []TrustedKey
+
+func init() {
+	if updatePublicKeyHex != "" {
+		TrustedKeys = append(TrustedKeys, TrustedKey{Label: "embedded", PublicKeyHex: updatePublicKeyHex})
+	}
+}
+
+// KeyRotationPolicy controls how many independently-signed manifest
+// signatures VerifyManifest requires before accepting it. Rotating a key
+// means cutting one release whose manifest is signed by both the outgoing
+// and incoming key, so MinSignatures stays satisfiable for builds trusting
+// only one of the two; it is not raised to require both, since that would
+// break verification for whichever build hasn't picked up the new key yet.
+type KeyRotationPolicy struct {
+	MinSignatures int
+}
+
+// DefaultKeyRotationPolicy accepts a manifest signed by any single trusted key.
+var DefaultKeyRotationPolicy = KeyRotationPolicy{MinSignatures: 1}
+
+// signingPayload returns the bytes a manifest signature is computed over: the
+// version plus each asset name and its checksum, sorted by name so the
+// payload is deterministic regardless of map iteration order or how the
+// manifest's JSON happened to lay the fields out.
+func (m *manifestFile) signingPayload() []byte {
+	names := make([]string, 0, len(m.Assets))
+	for name := range m.Assets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(m.Version)
+	buf.WriteByte('\n')
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte(':')
+		buf.WriteString(m.Assets[name].Checksum)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// VerifyManifest checks m.Signatures against policy using TrustedKeys,
+// matching each signature to the TrustedKey with the same label. It returns
+// nil once at least policy.MinSignatures of them verify; unknown labels (a
+// key this build doesn't trust yet, or doesn't trust anymore) are ignored
+// rather than treated as an error, which is what lets a dual-signed manifest
+// verify across a rotation.
+func VerifyManifest(m *manifestFile, policy KeyRotationPolicy) error {
+	if len(TrustedKeys) == 0 {
+		return fmt.Errorf("no trusted update keys embedded in this build")
+	}
+
+	payload := m.signingPayload()
+	valid := 0
+	for _, key := range TrustedKeys {
+		sigHex, ok := m.Signatures[key.Label]
+		if !ok {
+			continue
+		}
+		if verifySignature(key.PublicKeyHex, payload, sigHex) == nil {
+			valid++
+		}
+	}
+
+	if valid < policy.MinSignatures {
+		return fmt.Errorf("manifest has %d valid signature(s), need at least %d", valid, policy.MinSignatures)
+	}
+	return nil
+}
+
+// verifySignature checks a hex-encoded Ed25519 signature over payload
+// against a hex-encoded public key.
+func verifySignature(pubKeyHex string, payload []byte, sigHex string) error {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("public key has wrong length (%d bytes)", len(pubKey))
+	}
+
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("signature has wrong length (%d bytes)", len(sig))
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pubKey), payload, sig) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+// verifyAssetChecksum checks data's SHA-256 digest against the checksum m's
+// manifest lists for assetName — the independent, manifest-driven check
+// fetchVerified's detached-signature check doesn't provide, since a
+// compromised signing key would sign whatever bytes it was handed either way.
+func verifyAssetChecksum(m *manifestFile, assetName string, data []byte) error {
+	asset, ok := m.Assets[assetName]
+	if !ok {
+		return fmt.Errorf("manifest has no checksum entry for %s", assetName)
+	}
+	if asset.Checksum == "" {
+		return fmt.Errorf("manifest checksum entry for %s is empty", assetName)
+	}
+
+	digest := sha256.Sum256(data)
+	sum := hex.EncodeToString(digest[:])
+	if sum != strings.ToLower(asset.Checksum) {
+		return fmt.Errorf("checksum mismatch for %s: manifest says %s, downloaded bytes hash to %s", assetName, asset.Checksum, sum)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +13,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,12 +21,15 @@ import (
 	"github.com/ncruces/zenity"
 
 	"github.com/voice-relay/echo-desktop/internal/agent"
+	"github.com/voice-relay/echo-desktop/internal/cli"
 	"github.com/voice-relay/echo-desktop/internal/client"
 	"github.com/voice-relay/echo-desktop/internal/config"
 	"github.com/voice-relay/echo-desktop/internal/coordinator"
+	"github.com/voice-relay/echo-desktop/internal/engine"
 	"github.com/voice-relay/echo-desktop/internal/keyboard"
 	"github.com/voice-relay/echo-desktop/internal/llm"
 	"github.com/voice-relay/echo-desktop/internal/notifications"
+	"github.com/voice-relay/echo-desktop/internal/notifications/desktop"
 	"github.com/voice-relay/echo-desktop/internal/setup"
 	"github.com/voice-relay/echo-desktop/internal/stt"
 	"github.com/voice-relay/echo-desktop/internal/tray"
@@ -32,16 +38,112 @@ import (
 )
 
 var (
-	cfg        *config.Config
-	echoClient *client.Client
-	sttEngine  *stt.Engine
-	llmEngine  *llm.Engine
-	ttsEngine  *tts.Engine
+	cfg         *config.Config
+	echoClient  *client.Client
+	sttEngine   *stt.Engine
+	llmEngine   *llm.Engine
+	llmRegistry *llm.Registry // additional backends declared in cfg.LLMBackends
+	ttsEngine   *tts.Engine
+
+	ttsEngineCacheMu sync.Mutex
+	ttsEngineCache   = map[string]*tts.Engine{} // modelPath -> Engine, so previewing/streaming a voice reuses its persistent piper process
+
+	// engineRegistry holds the additional TTS/STT backends declared in
+	// cfg.Engines (see config.EngineSpec), keyed by name so a "<engine>:<voice>"
+	// spec can route a request away from the default Piper/whisper-server path.
+	engineRegistry = engine.NewRegistry()
 )
 
+// buildEngineRegistry populates engineRegistry from cfg.Engines.
+func buildEngineRegistry(cfg *config.Config) {
+	reg := engine.NewRegistry()
+	for name, spec := range cfg.Engines {
+		switch spec.Type {
+		case "openai":
+			backend := &engine.OpenAICompatible{BaseURL: spec.BaseURL, APIKey: spec.APIKey, Model: spec.Model}
+			reg.RegisterSynthesizer(name, backend)
+			reg.RegisterRecognizer(name, backend)
+		case "deepgram":
+			reg.RegisterRecognizer(name, &engine.Deepgram{BaseURL: spec.BaseURL, APIKey: spec.APIKey, Model: spec.Model})
+		case "kokoro":
+			reg.RegisterSynthesizer(name, &engine.Kokoro{
+				BinPath:    spec.BinPath,
+				ModelPath:  spec.ModelPath,
+				VoicesPath: spec.VoicesPath,
+				Voice:      spec.Model,
+			})
+		default:
+			log.Printf("Engine %q has unknown type %q, skipping", name, spec.Type)
+		}
+	}
+	engineRegistry = reg
+}
+
+// synthesizeWithEngine synthesizes text via the backend named engineName in
+// engineRegistry, for a "<engine>:<voice>" spec that picks something other
+// than the default Piper engine.
+func synthesizeWithEngine(engineName, text, voice string) ([]byte, error) {
+	synth, err := engineRegistry.Synthesizer(engineName)
+	if err != nil {
+		return nil, err
+	}
+	audio, format, err := synth.Synthesize(context.Background(), text, engine.SynthesizeOptions{Voice: voice})
+	if err != nil {
+		return nil, err
+	}
+	defer audio.Close()
+	if format.Container != "wav" {
+		return nil, fmt.Errorf("engine %q returned unsupported container %q, want wav", engineName, format.Container)
+	}
+	return io.ReadAll(audio)
+}
+
+// ttsEngineFor returns the cached Engine for modelPath, creating one (and
+// starting its persistent piper process) on first use.
+func ttsEngineFor(piperPath, modelPath string) *tts.Engine {
+	ttsEngineCacheMu.Lock()
+	defer ttsEngineCacheMu.Unlock()
+
+	if eng, ok := ttsEngineCache[modelPath]; ok {
+		return eng
+	}
+	eng := tts.NewEngine(piperPath, modelPath)
+	ttsEngineCache[modelPath] = eng
+	return eng
+}
+
 var devMode bool
 
+var stopConfigWatch func()
+var stopLLMProfilesWatch func()
+var stopLLMPluginsWatch func()
+var stopAgentToolsWatch func()
+var talkAgent *agent.Agent
+var desktopSink desktop.Sink
+var notifWatcher *notifications.Watcher
+
+// onConfigChanged is invoked by config.Watch whenever the config file changes on
+// disk. It updates the fields observable elsewhere in the app in place, since cfg
+// is shared by pointer; it does not re-run mode-specific startup (e.g. switching
+// coordinator<->client still requires a restart).
+func onConfigChanged(newCfg *config.Config) {
+	*cfg = *newCfg
+	tray.UpdateConfig(cfg)
+}
+
 func main() {
+	// Internal re-exec used by the Windows updater to swap the binary after this
+	// process exits. Never returns when it was the swap helper invocation.
+	if updater.RunSwapHelperIfRequested() {
+		return
+	}
+
+	// Headless/scriptable subcommands (voice-relay setup/connect/config/update/status)
+	// bypass the tray app and wizard entirely.
+	if len(os.Args) > 1 && cli.IsSubcommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args))
+	}
+
 	// --force: kill any existing VoiceRelay instances before starting
 	for _, arg := range os.Args[1:] {
 		if arg == "--force" {
@@ -62,6 +164,13 @@ func main() {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds)
 
 	cfg = config.Load()
+	updater.Configure(updater.Config{Fetcher: updater.FetcherConfig{
+		Source:      cfg.Updater.Source,
+		RepoOwner:   cfg.Updater.RepoOwner,
+		RepoName:    cfg.Updater.RepoName,
+		ManifestURL: cfg.Updater.ManifestURL,
+		BucketURL:   cfg.Updater.BucketURL,
+	}})
 
 	// First-run setup wizard
 	if !cfg.SetupComplete {
@@ -127,10 +236,32 @@ func onReady() {
 	// Check Accessibility permission (macOS only — needed for paste injection)
 	ensureAccessibility()
 
+	// Pick up manual edits to the config file (e.g. from `voice-relay config set`
+	// or a hand-edited YAML) without requiring a restart.
+	if stop, err := config.Watch(onConfigChanged); err != nil {
+		log.Printf("Config watch not available: %v", err)
+	} else {
+		stopConfigWatch = stop
+	}
+
+	// Pick up edits to the LLM prompt/parameter profiles (prompts, max_tokens,
+	// temperature — see llm.ProfilesPath) and new plugins/*.so extension
+	// profiles without requiring a restart.
+	if err := llm.LoadProfiles(llm.ProfilesPath(config.Dir())); err != nil {
+		log.Printf("Loading LLM profiles: %v", err)
+	}
+	if stop, err := llm.WatchProfiles(llm.ProfilesPath(config.Dir())); err != nil {
+		log.Printf("LLM profiles watch not available: %v", err)
+	} else {
+		stopLLMProfilesWatch = stop
+	}
+	stopLLMPluginsWatch = llm.WatchPlugins(filepath.Join(config.Dir(), "plugins"))
+
 	// Start coordinator if configured
 	if cfg.RunAsCoordinator {
 		// Set the URL before starting so the client knows where to connect
 		cfg.CoordinatorURL = fmt.Sprintf("ws://localhost:%d/ws", cfg.Port)
+		coordinator.SetTrustedProxies(cfg.TrustedProxies)
 
 		// Auto-start Tailscale Funnel and detect the URL
 		ts := setup.DetectTailscale()
@@ -162,17 +293,31 @@ func onReady() {
 			log.Printf("Tailscale not available, coordinator only accessible on localhost")
 		}
 
+		coordinator.StartCodeRotation()
+		pairingURL := coordinator.GetExternalURL()
+		if pairingURL == "" {
+			pairingURL = fmt.Sprintf("http://localhost:%d", cfg.Port)
+		}
+		coordinator.PrintPairingInstructions(pairingURL)
+
 		go initCoordinator()
 	}
 
 	// Create echo client
-	echoClient = client.New(cfg.Name, cfg.CoordinatorURL, tray.UpdateStatus)
+	echoClient = client.New(cfg.Name, cfg.CoordinatorURL, cfg.E2EPassphrase, tray.UpdateStatus)
 
 	// Setup systray menu
 	tray.SetupMenu(cfg, tray.Callbacks{
 		OnReconnect: handleReconnect,
 		OnQuit:      func() { echoClient.Close() },
 		DevMode:     devMode,
+		OnReloadTools: func() error {
+			if talkAgent == nil {
+				return fmt.Errorf("talk agent not available")
+			}
+			return talkAgent.ReloadTools()
+		},
+		OnDesktopNotificationsSet: setDesktopNotifications,
 	})
 
 	// Check for updates in background
@@ -195,6 +340,48 @@ func onReady() {
 	}()
 }
 
+// setDesktopNotifications starts or stops the native OS toast sink,
+// following cfg.DesktopNotifications both at startup and whenever the tray's
+// "Desktop Notifications" checkbox is toggled. notifWatcher.SetNotifyFunc is
+// cleared (not just left pointing at a closed sink) so a disabled toggle
+// actually takes effect immediately rather than erroring on every
+// notification until the next restart.
+func setDesktopNotifications(enabled bool) {
+	if notifWatcher == nil {
+		return
+	}
+
+	if !enabled {
+		notifWatcher.SetNotifyFunc(nil)
+		if desktopSink != nil {
+			desktopSink.Close()
+			desktopSink = nil
+		}
+		return
+	}
+
+	if desktopSink != nil {
+		return // already running
+	}
+
+	sink, err := desktop.NewSink(func(id string) {
+		if _, err := notifWatcher.Dismiss(id); err != nil {
+			log.Printf("Desktop notification click: failed to dismiss %s: %v", id, err)
+		}
+	})
+	if err != nil {
+		log.Printf("Desktop notifications not available: %v", err)
+		return
+	}
+
+	desktopSink = sink
+	notifWatcher.SetNotifyFunc(func(id, title, body string) {
+		if err := desktopSink.Notify(id, title, body); err != nil {
+			log.Printf("Desktop notification failed: %v", err)
+		}
+	})
+}
+
 func handleReconnect() {
 	// Coordinator mode: just reconnect to localhost, no dialog needed
 	if cfg.RunAsCoordinator {
@@ -237,7 +424,7 @@ func handleReconnect() {
 			dlg.Text("Connecting...")
 		}
 
-		wsURL, resolveErr := setup.ResolveCoordinatorURL(code)
+		wsURL, resolveErr := setup.ResolveCoordinatorURLWithProviders(code, cfg.ShortURLProviders)
 
 		if dlgErr == nil {
 			dlg.Close()
@@ -265,6 +452,8 @@ func handleReconnect() {
 }
 
 func initCoordinator() {
+	coordinator.SetE2EPassphrase(cfg.E2EPassphrase)
+
 	dataDir := config.Dir()
 	modelsDir := filepath.Join(dataDir, "models")
 	binDir := filepath.Join(dataDir, "bin")
@@ -278,7 +467,15 @@ func initCoordinator() {
 		if err != nil {
 			log.Printf("whisper-server not available: %v", err)
 		} else {
-			engine, err := stt.NewEngine(modelPath, serverPath, 8178)
+			sttSink := stt.NewFileSink(filepath.Join(dataDir, "logs"), "whisper-server.log", 10, 5, 14)
+			onSTTError := func(entry stt.LogEntry) {
+				// No generic error-toast UI exists yet (tray.UpdateStatus is
+				// specific to the coordinator connection icon, so reusing it
+				// here would misreport a transcription hiccup as a
+				// disconnect) — log prominently until one does.
+				log.Printf("whisper-server error: %s", entry.Message)
+			}
+			engine, err := stt.NewEngine(modelPath, serverPath, 8178, sttSink, onSTTError)
 			if err != nil {
 				log.Printf("Failed to initialize STT engine: %v", err)
 			} else {
@@ -300,22 +497,101 @@ func initCoordinator() {
 			if err != nil {
 				log.Printf("llama-server not available: %v", err)
 			} else {
-				engine, err := llm.NewEngine(llmModelPath, llmServerPath, 8179)
+				engine, err := llm.NewEngine(llmModelPath, llmServerPath, 8179, cfg.LLMWorkers)
 				if err != nil {
 					log.Printf("Failed to initialize LLM engine: %v", err)
 				} else {
 					llmEngine = engine
-					coordinator.SetLLMFunc(func(rawText string) (string, string, error) {
-						return llmEngine.CleanupText(rawText)
-					})
-					coordinator.SetNotifGenFunc(func() (map[string]string, error) {
-						return llmEngine.GenerateNotification()
-					})
 				}
 			}
 		}
 	}
 
+	llmBackends := make(map[string]llm.BackendSpec, len(cfg.LLMBackends))
+	for name, spec := range cfg.LLMBackends {
+		llmBackends[name] = llm.BackendSpec{
+			Type:    spec.Type,
+			BaseURL: spec.BaseURL,
+			APIKey:  spec.APIKey,
+			Target:  spec.Target,
+			Command: spec.Command,
+			Args:    spec.Args,
+			Model:   spec.Model,
+		}
+	}
+	reg, errs := llm.NewRegistry(llmBackends)
+	for _, err := range errs {
+		log.Printf("LLM backend unavailable: %v", err)
+	}
+	llmRegistry = reg
+
+	// activeLLM is the backend CleanupText/GenerateNotification use: the one
+	// named by cfg.LLMDefaultBackend if it started successfully, otherwise the
+	// local llama-server engine.
+	var activeLLM llm.Backend
+	if cfg.LLMDefaultBackend != "" {
+		if b, err := llmRegistry.Backend(cfg.LLMDefaultBackend); err != nil {
+			log.Printf("LLM default backend %q not available: %v", cfg.LLMDefaultBackend, err)
+		} else {
+			activeLLM = b
+		}
+	}
+	if activeLLM == nil && llmEngine != nil {
+		activeLLM = llmEngine
+	}
+	if activeLLM != nil {
+		coordinator.SetLLMFunc(func(rawText string) (string, string, error) {
+			return activeLLM.CleanupText(context.Background(), rawText)
+		})
+		coordinator.SetNotifGenFunc(func() (map[string]string, error) {
+			return activeLLM.GenerateNotification(context.Background())
+		})
+		coordinator.SetSummarizeNotifFunc(func(userText, assistantText string) (string, string, string, error) {
+			return activeLLM.SummarizeNotification(context.Background(), userText, assistantText)
+		})
+		if streaming, ok := activeLLM.(llm.StreamingBackend); ok {
+			coordinator.SetLLMStreamFunc(func(rawText string, onDelta func(cleaned, summary string)) (string, string, error) {
+				return streaming.CleanupTextStream(context.Background(), rawText, func(d llm.Delta) {
+					onDelta(d.Cleaned, d.Summary)
+				})
+			})
+		}
+	}
+
+	buildEngineRegistry(cfg)
+
+	// If the user declared STT routing rules, wrap the local whisper-server
+	// engine in an engine.Router so /transcribe can prefer a cloud backend
+	// (by language, audio length, or an explicit override) while still
+	// falling back to local whisper-server on a cloud failure. With no rules
+	// configured, sttEngine's direct SetSTTFunc from above is left in place.
+	if sttEngine != nil && len(cfg.STTRouter.Rules) > 0 {
+		router := &engine.Router{
+			Registry: engineRegistry,
+			Local:    engine.NewWhisperRecognizer(sttEngine),
+		}
+		for _, rule := range cfg.STTRouter.Rules {
+			router.Rules = append(router.Rules, engine.RouterRule{
+				Backend:       rule.Backend,
+				Language:      rule.Language,
+				MaxAudioBytes: rule.MaxAudioBytes,
+			})
+		}
+		coordinator.SetSTTFunc(func(audioData []byte, filename string) (string, error) {
+			t, err := router.Recognize(context.Background(), bytes.NewReader(audioData), engine.RecognizeOptions{})
+			return t.Text, err
+		})
+		log.Printf("STT router active with %d rule(s)", len(router.Rules))
+	}
+
+	// Initialize notification watcher's TTS funcs. Assigned inside the
+	// cfg.TTSEnabled block below (where piperPath is in scope) so they can
+	// honor a notification's own Voice override the same way
+	// SetTTSPreviewFunc does, rather than being pinned to whatever engine
+	// ttsEngine currently points at.
+	var notifTTSFunc notifications.TTSFunc
+	var notifStreamFunc notifications.TTSStreamFunc
+
 	// Initialize TTS engine
 	if cfg.TTSEnabled {
 		piperPath, err := tts.EnsureServer(binDir)
@@ -330,7 +606,7 @@ func initCoordinator() {
 			if err != nil {
 				log.Printf("TTS voice not available: %v", err)
 			} else {
-				ttsEngine = tts.NewEngine(piperPath, modelPath)
+				ttsEngine = ttsEngineFor(piperPath, modelPath)
 				coordinator.SetTTSFunc(func(text, voice, lang string) ([]byte, error) {
 					return ttsEngine.Synthesize(text)
 				})
@@ -340,7 +616,7 @@ func initCoordinator() {
 					if err != nil {
 						return err
 					}
-					ttsEngine = tts.NewEngine(piperPath, newModelPath)
+					ttsEngine = ttsEngineFor(piperPath, newModelPath)
 					cfg.TTSVoice = newVoice
 					cfg.Save()
 					log.Printf("TTS voice changed to: %s", newVoice)
@@ -349,12 +625,96 @@ func initCoordinator() {
 					return nil
 				})
 				coordinator.SetTTSPreviewFunc(func(text, voice string) ([]byte, error) {
+					if engineName, voiceName := engine.SplitVoiceSpec(voice); engineName != "" {
+						return synthesizeWithEngine(engineName, text, voiceName)
+					}
 					mp, err := tts.EnsureVoice(modelsDir, voice)
 					if err != nil {
 						return nil, err
 					}
-					return tts.NewEngine(piperPath, mp).Synthesize(text)
+					return ttsEngineFor(piperPath, mp).Synthesize(text)
+				})
+				coordinator.SetTTSStreamFunc(func(ctx context.Context, text, voice, format string) (<-chan coordinator.AudioChunk, string, error) {
+					mp, err := tts.EnsureVoice(modelsDir, voice)
+					if err != nil {
+						return nil, "", err
+					}
+					eng := ttsEngineFor(piperPath, mp)
+					pcmCh, err := eng.SynthesizeStream(ctx, text)
+					if err != nil {
+						return nil, "", err
+					}
+					enc, err := tts.EncoderNamed(format)
+					if err != nil {
+						return nil, "", err
+					}
+					encCh, err := enc.Encode(ctx, pcmCh, eng.SampleRate(), 1, 16)
+					if err != nil {
+						return nil, "", err
+					}
+					out := make(chan coordinator.AudioChunk)
+					go func() {
+						defer close(out)
+						for c := range encCh {
+							out <- coordinator.AudioChunk{Data: c.PCM, Err: c.Err}
+						}
+					}()
+					return out, enc.ContentType(), nil
+				})
+				coordinator.SetVoiceListFunc(func() ([]coordinator.VoiceInfo, error) {
+					voices, err := tts.ListVoices()
+					if err != nil {
+						return nil, err
+					}
+					out := make([]coordinator.VoiceInfo, len(voices))
+					for i, v := range voices {
+						out[i] = coordinator.VoiceInfo{
+							Name:       v.Name,
+							Language:   v.Language,
+							Quality:    v.Quality,
+							SampleRate: v.SampleRate,
+							SizeBytes:  v.SizeBytes,
+							SampleURL:  v.SampleURL,
+						}
+					}
+					return out, nil
 				})
+				notifTTSFunc = func(text, voice, language string) ([]byte, error) {
+					if engineName, voiceName := engine.SplitVoiceSpec(voice); engineName != "" {
+						return synthesizeWithEngine(engineName, text, voiceName)
+					}
+					mp, err := tts.EnsureVoice(modelsDir, voice)
+					if err != nil {
+						return nil, err
+					}
+					return ttsEngineFor(piperPath, mp).Synthesize(text)
+				}
+				// Details text is streamed (rather than rendered all at once) so a
+				// long, multi-sentence notification starts playback without waiting
+				// for the whole utterance, the same way /tts/stream does above.
+				notifStreamFunc = func(ctx context.Context, text, voice, language string) (<-chan notifications.AudioChunk, error) {
+					mp, err := tts.EnsureVoice(modelsDir, voice)
+					if err != nil {
+						return nil, err
+					}
+					eng := ttsEngineFor(piperPath, mp)
+					pcmCh, err := eng.SynthesizeStream(ctx, text)
+					if err != nil {
+						return nil, err
+					}
+					encCh, err := (tts.WAVEncoder{}).Encode(ctx, pcmCh, eng.SampleRate(), 1, 16)
+					if err != nil {
+						return nil, err
+					}
+					out := make(chan notifications.AudioChunk)
+					go func() {
+						defer close(out)
+						for c := range encCh {
+							out <- notifications.AudioChunk{PCM: c.PCM, Err: c.Err}
+						}
+					}()
+					return out, nil
+				}
 				log.Printf("TTS engine ready (Piper)")
 				// Pre-cache interim phrases for instant playback in talk mode
 				go coordinator.PreCacheInterimPhrases()
@@ -364,19 +724,19 @@ func initCoordinator() {
 
 	// Initialize notification watcher
 	notifDir := filepath.Join(dataDir, "notifications")
-	var notifTTSFunc notifications.TTSFunc
-	if ttsEngine != nil {
-		notifTTSFunc = func(text, voice, language string) ([]byte, error) {
-			return ttsEngine.Synthesize(text)
-		}
-	}
-	notifWatcher := notifications.NewWatcher(notifDir, notifTTSFunc, func() string {
+	notifWatcher = notifications.NewWatcher(notifDir, notifTTSFunc, func() string {
 		v := cfg.TTSVoice
 		if v == "" || v == "default" {
 			v = "en_US-lessac-high"
 		}
 		return v
 	}, coordinator.BroadcastNotificationsReady)
+	if notifStreamFunc != nil {
+		notifWatcher.SetTTSStreamFunc(notifStreamFunc)
+		notifWatcher.SetAudioChunkFunc(coordinator.PublishAudioChunk)
+	}
+	setDesktopNotifications(cfg.DesktopNotifications)
+
 	if err := notifWatcher.EnsureDirs(); err != nil {
 		log.Printf("Failed to create notification dirs: %v", err)
 	} else {
@@ -385,21 +745,78 @@ func initCoordinator() {
 		log.Printf("Notification watcher ready (%s)", notifDir)
 	}
 
+	// Routing rules fan submitted notifications out to external sinks
+	// (webhook, email, ntfy, FCM) in addition to the local PWA.
+	coordinator.SetRouteStore(notifications.NewRouteStore(dataDir, notifications.SinkConfig{
+		WebhookSecret: cfg.NotifWebhookSecret,
+		SMTPRelay:     cfg.NotifSMTPRelay,
+		SMTPFrom:      cfg.NotifSMTPFrom,
+		SMTPUser:      cfg.NotifSMTPUser,
+		SMTPPassword:  cfg.NotifSMTPPassword,
+		FCMServerKey:  cfg.NotifFCMServerKey,
+	}))
+
 	// Initialize talk-mode agent (uses the same llama-server as LLM cleanup)
 	toolsDir := filepath.Join(dataDir, "tools")
 	if err := agent.EnsureDefaultTools(toolsDir); err != nil {
 		log.Printf("Failed to create default tools: %v", err)
 	}
-	talkAgent, err := agent.NewAgent("http://127.0.0.1:8179", toolsDir)
+
+	searchProviderSpecs := make([]agent.ProviderSpec, len(cfg.SearchProviders))
+	for i, spec := range cfg.SearchProviders {
+		searchProviderSpecs[i] = agent.ProviderSpec{
+			Name:            spec.Name,
+			Type:            spec.Type,
+			BaseURL:         spec.BaseURL,
+			APIKey:          spec.APIKey,
+			RateLimitPerMin: spec.RateLimitPerMin,
+		}
+	}
+	searchProviders, err := agent.LoadSearchProviders(filepath.Join(dataDir, "searchproviders"), searchProviderSpecs)
+	if err != nil {
+		log.Printf("Failed to load search providers: %v", err)
+	}
+	agent.ConfigureSearchProviders(searchProviders)
+
+	agent.ConfigureFetch(agent.FetchConfig{
+		AllowHosts: cfg.WebFetch.AllowHosts,
+		DenyHosts:  cfg.WebFetch.DenyHosts,
+		MaxBytes:   cfg.WebFetch.MaxBytes,
+		CacheDir:   filepath.Join(dataDir, "fetchcache"),
+	})
+
+	toolProviderSpecs := make([]agent.ToolProviderSpec, 0, len(cfg.AgentToolProviders))
+	for name, spec := range cfg.AgentToolProviders {
+		toolProviderSpecs = append(toolProviderSpecs, agent.ToolProviderSpec{
+			Name:    name,
+			Command: spec.Command,
+			Args:    spec.Args,
+		})
+	}
+
+	talkAgent, err = agent.NewAgent("http://127.0.0.1:8179", toolsDir, cfg.AgentToolsSafeMode, toolProviderSpecs)
 	if err != nil {
 		log.Printf("Talk agent not available: %v", err)
 	} else {
+		talkAgent.SetRetryLimit(cfg.AgentRetryLimit)
 		coordinator.SetAgentFunc(func(rawText string, onProgress func(string, string)) (string, error) {
 			return talkAgent.RunWithProgress(rawText, agent.ProgressFunc(onProgress))
 		})
+		coordinator.SetAgentToolReloadFunc(talkAgent.ReloadTools)
+		if stop, err := talkAgent.WatchTools(); err != nil {
+			log.Printf("Talk agent tools watcher not available: %v", err)
+		} else {
+			stopAgentToolsWatch = stop
+		}
 		log.Printf("Talk agent ready")
 	}
 
+	if advertiser, err := coordinator.Advertise(cfg.Port, cfg.Name); err != nil {
+		log.Printf("mDNS advertise failed (clients will need a coordinator URL): %v", err)
+	} else {
+		defer advertiser.Stop()
+	}
+
 	// Start coordinator HTTP server (blocks)
 	if err := coordinator.Start(cfg.Port); err != nil {
 		log.Printf("Coordinator failed to start: %v", err)
@@ -407,6 +824,21 @@ func initCoordinator() {
 }
 
 func onExit() {
+	if stopConfigWatch != nil {
+		stopConfigWatch()
+	}
+	if stopLLMProfilesWatch != nil {
+		stopLLMProfilesWatch()
+	}
+	if stopLLMPluginsWatch != nil {
+		stopLLMPluginsWatch()
+	}
+	if stopAgentToolsWatch != nil {
+		stopAgentToolsWatch()
+	}
+	if desktopSink != nil {
+		desktopSink.Close()
+	}
 	if echoClient != nil {
 		echoClient.Close()
 	}
@@ -416,9 +848,17 @@ func onExit() {
 	if llmEngine != nil {
 		llmEngine.Close()
 	}
-	if ttsEngine != nil {
-		ttsEngine.Close()
+	if llmRegistry != nil {
+		llmRegistry.Close()
+	}
+	if talkAgent != nil {
+		talkAgent.Close()
+	}
+	ttsEngineCacheMu.Lock()
+	for _, eng := range ttsEngineCache {
+		eng.Close()
 	}
+	ttsEngineCacheMu.Unlock()
 }
 
 // killExisting terminates other running VoiceRelay processes (not ourselves).